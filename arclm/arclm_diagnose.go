@@ -0,0 +1,76 @@
+package arclm
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/yofu/st/matrix"
+)
+
+// SuspiciousPivot is a small pivot of the global stiffness mapped to its NODE and DOF.
+type SuspiciousPivot struct {
+	Node *Node
+	Dof  int
+	matrix.Pivot
+}
+
+func (sp SuspiciousPivot) String() string {
+	return fmt.Sprintf("NODE %d[%d] D=%12.5E K=%12.5E RATIO=%12.5E", sp.Node.Num, sp.Dof, sp.Value, sp.Diagonal, sp.Ratio)
+}
+
+// DOFNode returns the NODE and DOF corresponding to the index of the global matrix without constrained DOFs.
+func (frame *Frame) DOFNode(index int) (*Node, int) {
+	for _, n := range frame.Nodes {
+		for i := 0; i < 6; i++ {
+			if n.Conf[i] {
+				continue
+			}
+			if index == 0 {
+				return n, i
+			}
+			index--
+		}
+	}
+	return nil, -1
+}
+
+// Diagnose factorizes the elastic global stiffness and reports pivots smaller than tol times the original diagonal.
+func (frame *Frame) Diagnose(tol float64) (*matrix.Diagnosis, []SuspiciousPivot, error) {
+	gmtx, gvct, err := frame.KE(1.0)
+	if err != nil {
+		return nil, nil, err
+	}
+	csize, conf, _ := frame.AssemConf(gvct, 1.0)
+	mtx := gmtx.ToLLS(csize, conf)
+	d, err := mtx.Diagnose(nil, tol)
+	if err != nil {
+		return nil, nil, err
+	}
+	sps := make([]SuspiciousPivot, 0, len(d.Pivots))
+	for _, p := range d.Pivots {
+		n, dof := frame.DOFNode(p.Index)
+		if n == nil {
+			continue
+		}
+		sps = append(sps, SuspiciousPivot{n, dof, p})
+	}
+	return d, sps, nil
+}
+
+func DiagnosisReport(d *matrix.Diagnosis, sps []SuspiciousPivot) string {
+	var rtn bytes.Buffer
+	rtn.WriteString(fmt.Sprintf("SIZE        : %d\n", d.Size))
+	rtn.WriteString(fmt.Sprintf("PIVOT       : +%d 0:%d -%d\n", d.Positive, d.Zero, d.Negative))
+	rtn.WriteString(fmt.Sprintf("NORM        : %12.5E\n", d.Norm))
+	if math.IsInf(d.Condition, 1) {
+		rtn.WriteString("CONDITION   : SINGULAR\n")
+	} else {
+		rtn.WriteString(fmt.Sprintf("CONDITION   : %12.5E\n", d.Condition))
+	}
+	for _, sp := range sps {
+		rtn.WriteString(sp.String())
+		rtn.WriteString("\n")
+	}
+	return rtn.String()
+}
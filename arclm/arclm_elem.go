@@ -589,24 +589,12 @@ func (elem *Elem) Coefficients(estiff [][]float64) ([]float64, [][]float64, [][]
 }
 
 func (elem *Elem) TransMatrix() ([][]float64, error) {
-	t := make([][]float64, 12)
-	vecs := make([][]float64, 3)
 	err := elem.SetPrincipalAxis()
 	if err != nil {
 		return nil, err
 	}
-	vecs[0] = elem.Direction(true)
-	vecs[1] = elem.Strong
-	vecs[2] = elem.Weak
-	for n := 0; n < 4; n++ {
-		for i := 0; i < 3; i++ {
-			t[3*n+i] = make([]float64, 12)
-			for j := 0; j < 3; j++ {
-				t[3*n+i][3*n+j] = vecs[i][j]
-			}
-		}
-	}
-	return t, nil
+	r := matrix.NewDenseFrom([][]float64{elem.Direction(true), elem.Strong, elem.Weak})
+	return matrix.BlockDiag(r, 4).Slices(), nil
 }
 
 func (elem *Elem) StiffMatrix() ([][]float64, error) {
@@ -863,45 +851,29 @@ func (elem *Elem) GeoStiffMatrix() ([][]float64, error) {
 	return estiff, nil
 }
 
+// ModifyHinge condenses DOFs with bonds, which are rotational springs if their values are not zero.
 func (elem *Elem) ModifyHinge(estiff [][]float64) ([][]float64, error) {
-	h := make([][]float64, 12)
-	rtn := make([][]float64, 12)
+	released := make([]int, 0, 12)
 	for i := 0; i < 12; i++ {
-		h[i] = make([]float64, 12)
-		rtn[i] = make([]float64, 12)
-		for j := 0; j < 12; j++ {
-			rtn[i][j] = estiff[i][j]
+		// if elem.Bonds[i] != Rigid {
+		if elem.Bonds[i].Num != 0 {
+			released = append(released, i)
 		}
 	}
-	for n := 0; n < 2; n++ {
-		for i := 0; i < 6; i++ {
-			// if elem.Bonds[6*n+i] != Rigid {
-			if elem.Bonds[6*n+i].Num != 0 {
-				kk := 6*n + i
-				if rtn[kk][kk] == 0.0 {
-					return nil, errors.New(fmt.Sprintf("Modifyhinge: ELEM %d: Matrix Singular", elem.Num))
-				}
-				l := elem.Length()
-				k1 := (elem.Bonds[6*n+i].Value[1] * l) / (4.0 * elem.Sect.E * elem.Sect.Value[1])
-				k2 := (elem.Bonds[6*n+i].Value[2] * l) / (4.0 * elem.Sect.E * elem.Sect.Value[2])
-				for ii := 0; ii < 12; ii++ {
-					for jj := 0; jj < 12; jj++ {
-						if ii == 2 || ii == 4 || ii == 8 || ii == 10 {
-							h[ii][jj] = -rtn[ii][kk] / rtn[kk][kk] * rtn[kk][jj] * 1.0 / (k1 + 1.0)
-						} else {
-							h[ii][jj] = -rtn[ii][kk] / rtn[kk][kk] * rtn[kk][jj] * 1.0 / (k2 + 1.0)
-						}
-					}
-				}
-				for ii := 0; ii < 12; ii++ {
-					for jj := 0; jj < 12; jj++ {
-						rtn[ii][jj] += h[ii][jj]
-					}
-				}
-			}
+	l := elem.Length()
+	rtn, err := matrix.NewDenseFrom(estiff).Condense(released, func(ii, kk int) float64 {
+		var k float64
+		if ii == 2 || ii == 4 || ii == 8 || ii == 10 {
+			k = (elem.Bonds[kk].Value[1] * l) / (4.0 * elem.Sect.E * elem.Sect.Value[1])
+		} else {
+			k = (elem.Bonds[kk].Value[2] * l) / (4.0 * elem.Sect.E * elem.Sect.Value[2])
 		}
+		return 1.0 / (k + 1.0)
+	})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Modifyhinge: ELEM %d: Matrix Singular", elem.Num))
 	}
-	return rtn, nil
+	return rtn.Slices(), nil
 }
 
 // TODO: rotational spring
@@ -966,8 +938,7 @@ func (elem *Elem) AssemCMQ(tmatrix [][]float64, vec []float64, safety float64) [
 	for i := 0; i < len(vec); i++ {
 		rtn[i] = vec[i]
 	}
-	tt := matrix.MatrixTranspose(tmatrix)
-	load := matrix.MatrixVector(tt, elem.Cmq)
+	load := matrix.NewDenseFrom(tmatrix).TMulV(elem.Cmq)
 	for i := 0; i < 2; i++ {
 		for j := 0; j < 6; j++ {
 			if !elem.Enod[i].Conf[j] {
@@ -984,8 +955,7 @@ func (elem *Elem) ModifyTrueForce(tmatrix [][]float64, vec []float64) []float64
 	for i := 0; i < len(vec); i++ {
 		rtn[i] = vec[i]
 	}
-	tt := matrix.MatrixTranspose(tmatrix)
-	tforce := matrix.MatrixVector(tt, elem.Stress)
+	tforce := matrix.NewDenseFrom(tmatrix).TMulV(elem.Stress)
 	for i := 0; i < 2; i++ {
 		for j := 0; j < 6; j++ {
 			if !elem.Enod[i].Conf[j] {
@@ -997,11 +967,9 @@ func (elem *Elem) ModifyTrueForce(tmatrix [][]float64, vec []float64) []float64
 	return rtn
 }
 
+// Transformation returns T^T K T.
 func Transformation(estiff, tmatrix [][]float64) [][]float64 {
-	e := matrix.MatrixMatrix(estiff, tmatrix)
-	tt := matrix.MatrixTranspose(tmatrix)
-	rtn := matrix.MatrixMatrix(tt, e)
-	return rtn
+	return matrix.NewDenseFrom(estiff).Congruence(matrix.NewDenseFrom(tmatrix)).Slices()
 }
 
 func (elem *Elem) ElemStress(gdisp []float64) ([]float64, error) {
@@ -1017,8 +985,8 @@ func (elem *Elem) ElemStress(gdisp []float64) ([]float64, error) {
 	if err != nil {
 		return nil, err
 	}
-	edisp := matrix.MatrixVector(tmatrix, gdisp)
-	estress := matrix.MatrixVector(estiff, edisp)
+	edisp := matrix.NewDenseFrom(tmatrix).MulV(gdisp)
+	estress := matrix.NewDenseFrom(estiff).MulV(edisp)
 	for i := 0; i < 12; i++ {
 		elem.Stress[i] += estress[i]
 	}
@@ -1055,8 +1023,8 @@ func (elem *Elem) StainEnergy(gdisp []float64) (float64, error) {
 	if err != nil {
 		return 0.0, err
 	}
-	edisp := matrix.MatrixVector(tmatrix, gdisp)
-	estress := matrix.MatrixVector(estiff, edisp)
+	edisp := matrix.NewDenseFrom(tmatrix).MulV(gdisp)
+	estress := matrix.NewDenseFrom(estiff).MulV(edisp)
 	Ee := Dot(edisp, estress, 12)
 	elem.Energy += Ee
 	return Ee, nil
@@ -1083,9 +1051,9 @@ func (elem *Elem) BucklingEnergy(gdisp []float64) (float64, error) {
 	if err != nil {
 		return 0.0, err
 	}
-	edisp := matrix.MatrixVector(tmatrix, gdisp)
-	estress := matrix.MatrixVector(estiff, edisp)
-	gstress := matrix.MatrixVector(gstiff, edisp)
+	edisp := matrix.NewDenseFrom(tmatrix).MulV(gdisp)
+	estress := matrix.NewDenseFrom(estiff).MulV(edisp)
+	gstress := matrix.NewDenseFrom(gstiff).MulV(edisp)
 	Ee := Dot(edisp, estress, 12)
 	Eb := Dot(edisp, gstress, 12)
 	elem.Energy += Ee
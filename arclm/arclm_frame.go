@@ -12,8 +12,10 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/yofu/st/matrix"
@@ -262,16 +264,8 @@ func (frame *Frame) CheckSingularNode(e error) error {
 	if err != nil {
 		return e
 	}
-	for _, n := range frame.Nodes {
-		for i := 0; i < 6; i++ {
-			if !n.Conf[i] {
-				num--
-			}
-			if num == 0 {
-				return fmt.Errorf("matrix singular at NODE %d[%d]\n", n.Num, i)
-				return nil
-			}
-		}
+	if n, i := frame.DOFNode(int(num)); n != nil {
+		return fmt.Errorf("matrix singular at NODE %d[%d]\n", n.Num, i)
 	}
 	return e
 }
@@ -293,44 +287,90 @@ func (frame *Frame) AssemGlobalVector(safety float64) (int, []bool, []float64, e
 }
 
 func (frame *Frame) AssemGlobalMatrix(matf func(*Elem) ([][]float64, error), vecf func(*Elem, [][]float64, []float64, float64) []float64, safety float64) (*matrix.COOMatrix, []float64, error) { // TODO: UNDER CONSTRUCTION
-	var err error
-	var tmatrix, stiff [][]float64
+	return frame.assemGlobalMatrix(matf, vecf, safety, runtime.GOMAXPROCS(-1))
+}
+
+// assemGlobalMatrix computes element matrices with nworker goroutines.
+// Each goroutine adds its entries to its own COOMatrix, and they are appended to the global one at the end,
+// so matf must not modify data shared between elements when nworker > 1.
+func (frame *Frame) assemGlobalMatrix(matf func(*Elem) ([][]float64, error), vecf func(*Elem, [][]float64, []float64, float64) []float64, safety float64, nworker int) (*matrix.COOMatrix, []float64, error) {
 	size := 6 * len(frame.Nodes)
-	gmtx := matrix.NewCOOMatrix(size)
-	gvct := make([]float64, size)
-	for _, el := range frame.Elems {
-		tmatrix, err = el.TransMatrix()
-		if err != nil {
-			return nil, nil, err
-		}
-		stiff, err = matf(el)
-		if err != nil {
-			return nil, nil, err
-		}
-		if stiff == nil {
-			continue
-		}
-		stiff, err = el.ModifyHinge(stiff)
-		if err != nil {
-			return nil, nil, err
-		}
-		stiff = Transformation(stiff, tmatrix)
-		for n1 := 0; n1 < 2; n1++ {
-			for i := 0; i < 6; i++ {
-				row := 6*el.Enod[n1].Index + i
-				for n2 := 0; n2 < 2; n2++ {
-					for j := 0; j < 6; j++ {
-						col := 6*el.Enod[n2].Index + j
-						val := stiff[6*n1+i][6*n2+j]
-						if val != 0.0 {
-							gmtx.Add(row, col, val)
+	nelem := len(frame.Elems)
+	if nworker < 1 {
+		nworker = 1
+	}
+	if nworker > nelem {
+		nworker = nelem
+	}
+	tmatrices := make([][][]float64, nelem)
+	errs := make([]error, nelem)
+	buffers := make([]*matrix.COOMatrix, nworker)
+	var wg sync.WaitGroup
+	for w := 0; w < nworker; w++ {
+		first := w * nelem / nworker
+		last := (w + 1) * nelem / nworker
+		buffers[w] = matrix.NewCOOMatrixCap(size, 144*(last-first))
+		wg.Add(1)
+		go func(buf *matrix.COOMatrix, first, last int) {
+			defer wg.Done()
+			for ind := first; ind < last; ind++ {
+				el := frame.Elems[ind]
+				tmatrix, err := el.TransMatrix()
+				if err != nil {
+					errs[ind] = err
+					return
+				}
+				tmatrices[ind] = tmatrix
+				stiff, err := matf(el)
+				if err != nil {
+					errs[ind] = err
+					return
+				}
+				if stiff == nil {
+					continue
+				}
+				stiff, err = el.ModifyHinge(stiff)
+				if err != nil {
+					errs[ind] = err
+					return
+				}
+				stiff = Transformation(stiff, tmatrix)
+				for n1 := 0; n1 < 2; n1++ {
+					for i := 0; i < 6; i++ {
+						row := 6*el.Enod[n1].Index + i
+						for n2 := 0; n2 < 2; n2++ {
+							for j := 0; j < 6; j++ {
+								col := 6*el.Enod[n2].Index + j
+								val := stiff[6*n1+i][6*n2+j]
+								if val != 0.0 {
+									buf.Add(row, col, val)
+								}
+							}
 						}
 					}
 				}
 			}
+		}(buffers[w], first, last)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, nil, err
 		}
+	}
+	nz := 0
+	for _, buf := range buffers {
+		nz += buf.Len()
+	}
+	gmtx := matrix.NewCOOMatrixCap(size, nz)
+	for _, buf := range buffers {
+		gmtx.Append(buf, 1.0)
+	}
+	gmtx.Compress()
+	gvct := make([]float64, size)
+	for ind, el := range frame.Elems {
 		el.ModifyCMQ()
-		gvct = vecf(el, tmatrix, gvct, safety)
+		gvct = vecf(el, tmatrices[ind], gvct, safety)
 	}
 	return gmtx, gvct, nil
 }
@@ -350,6 +390,45 @@ func (frame *Frame) AssemMassMatrix() *matrix.COOMatrix {
 	return mmtx
 }
 
+// DumpMatrix writes the global stiffness, mass and load vector in Matrix Market format.
+// Constrained DOFs are removed as they are in the solvers,
+// and each row is mapped to its NODE and DOF in the comments.
+func (frame *Frame) DumpMatrix(fn string, safety float64) ([]string, error) {
+	gmtx, gvct, err := frame.KE(safety)
+	if err != nil {
+		return nil, err
+	}
+	csize, conf, vec := frame.AssemConf(gvct, safety)
+	mmtx := frame.AssemMassMatrix()
+	comments := make([]string, 0, len(vec)+1)
+	comments = append(comments, fmt.Sprintf("NODES=%d ELEMS=%d SECTS=%d SIZE=%d", len(frame.Nodes), len(frame.Elems), len(frame.Sects), len(vec)))
+	ind := 0
+	for _, n := range frame.Nodes {
+		for i := 0; i < 6; i++ {
+			if n.Conf[i] {
+				continue
+			}
+			ind++
+			comments = append(comments, fmt.Sprintf("DOF %d NODE %d %d", ind, n.Num, i))
+		}
+	}
+	base := strings.TrimSuffix(fn, filepath.Ext(fn))
+	fns := []string{fmt.Sprintf("%s_K.mtx", base), fmt.Sprintf("%s_M.mtx", base), fmt.Sprintf("%s_P.mtx", base)}
+	err = gmtx.Reduce(csize, conf).SaveMtx(fns[0], true, comments...)
+	if err != nil {
+		return nil, err
+	}
+	err = mmtx.Reduce(csize, conf).SaveMtx(fns[1], true, comments[0])
+	if err != nil {
+		return nil, err
+	}
+	err = matrix.SaveMtxVector(fns[2], [][]float64{vec}, comments[0])
+	if err != nil {
+		return nil, err
+	}
+	return fns, nil
+}
+
 func (frame *Frame) KE(safety float64) (*matrix.COOMatrix, []float64, error) { // TODO: UNDER CONSTRUCTION
 	matf := func(elem *Elem) ([][]float64, error) {
 		return elem.StiffMatrix()
@@ -404,16 +483,7 @@ func (frame *Frame) KEKG(safety float64) (*matrix.COOMatrix, []float64, error) {
 		if err != nil {
 			return nil, err
 		}
-		stiff := make([][]float64, 12)
-		for i := 0; i < 12; i++ {
-			stiff[i] = make([]float64, 12)
-		}
-		for i := 0; i < 12; i++ {
-			for j := 0; j < 12; j++ {
-				stiff[i][j] = estiff[i][j] + gstiff[i][j]
-			}
-		}
-		return stiff, nil
+		return matrix.NewDenseFrom(estiff).AddDense(matrix.NewDenseFrom(gstiff), 1.0).Slices(), nil
 	}
 	vecf := func(elem *Elem, tmatrix [][]float64, gvct []float64, safety float64) []float64 {
 		if !elem.IsValid {
@@ -756,6 +826,12 @@ type AnalysisCondition struct {
 	start float64
 	max   float64
 	eps   float64
+
+	memory  int64
+	tempdir string
+
+	result  string
+	periods []string
 }
 
 func NewAnalysisCondition() *AnalysisCondition {
@@ -771,6 +847,8 @@ func NewAnalysisCondition() *AnalysisCondition {
 		start:       0.0,
 		max:         1.0,
 		eps:         1e-12,
+		memory:      matrix.DefaultMemoryBudget,
+		tempdir:     "",
 	}
 }
 
@@ -816,6 +894,30 @@ func (cond *AnalysisCondition) SetMax(m float64) {
 func (cond *AnalysisCondition) SetEps(e float64) {
 	cond.eps = e
 }
+func (cond *AnalysisCondition) SetMemory(m int64) {
+	cond.memory = m
+}
+func (cond *AnalysisCondition) SetTempDir(d string) {
+	cond.tempdir = d
+}
+// SetResult sets the binary result file where every lap is stored.
+// periods are the names of the load and the extra loads.
+func (cond *AnalysisCondition) SetResult(fn string, periods []string) {
+	cond.result = fn
+	cond.periods = periods
+}
+func (cond *AnalysisCondition) Result() string {
+	return cond.result
+}
+func (cond *AnalysisCondition) period(ind int) string {
+	if ind < len(cond.periods) {
+		return cond.periods[ind]
+	}
+	if ind == 0 {
+		return "L"
+	}
+	return fmt.Sprintf("%02d", ind)
+}
 func (cond *AnalysisCondition) SetPostprocess(f func(*Frame, [][]float64, []float64, []float64) (float64, bool)) {
 	cond.postprocess = f
 }
@@ -824,8 +926,14 @@ func (cond *AnalysisCondition) String() string {
 	var rtn bytes.Buffer
 	rtn.WriteString(fmt.Sprintf("INITIALIZE  : %t\n", cond.init))
 	rtn.WriteString(fmt.Sprintf("OUTPUT FILE : %v\n", cond.otp))
+	if cond.result != "" {
+		rtn.WriteString(fmt.Sprintf("RESULT FILE : %s\n", cond.result))
+	}
 	rtn.WriteString(fmt.Sprintf("SOLVER      : %s\n", cond.solver))
 	rtn.WriteString(fmt.Sprintf("EPS         : %.3E\n", cond.eps))
+	if cond.solver == "OOC" {
+		rtn.WriteString(fmt.Sprintf("MEMORY      : %dMB\n", cond.memory>>20))
+	}
 	rtn.WriteString(fmt.Sprintf("EXTRA LOAD  : %d\n", len(cond.extra)))
 	rtn.WriteString("NON-LINEAR\n")
 	rtn.WriteString(fmt.Sprintf("  GEOMETRY  : %t\n", cond.nlgeometry))
@@ -870,6 +978,8 @@ func (frame *Frame) StaticAnalysis(cancel context.CancelFunc, cond *AnalysisCond
 		solver = LLS_CG(cond.eps, laptime)
 	case "PCG":
 		solver = LLS_PCG(cond.eps, laptime)
+	case "OOC":
+		solver = OOC(frame, cond.memory, cond.tempdir, laptime)
 	}
 	var err error
 	var gmtx *matrix.COOMatrix
@@ -897,6 +1007,20 @@ func (frame *Frame) StaticAnalysis(cancel context.CancelFunc, cond *AnalysisCond
 		frame.WriteTo(w)
 		return nil
 	}
+	var result *ResultWriter
+	if cond.result != "" {
+		result, err = CreateResult(cond.result, frame)
+		if err != nil {
+			return err
+		}
+		defer result.Close()
+	}
+	store := func(ind int, l int) error {
+		if result == nil {
+			return nil
+		}
+		return result.WriteLap(cond.period(ind), l)
+	}
 	lap := 0
 	total := cond.start + cond.delta
 	for {
@@ -930,7 +1054,7 @@ func (frame *Frame) StaticAnalysis(cancel context.CancelFunc, cond *AnalysisCond
 			rnorm = math.Sqrt(Dot(vec, vec, len(vec)))
 		}
 		laptime("ASSEM")
-		if cond.extra != nil && len(cond.extra) > 1 {
+		if len(cond.extra) > 0 {
 			vecs := make([][]float64, len(cond.extra)+1)
 			vecs[0] = vec
 			for i := 0; i < len(cond.extra); i++ {
@@ -989,6 +1113,10 @@ func (frame *Frame) StaticAnalysis(cancel context.CancelFunc, cond *AnalysisCond
 				frame.UpdateForm(vec)
 				laptime(fmt.Sprintf("%04d / %04d: TOTAL = %.3f NORM = %.5E", lap+1, cond.nlap, total, rnorm/bnorm))
 				output(cond.otp, nans, lap+1, cond.nlap)
+				err = store(nans, lap+1)
+				if err != nil {
+					return err
+				}
 				frame.Lapch <- lap + 1
 				<-frame.Lapch
 			}
@@ -1022,6 +1150,10 @@ func (frame *Frame) StaticAnalysis(cancel context.CancelFunc, cond *AnalysisCond
 				frame.RestoreState(f0)
 			} else {
 				laptime(fmt.Sprintf("%04d / %04d: TOTAL = %.3f NORM = %.5E", lap+1, cond.nlap, total, rnorm/bnorm))
+				err = store(0, lap+1)
+				if err != nil {
+					return err
+				}
 				lap++
 				total += cond.delta
 			}
@@ -1053,6 +1185,14 @@ func (frame *Frame) Arclm101(cancel context.CancelFunc, cond *AnalysisCondition)
 	var gvct, vec []float64
 	var csize int
 	var conf []bool
+	var result *ResultWriter
+	if cond.result != "" {
+		result, err = CreateResult(cond.result, frame)
+		if err != nil {
+			return err
+		}
+		defer result.Close()
+	}
 	safety := 0.0
 	for lap := 0; lap < nlap; lap++ {
 		safety += dsafety
@@ -1087,6 +1227,12 @@ func (frame *Frame) Arclm101(cancel context.CancelFunc, cond *AnalysisCondition)
 		}
 		defer w.Close()
 		frame.WriteTo(w)
+		if result != nil {
+			err = result.WriteLap(cond.period(0), lap+1)
+			if err != nil {
+				return err
+			}
+		}
 		frame.Lapch <- lap + 1
 		<-frame.Lapch
 	}
@@ -1146,7 +1292,8 @@ func (frame *Frame) Arclm301(otp string, init bool, sects []int, eps float64) er
 		vecf := func(elem *Elem, tmatrix [][]float64, gvct []float64, safety float64) []float64 {
 			return elem.AssemCMQ(tmatrix, gvct, safety)
 		}
-		return frame.AssemGlobalMatrix(matf, vecf, safety)
+		// matf modifies Sect.Value shared by soil springs
+		return frame.assemGlobalMatrix(matf, vecf, safety, 1)
 	}
 	kpilestress := func(f *Frame, vec []float64, sects []int) ([][]float64, error) {
 		rtn := make([][]float64, len(f.Elems))
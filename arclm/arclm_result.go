@@ -0,0 +1,370 @@
+package arclm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// Binary result file
+//
+//	header : "ARCLMRB1" nnode nelem {node number} {elem number enod1 enod2}
+//	record : len(period) period lap {disp[6]} {reaction[6]} {stress[12]} {phinge[2]}
+//	index  : nrecord {len(period) period lap offset}
+//	trailer: offset of index, "ARCLMIDX"
+//
+// All integers are little endian int32 except offsets (int64).
+// Records are appended while analysis is running and the index is written by Close.
+// If the index is missing (e.g. analysis was killed), records are scanned from the head.
+const (
+	resultMagic = "ARCLMRB1"
+	indexMagic  = "ARCLMIDX"
+)
+
+var byteorder = binary.LittleEndian
+
+// LapResult is the state of a frame at a lap of a period.
+type LapResult struct {
+	Period   string
+	Lap      int
+	Nodes    []int
+	Disp     [][]float64
+	Reaction [][]float64
+	Elems    []int
+	Enod     [][]int
+	Stress   [][]float64
+	Phinge   [][]bool
+}
+
+type resultIndex struct {
+	period string
+	lap    int
+	offset int64
+}
+
+// ResultWriter appends laps of a frame to a binary result file.
+type ResultWriter struct {
+	w      io.WriteSeeker
+	frame  *Frame
+	offset int64
+	index  []resultIndex
+}
+
+// CreateResult creates a binary result file fn for frame.
+func CreateResult(fn string, frame *Frame) (*ResultWriter, error) {
+	w, err := os.Create(fn)
+	if err != nil {
+		return nil, err
+	}
+	rw, err := NewResultWriter(w, frame)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	return rw, nil
+}
+
+// NewResultWriter writes the header of a binary result file to w.
+func NewResultWriter(w io.WriteSeeker, frame *Frame) (*ResultWriter, error) {
+	var buf bytes.Buffer
+	buf.WriteString(resultMagic)
+	binary.Write(&buf, byteorder, int32(len(frame.Nodes)))
+	binary.Write(&buf, byteorder, int32(len(frame.Elems)))
+	for _, n := range frame.Nodes {
+		binary.Write(&buf, byteorder, int32(n.Num))
+	}
+	for _, el := range frame.Elems {
+		binary.Write(&buf, byteorder, []int32{int32(el.Num), int32(el.Enod[0].Num), int32(el.Enod[1].Num)})
+	}
+	size, err := buf.WriteTo(w)
+	if err != nil {
+		return nil, err
+	}
+	return &ResultWriter{
+		w:      w,
+		frame:  frame,
+		offset: size,
+		index:  make([]resultIndex, 0),
+	}, nil
+}
+
+// WriteLap appends the current displacements, reactions, stresses and hinge states of the frame as lap of period.
+func (rw *ResultWriter) WriteLap(period string, lap int) error {
+	if len(period) > math.MaxUint8 {
+		return fmt.Errorf("WriteLap: period name too long: %s", period)
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(byte(len(period)))
+	buf.WriteString(period)
+	binary.Write(&buf, byteorder, int32(lap))
+	for _, n := range rw.frame.Nodes {
+		binary.Write(&buf, byteorder, n.Disp[:6])
+		binary.Write(&buf, byteorder, n.Reaction[:6])
+	}
+	for _, el := range rw.frame.Elems {
+		binary.Write(&buf, byteorder, el.Stress[:12])
+		binary.Write(&buf, byteorder, el.Phinge[:2])
+	}
+	size, err := buf.WriteTo(rw.w)
+	if err != nil {
+		return err
+	}
+	rw.index = append(rw.index, resultIndex{period, lap, rw.offset})
+	rw.offset += size
+	return nil
+}
+
+// Close writes the index and closes the file.
+func (rw *ResultWriter) Close() error {
+	var buf bytes.Buffer
+	binary.Write(&buf, byteorder, int32(len(rw.index)))
+	for _, ind := range rw.index {
+		buf.WriteByte(byte(len(ind.period)))
+		buf.WriteString(ind.period)
+		binary.Write(&buf, byteorder, int32(ind.lap))
+		binary.Write(&buf, byteorder, ind.offset)
+	}
+	binary.Write(&buf, byteorder, rw.offset)
+	buf.WriteString(indexMagic)
+	_, err := buf.WriteTo(rw.w)
+	if c, ok := rw.w.(io.Closer); ok {
+		cerr := c.Close()
+		if err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// ResultReader gives random access to laps of a binary result file.
+type ResultReader struct {
+	r      io.ReaderAt
+	closer io.Closer
+	nodes  []int
+	elems  []int
+	enod   [][]int
+	size   int64
+	laps   map[string]map[int]int64
+}
+
+// OpenResult opens a binary result file fn.
+func OpenResult(fn string) (*ResultReader, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	rr, err := NewResultReader(f, stat.Size())
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("OpenResult: %s: %s", fn, err)
+	}
+	rr.closer = f
+	return rr, nil
+}
+
+// NewResultReader reads the header and the index of a binary result file of the given size.
+func NewResultReader(r io.ReaderAt, size int64) (*ResultReader, error) {
+	rr := &ResultReader{
+		r:    r,
+		size: size,
+		laps: make(map[string]map[int]int64),
+	}
+	sr := io.NewSectionReader(r, 0, size)
+	magic := make([]byte, len(resultMagic))
+	if _, err := io.ReadFull(sr, magic); err != nil || string(magic) != resultMagic {
+		return nil, errors.New("NewResultReader: not a binary result file")
+	}
+	var nnode, nelem int32
+	binary.Read(sr, byteorder, &nnode)
+	if err := binary.Read(sr, byteorder, &nelem); err != nil {
+		return nil, fmt.Errorf("NewResultReader: %s", err)
+	}
+	nums := make([]int32, nnode)
+	if err := binary.Read(sr, byteorder, nums); err != nil {
+		return nil, fmt.Errorf("NewResultReader: %s", err)
+	}
+	rr.nodes = make([]int, nnode)
+	for i, num := range nums {
+		rr.nodes[i] = int(num)
+	}
+	enums := make([]int32, 3*nelem)
+	if err := binary.Read(sr, byteorder, enums); err != nil {
+		return nil, fmt.Errorf("NewResultReader: %s", err)
+	}
+	rr.elems = make([]int, nelem)
+	rr.enod = make([][]int, nelem)
+	for i := 0; i < int(nelem); i++ {
+		rr.elems[i] = int(enums[3*i])
+		rr.enod[i] = []int{int(enums[3*i+1]), int(enums[3*i+2])}
+	}
+	head, _ := sr.Seek(0, io.SeekCurrent)
+	if err := rr.readIndex(head); err != nil {
+		rr.laps = make(map[string]map[int]int64)
+		return rr, rr.scan(head)
+	}
+	return rr, nil
+}
+
+func (rr *ResultReader) recordSize(period string) int64 {
+	return int64(1+len(period)+4) + int64(len(rr.nodes))*12*8 + int64(len(rr.elems))*(12*8+2)
+}
+
+func (rr *ResultReader) add(period string, lap int, offset int64) {
+	if _, ok := rr.laps[period]; !ok {
+		rr.laps[period] = make(map[int]int64)
+	}
+	rr.laps[period][lap] = offset
+}
+
+func (rr *ResultReader) readIndex(head int64) error {
+	if rr.size < head+8+int64(len(indexMagic)) {
+		return errors.New("no index")
+	}
+	trailer := make([]byte, 8+len(indexMagic))
+	if _, err := rr.r.ReadAt(trailer, rr.size-int64(len(trailer))); err != nil {
+		return err
+	}
+	if string(trailer[8:]) != indexMagic {
+		return errors.New("no index")
+	}
+	offset := int64(byteorder.Uint64(trailer[:8]))
+	if offset < head || offset > rr.size {
+		return errors.New("broken index")
+	}
+	sr := io.NewSectionReader(rr.r, offset, rr.size-offset)
+	var num int32
+	if err := binary.Read(sr, byteorder, &num); err != nil {
+		return err
+	}
+	for i := 0; i < int(num); i++ {
+		period, lap, err := readLapHeader(sr)
+		if err != nil {
+			return err
+		}
+		var off int64
+		if err := binary.Read(sr, byteorder, &off); err != nil {
+			return err
+		}
+		rr.add(period, lap, off)
+	}
+	return nil
+}
+
+// scan rebuilds the index from records. An incomplete last record is ignored.
+func (rr *ResultReader) scan(head int64) error {
+	offset := head
+	for offset < rr.size {
+		sr := io.NewSectionReader(rr.r, offset, rr.size-offset)
+		period, lap, err := readLapHeader(sr)
+		if err != nil {
+			break
+		}
+		next := offset + rr.recordSize(period)
+		if next > rr.size {
+			break
+		}
+		rr.add(period, lap, offset)
+		offset = next
+	}
+	if len(rr.laps) == 0 {
+		return errors.New("NewResultReader: no lap found")
+	}
+	return nil
+}
+
+func readLapHeader(r io.Reader) (string, int, error) {
+	var l uint8
+	if err := binary.Read(r, byteorder, &l); err != nil {
+		return "", 0, err
+	}
+	name := make([]byte, l)
+	if _, err := io.ReadFull(r, name); err != nil {
+		return "", 0, err
+	}
+	var lap int32
+	if err := binary.Read(r, byteorder, &lap); err != nil {
+		return "", 0, err
+	}
+	return string(name), int(lap), nil
+}
+
+// Periods returns the names of stored periods in alphabetical order.
+func (rr *ResultReader) Periods() []string {
+	rtn := make([]string, 0, len(rr.laps))
+	for per := range rr.laps {
+		rtn = append(rtn, per)
+	}
+	sort.Strings(rtn)
+	return rtn
+}
+
+// Nlap returns the largest lap number stored for period.
+func (rr *ResultReader) Nlap(period string) int {
+	rtn := 0
+	for lap := range rr.laps[period] {
+		if lap > rtn {
+			rtn = lap
+		}
+	}
+	return rtn
+}
+
+// Lap reads lap of period.
+func (rr *ResultReader) Lap(period string, lap int) (*LapResult, error) {
+	offset, ok := rr.laps[period][lap]
+	if !ok {
+		return nil, fmt.Errorf("Lap: %s@%d not found", period, lap)
+	}
+	size := rr.recordSize(period)
+	buf := make([]byte, size)
+	if _, err := rr.r.ReadAt(buf, offset); err != nil {
+		return nil, fmt.Errorf("Lap: %s@%d: %s", period, lap, err)
+	}
+	r := bytes.NewReader(buf[1+len(period)+4:])
+	nnode := len(rr.nodes)
+	nelem := len(rr.elems)
+	rtn := &LapResult{
+		Period:   period,
+		Lap:      lap,
+		Nodes:    rr.nodes,
+		Disp:     make([][]float64, nnode),
+		Reaction: make([][]float64, nnode),
+		Elems:    rr.elems,
+		Enod:     rr.enod,
+		Stress:   make([][]float64, nelem),
+		Phinge:   make([][]bool, nelem),
+	}
+	for i := 0; i < nnode; i++ {
+		rtn.Disp[i] = make([]float64, 6)
+		rtn.Reaction[i] = make([]float64, 6)
+		binary.Read(r, byteorder, rtn.Disp[i])
+		binary.Read(r, byteorder, rtn.Reaction[i])
+	}
+	for i := 0; i < nelem; i++ {
+		rtn.Stress[i] = make([]float64, 12)
+		rtn.Phinge[i] = make([]bool, 2)
+		binary.Read(r, byteorder, rtn.Stress[i])
+		if err := binary.Read(r, byteorder, rtn.Phinge[i]); err != nil {
+			return nil, fmt.Errorf("Lap: %s@%d: %s", period, lap, err)
+		}
+	}
+	return rtn, nil
+}
+
+// Close closes the underlying file.
+func (rr *ResultReader) Close() error {
+	if rr.closer != nil {
+		return rr.closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package arclm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testResultFrame returns a frame with two nodes (101, 102) and an element (201).
+func testResultFrame() *Frame {
+	frame := NewFrame()
+	frame.Nodes = make([]*Node, 2)
+	for i := range frame.Nodes {
+		n := NewNode()
+		n.Num = 101 + i
+		n.Index = i
+		frame.Nodes[i] = n
+	}
+	el := NewElem()
+	el.Num = 201
+	el.Enod[0] = frame.Nodes[0]
+	el.Enod[1] = frame.Nodes[1]
+	frame.Elems = []*Elem{el}
+	return frame
+}
+
+// setLap fills the state of frame with values depending on seed.
+func setLap(frame *Frame, seed float64) {
+	for i, n := range frame.Nodes {
+		for j := 0; j < 6; j++ {
+			n.Disp[j] = seed + float64(10*i+j)
+			n.Reaction[j] = -seed - float64(10*i+j)
+		}
+	}
+	for _, el := range frame.Elems {
+		for j := 0; j < 12; j++ {
+			el.Stress[j] = seed * float64(j+1)
+		}
+		el.Phinge[0] = seed > 1.5
+		el.Phinge[1] = !el.Phinge[0]
+	}
+}
+
+func checkLap(t *testing.T, lr *LapResult, seed float64) {
+	t.Helper()
+	for i := range lr.Nodes {
+		for j := 0; j < 6; j++ {
+			if want := seed + float64(10*i+j); lr.Disp[i][j] != want {
+				t.Errorf("%s@%d: disp[%d][%d] = %g, want %g", lr.Period, lr.Lap, i, j, lr.Disp[i][j], want)
+			}
+			if want := -seed - float64(10*i+j); lr.Reaction[i][j] != want {
+				t.Errorf("%s@%d: reaction[%d][%d] = %g, want %g", lr.Period, lr.Lap, i, j, lr.Reaction[i][j], want)
+			}
+		}
+	}
+	for i := range lr.Elems {
+		for j := 0; j < 12; j++ {
+			if want := seed * float64(j+1); lr.Stress[i][j] != want {
+				t.Errorf("%s@%d: stress[%d][%d] = %g, want %g", lr.Period, lr.Lap, i, j, lr.Stress[i][j], want)
+			}
+		}
+		if lr.Phinge[i][0] != (seed > 1.5) || lr.Phinge[i][1] == lr.Phinge[i][0] {
+			t.Errorf("%s@%d: phinge[%d] = %v", lr.Period, lr.Lap, i, lr.Phinge[i])
+		}
+	}
+}
+
+var testLaps = []struct {
+	period string
+	lap    int
+	seed   float64
+}{
+	{"L", 1, 1.0},
+	{"X", 1, 2.0},
+	{"X", 2, 3.0},
+	{"SNOW", 1, 0.5},
+}
+
+func writeTestLaps(t *testing.T, rw *ResultWriter, frame *Frame) {
+	t.Helper()
+	for _, l := range testLaps {
+		setLap(frame, l.seed)
+		if err := rw.WriteLap(l.period, l.lap); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func checkResult(t *testing.T, rr *ResultReader) {
+	t.Helper()
+	pers := rr.Periods()
+	if len(pers) != 3 || pers[0] != "L" || pers[1] != "SNOW" || pers[2] != "X" {
+		t.Errorf("periods = %v", pers)
+	}
+	if n := rr.Nlap("X"); n != 2 {
+		t.Errorf("nlap X = %d, want 2", n)
+	}
+	for _, l := range testLaps {
+		lr, err := rr.Lap(l.period, l.lap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lr.Nodes[0] != 101 || lr.Nodes[1] != 102 || lr.Elems[0] != 201 || lr.Enod[0][0] != 101 || lr.Enod[0][1] != 102 {
+			t.Errorf("numbers: nodes %v elems %v enod %v", lr.Nodes, lr.Elems, lr.Enod)
+		}
+		checkLap(t, lr, l.seed)
+	}
+	if _, err := rr.Lap("Y", 1); err == nil {
+		t.Error("Y@1 is found")
+	}
+}
+
+func TestResultRoundTrip(t *testing.T) {
+	frame := testResultFrame()
+	fn := filepath.Join(t.TempDir(), "test.otb")
+	rw, err := CreateResult(fn, frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestLaps(t, rw, frame)
+	if err := rw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	rr, err := OpenResult(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rr.Close()
+	checkResult(t, rr)
+}
+
+func TestResultWithoutIndex(t *testing.T) {
+	frame := testResultFrame()
+	fn := filepath.Join(t.TempDir(), "test.otb")
+	w, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw, err := NewResultWriter(w, frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestLaps(t, rw, frame)
+	// killed while writing the next lap: no index and a broken record
+	w.Write([]byte{1, 'Y', 1, 0, 0, 0, 0})
+	w.Close()
+	rr, err := OpenResult(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rr.Close()
+	checkResult(t, rr)
+}
+
+func TestResultNotResult(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "test.otb")
+	if err := os.WriteFile(fn, []byte("NOT A RESULT FILE"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if rr, err := OpenResult(fn); err == nil {
+		rr.Close()
+		t.Error("no error")
+	}
+}
@@ -1,6 +1,7 @@
 package arclm
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/yofu/st/matrix"
@@ -100,3 +101,29 @@ func LLS_PCG(eps float64, laptime func(string)) Solver {
 		},
 	}
 }
+
+// OOC solves with out-of-core skyline matrix, which keeps at most budget bytes of the factor in RAM.
+func OOC(frame *Frame, budget int64, dir string, laptime func(string)) Solver {
+	return Solver{
+		name: "OOC",
+		solve: func(gmtx *matrix.COOMatrix, csize int, conf []bool, vecs ...[]float64) ([][]float64, error) {
+			mtx, err := matrix.NewOOCMatrix(gmtx, csize, conf, budget, dir)
+			if err != nil {
+				return nil, err
+			}
+			defer mtx.Close()
+			laptime(fmt.Sprintf("ToOOC: PROFILE=%d PANELS=%d", mtx.ProfileSize(), mtx.Panels()))
+			err = mtx.LDLT(frame.Pivot)
+			if err != nil {
+				return nil, err
+			}
+			laptime("LDLT")
+			answers, err := mtx.Solve(vecs...)
+			laptime("Solve")
+			if err != nil {
+				return nil, err
+			}
+			return answers, nil
+		},
+	}
+}
@@ -2742,8 +2742,21 @@ func extractarclm(stw *Window) {
 	var name string
 	var ok bool
 	saved := true
-	stw.frame.ExtractArclm("")
-	ans := stw.Yna("Extract Arclm", ".inl, .ihx, .ihyを保存しますか?", "別名で保存")
+	err = stw.frame.ExtractArclm("")
+	if err != nil {
+		stw.addHistory(err.Error())
+		return
+	}
+	pers, err := stw.frame.ArclmPeriods()
+	if err != nil {
+		stw.addHistory(err.Error())
+		return
+	}
+	exts := make([]string, 0)
+	for _, p := range pers {
+		exts = append(exts, p.Input)
+	}
+	ans := stw.Yna("Extract Arclm", fmt.Sprintf("%sを保存しますか?", strings.Join(exts, ", ")), "別名で保存")
 	switch ans {
 	default:
 		saved = false
@@ -2765,7 +2778,7 @@ func extractarclm(stw *Window) {
 		}
 	}
 	if saved {
-		for _, ext := range st.InputExt {
+		for _, ext := range exts {
 			fn := st.Ce(name, ext)
 			stw.addHistory(fmt.Sprintf("保存しました: %s", fn))
 		}
@@ -3548,7 +3561,7 @@ func reaction(stw *Window) {
 	sns := stw.SelectedNodes()
 	sort.Sort(st.NodeByNum{sns})
 	fn := st.Ce(stw.frame.Path, ".rct")
-	err = st.WriteReaction(fn, sns, d, stw.frame.Show.Unit[0])
+	err = st.WriteReaction(fn, sns, d, stw.frame.Show.Unit[0], stw.frame.ExtraPeriodNames()...)
 	if err != nil {
 		st.ErrorMessage(stw, err, st.ERROR)
 		stw.EscapeCB()
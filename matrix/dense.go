@@ -1,39 +1,523 @@
 package matrix
 
-func MatrixVector(mat [][]float64, vec []float64) []float64 {
-	size := len(mat)
-	rtn := make([]float64, size)
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Dense is a small dense matrix stored in row-major order.
+// It is intended for element-level computations such as 12x12 stiffness matrices.
+type Dense struct {
+	Rows int
+	Cols int
+	data []float64
+}
+
+func NewDense(rows, cols int) *Dense {
+	return &Dense{
+		Rows: rows,
+		Cols: cols,
+		data: make([]float64, rows*cols),
+	}
+}
+
+// NewDenseFrom copies m, which is assumed to be rectangular.
+func NewDenseFrom(m [][]float64) *Dense {
+	rows := len(m)
+	cols := 0
+	if rows > 0 {
+		cols = len(m[0])
+	}
+	rtn := NewDense(rows, cols)
+	for i := 0; i < rows; i++ {
+		copy(rtn.data[i*cols:(i+1)*cols], m[i])
+	}
+	return rtn
+}
+
+func Identity(size int) *Dense {
+	rtn := NewDense(size, size)
 	for i := 0; i < size; i++ {
-		rtn[i] = 0.0
-		for j := 0; j < size; j++ {
-			rtn[i] += mat[i][j] * vec[j]
+		rtn.data[i*size+i] = 1.0
+	}
+	return rtn
+}
+
+// BlockDiag returns a matrix with n copies of b on its diagonal.
+func BlockDiag(b *Dense, n int) *Dense {
+	rtn := NewDense(b.Rows*n, b.Cols*n)
+	for k := 0; k < n; k++ {
+		for i := 0; i < b.Rows; i++ {
+			for j := 0; j < b.Cols; j++ {
+				rtn.Set(k*b.Rows+i, k*b.Cols+j, b.At(i, j))
+			}
 		}
 	}
 	return rtn
 }
 
-func MatrixMatrix(a, b [][]float64) [][]float64 {
-	size := len(a)
-	rtn := make([][]float64, size)
-	for i := 0; i < size; i++ {
-		rtn[i] = make([]float64, size)
-		for j := 0; j < size; j++ {
-			for k := 0; k < size; k++ {
-				rtn[i][j] += a[i][k] * b[k][j]
+func (d *Dense) At(i, j int) float64 {
+	return d.data[i*d.Cols+j]
+}
+
+func (d *Dense) Set(i, j int, val float64) {
+	d.data[i*d.Cols+j] = val
+}
+
+func (d *Dense) Add(i, j int, val float64) {
+	d.data[i*d.Cols+j] += val
+}
+
+func (d *Dense) Row(i int) []float64 {
+	return d.data[i*d.Cols : (i+1)*d.Cols]
+}
+
+func (d *Dense) Copy() *Dense {
+	rtn := NewDense(d.Rows, d.Cols)
+	copy(rtn.data, d.data)
+	return rtn
+}
+
+// Slices returns a copy as [][]float64.
+func (d *Dense) Slices() [][]float64 {
+	rtn := make([][]float64, d.Rows)
+	for i := 0; i < d.Rows; i++ {
+		rtn[i] = make([]float64, d.Cols)
+		copy(rtn[i], d.Row(i))
+	}
+	return rtn
+}
+
+func (d *Dense) String() string {
+	var rtn bytes.Buffer
+	for i := 0; i < d.Rows; i++ {
+		for j := 0; j < d.Cols; j++ {
+			rtn.WriteString(fmt.Sprintf("%12.5E ", d.At(i, j)))
+		}
+		rtn.WriteString("\n")
+	}
+	return rtn.String()
+}
+
+func (d *Dense) T() *Dense {
+	rtn := NewDense(d.Cols, d.Rows)
+	for i := 0; i < d.Rows; i++ {
+		for j := 0; j < d.Cols; j++ {
+			rtn.data[j*d.Rows+i] = d.data[i*d.Cols+j]
+		}
+	}
+	return rtn
+}
+
+func (d *Dense) Scale(factor float64) *Dense {
+	rtn := d.Copy()
+	for i := range rtn.data {
+		rtn.data[i] *= factor
+	}
+	return rtn
+}
+
+// AddDense returns d + factor * b.
+func (d *Dense) AddDense(b *Dense, factor float64) *Dense {
+	rtn := d.Copy()
+	for i := range rtn.data {
+		rtn.data[i] += factor * b.data[i]
+	}
+	return rtn
+}
+
+func (d *Dense) Mul(b *Dense) *Dense {
+	rtn := NewDense(d.Rows, b.Cols)
+	for i := 0; i < d.Rows; i++ {
+		for k := 0; k < d.Cols; k++ {
+			a := d.data[i*d.Cols+k]
+			if a == 0.0 {
+				continue
+			}
+			for j := 0; j < b.Cols; j++ {
+				rtn.data[i*b.Cols+j] += a * b.data[k*b.Cols+j]
 			}
 		}
 	}
 	return rtn
 }
 
-func MatrixTranspose(m [][]float64) [][]float64 {
-	size := len(m)
-	rtn := make([][]float64, size)
-	for i := 0; i < size; i++ {
-		rtn[i] = make([]float64, size)
-		for j := 0; j < size; j++ {
-			rtn[i][j] = m[j][i]
+func (d *Dense) MulV(vec []float64) []float64 {
+	rtn := make([]float64, d.Rows)
+	for i := 0; i < d.Rows; i++ {
+		rtn[i] = Dot(d.Row(i), vec, d.Cols)
+	}
+	return rtn
+}
+
+// TMulV returns d^T vec.
+func (d *Dense) TMulV(vec []float64) []float64 {
+	rtn := make([]float64, d.Cols)
+	for i := 0; i < d.Rows; i++ {
+		if vec[i] == 0.0 {
+			continue
+		}
+		for j := 0; j < d.Cols; j++ {
+			rtn[j] += d.data[i*d.Cols+j] * vec[i]
+		}
+	}
+	return rtn
+}
+
+// Congruence returns t^T d t, which transforms a matrix in local coordinate into global one.
+func (d *Dense) Congruence(t *Dense) *Dense {
+	return t.T().Mul(d.Mul(t))
+}
+
+// Condense eliminates released DOFs by static condensation.
+// factor(i, k) scales the elimination of DOF k from row i, which is 1.0 for every row if factor is nil;
+// a value less than 1.0 leaves a part of the stiffness as a spring.
+// Rows and columns of DOFs released with nil factor are zero in the returned matrix of the same size.
+func (d *Dense) Condense(released []int, factor func(int, int) float64) (*Dense, error) {
+	rtn := d.Copy()
+	col := make([]float64, rtn.Rows)
+	row := make([]float64, rtn.Cols)
+	for _, k := range released {
+		kk := rtn.At(k, k)
+		if kk == 0.0 {
+			return nil, fmt.Errorf("Condense: matrix singular at %d", k)
+		}
+		for i := 0; i < rtn.Rows; i++ {
+			col[i] = rtn.At(i, k)
+		}
+		copy(row, rtn.Row(k))
+		for i := 0; i < rtn.Rows; i++ {
+			if col[i] == 0.0 {
+				continue
+			}
+			a := -col[i] / kk
+			if factor != nil {
+				a *= factor(i, k)
+			}
+			for j := 0; j < rtn.Cols; j++ {
+				rtn.Add(i, j, a*row[j])
+			}
+		}
+		if factor == nil {
+			for i := 0; i < rtn.Rows; i++ {
+				rtn.Set(i, k, 0.0)
+			}
+			for j := 0; j < rtn.Cols; j++ {
+				rtn.Set(k, j, 0.0)
+			}
+		}
+	}
+	return rtn, nil
+}
+
+// LU is LU decomposition with partial pivoting; PA = LU.
+type LU struct {
+	lu   *Dense
+	piv  []int
+	sign float64
+}
+
+func (d *Dense) LU() (*LU, error) {
+	if d.Rows != d.Cols {
+		return nil, fmt.Errorf("LU: not square: %dx%d", d.Rows, d.Cols)
+	}
+	n := d.Rows
+	lu := d.Copy()
+	piv := make([]int, n)
+	for i := 0; i < n; i++ {
+		piv[i] = i
+	}
+	sign := 1.0
+	for k := 0; k < n; k++ {
+		p := k
+		max := math.Abs(lu.At(k, k))
+		for i := k + 1; i < n; i++ {
+			if v := math.Abs(lu.At(i, k)); v > max {
+				max = v
+				p = i
+			}
+		}
+		if max == 0.0 {
+			return nil, fmt.Errorf("LU: matrix singular at %d", k)
+		}
+		if p != k {
+			rp := lu.Row(p)
+			rk := lu.Row(k)
+			for j := 0; j < n; j++ {
+				rp[j], rk[j] = rk[j], rp[j]
+			}
+			piv[p], piv[k] = piv[k], piv[p]
+			sign = -sign
+		}
+		w := 1.0 / lu.At(k, k)
+		for i := k + 1; i < n; i++ {
+			l := lu.At(i, k) * w
+			lu.Set(i, k, l)
+			if l == 0.0 {
+				continue
+			}
+			for j := k + 1; j < n; j++ {
+				lu.Add(i, j, -l*lu.At(k, j))
+			}
 		}
 	}
+	return &LU{lu, piv, sign}, nil
+}
+
+func (lu *LU) Solve(vec []float64) []float64 {
+	n := lu.lu.Rows
+	x := make([]float64, n)
+	for i := 0; i < n; i++ {
+		x[i] = vec[lu.piv[i]]
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < i; j++ {
+			x[i] -= lu.lu.At(i, j) * x[j]
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := i + 1; j < n; j++ {
+			x[i] -= lu.lu.At(i, j) * x[j]
+		}
+		x[i] /= lu.lu.At(i, i)
+	}
+	return x
+}
+
+func (lu *LU) Det() float64 {
+	rtn := lu.sign
+	for i := 0; i < lu.lu.Rows; i++ {
+		rtn *= lu.lu.At(i, i)
+	}
 	return rtn
 }
+
+// Det returns 0.0 if d is singular.
+func (d *Dense) Det() float64 {
+	lu, err := d.LU()
+	if err != nil {
+		return 0.0
+	}
+	return lu.Det()
+}
+
+func (d *Dense) Inverse() (*Dense, error) {
+	lu, err := d.LU()
+	if err != nil {
+		return nil, err
+	}
+	n := d.Rows
+	rtn := NewDense(n, n)
+	e := make([]float64, n)
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			e[i] = 0.0
+		}
+		e[j] = 1.0
+		x := lu.Solve(e)
+		for i := 0; i < n; i++ {
+			rtn.Set(i, j, x[i])
+		}
+	}
+	return rtn, nil
+}
+
+// Cholesky returns lower triangular L where d = L L^T.
+func (d *Dense) Cholesky() (*Dense, error) {
+	if d.Rows != d.Cols {
+		return nil, fmt.Errorf("Cholesky: not square: %dx%d", d.Rows, d.Cols)
+	}
+	n := d.Rows
+	l := NewDense(n, n)
+	for j := 0; j < n; j++ {
+		s := d.At(j, j)
+		for k := 0; k < j; k++ {
+			s -= l.At(j, k) * l.At(j, k)
+		}
+		if s <= 0.0 {
+			return nil, fmt.Errorf("Cholesky: not positive definite at %d", j)
+		}
+		ljj := math.Sqrt(s)
+		l.Set(j, j, ljj)
+		for i := j + 1; i < n; i++ {
+			s := d.At(i, j)
+			for k := 0; k < j; k++ {
+				s -= l.At(i, k) * l.At(j, k)
+			}
+			l.Set(i, j, s/ljj)
+		}
+	}
+	return l, nil
+}
+
+// CholeskySolve solves L L^T x = vec.
+func CholeskySolve(l *Dense, vec []float64) []float64 {
+	n := l.Rows
+	x := make([]float64, n)
+	copy(x, vec)
+	for i := 0; i < n; i++ {
+		for k := 0; k < i; k++ {
+			x[i] -= l.At(i, k) * x[k]
+		}
+		x[i] /= l.At(i, i)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for k := i + 1; k < n; k++ {
+			x[i] -= l.At(k, i) * x[k]
+		}
+		x[i] /= l.At(i, i)
+	}
+	return x
+}
+
+// QR returns thin QR decomposition of d (Rows >= Cols) by Householder reflections.
+// Q is Rows x Cols with orthonormal columns and R is Cols x Cols upper triangular.
+func (d *Dense) QR() (*Dense, *Dense, error) {
+	m := d.Rows
+	n := d.Cols
+	if m < n {
+		return nil, nil, fmt.Errorf("QR: rows < cols: %dx%d", m, n)
+	}
+	a := d.Copy()
+	vs := make([][]float64, n)
+	for k := 0; k < n; k++ {
+		v := make([]float64, m)
+		norm := 0.0
+		for i := k; i < m; i++ {
+			v[i] = a.At(i, k)
+			norm += v[i] * v[i]
+		}
+		norm = math.Sqrt(norm)
+		if norm == 0.0 {
+			continue
+		}
+		if v[k] > 0.0 {
+			norm = -norm
+		}
+		v[k] -= norm
+		vnorm := Dot(v, v, m)
+		if vnorm == 0.0 {
+			continue
+		}
+		for j := k; j < n; j++ {
+			s := 0.0
+			for i := k; i < m; i++ {
+				s += v[i] * a.At(i, j)
+			}
+			s *= 2.0 / vnorm
+			for i := k; i < m; i++ {
+				a.Add(i, j, -s*v[i])
+			}
+		}
+		for i := range v {
+			v[i] /= math.Sqrt(vnorm)
+		}
+		vs[k] = v
+	}
+	r := NewDense(n, n)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			r.Set(i, j, a.At(i, j))
+		}
+	}
+	q := NewDense(m, n)
+	for j := 0; j < n; j++ {
+		e := make([]float64, m)
+		e[j] = 1.0
+		for k := n - 1; k >= 0; k-- {
+			v := vs[k]
+			if v == nil {
+				continue
+			}
+			s := 2.0 * Dot(v, e, m)
+			for i := k; i < m; i++ {
+				e[i] -= s * v[i]
+			}
+		}
+		for i := 0; i < m; i++ {
+			q.Set(i, j, e[i])
+		}
+	}
+	return q, r, nil
+}
+
+// SymEigen computes eigenvalues and eigenvectors of symmetric d by cyclic Jacobi method.
+// Eigenvalues are sorted in ascending order and eigenvectors are the corresponding columns of the returned matrix.
+func (d *Dense) SymEigen(eps float64, maxiter int) ([]float64, *Dense, error) {
+	if d.Rows != d.Cols {
+		return nil, nil, fmt.Errorf("SymEigen: not square: %dx%d", d.Rows, d.Cols)
+	}
+	n := d.Rows
+	a := d.Copy()
+	v := Identity(n)
+	offdiag := func() float64 {
+		s := 0.0
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				s += a.At(i, j) * a.At(i, j)
+			}
+		}
+		return math.Sqrt(s)
+	}
+	scale := 0.0
+	for _, val := range a.data {
+		scale += val * val
+	}
+	scale = math.Sqrt(scale)
+	converged := n < 2 || scale == 0.0
+	for iter := 0; iter < maxiter && !converged; iter++ {
+		for p := 0; p < n-1; p++ {
+			for q := p + 1; q < n; q++ {
+				apq := a.At(p, q)
+				if apq == 0.0 {
+					continue
+				}
+				theta := (a.At(q, q) - a.At(p, p)) / (2.0 * apq)
+				t := 1.0 / (math.Abs(theta) + math.Sqrt(theta*theta+1.0))
+				if theta < 0.0 {
+					t = -t
+				}
+				c := 1.0 / math.Sqrt(t*t+1.0)
+				s := t * c
+				for k := 0; k < n; k++ {
+					akp := a.At(k, p)
+					akq := a.At(k, q)
+					a.Set(k, p, c*akp-s*akq)
+					a.Set(k, q, s*akp+c*akq)
+				}
+				for k := 0; k < n; k++ {
+					apk := a.At(p, k)
+					aqk := a.At(q, k)
+					a.Set(p, k, c*apk-s*aqk)
+					a.Set(q, k, s*apk+c*aqk)
+				}
+				for k := 0; k < n; k++ {
+					vkp := v.At(k, p)
+					vkq := v.At(k, q)
+					v.Set(k, p, c*vkp-s*vkq)
+					v.Set(k, q, s*vkp+c*vkq)
+				}
+			}
+		}
+		converged = offdiag() <= eps*scale
+	}
+	if !converged {
+		return nil, nil, fmt.Errorf("SymEigen: not converged in %d sweeps", maxiter)
+	}
+	order := make([]int, n)
+	for i := 0; i < n; i++ {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return a.At(order[i], order[i]) < a.At(order[j], order[j]) })
+	vals := make([]float64, n)
+	vecs := NewDense(n, n)
+	for i, o := range order {
+		vals[i] = a.At(o, o)
+		for k := 0; k < n; k++ {
+			vecs.Set(k, i, v.At(k, o))
+		}
+	}
+	return vals, vecs, nil
+}
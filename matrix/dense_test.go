@@ -0,0 +1,147 @@
+package matrix
+
+import (
+	"math"
+	"testing"
+)
+
+// testSPD returns a symmetric positive definite matrix.
+func testSPD() *Dense {
+	return NewDenseFrom([][]float64{
+		{4.0, 1.0, 0.0, 1.0},
+		{1.0, 5.0, 2.0, 0.0},
+		{0.0, 2.0, 6.0, 1.0},
+		{1.0, 0.0, 1.0, 3.0},
+	})
+}
+
+func assertDense(t *testing.T, name string, got, want *Dense, eps float64) {
+	t.Helper()
+	if got.Rows != want.Rows || got.Cols != want.Cols {
+		t.Fatalf("%s: size %dx%d, want %dx%d", name, got.Rows, got.Cols, want.Rows, want.Cols)
+	}
+	for i := 0; i < got.Rows; i++ {
+		for j := 0; j < got.Cols; j++ {
+			if math.Abs(got.At(i, j)-want.At(i, j)) > eps {
+				t.Errorf("%s: (%d, %d) = %g, want %g", name, i, j, got.At(i, j), want.At(i, j))
+			}
+		}
+	}
+}
+
+func TestDenseInverse(t *testing.T) {
+	a := NewDenseFrom([][]float64{
+		{0.0, 2.0, 1.0},
+		{1.0, 1.0, 0.0},
+		{3.0, 0.0, 1.0},
+	})
+	inv, err := a.Inverse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertDense(t, "A A^-1", a.Mul(inv), Identity(3), 1e-12)
+	assertDense(t, "A^-1 A", inv.Mul(a), Identity(3), 1e-12)
+	if d := a.Det(); math.Abs(d-(-5.0)) > 1e-12 {
+		t.Errorf("det = %g, want -5", d)
+	}
+	s := NewDenseFrom([][]float64{{1.0, 2.0}, {2.0, 4.0}})
+	if _, err := s.Inverse(); err == nil {
+		t.Error("singular matrix is inverted")
+	}
+	if d := s.Det(); d != 0.0 {
+		t.Errorf("det = %g, want 0", d)
+	}
+}
+
+func TestDenseCholesky(t *testing.T) {
+	a := testSPD()
+	l, err := a.Cholesky()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertDense(t, "L L^T", l.Mul(l.T()), a, 1e-12)
+	b := []float64{1.0, 2.0, 3.0, 4.0}
+	x := CholeskySolve(l, b)
+	ax := a.MulV(x)
+	for i := range b {
+		if math.Abs(ax[i]-b[i]) > 1e-12 {
+			t.Errorf("A x [%d] = %g, want %g", i, ax[i], b[i])
+		}
+	}
+	if _, err := NewDenseFrom([][]float64{{1.0, 2.0}, {2.0, 1.0}}).Cholesky(); err == nil {
+		t.Error("indefinite matrix is factorized")
+	}
+}
+
+func TestDenseQR(t *testing.T) {
+	a := NewDenseFrom([][]float64{
+		{12.0, -51.0, 4.0},
+		{6.0, 167.0, -68.0},
+		{-4.0, 24.0, -41.0},
+		{1.0, 2.0, 3.0},
+	})
+	q, r, err := a.QR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertDense(t, "Q R", q.Mul(r), a, 1e-10)
+	assertDense(t, "Q^T Q", q.T().Mul(q), Identity(3), 1e-12)
+	for i := 0; i < r.Rows; i++ {
+		for j := 0; j < i; j++ {
+			if r.At(i, j) != 0.0 {
+				t.Errorf("R (%d, %d) = %g, want 0", i, j, r.At(i, j))
+			}
+		}
+	}
+	if _, _, err := a.T().QR(); err == nil {
+		t.Error("QR of wide matrix")
+	}
+}
+
+func TestDenseSymEigen(t *testing.T) {
+	a := NewDenseFrom([][]float64{
+		{2.0, -1.0, 0.0},
+		{-1.0, 2.0, -1.0},
+		{0.0, -1.0, 2.0},
+	})
+	vals, vecs, err := a.SymEigen(1e-14, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{2.0 - math.Sqrt2, 2.0, 2.0 + math.Sqrt2}
+	for i := range want {
+		if math.Abs(vals[i]-want[i]) > 1e-12 {
+			t.Errorf("lambda[%d] = %g, want %g", i, vals[i], want[i])
+		}
+	}
+	d := NewDense(3, 3)
+	for i := range vals {
+		d.Set(i, i, vals[i])
+	}
+	assertDense(t, "V D V^T", vecs.Mul(d).Mul(vecs.T()), a, 1e-12)
+	assertDense(t, "V^T V", vecs.T().Mul(vecs), Identity(3), 1e-12)
+}
+
+func TestDenseCondense(t *testing.T) {
+	a := testSPD()
+	c, err := a.Condense([]int{3}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Schur complement: A11 - A13 A33^-1 A31
+	want := NewDense(4, 4)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			want.Set(i, j, a.At(i, j)-a.At(i, 3)*a.At(3, j)/a.At(3, 3))
+		}
+	}
+	assertDense(t, "condensed", c, want, 1e-12)
+	half, err := a.Condense([]int{3}, func(i, k int) float64 { return 0.5 })
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertDense(t, "half", half, a.AddDense(c, 1.0).Scale(0.5), 1e-12)
+	if _, err := NewDense(2, 2).Condense([]int{0}, nil); err == nil {
+		t.Error("zero pivot is condensed")
+	}
+}
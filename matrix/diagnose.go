@@ -0,0 +1,191 @@
+package matrix
+
+import (
+	"math"
+)
+
+// Pivot is a pivot of LDLT factorization which is small compared with the original diagonal.
+type Pivot struct {
+	Index    int
+	Value    float64
+	Diagonal float64
+	Ratio    float64
+}
+
+// Diagnosis is the result of LLSMatrix.Diagnose.
+// Condition is the estimate of 1-norm condition number; it is +Inf if any pivot is zero.
+type Diagnosis struct {
+	Size      int
+	Norm      float64
+	InvNorm   float64
+	Condition float64
+	Pivots    []Pivot
+	Zero      int
+	Positive  int
+	Negative  int
+}
+
+// Diagonal returns the diagonal elements.
+func (ll *LLSMatrix) Diagonal() []float64 {
+	rtn := make([]float64, ll.Size)
+	for i := 0; i < ll.Size; i++ {
+		rtn[i] = ll.diag[i].value
+	}
+	return rtn
+}
+
+// Norm1 returns 1-norm (maximum absolute column sum) of the symmetric matrix.
+// It must be called before factorization.
+func (ll *LLSMatrix) Norm1() float64 {
+	var n *LLSNode
+	sums := make([]float64, ll.Size)
+	for col := 0; col < ll.Size; col++ {
+		sums[col] += math.Abs(ll.diag[col].value)
+		for n = ll.diag[col].down; n != nil; n = n.down {
+			v := math.Abs(n.value)
+			sums[col] += v
+			sums[n.row] += v
+		}
+	}
+	rtn := 0.0
+	for _, v := range sums {
+		if v > rtn {
+			rtn = v
+		}
+	}
+	return rtn
+}
+
+// Substitute solves LDLT x = vec with the factorized matrix.
+// DiagUp must have been called.
+func (ll *LLSMatrix) Substitute(vec []float64) []float64 {
+	tmp := make([]float64, ll.Size)
+	copy(tmp, vec)
+	tmp = ll.FELower(tmp)
+	for i := 0; i < ll.Size; i++ {
+		tmp[i] /= ll.diag[i].value
+	}
+	return ll.BSUpper(tmp)
+}
+
+// InvNorm1Est estimates 1-norm of the inverse by Hager's algorithm with Higham's modification.
+// ll must be factorized and DiagUp must have been called.
+// As the matrix is symmetric, solving with its transpose is the same as solving with itself.
+func (ll *LLSMatrix) InvNorm1Est(maxiter int) float64 {
+	size := ll.Size
+	if size == 0 {
+		return 0.0
+	}
+	norm1 := func(vec []float64) float64 {
+		rtn := 0.0
+		for _, v := range vec {
+			rtn += math.Abs(v)
+		}
+		return rtn
+	}
+	x := make([]float64, size)
+	for i := 0; i < size; i++ {
+		x[i] = 1.0 / float64(size)
+	}
+	est := 0.0
+	last := -1
+	for iter := 0; iter < maxiter; iter++ {
+		y := ll.Substitute(x)
+		est = norm1(y)
+		xi := make([]float64, size)
+		for i, v := range y {
+			if v >= 0.0 {
+				xi[i] = 1.0
+			} else {
+				xi[i] = -1.0
+			}
+		}
+		z := ll.Substitute(xi)
+		zmax := 0.0
+		jmax := 0
+		for i, v := range z {
+			if math.Abs(v) > zmax {
+				zmax = math.Abs(v)
+				jmax = i
+			}
+		}
+		if zmax <= Dot(z, x, size) || jmax == last {
+			break
+		}
+		for i := 0; i < size; i++ {
+			x[i] = 0.0
+		}
+		x[jmax] = 1.0
+		last = jmax
+	}
+	b := make([]float64, size)
+	sign := 1.0
+	for i := 0; i < size; i++ {
+		if size > 1 {
+			b[i] = sign * (1.0 + float64(i)/float64(size-1))
+		} else {
+			b[i] = 1.0
+		}
+		sign = -sign
+	}
+	alt := 2.0 * norm1(ll.Substitute(b)) / (3.0 * float64(size))
+	if alt > est {
+		return alt
+	}
+	return est
+}
+
+// Diagnose factorizes ll in place, and reports pivots whose ratio to the original diagonal is less than tol,
+// the number of positive, zero and negative pivots, and the estimate of the condition number.
+// Unlike LDLT, it doesn't stop at zero pivot; it is replaced by the original diagonal
+// (or 1.0 if the diagonal is also zero) so that all the singular DOFs are found at once.
+func (ll *LLSMatrix) Diagnose(ch chan int, tol float64) (*Diagnosis, error) {
+	rtn := &Diagnosis{
+		Size:   ll.Size,
+		Norm:   ll.Norm1(),
+		Pivots: make([]Pivot, 0),
+	}
+	diag := ll.Diagonal()
+	_, err := ll.ldlt(ch, func(col int, pivot float64) (float64, error) {
+		d := math.Abs(diag[col])
+		ratio := 0.0
+		if d != 0.0 {
+			ratio = math.Abs(pivot) / d
+		}
+		switch {
+		case pivot == 0.0:
+			rtn.Zero++
+		case pivot > 0.0:
+			rtn.Positive++
+		default:
+			rtn.Negative++
+		}
+		if pivot == 0.0 || ratio < tol {
+			rtn.Pivots = append(rtn.Pivots, Pivot{
+				Index:    col,
+				Value:    pivot,
+				Diagonal: diag[col],
+				Ratio:    ratio,
+			})
+		}
+		if pivot == 0.0 {
+			if d != 0.0 {
+				return d, nil
+			}
+			return 1.0, nil
+		}
+		return pivot, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ll.DiagUp()
+	if rtn.Zero > 0 {
+		rtn.InvNorm = math.Inf(1)
+		rtn.Condition = math.Inf(1)
+	} else {
+		rtn.InvNorm = ll.InvNorm1Est(5)
+		rtn.Condition = rtn.Norm * rtn.InvNorm
+	}
+	return rtn, nil
+}
@@ -0,0 +1,449 @@
+package matrix
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Matrix Market exchange format
+// https://math.nist.gov/MatrixMarket/formats.html
+const (
+	MtxHeader      = "%%MatrixMarket"
+	MtxCoordinate  = "coordinate"
+	MtxArray       = "array"
+	MtxGeneral     = "general"
+	MtxSymmetric   = "symmetric"
+	MtxFloatFormat = "%.18E"
+)
+
+type MtxInfo struct {
+	Format   string
+	Symmetry string
+	Rows     int
+	Columns  int
+	Entries  int
+	Comments []string
+}
+
+func (info *MtxInfo) Banner() string {
+	return fmt.Sprintf("%s matrix %s real %s", MtxHeader, info.Format, info.Symmetry)
+}
+
+func writeMtxHeader(w *bufio.Writer, info *MtxInfo) {
+	w.WriteString(info.Banner())
+	w.WriteString("\n")
+	for _, c := range info.Comments {
+		w.WriteString("% ")
+		w.WriteString(c)
+		w.WriteString("\n")
+	}
+	if info.Format == MtxArray {
+		w.WriteString(fmt.Sprintf("%d %d\n", info.Rows, info.Columns))
+	} else {
+		w.WriteString(fmt.Sprintf("%d %d %d\n", info.Rows, info.Columns, info.Entries))
+	}
+}
+
+func writeMtxFile(fn string, f func(io.Writer) error) error {
+	w, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	return f(w)
+}
+
+// WriteMtx writes the lower triangle of co in symmetric coordinate format if symmetric is true,
+// otherwise all the entries in general coordinate format.
+func (co *COOMatrix) WriteMtx(w io.Writer, symmetric bool, comments ...string) error {
+	co.Compress()
+	info := &MtxInfo{
+		Format:   MtxCoordinate,
+		Symmetry: MtxGeneral,
+		Rows:     co.Size,
+		Columns:  co.Size,
+		Comments: comments,
+	}
+	if symmetric {
+		info.Symmetry = MtxSymmetric
+		for i := 0; i < co.nz; i++ {
+			if co.column[i] <= co.row[i] {
+				info.Entries++
+			}
+		}
+	} else {
+		info.Entries = co.nz
+	}
+	bw := bufio.NewWriter(w)
+	writeMtxHeader(bw, info)
+	for i := 0; i < co.nz; i++ {
+		if symmetric && co.column[i] > co.row[i] {
+			continue
+		}
+		bw.WriteString(fmt.Sprintf("%d %d "+MtxFloatFormat+"\n", co.row[i]+1, co.column[i]+1, co.value[i]))
+	}
+	return bw.Flush()
+}
+
+func (co *COOMatrix) SaveMtx(fn string, symmetric bool, comments ...string) error {
+	return writeMtxFile(fn, func(w io.Writer) error {
+		return co.WriteMtx(w, symmetric, comments...)
+	})
+}
+
+// WriteMtx writes cr in general coordinate format.
+func (cr *CRSMatrix) WriteMtx(w io.Writer, comments ...string) error {
+	info := &MtxInfo{
+		Format:   MtxCoordinate,
+		Symmetry: MtxGeneral,
+		Rows:     cr.Size,
+		Columns:  cr.Size,
+		Entries:  cr.row[cr.Size],
+		Comments: comments,
+	}
+	bw := bufio.NewWriter(w)
+	writeMtxHeader(bw, info)
+	for row := 0; row < cr.Size; row++ {
+		for c := cr.row[row]; c < cr.row[row+1]; c++ {
+			bw.WriteString(fmt.Sprintf("%d %d "+MtxFloatFormat+"\n", row+1, cr.column[c]+1, cr.value[c]))
+		}
+	}
+	return bw.Flush()
+}
+
+func (cr *CRSMatrix) SaveMtx(fn string, comments ...string) error {
+	return writeMtxFile(fn, func(w io.Writer) error {
+		return cr.WriteMtx(w, comments...)
+	})
+}
+
+// WriteMtx writes the lower triangle of ll in symmetric coordinate format.
+// After LDLT, it writes the factor L with D on its diagonal.
+func (ll *LLSMatrix) WriteMtx(w io.Writer, comments ...string) error {
+	var n *LLSNode
+	info := &MtxInfo{
+		Format:   MtxCoordinate,
+		Symmetry: MtxSymmetric,
+		Rows:     ll.Size,
+		Columns:  ll.Size,
+		Comments: comments,
+	}
+	for col := 0; col < ll.Size; col++ {
+		for n = ll.diag[col]; n != nil; n = n.down {
+			info.Entries++
+		}
+	}
+	bw := bufio.NewWriter(w)
+	writeMtxHeader(bw, info)
+	for col := 0; col < ll.Size; col++ {
+		for n = ll.diag[col]; n != nil; n = n.down {
+			bw.WriteString(fmt.Sprintf("%d %d "+MtxFloatFormat+"\n", n.row+1, n.column+1, n.value))
+		}
+	}
+	return bw.Flush()
+}
+
+func (ll *LLSMatrix) SaveMtx(fn string, comments ...string) error {
+	return writeMtxFile(fn, func(w io.Writer) error {
+		return ll.WriteMtx(w, comments...)
+	})
+}
+
+// WriteMtxVector writes vecs as columns of a dense matrix in array format.
+func WriteMtxVector(w io.Writer, vecs [][]float64, comments ...string) error {
+	if len(vecs) == 0 {
+		return fmt.Errorf("WriteMtxVector: no vector")
+	}
+	size := len(vecs[0])
+	for _, vec := range vecs[1:] {
+		if len(vec) != size {
+			return fmt.Errorf("WriteMtxVector: size mismatch %d != %d", len(vec), size)
+		}
+	}
+	info := &MtxInfo{
+		Format:   MtxArray,
+		Symmetry: MtxGeneral,
+		Rows:     size,
+		Columns:  len(vecs),
+		Comments: comments,
+	}
+	bw := bufio.NewWriter(w)
+	writeMtxHeader(bw, info)
+	for _, vec := range vecs {
+		for _, val := range vec {
+			bw.WriteString(fmt.Sprintf(MtxFloatFormat+"\n", val))
+		}
+	}
+	return bw.Flush()
+}
+
+func SaveMtxVector(fn string, vecs [][]float64, comments ...string) error {
+	return writeMtxFile(fn, func(w io.Writer) error {
+		return WriteMtxVector(w, vecs, comments...)
+	})
+}
+
+func mtxWords(line string) []string {
+	return strings.Fields(line)
+}
+
+// mtxScanner counts lines for error messages.
+type mtxScanner struct {
+	*bufio.Scanner
+	line int
+}
+
+func newMtxScanner(r io.Reader) *mtxScanner {
+	return &mtxScanner{Scanner: bufio.NewScanner(r)}
+}
+
+func (sc *mtxScanner) Scan() bool {
+	if sc.Scanner.Scan() {
+		sc.line++
+		return true
+	}
+	return false
+}
+
+// readMtxInfo reads the banner, comments and size line.
+func readMtxInfo(sc *mtxScanner) (*MtxInfo, error) {
+	info := &MtxInfo{
+		Format:   MtxCoordinate,
+		Symmetry: MtxGeneral,
+		Comments: make([]string, 0),
+	}
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, MtxHeader) {
+			words := mtxWords(strings.ToLower(line))
+			if len(words) < 5 {
+				return nil, fmt.Errorf("ReadMtx: line %d: invalid banner: %s", sc.line, line)
+			}
+			if words[3] != "real" && words[3] != "integer" {
+				return nil, fmt.Errorf("ReadMtx: line %d: unsupported field: %s", sc.line, words[3])
+			}
+			info.Format = words[2]
+			info.Symmetry = words[4]
+			continue
+		}
+		if strings.HasPrefix(line, "%") {
+			info.Comments = append(info.Comments, strings.TrimSpace(strings.TrimPrefix(line, "%")))
+			continue
+		}
+		words := mtxWords(line)
+		if len(words) == 0 {
+			continue
+		}
+		nums := make([]int, len(words))
+		for i, word := range words {
+			num, err := strconv.ParseInt(word, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("ReadMtx: line %d: %s", sc.line, err)
+			}
+			nums[i] = int(num)
+		}
+		switch info.Format {
+		case MtxCoordinate:
+			if len(nums) < 3 {
+				return nil, fmt.Errorf("ReadMtx: line %d: invalid size line: %s", sc.line, line)
+			}
+			info.Entries = nums[2]
+		case MtxArray:
+			if len(nums) < 2 {
+				return nil, fmt.Errorf("ReadMtx: line %d: invalid size line: %s", sc.line, line)
+			}
+			info.Entries = nums[0] * nums[1]
+		default:
+			return nil, fmt.Errorf("ReadMtx: unknown format: %s", info.Format)
+		}
+		if nums[0] < 0 || nums[1] < 0 {
+			return nil, fmt.Errorf("ReadMtx: line %d: invalid size line: %s", sc.line, line)
+		}
+		info.Rows = nums[0]
+		info.Columns = nums[1]
+		return info, nil
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("ReadMtx: no size line")
+}
+
+// ReadMtxCOO reads a square matrix in coordinate format.
+// Symmetric files are expanded to both triangles.
+func ReadMtxCOO(r io.Reader) (*COOMatrix, *MtxInfo, error) {
+	sc := newMtxScanner(r)
+	info, err := readMtxInfo(sc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Format != MtxCoordinate {
+		return nil, info, fmt.Errorf("ReadMtx: not coordinate format: %s", info.Format)
+	}
+	if info.Rows != info.Columns {
+		return nil, info, fmt.Errorf("ReadMtx: not square: %d x %d", info.Rows, info.Columns)
+	}
+	symmetric := info.Symmetry == MtxSymmetric
+	capacity := info.Entries
+	if symmetric {
+		capacity *= 2
+	}
+	co := NewCOOMatrixCap(info.Rows, capacity)
+	num := 0
+	for sc.Scan() {
+		words := mtxWords(sc.Text())
+		if len(words) == 0 || strings.HasPrefix(words[0], "%") {
+			continue
+		}
+		if len(words) < 3 {
+			return nil, info, fmt.Errorf("ReadMtx: line %d: invalid entry: %s", sc.line, sc.Text())
+		}
+		row, err := strconv.ParseInt(words[0], 10, 64)
+		if err != nil {
+			return nil, info, fmt.Errorf("ReadMtx: line %d: %s", sc.line, err)
+		}
+		col, err := strconv.ParseInt(words[1], 10, 64)
+		if err != nil {
+			return nil, info, fmt.Errorf("ReadMtx: line %d: %s", sc.line, err)
+		}
+		val, err := strconv.ParseFloat(words[2], 64)
+		if err != nil {
+			return nil, info, fmt.Errorf("ReadMtx: line %d: %s", sc.line, err)
+		}
+		if row < 1 || int(row) > info.Rows || col < 1 || int(col) > info.Columns {
+			return nil, info, fmt.Errorf("ReadMtx: line %d: index out of range: (%d, %d) in %d x %d", sc.line, row, col, info.Rows, info.Columns)
+		}
+		if num >= info.Entries {
+			return nil, info, fmt.Errorf("ReadMtx: line %d: too many entries", sc.line)
+		}
+		co.Add(int(row)-1, int(col)-1, val)
+		if symmetric && row != col {
+			co.Add(int(col)-1, int(row)-1, val)
+		}
+		num++
+	}
+	if err := sc.Err(); err != nil {
+		return nil, info, err
+	}
+	if num != info.Entries {
+		return nil, info, fmt.Errorf("ReadMtx: %d entries expected, %d found", info.Entries, num)
+	}
+	co.Compress()
+	return co, info, nil
+}
+
+// ReadMtxVector reads a dense matrix in array format and returns its columns.
+func ReadMtxVector(r io.Reader) ([][]float64, *MtxInfo, error) {
+	sc := newMtxScanner(r)
+	info, err := readMtxInfo(sc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Format != MtxArray {
+		return nil, info, fmt.Errorf("ReadMtx: not array format: %s", info.Format)
+	}
+	rtn := make([][]float64, info.Columns)
+	for i := 0; i < info.Columns; i++ {
+		rtn[i] = make([]float64, info.Rows)
+	}
+	num := 0
+	for sc.Scan() {
+		words := mtxWords(sc.Text())
+		if len(words) == 0 || strings.HasPrefix(words[0], "%") {
+			continue
+		}
+		if num >= info.Entries {
+			return nil, info, fmt.Errorf("ReadMtx: line %d: too many entries", sc.line)
+		}
+		val, err := strconv.ParseFloat(words[0], 64)
+		if err != nil {
+			return nil, info, fmt.Errorf("ReadMtx: line %d: %s", sc.line, err)
+		}
+		rtn[num/info.Rows][num%info.Rows] = val
+		num++
+	}
+	if err := sc.Err(); err != nil {
+		return nil, info, err
+	}
+	if num != info.Entries {
+		return nil, info, fmt.Errorf("ReadMtx: %d entries expected, %d found", info.Entries, num)
+	}
+	return rtn, info, nil
+}
+
+// ReadMtx reads a square matrix file into CRSMatrix.
+// Files without banner are regarded as symmetric, as they used to be.
+func ReadMtx(fn string) (*CRSMatrix, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	head, _ := r.Peek(len(MtxHeader))
+	var rd io.Reader = r
+	if string(head) != MtxHeader {
+		rd = io.MultiReader(strings.NewReader(fmt.Sprintf("%s matrix %s real %s\n", MtxHeader, MtxCoordinate, MtxSymmetric)), r)
+	}
+	co, _, err := ReadMtxCOO(rd)
+	if err != nil {
+		return nil, err
+	}
+	conf := make([]bool, co.Size)
+	return co.ToCRS(0, conf), nil
+}
+
+// ReadMtxLLS reads a symmetric matrix in coordinate format into LLSMatrix.
+// General files are accepted only if they are symmetric.
+func ReadMtxLLS(r io.Reader) (*LLSMatrix, *MtxInfo, error) {
+	co, info, err := ReadMtxCOO(r)
+	if err != nil {
+		return nil, info, err
+	}
+	if info.Symmetry != MtxSymmetric {
+		for i := 0; i < co.nz; i++ {
+			if co.column[i] <= co.row[i] {
+				continue
+			}
+			if co.Query(co.column[i], co.row[i]) != co.value[i] {
+				return nil, info, fmt.Errorf("ReadMtx: not symmetric: (%d, %d)", co.row[i]+1, co.column[i]+1)
+			}
+		}
+	}
+	conf := make([]bool, co.Size)
+	return co.ToLLS(0, conf), info, nil
+}
+
+func LoadMtxLLS(fn string) (*LLSMatrix, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ll, _, err := ReadMtxLLS(f)
+	return ll, err
+}
+
+func LoadMtxCOO(fn string) (*COOMatrix, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	co, _, err := ReadMtxCOO(f)
+	return co, err
+}
+
+func LoadMtxVector(fn string) ([][]float64, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	vecs, _, err := ReadMtxVector(f)
+	return vecs, err
+}
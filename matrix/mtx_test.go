@@ -0,0 +1,120 @@
+package matrix
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func testCOO() *COOMatrix {
+	co := NewCOOMatrix(3)
+	co.Add(0, 0, 4.0)
+	co.Add(1, 1, 5.0)
+	co.Add(2, 2, 6.0)
+	co.Add(0, 1, 1.0)
+	co.Add(1, 0, 1.0)
+	co.Add(1, 2, 2.0)
+	co.Add(2, 1, 2.0)
+	return co
+}
+
+func TestMtxCOORoundTrip(t *testing.T) {
+	co := testCOO()
+	for _, symmetric := range []bool{true, false} {
+		var buf bytes.Buffer
+		if err := co.WriteMtx(&buf, symmetric, "test"); err != nil {
+			t.Fatal(err)
+		}
+		rd, info, err := ReadMtxCOO(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(info.Comments) != 1 || info.Comments[0] != "test" {
+			t.Errorf("comments = %v", info.Comments)
+		}
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				if rd.Query(i, j) != co.Query(i, j) {
+					t.Errorf("symmetric=%t (%d, %d) = %f, want %f", symmetric, i, j, rd.Query(i, j), co.Query(i, j))
+				}
+			}
+		}
+	}
+}
+
+func TestMtxLLSRoundTrip(t *testing.T) {
+	co := testCOO()
+	var buf bytes.Buffer
+	if err := co.WriteMtx(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	ll, _, err := ReadMtxLLS(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if ll.Query(i, j) != co.Query(i, j) {
+				t.Errorf("(%d, %d) = %f, want %f", i, j, ll.Query(i, j), co.Query(i, j))
+			}
+		}
+	}
+	buf.Reset()
+	if err := ll.WriteMtx(&buf); err != nil {
+		t.Fatal(err)
+	}
+	ll2, _, err := ReadMtxLLS(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if ll2.Query(i, j) != co.Query(i, j) {
+				t.Errorf("(%d, %d) = %f, want %f", i, j, ll2.Query(i, j), co.Query(i, j))
+			}
+		}
+	}
+	_, _, err = ReadMtxLLS(strings.NewReader("%%MatrixMarket matrix coordinate real general\n2 2 2\n1 2 1.0\n2 1 2.0\n"))
+	if err == nil {
+		t.Error("unsymmetric matrix is read into LLSMatrix")
+	}
+}
+
+func TestMtxVectorRoundTrip(t *testing.T) {
+	vecs := [][]float64{{1.0, 2.0, 3.0}, {-1.5, 0.0, 1e-20}}
+	var buf bytes.Buffer
+	if err := WriteMtxVector(&buf, vecs); err != nil {
+		t.Fatal(err)
+	}
+	rd, _, err := ReadMtxVector(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range vecs {
+		for j := range vecs[i] {
+			if rd[i][j] != vecs[i][j] {
+				t.Errorf("[%d][%d] = %g, want %g", i, j, rd[i][j], vecs[i][j])
+			}
+		}
+	}
+}
+
+func TestMtxIndexError(t *testing.T) {
+	for _, c := range []struct {
+		src  string
+		line string
+	}{
+		{"%%MatrixMarket matrix coordinate real general\n2 2 2\n1 1 1.0\n0 1 1.0\n", "line 4"},
+		{"%%MatrixMarket matrix coordinate real general\n% comment\n2 2 2\n1 1 1.0\n1 3 1.0\n", "line 5"},
+		{"%%MatrixMarket matrix coordinate real general\n2 2 1\n1 1 1.0\n2 2 1.0\n", "line 4"},
+	} {
+		_, _, err := ReadMtxCOO(strings.NewReader(c.src))
+		if err == nil {
+			t.Errorf("no error: %q", c.src)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.line) {
+			t.Errorf("error %q does not contain %q", err, c.line)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package matrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+const (
+	DefaultMemoryBudget = 256 << 20 // bytes
+	float64Size         = 8
+)
+
+// OOCMatrix is an out-of-core skyline (profile) matrix.
+// Columns of the upper triangle are grouped into panels whose size is limited by the memory budget.
+// Each panel is factorized in memory streaming the factorized panels before it from a temporary file.
+// A panel holds at most Budget/4 bytes (or one column if it is longer), and the panel being factorized,
+// the panel read from the file and its byte buffer are reused, so the factor takes at most 3/4 of the budget
+// in RAM besides diagonals and indices.
+// The budget bounds the factor only: the reduced input matrix is kept in RAM as a COOMatrix
+// until LDLT finishes, which is of the same order as the input given to NewOOCMatrix.
+// DOFs are renumbered by reverse Cuthill-McKee ordering to reduce the profile.
+type OOCMatrix struct {
+	Size    int
+	Budget  int64
+	mtx     *COOMatrix
+	perm    []int // new -> old
+	iperm   []int // old -> new
+	first   []int
+	colptr  []int64
+	panels  []oocPanel
+	diag    []float64
+	file    *os.File
+	work    []float64
+	buf     []float64
+	bytebuf []byte
+}
+
+type oocPanel struct {
+	start int
+	end   int
+}
+
+// NewOOCMatrix creates an out-of-core matrix from co without constrained DOFs.
+// Temporary file is created in dir (os.TempDir() if dir is empty) and removed by Close.
+func NewOOCMatrix(co *COOMatrix, csize int, conf []bool, budget int64, dir string) (*OOCMatrix, error) {
+	if budget <= 0 {
+		budget = DefaultMemoryBudget
+	}
+	rtn := new(OOCMatrix)
+	rtn.mtx = co.Reduce(csize, conf)
+	rtn.Size = rtn.mtx.Size
+	rtn.Budget = budget
+	rtn.perm, rtn.iperm = rtn.mtx.RCM()
+	rtn.profile()
+	f, err := os.CreateTemp(dir, "st_ooc_*.bin")
+	if err != nil {
+		return nil, err
+	}
+	rtn.file = f
+	return rtn, nil
+}
+
+// RCM returns reverse Cuthill-McKee ordering of the graph of co.
+// perm maps a new index to the old one, and iperm does the opposite.
+func (co *COOMatrix) RCM() ([]int, []int) {
+	co.Compress()
+	size := co.Size
+	degree := make([]int, size)
+	for r := 0; r < size; r++ {
+		for i := co.rowptr[r]; i < co.rowptr[r+1]; i++ {
+			if co.column[i] != r {
+				degree[r]++
+			}
+		}
+	}
+	nodes := make([]int, size)
+	for i := 0; i < size; i++ {
+		nodes[i] = i
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return degree[nodes[i]] < degree[nodes[j]] })
+	visited := make([]bool, size)
+	order := make([]int, 0, size)
+	neighbors := make([]int, 0)
+	for _, start := range nodes {
+		if visited[start] {
+			continue
+		}
+		visited[start] = true
+		head := len(order)
+		order = append(order, start)
+		for head < len(order) {
+			r := order[head]
+			head++
+			neighbors = neighbors[:0]
+			for i := co.rowptr[r]; i < co.rowptr[r+1]; i++ {
+				c := co.column[i]
+				if !visited[c] {
+					visited[c] = true
+					neighbors = append(neighbors, c)
+				}
+			}
+			sort.SliceStable(neighbors, func(i, j int) bool { return degree[neighbors[i]] < degree[neighbors[j]] })
+			order = append(order, neighbors...)
+		}
+	}
+	perm := make([]int, size)
+	iperm := make([]int, size)
+	for i, old := range order {
+		perm[size-1-i] = old
+		iperm[old] = size - 1 - i
+	}
+	return perm, iperm
+}
+
+// profile determines the first row of each column and divides columns into panels.
+func (oc *OOCMatrix) profile() {
+	size := oc.Size
+	oc.first = make([]int, size)
+	oc.colptr = make([]int64, size+1)
+	for j := 0; j < size; j++ {
+		oc.first[j] = j
+		old := oc.perm[j]
+		for i := oc.mtx.rowptr[old]; i < oc.mtx.rowptr[old+1]; i++ {
+			r := oc.iperm[oc.mtx.column[i]]
+			if r < oc.first[j] {
+				oc.first[j] = r
+			}
+		}
+		oc.colptr[j+1] = oc.colptr[j] + int64(j-oc.first[j]+1)
+	}
+	limit := oc.Budget / (4 * float64Size)
+	oc.panels = make([]oocPanel, 0)
+	start := 0
+	for j := 0; j < size; j++ {
+		if j > start && oc.colptr[j+1]-oc.colptr[start] > limit {
+			oc.panels = append(oc.panels, oocPanel{start, j})
+			start = j
+		}
+	}
+	if start < size {
+		oc.panels = append(oc.panels, oocPanel{start, size})
+	}
+}
+
+// ProfileSize returns the number of entries stored in the skyline.
+func (oc *OOCMatrix) ProfileSize() int64 {
+	return oc.colptr[oc.Size]
+}
+
+func (oc *OOCMatrix) Panels() int {
+	return len(oc.panels)
+}
+
+func (oc *OOCMatrix) panelLength(p oocPanel) int64 {
+	return oc.colptr[p.end] - oc.colptr[p.start]
+}
+
+func (oc *OOCMatrix) buffer(n int64) ([]float64, []byte) {
+	if int64(len(oc.buf)) < n {
+		oc.buf = make([]float64, n)
+	}
+	return oc.buf[:n], oc.byteBuffer(n)
+}
+
+func (oc *OOCMatrix) byteBuffer(n int64) []byte {
+	if int64(len(oc.bytebuf)) < n*float64Size {
+		oc.bytebuf = make([]byte, n*float64Size)
+	}
+	return oc.bytebuf[:n*float64Size]
+}
+
+func (oc *OOCMatrix) writePanel(p oocPanel, data []float64) error {
+	b := oc.byteBuffer(int64(len(data)))
+	for i, v := range data {
+		binary.LittleEndian.PutUint64(b[i*float64Size:], math.Float64bits(v))
+	}
+	_, err := oc.file.WriteAt(b, oc.colptr[p.start]*float64Size)
+	return err
+}
+
+func (oc *OOCMatrix) readPanel(p oocPanel) ([]float64, error) {
+	data, b := oc.buffer(oc.panelLength(p))
+	_, err := oc.file.ReadAt(b, oc.colptr[p.start]*float64Size)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	for i := range data {
+		data[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[i*float64Size:]))
+	}
+	return data, nil
+}
+
+// load fills the work panel with the original entries.
+// Entry (i, j) (first[j] <= i <= j) is stored at colptr[j] + i - first[j].
+func (oc *OOCMatrix) load(p oocPanel) []float64 {
+	base := oc.colptr[p.start]
+	n := oc.panelLength(p)
+	if int64(len(oc.work)) < n {
+		oc.work = make([]float64, n)
+	}
+	rtn := oc.work[:n]
+	for i := range rtn {
+		rtn[i] = 0.0
+	}
+	for j := p.start; j < p.end; j++ {
+		old := oc.perm[j]
+		for i := oc.mtx.rowptr[old]; i < oc.mtx.rowptr[old+1]; i++ {
+			r := oc.iperm[oc.mtx.column[i]]
+			if r > j {
+				continue
+			}
+			rtn[oc.colptr[j]-base+int64(r-oc.first[j])] += oc.mtx.value[i]
+		}
+	}
+	return rtn
+}
+
+// update computes g_ij = a_ij - sum_k u_ki g_kj for all the columns j in work using column i.
+// ui is the factorized column i whose first row is fi.
+func (oc *OOCMatrix) update(work []float64, p oocPanel, i int, ui []float64) {
+	base := oc.colptr[p.start]
+	fi := oc.first[i]
+	for j := p.start; j < p.end; j++ {
+		fj := oc.first[j]
+		if fj > i || j <= i {
+			continue
+		}
+		col := work[oc.colptr[j]-base : oc.colptr[j+1]-base]
+		k0 := fi
+		if fj > k0 {
+			k0 = fj
+		}
+		sum := 0.0
+		for k := k0; k < i; k++ {
+			sum += ui[k-fi] * col[k-fj]
+		}
+		col[i-fj] -= sum
+	}
+}
+
+// LDLT factorizes the matrix panel by panel.
+// Each column is stored as U with D on the diagonal.
+func (oc *OOCMatrix) LDLT(ch chan int) error {
+	size := oc.Size
+	oc.diag = make([]float64, size)
+	for pn, p := range oc.panels {
+		work := oc.load(p)
+		base := oc.colptr[p.start]
+		minfirst := p.start
+		for j := p.start; j < p.end; j++ {
+			if oc.first[j] < minfirst {
+				minfirst = oc.first[j]
+			}
+		}
+		for _, q := range oc.panels[:pn] {
+			if q.end <= minfirst {
+				continue
+			}
+			data, err := oc.readPanel(q)
+			if err != nil {
+				return err
+			}
+			qbase := oc.colptr[q.start]
+			for i := q.start; i < q.end; i++ {
+				oc.update(work, p, i, data[oc.colptr[i]-qbase:oc.colptr[i+1]-qbase])
+			}
+		}
+		for j := p.start; j < p.end; j++ {
+			col := work[oc.colptr[j]-base : oc.colptr[j+1]-base]
+			fj := oc.first[j]
+			d := col[j-fj]
+			for k := fj; k < j; k++ {
+				g := col[k-fj]
+				col[k-fj] = g / oc.diag[k]
+				d -= col[k-fj] * g
+			}
+			if d == 0.0 {
+				return errors.New(fmt.Sprintf("matrix singular: %d/%d", oc.perm[j], size))
+			}
+			col[j-fj] = d
+			oc.diag[j] = d
+			oc.update(work, p, j, col)
+			if ch != nil {
+				ch <- j
+			} else {
+				fmt.Printf("%d/%d\r", j, size)
+			}
+		}
+		err := oc.writePanel(p, work)
+		if err != nil {
+			return err
+		}
+	}
+	oc.mtx = nil
+	oc.work = nil
+	return nil
+}
+
+// Solve solves the equations for vecs with the factorized matrix.
+// U^T is applied streaming panels forward, and U backward.
+func (oc *OOCMatrix) Solve(vecs ...[]float64) ([][]float64, error) {
+	size := oc.Size
+	xs := make([][]float64, len(vecs))
+	for v, vec := range vecs {
+		xs[v] = make([]float64, size)
+		for j := 0; j < size; j++ {
+			xs[v][j] = vec[oc.perm[j]]
+		}
+	}
+	for _, p := range oc.panels {
+		data, err := oc.readPanel(p)
+		if err != nil {
+			return nil, err
+		}
+		base := oc.colptr[p.start]
+		for j := p.start; j < p.end; j++ {
+			fj := oc.first[j]
+			col := data[oc.colptr[j]-base : oc.colptr[j+1]-base]
+			for _, x := range xs {
+				for k := fj; k < j; k++ {
+					x[j] -= col[k-fj] * x[k]
+				}
+			}
+		}
+	}
+	for _, x := range xs {
+		for j := 0; j < size; j++ {
+			x[j] /= oc.diag[j]
+		}
+	}
+	for pn := len(oc.panels) - 1; pn >= 0; pn-- {
+		p := oc.panels[pn]
+		data, err := oc.readPanel(p)
+		if err != nil {
+			return nil, err
+		}
+		base := oc.colptr[p.start]
+		for j := p.end - 1; j >= p.start; j-- {
+			fj := oc.first[j]
+			col := data[oc.colptr[j]-base : oc.colptr[j+1]-base]
+			for _, x := range xs {
+				for k := fj; k < j; k++ {
+					x[k] -= col[k-fj] * x[j]
+				}
+			}
+		}
+	}
+	rtn := make([][]float64, len(vecs))
+	for v, x := range xs {
+		rtn[v] = make([]float64, size)
+		for j := 0; j < size; j++ {
+			rtn[v][oc.perm[j]] = x[j]
+		}
+	}
+	return rtn, nil
+}
+
+// Close removes the temporary file.
+func (oc *OOCMatrix) Close() error {
+	if oc.file == nil {
+		return nil
+	}
+	fn := oc.file.Name()
+	err := oc.file.Close()
+	oc.file = nil
+	if err != nil {
+		return err
+	}
+	return os.Remove(fn)
+}
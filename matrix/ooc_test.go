@@ -0,0 +1,69 @@
+package matrix
+
+import (
+	"math"
+	"testing"
+)
+
+// testBand returns a symmetric positive definite band matrix with a few long couplings.
+func testBand(size int) *COOMatrix {
+	co := NewCOOMatrix(size)
+	for i := 0; i < size; i++ {
+		co.Add(i, i, 10.0+float64(i%3))
+		if i+1 < size {
+			co.Add(i, i+1, -2.0)
+			co.Add(i+1, i, -2.0)
+		}
+		if i+7 < size && i%5 == 0 {
+			co.Add(i, i+7, -1.0)
+			co.Add(i+7, i, -1.0)
+		}
+	}
+	return co
+}
+
+func TestOOCMatrixSolve(t *testing.T) {
+	size := 60
+	conf := make([]bool, size)
+	conf[0] = true
+	conf[31] = true
+	csize := 2
+	vecs := make([][]float64, 2)
+	for v := range vecs {
+		vecs[v] = make([]float64, size-csize)
+		for i := range vecs[v] {
+			vecs[v][i] = math.Sin(float64(i*(v+1))) + 1.0
+		}
+	}
+	want, err := testBand(size).ToLLS(csize, conf).Solve(nil, vecs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, budget := range []int64{0, 20 * 2 * float64Size} {
+		oc, err := NewOOCMatrix(testBand(size), csize, conf, budget, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if budget > 0 && oc.Panels() < 2 {
+			t.Errorf("budget %d: panels = %d, want several", budget, oc.Panels())
+		}
+		err = oc.LDLT(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := oc.Solve(vecs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := oc.Close(); err != nil {
+			t.Error(err)
+		}
+		for v := range want {
+			for i := range want[v] {
+				if math.Abs(got[v][i]-want[v][i]) > 1e-10 {
+					t.Errorf("budget %d: x[%d][%d] = %g, want %g", budget, v, i, got[v][i], want[v][i])
+				}
+			}
+		}
+	}
+}
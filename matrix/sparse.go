@@ -4,10 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"math"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 )
@@ -23,225 +20,377 @@ import (
 //     value  float64
 // }
 
+// COOMatrix stores entries as triplets in preallocated arrays.
+// Duplicated entries are allowed while assembling and are summed up by Compress,
+// which sorts the triplets by row and column and builds row pointers (CSR).
+// Set on an uncompressed matrix uses a map from (row, column) to the triplet index,
+// which is built once and kept until the next Compress.
 type COOMatrix struct {
-	Size int
-	nz   int
-	data map[int]map[int]float64
+	Size       int
+	nz         int
+	row        []int
+	column     []int
+	value      []float64
+	rowptr     []int
+	compressed bool
+	index      map[[2]int]int
 }
 
 func NewCOOMatrix(size int) *COOMatrix {
+	return NewCOOMatrixCap(size, 0)
+}
+
+// NewCOOMatrixCap returns a COOMatrix whose triplet arrays can hold capacity entries without reallocation.
+func NewCOOMatrixCap(size, capacity int) *COOMatrix {
 	rtn := new(COOMatrix)
 	rtn.Size = size
-	rtn.data = make(map[int]map[int]float64)
+	rtn.row = make([]int, 0, capacity)
+	rtn.column = make([]int, 0, capacity)
+	rtn.value = make([]float64, 0, capacity)
+	rtn.compressed = true
+	rtn.rowptr = make([]int, size+1)
 	return rtn
 }
 
 func (co *COOMatrix) Copy() *COOMatrix {
-	rtn := NewCOOMatrix(co.Size)
-	for k, v := range co.data {
-		if _, ok := rtn.data[k]; !ok {
-			rtn.data[k] = make(map[int]float64)
+	co.Compress()
+	rtn := new(COOMatrix)
+	rtn.Size = co.Size
+	rtn.nz = co.nz
+	rtn.row = make([]int, co.nz)
+	rtn.column = make([]int, co.nz)
+	rtn.value = make([]float64, co.nz)
+	rtn.rowptr = make([]int, co.Size+1)
+	copy(rtn.row, co.row)
+	copy(rtn.column, co.column)
+	copy(rtn.value, co.value)
+	copy(rtn.rowptr, co.rowptr)
+	rtn.compressed = true
+	return rtn
+}
+
+// Len returns the number of stored triplets including duplicated ones.
+func (co *COOMatrix) Len() int {
+	return len(co.value)
+}
+
+// Reset removes all entries keeping allocated arrays.
+func (co *COOMatrix) Reset() {
+	co.row = co.row[:0]
+	co.column = co.column[:0]
+	co.value = co.value[:0]
+	for i := range co.rowptr {
+		co.rowptr[i] = 0
+	}
+	co.nz = 0
+	co.compressed = true
+	co.index = nil
+}
+
+// Compress sorts triplets by row and column, sums duplicated entries up and builds row pointers.
+// Rows are bucketed by counting sort, and columns in each row are sorted in place.
+func (co *COOMatrix) Compress() {
+	if co.compressed {
+		return
+	}
+	co.index = nil
+	n := len(co.value)
+	count := make([]int, co.Size+1)
+	for _, r := range co.row {
+		count[r+1]++
+	}
+	for i := 0; i < co.Size; i++ {
+		count[i+1] += count[i]
+	}
+	row := make([]int, n)
+	column := make([]int, n)
+	value := make([]float64, n)
+	next := make([]int, co.Size)
+	copy(next, count[:co.Size])
+	for i := 0; i < n; i++ {
+		r := co.row[i]
+		ind := next[r]
+		row[ind] = r
+		column[ind] = co.column[i]
+		value[ind] = co.value[i]
+		next[r]++
+	}
+	nz := 0
+	for r := 0; r < co.Size; r++ {
+		start := count[r]
+		end := count[r+1]
+		sortTriplet(column[start:end], value[start:end])
+		co.rowptr[r] = nz
+		for i := start; i < end; i++ {
+			if nz > co.rowptr[r] && column[nz-1] == column[i] {
+				value[nz-1] += value[i]
+				continue
+			}
+			row[nz] = r
+			column[nz] = column[i]
+			value[nz] = value[i]
+			nz++
+		}
+	}
+	co.rowptr[co.Size] = nz
+	co.row = row[:nz]
+	co.column = column[:nz]
+	co.value = value[:nz]
+	co.nz = nz
+	co.compressed = true
+}
+
+// sortTriplet sorts column indices of a row together with their values by insertion sort.
+// A row of a global stiffness matrix has only tens of entries.
+func sortTriplet(column []int, value []float64) {
+	for i := 1; i < len(column); i++ {
+		c := column[i]
+		v := value[i]
+		j := i - 1
+		for ; j >= 0 && column[j] > c; j-- {
+			column[j+1] = column[j]
+			value[j+1] = value[j]
 		}
-		for kk, vv := range v {
-			rtn.data[k][kk] = vv
+		column[j+1] = c
+		value[j+1] = v
+	}
+}
+
+// find returns the index of (row, col) in the compressed arrays, or -1 if it is not stored.
+func (co *COOMatrix) find(row, col int) int {
+	co.Compress()
+	lo := co.rowptr[row]
+	hi := co.rowptr[row+1]
+	for lo < hi {
+		mid := (lo + hi) / 2
+		c := co.column[mid]
+		if c == col {
+			return mid
+		} else if c < col {
+			lo = mid + 1
+		} else {
+			hi = mid
 		}
 	}
-	rtn.nz = co.nz
-	return rtn
+	return -1
 }
 
 func (co *COOMatrix) String() string {
 	var rtn bytes.Buffer
-	for row := 0; row < co.Size; row++ {
-		if rdata, rok := co.data[row]; rok {
-			for col := 0; col < co.Size; col++ {
-				if tmp, cok := rdata[col]; cok {
-					rtn.WriteString(fmt.Sprintf("%d %d %25.18f\n", row+1, col+1, tmp))
-				}
-			}
-		}
+	co.Compress()
+	for i := 0; i < co.nz; i++ {
+		rtn.WriteString(fmt.Sprintf("%d %d %25.18f\n", co.row[i]+1, co.column[i]+1, co.value[i]))
 	}
 	return rtn.String()
 }
 
 func (co *COOMatrix) Query(row, col int) float64 {
-	if row > co.Size || col > co.Size {
+	if row >= co.Size || col >= co.Size {
 		return 0.0
 	}
-	if rdata, rok := co.data[row]; rok {
-		if tmp, cok := rdata[col]; cok {
-			return tmp
-		}
+	if ind := co.find(row, col); ind >= 0 {
+		return co.value[ind]
 	}
 	return 0.0
 }
 
 func (co *COOMatrix) Set(row, col int, val float64) {
-	if row > co.Size || col > co.Size {
+	if row >= co.Size || col >= co.Size {
 		return
 	}
-	if rdata, rok := co.data[row]; rok {
-		if _, cok := rdata[col]; cok {
-			co.data[row][col] = val
-		} else {
-			co.data[row][col] = val
-			co.nz++
+	if co.compressed {
+		if ind := co.find(row, col); ind >= 0 {
+			co.value[ind] = val
+			return
 		}
 	} else {
-		co.data[row] = make(map[int]float64)
-		co.data[row][col] = val
-		co.nz++
+		co.buildIndex()
+		if ind, ok := co.index[[2]int{row, col}]; ok {
+			co.value[ind] = val
+			return
+		}
+	}
+	co.Add(row, col, val)
+}
+
+// buildIndex builds the map from (row, column) to the triplet index.
+// Duplicated triplets are merged into the first one, leaving zeros which are removed by Compress.
+func (co *COOMatrix) buildIndex() {
+	if co.index != nil {
+		return
+	}
+	co.index = make(map[[2]int]int, len(co.value))
+	for i := range co.value {
+		key := [2]int{co.row[i], co.column[i]}
+		if j, ok := co.index[key]; ok {
+			co.value[j] += co.value[i]
+			co.value[i] = 0.0
+			continue
+		}
+		co.index[key] = i
 	}
 }
 
+// Add appends a triplet. It does not check duplication, so it costs no hashing unless Set has been called since the last Compress.
 func (co *COOMatrix) Add(row, col int, val float64) {
-	if row > co.Size || col > co.Size {
+	if row >= co.Size || col >= co.Size {
 		return
 	}
-	if rdata, rok := co.data[row]; rok {
-		if _, cok := rdata[col]; cok {
-			co.data[row][col] += val
-		} else {
-			co.data[row][col] = val
-			co.nz++
+	if co.index != nil {
+		key := [2]int{row, col}
+		if ind, ok := co.index[key]; ok {
+			co.value[ind] += val
+			return
+		}
+		co.index[key] = len(co.value)
+	}
+	co.row = append(co.row, row)
+	co.column = append(co.column, col)
+	co.value = append(co.value, val)
+	co.compressed = false
+}
+
+// Append appends all triplets of M to co.
+// It is used to gather thread-local buffers into the global matrix.
+func (co *COOMatrix) Append(M *COOMatrix, factor float64) {
+	if len(M.value) == 0 {
+		return
+	}
+	co.index = nil
+	co.row = append(co.row, M.row...)
+	co.column = append(co.column, M.column...)
+	start := len(co.value)
+	co.value = append(co.value, M.value...)
+	if factor != 1.0 {
+		for i := start; i < len(co.value); i++ {
+			co.value[i] *= factor
 		}
-	} else {
-		co.data[row] = make(map[int]float64)
-		co.data[row][col] = val
-		co.nz++
 	}
+	co.compressed = false
 }
 
 func (co *COOMatrix) AddMat(M *COOMatrix, factor float64) *COOMatrix {
-	rtn := co.Copy()
-	for row := 0; row < co.Size; row++ {
-		if Mdata, ok := M.data[row]; ok {
-			var rdata map[int]float64
-			if _, ok := rtn.data[row]; ok {
-				rdata = rtn.data[row]
-			} else {
-				rdata = make(map[int]float64)
-				rtn.data[row] = rdata
-			}
-			for col, val := range Mdata {
-				if _, cok := rdata[col]; cok {
-					rdata[col] += factor * val
-				} else {
-					rdata[col] = factor * val
-					rtn.nz++
-				}
-			}
+	co.Compress()
+	M.Compress()
+	rtn := NewCOOMatrixCap(co.Size, co.nz+M.nz)
+	rtn.Append(co, 1.0)
+	rtn.Append(M, factor)
+	rtn.Compress()
+	return rtn
+}
+
+// reducedIndex returns a map from full indices to indices without constrained ones (-1 if constrained).
+func reducedIndex(size int, conf []bool) []int {
+	rtn := make([]int, size)
+	ind := 0
+	for i := 0; i < size; i++ {
+		if conf[i] {
+			rtn[i] = -1
+			continue
 		}
+		rtn[i] = ind
+		ind++
 	}
 	return rtn
 }
 
 func (co *COOMatrix) MulV(csize int, conf []bool, vec []float64) []float64 {
+	co.Compress()
 	size := co.Size - csize
 	rtn := make([]float64, size)
-	rind := 0
-	for row := 0; row < co.Size; row++ {
-		if conf[row] {
-			rind++
+	index := reducedIndex(co.Size, conf)
+	for i := 0; i < co.nz; i++ {
+		r := index[co.row[i]]
+		c := index[co.column[i]]
+		if r < 0 || c < 0 {
 			continue
 		}
-		cind := 0
-		if rdata, rok := co.data[row]; rok {
-			for col := 0; col < co.Size; col++ {
-				if conf[col] {
-					cind++
-					continue
-				}
-				if val, cok := rdata[col]; cok {
-					rtn[row-rind] += vec[col-cind] * val
-				}
-			}
-		}
+		rtn[r] += vec[c] * co.value[i]
 	}
 	return rtn
 }
 
-// func (co *COOMatrix) ToCRS() *CRSMatrix {
-// 	nz := 0
-// 	rtn := NewCRSMatrix(co.Size, co.nz)
-// 	for row := 0; row < co.Size; row++ {
-// 		rtn.row[row] = nz
-// 		if rdata, rok := co.data[row]; rok {
-// 			for col := 0; col < co.Size; col++ {
-// 				if val, cok := rdata[col]; cok {
-// 					rtn.value[nz] = val
-// 					rtn.column[nz] = col
-// 					nz++
-// 				}
-// 			}
-// 		}
-// 	}
-// 	rtn.row[co.Size] = nz
-// 	return rtn
-// }
+// Reduce returns a COOMatrix without constrained rows and columns.
+func (co *COOMatrix) Reduce(csize int, conf []bool) *COOMatrix {
+	co.Compress()
+	rtn := NewCOOMatrixCap(co.Size-csize, co.nz)
+	index := reducedIndex(co.Size, conf)
+	for i := 0; i < co.nz; i++ {
+		r := index[co.row[i]]
+		c := index[co.column[i]]
+		if r < 0 || c < 0 {
+			continue
+		}
+		rtn.Add(r, c, co.value[i])
+	}
+	rtn.Compress()
+	return rtn
+}
 
 func (co *COOMatrix) ToCRS(csize int, conf []bool) *CRSMatrix {
+	co.Compress()
 	nz := 0
 	size := co.Size - csize
 	rtn := NewCRSMatrix(size, co.nz)
-	rind := 0
+	index := reducedIndex(co.Size, conf)
 	for row := 0; row < co.Size; row++ {
-		if conf[row] {
-			rind++
+		r := index[row]
+		if r < 0 {
 			continue
 		}
-		cind := 0
-		rtn.row[row-rind] = nz
-		if rdata, rok := co.data[row]; rok {
-			for col := 0; col < co.Size; col++ {
-				if conf[col] {
-					cind++
-					continue
-				}
-				if val, cok := rdata[col]; cok {
-					rtn.value[nz] = val
-					rtn.column[nz] = col - cind
-					nz++
-				}
+		rtn.row[r] = nz
+		for i := co.rowptr[row]; i < co.rowptr[row+1]; i++ {
+			c := index[co.column[i]]
+			if c < 0 {
+				continue
 			}
+			rtn.value[nz] = co.value[i]
+			rtn.column[nz] = c
+			nz++
 		}
 	}
 	rtn.row[size] = nz
+	rtn.nz = nz
+	rtn.value = rtn.value[:nz]
+	rtn.column = rtn.column[:nz]
 	return rtn
 }
 
 func (co *COOMatrix) ToLLS(csize int, conf []bool) *LLSMatrix {
 	var wg sync.WaitGroup
+	co.Compress()
 	size := co.Size - csize
 	rtn := NewLLSMatrix(size)
-	rind := 0
+	index := reducedIndex(co.Size, conf)
 	for row := 0; row < co.Size; row++ {
-		if conf[row] {
-			rind++
+		if index[row] < 0 {
 			continue
 		}
 		wg.Add(1)
-		go func(r, ri int) {
+		go func(r int) {
 			var n *LLSNode
 			defer wg.Done()
-			cind := ri
-			if rdata, rok := co.data[r]; rok {
-				rtn.diag[r-ri].value = rdata[r]
-				n = rtn.diag[r-ri]
-				for col := r + 1; col < co.Size; col++ {
-					if conf[col] {
-						cind++
-						continue
-					}
-					if val, cok := rdata[col]; cok {
-						newnode := NewLLSNode(col-cind, r-ri, val)
-						n.down = newnode
-						newnode.up = n
-						n = newnode
-					}
+			ri := index[r]
+			n = rtn.diag[ri]
+			for i := co.rowptr[r]; i < co.rowptr[r+1]; i++ {
+				col := co.column[i]
+				if col < r {
+					continue
+				}
+				if col == r {
+					n.value = co.value[i]
+					continue
 				}
+				ci := index[col]
+				if ci < 0 {
+					continue
+				}
+				newnode := NewLLSNode(ci, ri, co.value[i])
+				n.down = newnode
+				newnode.up = n
+				n = newnode
 			}
-		}(row, rind)
+		}(row)
 	}
 	wg.Wait()
 	return rtn
@@ -315,82 +464,6 @@ func (cr *CRSMatrix) Copy() *CRSMatrix {
 	return rtn
 }
 
-func ReadMtx(fn string) (*CRSMatrix, error) {
-	f, err := ioutil.ReadFile(fn)
-	if err != nil {
-		return nil, err
-	}
-	var lis []string
-	if ok := strings.HasSuffix(string(f), "\r\n"); ok {
-		lis = strings.Split(string(f), "\r\n")
-	} else {
-		lis = strings.Split(string(f), "\n")
-	}
-	ind := 0
-	for _, j := range lis {
-		if strings.HasPrefix(j, "%") {
-			ind++
-			continue
-		}
-		var words []string
-		for _, k := range strings.Split(j, " ") {
-			if k != "" {
-				words = append(words, k)
-			}
-		}
-		if len(words) == 0 {
-			ind++
-			continue
-		} else {
-			break
-		}
-	}
-	var words []string
-	for _, k := range strings.Split(lis[ind], " ") {
-		if k != "" {
-			words = append(words, k)
-		}
-	}
-	nums := make([]int, 3) // [row, col, nz]
-	for i := 0; i < 3; i++ {
-		num, err := strconv.ParseInt(words[i], 10, 64)
-		if err != nil {
-			return nil, err
-		}
-		nums[i] = int(num)
-	}
-	co := NewCOOMatrix(nums[0])
-	for _, j := range lis[ind+1:] {
-		var words []string
-		for _, k := range strings.Split(j, " ") {
-			if k != "" {
-				words = append(words, k)
-			}
-		}
-		if len(words) == 0 {
-			continue
-		}
-		row, err := strconv.ParseInt(words[0], 10, 64)
-		if err != nil {
-			continue
-		}
-		col, err := strconv.ParseInt(words[1], 10, 64)
-		if err != nil {
-			continue
-		}
-		val, err := strconv.ParseFloat(words[2], 64)
-		if err != nil {
-			continue
-		}
-		co.Add(int(row)-1, int(col)-1, val)
-		if row != col {
-			co.Add(int(col)-1, int(row)-1, val)
-		}
-	}
-	conf := make([]bool, nums[0])
-	return co.ToCRS(0, conf), nil
-}
-
 func (cr *CRSMatrix) Print() string {
 	var rtn bytes.Buffer
 	for row := 0; row < cr.Size; row++ {
@@ -962,13 +1035,27 @@ func (ll *LLSMatrix) Add(row, col int, val float64) float64 {
 }
 
 func (ll *LLSMatrix) LDLT(ch chan int) (*LLSMatrix, error) {
+	size := ll.Size
+	return ll.ldlt(ch, func(col int, pivot float64) (float64, error) {
+		if pivot == 0.0 {
+			return 0.0, errors.New(fmt.Sprintf("matrix singular: %d/%d", col, size))
+		}
+		return pivot, nil
+	})
+}
+
+// ldlt factorizes ll in place.
+// pivotf is called with each pivot before elimination and returns the pivot to be used.
+func (ll *LLSMatrix) ldlt(ch chan int, pivotf func(int, float64) (float64, error)) (*LLSMatrix, error) {
 	var n *LLSNode
 	size := ll.Size
 	for col := 0; col < size; col++ {
 		n = ll.diag[col]
-		if n.value == 0.0 {
-			return nil, errors.New(fmt.Sprintf("matrix singular: %d/%d", col, size))
+		pivot, err := pivotf(col, n.value)
+		if err != nil {
+			return nil, err
 		}
+		n.value = pivot
 		w := 1.0 / n.value
 		for {
 			n = n.down
@@ -1274,11 +1361,3 @@ func (ll *LLSMatrix) PCG(C *LLSMatrix, vec []float64) []float64 {
 		}
 	}
 }
-
-func Dot(x, y []float64, size int) float64 {
-	rtn := 0.0
-	for i := 0; i < size; i++ {
-		rtn += x[i] * y[i]
-	}
-	return rtn
-}
@@ -0,0 +1,50 @@
+package matrix
+
+import (
+	"testing"
+)
+
+func TestCOOMatrixSetAdd(t *testing.T) {
+	co := NewCOOMatrix(3)
+	co.Add(0, 0, 1.0)
+	co.Add(0, 0, 2.0)
+	co.Add(1, 2, 4.0)
+	co.Set(0, 0, 5.0)
+	co.Add(0, 0, 1.0)
+	co.Set(2, 1, 7.0)
+	co.Set(2, 1, 8.0)
+	co.Add(1, 2, 1.0)
+	for _, c := range []struct {
+		row, col int
+		val      float64
+	}{
+		{0, 0, 6.0},
+		{1, 2, 5.0},
+		{2, 1, 8.0},
+		{1, 1, 0.0},
+	} {
+		if v := co.Query(c.row, c.col); v != c.val {
+			t.Errorf("(%d, %d) = %f, want %f", c.row, c.col, v, c.val)
+		}
+	}
+	if co.nz != 3 {
+		t.Errorf("nz = %d, want 3", co.nz)
+	}
+	co.Set(0, 0, 1.0)
+	co.Add(0, 0, 1.0)
+	if v := co.Query(0, 0); v != 2.0 {
+		t.Errorf("(0, 0) = %f, want 2.0", v)
+	}
+}
+
+func BenchmarkCOOMatrixSet(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		co := NewCOOMatrix(2000)
+		for i := 0; i < 2000; i++ {
+			for j := i; j < i+10 && j < 2000; j++ {
+				co.Set(i, j, float64(i+j))
+			}
+		}
+		co.Compress()
+	}
+}
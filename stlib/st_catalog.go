@@ -0,0 +1,410 @@
+package st
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Catalogue of rolled and formed steel shapes.
+// Dimensions are read from the files in catalog/ (in [mm]) and section properties are calculated
+// including fillets and corner radii, as listed in catalogues.
+// A shape from the catalogue is designated like "H-400x200x8x13", "BCR-400x400x19" or "STK-267.4x6.6".
+//
+//	H    : H-shapes                                H-HxBxtwxtf
+//	CT   : cut T from H-shapes                     CT-(H/2)xBxtwxtf
+//	C    : channels                                C-HxBxt1xt2 ("[" is also accepted)
+//	L    : angles                                  L-AxBxt
+//	STKR : square and rectangular hollow sections  STKR-HxBxt
+//	BCR  : roll formed square hollow sections      BCR-HxBxt
+//	BCP  : press formed square hollow sections     BCP-HxBxt
+//	STK  : circular hollow sections                STK-Dxt ("P" is also accepted)
+//
+// "□", "SHS" and "RHS" search STKR, BCR and BCP in this order.
+
+//go:embed catalog
+var catalogdir embed.FS
+
+// CatalogEntry is a shape of the steel catalogue. Section properties are in [cm].
+type CatalogEntry struct {
+	Designation string
+	Family      string
+	Dims        []float64 // [mm]
+	A           float64
+	Ix          float64
+	Iy          float64
+	Imin        float64
+	J           float64
+	Iw          float64
+	Zx          float64
+	Zy          float64
+	Zpx         float64
+	Zpy         float64
+}
+
+// CatalogGrades are the steel grades usually used for each family.
+var CatalogGrades = map[string][]string{
+	"H":    []string{"SN400", "SN490", "SS400", "SM490"},
+	"CT":   []string{"SN400", "SN490", "SS400", "SM490"},
+	"C":    []string{"SS400", "SM490"},
+	"L":    []string{"SS400", "SM490"},
+	"STKR": []string{"STKR400", "STKR490"},
+	"BCR":  []string{"BCR295"},
+	"BCP":  []string{"BCP235", "BCP325"},
+	"STK":  []string{"STK400", "STK490"},
+}
+
+// outside corner radius of hollow sections / thickness
+var cornerRadius = map[string]float64{
+	"STKR": 2.0,
+	"BCR":  2.5,
+	"BCP":  3.5,
+}
+
+// slope of inner faces of channel flanges. t2 of channels is the thickness at the middle of the flange.
+const channelTaper = 0.1
+
+var catalogAlias = map[string][]string{
+	"[":   []string{"C"},
+	"P":   []string{"STK"},
+	"CHS": []string{"STK"},
+	"□":   []string{"STKR", "BCR", "BCP"},
+	"SHS": []string{"STKR", "BCR", "BCP"},
+	"RHS": []string{"STKR", "BCR", "BCP"},
+}
+
+var (
+	catalog     map[string]*CatalogEntry
+	catalogList []*CatalogEntry
+	catalogErr  error
+	catalogOnce sync.Once
+)
+
+func loadCatalog() {
+	catalog = make(map[string]*CatalogEntry)
+	catalogList = make([]*CatalogEntry, 0)
+	files, err := catalogdir.ReadDir("catalog")
+	if err != nil {
+		catalogErr = err
+		return
+	}
+	for _, f := range files {
+		data, err := catalogdir.ReadFile("catalog/" + f.Name())
+		if err != nil {
+			catalogErr = err
+			return
+		}
+		s := bufio.NewScanner(bytes.NewReader(data))
+		line := 0
+		for s.Scan() {
+			line++
+			words := strings.Fields(s.Text())
+			if len(words) == 0 || strings.HasPrefix(words[0], "#") {
+				continue
+			}
+			dims := make([]float64, len(words)-1)
+			for i, w := range words[1:] {
+				val, err := strconv.ParseFloat(w, 64)
+				if err != nil {
+					catalogErr = fmt.Errorf("loadCatalog: %s:%d: %s", f.Name(), line, err)
+					return
+				}
+				dims[i] = val
+			}
+			entries, err := newCatalogEntries(words[0], dims)
+			if err != nil {
+				catalogErr = fmt.Errorf("loadCatalog: %s:%d: %s", f.Name(), line, err)
+				return
+			}
+			for _, e := range entries {
+				catalog[e.Designation] = e
+				catalogList = append(catalogList, e)
+			}
+		}
+	}
+}
+
+func designation(family string, dims []float64) string {
+	ds := make([]string, len(dims))
+	for i, d := range dims {
+		ds[i] = strconv.FormatFloat(d, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%s-%s", family, strings.Join(ds, "x"))
+}
+
+// newCatalogEntries calculates section properties of a line of catalogue files.
+// A line of H-shapes gives CT as well.
+func newCatalogEntries(family string, dims []float64) ([]*CatalogEntry, error) {
+	size := map[string]int{"H": 5, "C": 6, "L": 5, "STKR": 3, "BCR": 3, "BCP": 3, "STK": 2}
+	n, ok := size[family]
+	if !ok {
+		return nil, fmt.Errorf("unknown family %s", family)
+	}
+	if len(dims) < n {
+		return nil, fmt.Errorf("%s: not enough data", family)
+	}
+	cm := make([]float64, len(dims))
+	for i, d := range dims {
+		cm[i] = d * 0.1
+	}
+	switch family {
+	case "H":
+		h, b, tw, tf, r := cm[0], cm[1], cm[2], cm[3], cm[4]
+		e := &CatalogEntry{Designation: designation("H", dims[:4]), Family: "H", Dims: dims[:4]}
+		e.setPolygon([][][]float64{roundCorners([][]float64{
+			{-0.5 * b, -0.5 * h}, {0.5 * b, -0.5 * h}, {0.5 * b, -0.5*h + tf}, {0.5 * tw, -0.5*h + tf},
+			{0.5 * tw, 0.5*h - tf}, {0.5 * b, 0.5*h - tf}, {0.5 * b, 0.5 * h}, {-0.5 * b, 0.5 * h},
+			{-0.5 * b, 0.5*h - tf}, {-0.5 * tw, 0.5*h - tf}, {-0.5 * tw, -0.5*h + tf}, {-0.5 * b, -0.5*h + tf},
+		}, []float64{0, 0, 0, r, r, 0, 0, 0, 0, r, r, 0})}, nil)
+		alpha, d := filletJ(tw, tf, r)
+		e.J = 2.0*b*math.Pow(tf, 3.0)/3.0 + (h-2*tf)*math.Pow(tw, 3.0)/3.0 + 2.0*alpha*math.Pow(d, 4.0) - 0.42*math.Pow(tf, 4.0)
+		e.Iw = tf * math.Pow(b, 3.0) * math.Pow(h-tf, 2.0) / 24.0
+		tdims := []float64{0.5 * dims[0], dims[1], dims[2], dims[3]}
+		t := &CatalogEntry{Designation: designation("CT", tdims), Family: "CT", Dims: tdims}
+		h *= 0.5
+		t.setPolygon([][][]float64{roundCorners([][]float64{
+			{-0.5 * tw, 0.0}, {0.5 * tw, 0.0}, {0.5 * tw, h - tf}, {0.5 * b, h - tf},
+			{0.5 * b, h}, {-0.5 * b, h}, {-0.5 * b, h - tf}, {-0.5 * tw, h - tf},
+		}, []float64{0, 0, r, 0, 0, 0, 0, r})}, nil)
+		t.J = b*math.Pow(tf, 3.0)/3.0 + (h-tf)*math.Pow(tw, 3.0)/3.0 + alpha*math.Pow(d, 4.0) - 0.21*math.Pow(tf, 4.0)
+		t.Iw = 0.0
+		return []*CatalogEntry{e, t}, nil
+	case "C":
+		h, b, tw, tf, r1, r2 := cm[0], cm[1], cm[2], cm[3], cm[4], cm[5]
+		e := &CatalogEntry{Designation: designation("C", dims[:4]), Family: "C", Dims: dims[:4]}
+		d := channelTaper * 0.5 * (b - tw)
+		e.setPolygon([][][]float64{roundCorners([][]float64{
+			{0.0, 0.0}, {b, 0.0}, {b, tf - d}, {tw, tf + d}, {tw, h - tf - d}, {b, h - tf + d}, {b, h}, {0.0, h},
+		}, []float64{0, 0, r2, r1, r1, r2, 0, 0})}, nil)
+		ck := CKYOU{H: h, B: b, Tw: tw, Tf: tf}
+		e.J = ck.J()
+		e.Iw = ck.Iw()
+		return []*CatalogEntry{e}, nil
+	case "L":
+		h, b, t, r1, r2 := cm[0], cm[1], cm[2], cm[3], cm[4]
+		e := &CatalogEntry{Designation: designation("L", dims[:3]), Family: "L", Dims: dims[:3]}
+		e.setPolygon([][][]float64{roundCorners([][]float64{
+			{0.0, 0.0}, {b, 0.0}, {b, t}, {t, t}, {t, h}, {0.0, h},
+		}, []float64{0, 0, r2, r1, r2, 0})}, nil)
+		an := ANGLE{H: h, B: b, Tw: t, Tf: t}
+		e.J = an.J()
+		e.Iw = 0.0
+		return []*CatalogEntry{e}, nil
+	case "STKR", "BCR", "BCP":
+		h, b, t := cm[0], cm[1], cm[2]
+		ro := cornerRadius[family] * t
+		ri := math.Max(ro-t, 0.0)
+		e := &CatalogEntry{Designation: designation(family, dims[:3]), Family: family, Dims: dims[:3]}
+		rect := func(x, y float64) [][]float64 {
+			return [][]float64{{-x, -y}, {x, -y}, {x, y}, {-x, y}}
+		}
+		e.setPolygon([][][]float64{roundCorners(rect(0.5*b, 0.5*h), []float64{ro, ro, ro, ro})},
+			[][][]float64{roundCorners(rect(0.5*b-t, 0.5*h-t), []float64{ri, ri, ri, ri})})
+		rm := ro - 0.5*t
+		am := (b-t)*(h-t) - (4.0-math.Pi)*rm*rm
+		p := 2.0*((b-t)+(h-t)) - (8.0-2.0*math.Pi)*rm
+		e.J = 4.0 * am * am * t / p
+		e.Iw = 0.0
+		return []*CatalogEntry{e}, nil
+	case "STK":
+		cp := CPIPE{D: cm[0], T: cm[1]}
+		e := &CatalogEntry{
+			Designation: designation("STK", dims[:2]),
+			Family:      "STK",
+			Dims:        dims[:2],
+			A:           cp.A(),
+			Ix:          cp.Ix(),
+			Iy:          cp.Iy(),
+			Imin:        cp.Ix(),
+			J:           cp.J(),
+			Iw:          0.0,
+			Zx:          cp.Zx(),
+			Zy:          cp.Zy(),
+			Zpx:         cp.Zpx(),
+			Zpy:         cp.Zpy(),
+		}
+		return []*CatalogEntry{e}, nil
+	}
+	return nil, nil
+}
+
+// filletJ returns the coefficient and the diameter of the inscribed circle at a web-to-flange junction
+// for the torsional constant (El Darwish and Johnston).
+func filletJ(tw, tf, r float64) (float64, float64) {
+	alpha := -0.042 + 0.2204*tw/tf + 0.1355*r/tf - 0.0865*r*tw/(tf*tf) - 0.0725*tw*tw/(tf*tf)
+	d := (math.Pow(tf+r, 2.0) + tw*(r+0.25*tw)) / (2.0*r + tf)
+	return alpha, d
+}
+
+// roundCorners replaces the vertices of a polygon whose radius is positive by circular arcs
+// tangent to both adjacent edges. It works both for convex corners and re-entrant ones (fillets).
+func roundCorners(vertices [][]float64, radius []float64) [][]float64 {
+	ndiv := 16
+	n := len(vertices)
+	rtn := make([][]float64, 0, n+ndiv*4)
+	for i, p := range vertices {
+		r := radius[i]
+		if r <= 0.0 {
+			rtn = append(rtn, p)
+			continue
+		}
+		prev := vertices[(i+n-1)%n]
+		next := vertices[(i+1)%n]
+		u1 := Normalize([]float64{prev[0] - p[0], prev[1] - p[1], 0.0})
+		u2 := Normalize([]float64{next[0] - p[0], next[1] - p[1], 0.0})
+		half := 0.5 * math.Acos(math.Max(-1.0, math.Min(1.0, Dot(u1, u2, 2))))
+		if half < 1e-6 || math.Pi-2.0*half < 1e-6 {
+			rtn = append(rtn, p)
+			continue
+		}
+		d := r / math.Tan(half)
+		bis := Normalize([]float64{u1[0] + u2[0], u1[1] + u2[1], 0.0})
+		c := []float64{p[0] + bis[0]*r/math.Sin(half), p[1] + bis[1]*r/math.Sin(half)}
+		t1 := []float64{p[0] + u1[0]*d - c[0], p[1] + u1[1]*d - c[1]}
+		t2 := []float64{p[0] + u2[0]*d - c[0], p[1] + u2[1]*d - c[1]}
+		a1 := math.Atan2(t1[1], t1[0])
+		a2 := math.Atan2(t2[1], t2[0])
+		da := a2 - a1
+		for da > math.Pi {
+			da -= 2.0 * math.Pi
+		}
+		for da < -math.Pi {
+			da += 2.0 * math.Pi
+		}
+		for j := 0; j <= ndiv; j++ {
+			a := a1 + da*float64(j)/float64(ndiv)
+			rtn = append(rtn, []float64{c[0] + r*math.Cos(a), c[1] + r*math.Sin(a)})
+		}
+	}
+	return rtn
+}
+
+// setPolygon calculates section properties of a region bounded by outer rings with holes.
+func (e *CatalogEntry) setPolygon(outer, holes [][][]float64) {
+	rg := region{outer, holes}
+	a, sx, sy, ixx, iyy, ixy := rg.moments()
+	cx := sy / a
+	cy := sx / a
+	min, max := rg.bounds()
+	e.A = a
+	e.Ix = ixx - a*cy*cy
+	e.Iy = iyy - a*cx*cx
+	_, e.Imin, _ = principal(e.Ix, e.Iy, ixy-a*cx*cy)
+	e.Zx = e.Ix / math.Max(max[1]-cy, cy-min[1])
+	e.Zy = e.Iy / math.Max(max[0]-cx, cx-min[0])
+	e.Zpx = rg.plasticModulus(1)
+	e.Zpy = rg.plasticModulus(0)
+}
+
+// Shape returns the shape of the entry. Section properties of the shape are the ones of the catalogue.
+func (e *CatalogEntry) Shape() Shape {
+	cm := make([]float64, len(e.Dims))
+	for i, d := range e.Dims {
+		cm[i] = d * 0.1
+	}
+	switch e.Family {
+	case "H":
+		return HKYOU{H: cm[0], B: cm[1], Tw: cm[2], Tf: cm[3], Catalog: e}
+	case "CT":
+		return TKYOU{H: cm[0], B: cm[1], Tw: cm[2], Tf: cm[3], Catalog: e}
+	case "C":
+		return CKYOU{H: cm[0], B: cm[1], Tw: cm[2], Tf: cm[3], Catalog: e}
+	case "L":
+		return ANGLE{H: cm[0], B: cm[1], Tw: cm[2], Tf: cm[2], Catalog: e}
+	case "STKR", "BCR", "BCP":
+		return RPIPE{H: cm[0], B: cm[1], Tw: cm[2], Tf: cm[2], Catalog: e}
+	case "STK":
+		return CPIPE{D: cm[0], T: cm[1], Catalog: e}
+	}
+	return nil
+}
+
+// normalizeDesignation splits a designation into family and dimensions.
+func normalizeDesignation(name string) (string, []float64, error) {
+	name = strings.NewReplacer("×", "x", "X", "x", "*", "x").Replace(strings.TrimSpace(name))
+	var family, rest string
+	if strings.HasPrefix(name, "[") {
+		family, rest = "[", strings.TrimPrefix(strings.TrimPrefix(name, "["), "-")
+	} else if ind := strings.Index(name, "-"); ind > 0 {
+		family, rest = strings.ToUpper(name[:ind]), name[ind+1:]
+	} else {
+		return "", nil, fmt.Errorf("LookupSteel: unknown designation: %s", name)
+	}
+	words := strings.Split(rest, "x")
+	dims := make([]float64, len(words))
+	for i, w := range words {
+		val, err := strconv.ParseFloat(w, 64)
+		if err != nil {
+			return "", nil, fmt.Errorf("LookupSteel: unknown designation: %s", name)
+		}
+		dims[i] = val
+	}
+	return family, dims, nil
+}
+
+// LookupSteel returns the catalogue entry designated by name (e.g. "H-400x200x8x13").
+func LookupSteel(name string) (*CatalogEntry, error) {
+	catalogOnce.Do(loadCatalog)
+	if catalogErr != nil {
+		return nil, catalogErr
+	}
+	family, dims, err := normalizeDesignation(name)
+	if err != nil {
+		return nil, err
+	}
+	families := []string{family}
+	if fs, ok := catalogAlias[family]; ok {
+		families = fs
+	}
+	for _, f := range families {
+		if e, ok := catalog[designation(f, dims)]; ok {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("LookupSteel: %s is not in the catalogue", name)
+}
+
+// IsCatalogDesignation reports whether name looks like a designation of the catalogue.
+func IsCatalogDesignation(name string) bool {
+	family, _, err := normalizeDesignation(name)
+	if err != nil {
+		return false
+	}
+	if _, ok := catalogAlias[family]; ok {
+		return true
+	}
+	_, ok := CatalogGrades[family]
+	return ok
+}
+
+// SearchSteel returns catalogue entries whose designation starts with prefix, sorted by area.
+func SearchSteel(prefix string) ([]*CatalogEntry, error) {
+	catalogOnce.Do(loadCatalog)
+	if catalogErr != nil {
+		return nil, catalogErr
+	}
+	prefix = strings.ToUpper(strings.NewReplacer("×", "x", "*", "x").Replace(prefix))
+	rtn := make([]*CatalogEntry, 0)
+	for _, e := range catalogList {
+		if strings.HasPrefix(strings.ToUpper(e.Designation), prefix) {
+			rtn = append(rtn, e)
+		}
+	}
+	sort.SliceStable(rtn, func(i, j int) bool {
+		if rtn[i].Family != rtn[j].Family {
+			return rtn[i].Family < rtn[j].Family
+		}
+		return rtn[i].A < rtn[j].A
+	})
+	return rtn, nil
+}
+
+func (e *CatalogEntry) String() string {
+	return fmt.Sprintf("%-22s A=%9.2f Ix=%11.1f Iy=%11.1f Zx=%9.1f Zy=%9.1f Zpx=%9.1f Zpy=%9.1f J=%9.2f Iw=%11.1f", e.Designation, e.A, e.Ix, e.Iy, e.Zx, e.Zy, e.Zpx, e.Zpy, e.J, e.Iw)
+}
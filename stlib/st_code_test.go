@@ -0,0 +1,104 @@
+package st
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testCode implements only DesignCode: it changes the short-term factor of steel and uses AIJ for the strengths.
+type testCode struct{}
+
+func (code testCode) Name() string                     { return "TEST" }
+func (code testCode) Description() string              { return "test code" }
+func (code testCode) StressFactor(name string) float64 { return 1.0 }
+func (code testCode) Amplify(sr SectionRate, name string, fact float64) float64 {
+	return fact
+}
+func (code testCode) AllowableFactor(material string, period string) float64 {
+	if material == ALLOW_STEEL && period == "X" {
+		return 1.2
+	}
+	return AIJ{}.AllowableFactor(material, period)
+}
+
+func TestDesignCodeFallback(t *testing.T) {
+	hk, err := NewHKYOU([]string{"400.0", "200.0", "8.0", "13.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := NewSColumn(101, hk, SN400)
+	cond := NewCondition()
+	cond.Period = "X"
+	aij := sc.Qa(cond)
+	cond.SetCode(testCode{})
+	if _, ok := steelCode(cond).(AIJ); !ok {
+		t.Errorf("steel code = %T, want AIJ", steelCode(cond))
+	}
+	if got, want := sc.Qa(cond), aij*1.2/1.5; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Qa = %g, want %g", got, want)
+	}
+	if cond.Qfact != 1.0 {
+		t.Errorf("Qfact = %g, want 1.0", cond.Qfact)
+	}
+	cond.Period = "L"
+	if got, want := sc.Ft(cond), SN400.F/1.5; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Ft = %g, want %g", got, want)
+	}
+}
+
+func TestReadLstDesignCode(t *testing.T) {
+	RegisterDesignCode(testCode{})
+	defer delete(designcodes, "TEST")
+	dir := t.TempDir()
+	withcode := filepath.Join(dir, "withcode.lst")
+	if err := os.WriteFile(withcode, []byte("DESIGNCODE TEST\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	without := filepath.Join(dir, "without.lst")
+	if err := os.WriteFile(without, []byte("# no design code\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	frame := NewFrame()
+	if err := frame.ReadLst(withcode); err != nil {
+		t.Fatal(err)
+	}
+	if name := frame.DesignCode.Name(); name != "TEST" {
+		t.Errorf("design code = %s, want TEST", name)
+	}
+	if err := frame.ReadLst(without); err != nil {
+		t.Fatal(err)
+	}
+	if name := frame.DesignCode.Name(); name != DefaultDesignCode.Name() {
+		t.Errorf("design code = %s, want %s", name, DefaultDesignCode.Name())
+	}
+}
+
+// TestSetDesignCodeReload selects a code as :designcode does and reloads .lst as :srcal does by default.
+func TestSetDesignCodeReload(t *testing.T) {
+	RegisterDesignCode(testCode{})
+	defer delete(designcodes, "TEST")
+	lst := filepath.Join(t.TempDir(), "test.lst")
+	if err := os.WriteFile(lst, []byte("# no design code\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	frame := NewFrame()
+	code, err := LookupDesignCode("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame.SetDesignCode(code)
+	if err := frame.ReadLst(lst); err != nil {
+		t.Fatal(err)
+	}
+	if name := frame.DesignCode.Name(); name != "TEST" {
+		t.Errorf("design code after reload = %s, want TEST", name)
+	}
+	if name := frame.Snapshot().DesignCode.Name(); name != "TEST" {
+		t.Errorf("design code of snapshot = %s, want TEST", name)
+	}
+	if name := NewFrame().DesignCode.Name(); name != DefaultDesignCode.Name() {
+		t.Errorf("design code of another model = %s, want %s", name, DefaultDesignCode.Name())
+	}
+}
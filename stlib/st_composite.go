@@ -0,0 +1,351 @@
+package st
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Composite sections of steel and concrete.
+// CFTColumn is a concrete filled steel tube (RPIPE or CPIPE) and SRCColumn (SRCGirder) is a steel shape encased in reinforced concrete.
+// Their strengths are the superposition of the steel part and the concrete (RC) part:
+// for a given N, M is the maximum of sM(sN)+cM(cN) over all the partitions N = sN+cN.
+//
+// In .lst files, they are written as
+//
+//	CODE 101 CFT COLUMN "name"
+//	         RPIPE 50.0 50.0 2.2 2.2 BCR295 "..."
+//	         CONCRETE FC36
+//	CODE 102 SRC COLUMN "name"
+//	         HKYOU 50.0 20.0 0.9 1.6 SN490 "..."
+//	         REINS ..., CRECT ..., HOOPS ... (same as RC)
+//	         XFACE ..., YFACE ...
+
+// Composite is a section whose axial strength has upper and lower limits used for N-M interaction.
+type Composite interface {
+	SectionRate
+	Nmax(*Condition) float64
+	Nmin(*Condition) float64
+}
+
+// steelCondition returns the condition for the steel part.
+// Ultimate strength of steel is F, which is the short-term one.
+func steelCondition(cond *Condition) *Condition {
+	if cond.Period != "U" {
+		return cond
+	}
+	c := *cond
+	c.Period = "S"
+	return &c
+}
+
+// superpose returns the maximum of m1(n1)+m2(n2) where n1+n2 = n, min1 <= n1 <= max1 and min2 <= n2 <= max2,
+// together with n1 and n2.
+func superpose(n, min1, max1, min2, max2 float64, m1, m2 func(float64) float64) (float64, float64, float64) {
+	lo := math.Max(min1, n-max2)
+	hi := math.Min(max1, n-min2)
+	if lo > hi {
+		return 0.0, 0.0, 0.0
+	}
+	ndiv := 100
+	rtn := -1.0
+	var n1 float64
+	for i := 0; i <= ndiv; i++ {
+		val := lo + (hi-lo)*float64(i)/float64(ndiv)
+		if m := m1(val) + m2(n-val); m > rtn {
+			rtn = m
+			n1 = val
+		}
+	}
+	return rtn, n1, n - n1
+}
+
+// steelM returns the allowable bending moment [tfm] of a steel part with axial force sn.
+func steelM(sc *SColumn, sn float64, cond *Condition) float64 {
+	ft := sc.Ft(cond)
+	z := sc.Zx()
+	if !cond.Strong {
+		z = sc.Zy()
+	}
+	return z * (ft - math.Abs(sn)/sc.A()) * 0.01
+}
+
+type CFTColumn struct {
+	Concrete
+	Tube  *SColumn
+	num   int
+	Etype string
+	name  string
+}
+
+func NewCFTColumn(num int, shape Shape, material Steel, concrete Concrete) (*CFTColumn, error) {
+	switch shape.(type) {
+	case RPIPE, CPIPE:
+	default:
+		return nil, fmt.Errorf("NewCFTColumn: steel tube must be RPIPE or CPIPE: %s", shape.String())
+	}
+	return &CFTColumn{
+		Concrete: concrete,
+		Tube:     NewSColumn(num, shape, material),
+		num:      num,
+		Etype:    "COLUMN",
+		name:     "",
+	}, nil
+}
+func (cf *CFTColumn) Num() int {
+	return cf.num
+}
+func (cf *CFTColumn) TypeString() string {
+	return "ＣＦＴ柱"
+}
+func (cf *CFTColumn) Snapshot() SectionRate {
+	return &CFTColumn{
+		Concrete: cf.Concrete,
+		Tube:     cf.Tube.Snapshot().(*SColumn),
+		num:      cf.num,
+		Etype:    cf.Etype,
+		name:     cf.name,
+	}
+}
+func (cf *CFTColumn) String() string {
+	var rtn bytes.Buffer
+	rtn.WriteString(fmt.Sprintf("CODE %3d CFT %s %55s\n", cf.num, cf.Etype, fmt.Sprintf("\"%s\"", cf.name)))
+	lines := strings.SplitAfter(cf.Tube.String(), "\n")
+	rtn.WriteString(lines[1])
+	rtn.WriteString(fmt.Sprintf("         CONCRETE %s\n", cf.Concrete.Name()))
+	for _, l := range lines[2:] {
+		rtn.WriteString(l)
+	}
+	return rtn.String()
+}
+func (cf *CFTColumn) Name() string {
+	return cf.name
+}
+func (cf *CFTColumn) SetName(name string) {
+	cf.name = name
+	cf.Tube.SetName(name)
+}
+func (cf *CFTColumn) SetValue(name string, vals []float64) {
+	cf.Tube.SetValue(name, vals)
+}
+func (cf *CFTColumn) Factor(p string) float64 {
+	return DefaultDesignCode.AllowableFactor(ALLOW_CONCRETE, p)
+}
+
+// Fc returns the allowable compressive stress of the filled concrete.
+func (cf *CFTColumn) Fc(cond *Condition) float64 {
+	return cf.fc / 3.0 * allowableFactor(cond, ALLOW_CONCRETE)
+}
+
+// Ft returns the allowable stress of the steel tube.
+func (cf *CFTColumn) Ft(cond *Condition) float64 {
+	return cf.Tube.Ft(steelCondition(cond))
+}
+
+// Core returns the region of the filled concrete.
+func (cf *CFTColumn) Core() region {
+	switch sh := cf.Tube.Shape.(type) {
+	case RPIPE:
+		x := 0.5*sh.B - sh.Tw
+		y := 0.5*sh.H - sh.Tf
+		return region{[][][]float64{{{-x, -y}, {x, -y}, {x, y}, {-x, y}}}, nil}
+	case CPIPE:
+		ndiv := 64
+		r := 0.5*sh.D - sh.T
+		ring := make([][]float64, ndiv)
+		for i := 0; i < ndiv; i++ {
+			theta := 2.0 * math.Pi * float64(i) / float64(ndiv)
+			ring[i] = []float64{r * math.Cos(theta), r * math.Sin(theta)}
+		}
+		return region{[][][]float64{ring}, nil}
+	}
+	return region{}
+}
+func (cf *CFTColumn) CoreArea() float64 {
+	switch sh := cf.Tube.Shape.(type) {
+	case RPIPE:
+		return (sh.B - 2.0*sh.Tw) * (sh.H - 2.0*sh.Tf)
+	case CPIPE:
+		return 0.25 * math.Pi * math.Pow(sh.D-2.0*sh.T, 2.0)
+	}
+	return 0.0
+}
+
+// ConcreteM returns the bending moment [tfm] of the filled concrete with axial force cn
+// assuming rectangular stress block of Fc.
+func (cf *CFTColumn) ConcreteM(cn float64, cond *Condition) float64 {
+	fc := cf.Fc(cond)
+	if cn <= 0.0 || fc <= 0.0 {
+		return 0.0
+	}
+	axis := 1
+	if !cond.Strong {
+		axis = 0
+	}
+	core := cf.Core()
+	min, max := core.bounds()
+	a := cn / fc
+	lo, hi := min[axis], max[axis]
+	for i := 0; i < 50; i++ {
+		mid := 0.5 * (lo + hi)
+		if am, _ := core.above(axis, mid); am > a {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	am, sm := core.above(axis, 0.5*(lo+hi))
+	if am <= 0.0 {
+		return 0.0
+	}
+	return cn * sm / am * 0.01
+}
+func (cf *CFTColumn) Nmax(cond *Condition) float64 {
+	return cf.Ft(cond)*cf.Tube.A() + cf.Fc(cond)*cf.CoreArea()
+}
+func (cf *CFTColumn) Nmin(cond *Condition) float64 {
+	return -cf.Ft(cond) * cf.Tube.A()
+}
+func (cf *CFTColumn) Na(cond *Condition) float64 {
+	return cftCode(cond).CFTNa(cf, cond)
+}
+func (cf *CFTColumn) Qa(cond *Condition) float64 {
+	return cftCode(cond).CFTQa(cf, cond)
+}
+func (cf *CFTColumn) Ma(cond *Condition) float64 {
+	return cftCode(cond).CFTMa(cf, cond)
+}
+func (cf *CFTColumn) Mza(cond *Condition) float64 {
+	return cftCode(cond).CFTMza(cf, cond)
+}
+func (cf *CFTColumn) Amount() Amount {
+	a := cf.Tube.Amount()
+	a["CONCRETE"] = cf.CoreArea() * 0.0001
+	return a
+}
+
+type SRCColumn struct {
+	SPart  *SColumn
+	RCPart *RCColumn
+	num    int
+	Etype  string
+	name   string
+}
+
+func NewSRCColumn(num int, shape Shape, material Steel) *SRCColumn {
+	return &SRCColumn{
+		SPart:  NewSColumn(num, shape, material),
+		RCPart: NewRCColumn(num),
+		num:    num,
+		Etype:  "COLUMN",
+		name:   "",
+	}
+}
+func (src *SRCColumn) Num() int {
+	return src.num
+}
+func (src *SRCColumn) TypeString() string {
+	return "ＳＲＣ柱"
+}
+func (src *SRCColumn) Snapshot() SectionRate {
+	return &SRCColumn{
+		SPart:  src.SPart.Snapshot().(*SColumn),
+		RCPart: src.RCPart.Snapshot().(*RCColumn),
+		num:    src.num,
+		Etype:  src.Etype,
+		name:   src.name,
+	}
+}
+func (src *SRCColumn) String() string {
+	var rtn bytes.Buffer
+	rtn.WriteString(fmt.Sprintf("CODE %3d SRC %s %55s\n", src.num, src.Etype, fmt.Sprintf("\"%s\"", src.name)))
+	rtn.WriteString(strings.SplitAfter(src.SPart.String(), "\n")[1])
+	rtn.WriteString(strings.SplitN(src.RCPart.String(), "\n", 2)[1])
+	return rtn.String()
+}
+func (src *SRCColumn) Name() string {
+	return src.name
+}
+func (src *SRCColumn) SetName(name string) {
+	src.name = name
+	src.SPart.SetName(name)
+	src.RCPart.SetName(name)
+}
+func (src *SRCColumn) SetValue(name string, vals []float64) {
+	src.SPart.SetValue(name, vals)
+	src.RCPart.SetValue(name, vals)
+}
+func (src *SRCColumn) Factor(p string) float64 {
+	return src.RCPart.Factor(p)
+}
+
+// Ft returns the allowable stress of the steel part.
+func (src *SRCColumn) Ft(cond *Condition) float64 {
+	return src.SPart.Ft(steelCondition(cond))
+}
+
+// RC returns the RC part whose concrete strength is reduced by the steel ratio sp as (1-15sp/4).
+func (src *SRCColumn) RC() *RCColumn {
+	rc := *src.RCPart
+	if area := rc.Area(); area > 0.0 {
+		rc.Concrete.fc *= math.Max(1.0-15.0*src.SPart.A()/area/4.0, 0.0)
+	}
+	return &rc
+}
+
+// rcRate returns the RC part as RCColumn or RCGirder.
+func (src *SRCColumn) rcRate() SectionRate {
+	if src.Etype == "GIRDER" {
+		return &RCGirder{*src.RC()}
+	}
+	return src.RC()
+}
+func (src *SRCColumn) Nmax(cond *Condition) float64 {
+	return src.Ft(cond)*src.SPart.A() + src.rcNmax(cond)
+}
+
+// rcNmax returns the compressive strength of the RC part without the concrete displaced by the steel part.
+func (src *SRCColumn) rcNmax(cond *Condition) float64 {
+	rc := src.RC()
+	return rc.Nmax(cond) - rc.Fc(cond)*src.SPart.A()
+}
+func (src *SRCColumn) Nmin(cond *Condition) float64 {
+	return -src.Ft(cond)*src.SPart.A() + src.RC().Nmin(cond)
+}
+func (src *SRCColumn) Na(cond *Condition) float64 {
+	return srcCode(cond).SRCNa(src, cond)
+}
+func (src *SRCColumn) Qa(cond *Condition) float64 {
+	return srcCode(cond).SRCQa(src, cond)
+}
+func (src *SRCColumn) Ma(cond *Condition) float64 {
+	return srcCode(cond).SRCMa(src, cond)
+}
+func (src *SRCColumn) Mza(cond *Condition) float64 {
+	return srcCode(cond).SRCMza(src, cond)
+}
+func (src *SRCColumn) Amount() Amount {
+	a := src.RCPart.Amount()
+	a["STEEL"] = src.SPart.A() * 0.0001
+	a["CONCRETE"] -= src.SPart.A() * 0.0001
+	return a
+}
+
+type SRCGirder struct {
+	SRCColumn
+}
+
+func NewSRCGirder(num int, shape Shape, material Steel) *SRCGirder {
+	src := NewSRCColumn(num, shape, material)
+	src.Etype = "GIRDER"
+	src.SPart.Etype = "GIRDER"
+	src.RCPart.Etype = "GIRDER"
+	return &SRCGirder{*src}
+}
+func (sg *SRCGirder) TypeString() string {
+	return "ＳＲＣ梁"
+}
+func (sg *SRCGirder) Snapshot() SectionRate {
+	return &SRCGirder{*sg.SRCColumn.Snapshot().(*SRCColumn)}
+}
@@ -0,0 +1,532 @@
+package st
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CSV (or TSV) tables for spreadsheets.
+// The first column of the header tells the kind of the table: NODE, ELEM or SECT.
+// Tables of nodes, elems and sects can be read back by ReadCsv to update the frame;
+// tables of results (with a PERIOD column) are only written.
+
+var (
+	CsvDirections = []string{"X", "Y", "Z", "TX", "TY", "TZ"}
+	CsvStresses   = []string{"N", "QX", "QY", "MZ", "MX", "MY"}
+	CsvRates      = []string{"QL", "QS", "QU", "ML", "MS", "MU"}
+)
+
+// CsvComma returns the field delimiter for fn: a tab for .tsv and .txt, otherwise a comma.
+func CsvComma(fn string) rune {
+	switch strings.ToLower(filepath.Ext(fn)) {
+	case ".tsv", ".txt":
+		return '\t'
+	default:
+		return ','
+	}
+}
+
+func csvFloat(val float64) string {
+	return strconv.FormatFloat(val, 'g', -1, 64)
+}
+
+func csvBool(val bool) string {
+	if val {
+		return "1"
+	}
+	return "0"
+}
+
+func writeCsv(fn string, records [][]string) error {
+	w, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	cw := csv.NewWriter(w)
+	cw.Comma = CsvComma(fn)
+	cw.WriteAll(records)
+	return cw.Error()
+}
+
+func sortedNodes(frame *Frame, nodes []*Node) []*Node {
+	if len(nodes) == 0 {
+		nodes = make([]*Node, 0, len(frame.Nodes))
+		for _, n := range frame.Nodes {
+			nodes = append(nodes, n)
+		}
+	} else {
+		tmp := make([]*Node, 0, len(nodes))
+		for _, n := range nodes {
+			if n != nil {
+				tmp = append(tmp, n)
+			}
+		}
+		nodes = tmp
+	}
+	sort.Sort(NodeByNum{nodes})
+	return nodes
+}
+
+func sortedElems(frame *Frame, elems []*Elem) []*Elem {
+	if len(elems) == 0 {
+		elems = make([]*Elem, 0, len(frame.Elems))
+		for _, el := range frame.Elems {
+			elems = append(elems, el)
+		}
+	} else {
+		tmp := make([]*Elem, 0, len(elems))
+		for _, el := range elems {
+			if el != nil {
+				tmp = append(tmp, el)
+			}
+		}
+		elems = tmp
+	}
+	sort.Sort(ElemByNum{elems})
+	return elems
+}
+
+// WriteCsvNode writes coordinates, boundary conditions and loads of nodes.
+// If nodes is empty, all the nodes are written.
+func (frame *Frame) WriteCsvNode(fn string, nodes []*Node) error {
+	header := []string{"NODE", "X", "Y", "Z"}
+	for _, d := range CsvDirections {
+		header = append(header, "CONF"+d)
+	}
+	for _, d := range CsvDirections {
+		header = append(header, "LOAD"+d)
+	}
+	records := [][]string{header}
+	for _, n := range sortedNodes(frame, nodes) {
+		rec := []string{fmt.Sprintf("%d", n.Num)}
+		for i := 0; i < 3; i++ {
+			rec = append(rec, csvFloat(n.Coord[i]))
+		}
+		for i := 0; i < 6; i++ {
+			rec = append(rec, csvBool(n.Conf[i]))
+		}
+		for i := 0; i < 6; i++ {
+			rec = append(rec, csvFloat(n.Load[i]))
+		}
+		records = append(records, rec)
+	}
+	return writeCsv(fn, records)
+}
+
+// WriteCsvElem writes connectivity, section, type, code angle and bonds of elems.
+// Nodes and bonds are written in one column separated by spaces as in .inp.
+// If elems is empty, all the elems are written.
+func (frame *Frame) WriteCsvElem(fn string, elems []*Elem) error {
+	records := [][]string{{"ELEM", "ETYPE", "SECT", "ENOD", "CANG", "BONDS"}}
+	for _, el := range sortedElems(frame, elems) {
+		enod := make([]string, el.Enods)
+		for i, en := range el.Enod {
+			enod[i] = fmt.Sprintf("%d", en.Num)
+		}
+		bonds := ""
+		if el.IsLineElem() {
+			b := make([]string, len(el.Bonds))
+			for i, bond := range el.Bonds {
+				if bond == nil {
+					b[i] = "0"
+				} else {
+					b[i] = fmt.Sprintf("%d", bond.Num)
+				}
+			}
+			bonds = strings.Join(b, " ")
+		}
+		records = append(records, []string{fmt.Sprintf("%d", el.Num), ETYPES[el.Etype], fmt.Sprintf("%d", el.Sect.Num), strings.Join(enod, " "), csvFloat(el.Cang), bonds})
+	}
+	return writeCsv(fn, records)
+}
+
+// WriteCsvSect writes section properties, one row for each figure.
+// The columns of values are the union of the keys of Fig.Value.
+// If sects is empty, all the sects are written.
+func (frame *Frame) WriteCsvSect(fn string, sects []*Sect) error {
+	if len(sects) == 0 {
+		sects = make([]*Sect, 0, len(frame.Sects))
+		for _, sec := range frame.Sects {
+			sects = append(sects, sec)
+		}
+	} else {
+		sects = append([]*Sect{}, sects...)
+	}
+	sort.Sort(SectByNum{sects})
+	keys := make([]string, 0)
+	found := make(map[string]bool)
+	for _, sec := range sects {
+		for _, f := range sec.Figs {
+			for k := range f.Value {
+				if !found[k] {
+					found[k] = true
+					keys = append(keys, k)
+				}
+			}
+		}
+	}
+	sort.Strings(keys)
+	header := append([]string{"SECT", "NAME", "FIG", "PROP", "SHAPE"}, keys...)
+	records := [][]string{header}
+	for _, sec := range sects {
+		for _, f := range sec.Figs {
+			prop := ""
+			if f.Prop != nil {
+				prop = fmt.Sprintf("%d", f.Prop.Num)
+			}
+			shape := ""
+			if f.Shape != nil {
+				shape = strings.Join(strings.Fields(f.Shape.String()), " ")
+			}
+			rec := []string{fmt.Sprintf("%d", sec.Num), sec.Name, fmt.Sprintf("%d", f.Num), prop, shape}
+			for _, k := range keys {
+				if val, ok := f.Value[k]; ok {
+					rec = append(rec, csvFloat(val))
+				} else {
+					rec = append(rec, "")
+				}
+			}
+			records = append(records, rec)
+		}
+	}
+	return writeCsv(fn, records)
+}
+
+// WriteCsvDisp writes displacements of nodes for each period.
+func (frame *Frame) WriteCsvDisp(fn string, nodes []*Node, periods []string) error {
+	header := []string{"NODE", "PERIOD"}
+	for _, d := range CsvDirections {
+		header = append(header, "D"+d)
+	}
+	records := [][]string{header}
+	for _, n := range sortedNodes(frame, nodes) {
+		for _, p := range periods {
+			disp, ok := n.Disp[p]
+			if !ok {
+				continue
+			}
+			rec := []string{fmt.Sprintf("%d", n.Num), p}
+			for i := 0; i < 6; i++ {
+				rec = append(rec, csvFloat(disp[i]))
+			}
+			records = append(records, rec)
+		}
+	}
+	return writeCsv(fn, records)
+}
+
+// WriteCsvReaction writes reactions of supported nodes for each period.
+// Reactions of free directions are left blank.
+func (frame *Frame) WriteCsvReaction(fn string, nodes []*Node, periods []string) error {
+	header := []string{"NODE", "PERIOD"}
+	for _, d := range CsvDirections {
+		header = append(header, "R"+d)
+	}
+	records := [][]string{header}
+	for _, n := range sortedNodes(frame, nodes) {
+		if n.ConfState() == 0 {
+			continue
+		}
+		for _, p := range periods {
+			reaction, ok := n.Reaction[p]
+			if !ok {
+				continue
+			}
+			rec := []string{fmt.Sprintf("%d", n.Num), p}
+			for i := 0; i < 6; i++ {
+				if n.Conf[i] {
+					rec = append(rec, csvFloat(reaction[i]))
+				} else {
+					rec = append(rec, "")
+				}
+			}
+			records = append(records, rec)
+		}
+	}
+	return writeCsv(fn, records)
+}
+
+// WriteCsvStress writes stresses at both ends of line elems for each period.
+func (frame *Frame) WriteCsvStress(fn string, elems []*Elem, periods []string) error {
+	header := append([]string{"ELEM", "NODE", "PERIOD"}, CsvStresses...)
+	records := [][]string{header}
+	for _, el := range sortedElems(frame, elems) {
+		if !el.IsLineElem() {
+			continue
+		}
+		for _, p := range periods {
+			stress, ok := el.Stress[p]
+			if !ok {
+				continue
+			}
+			for _, en := range el.Enod {
+				s, ok := stress[en.Num]
+				if !ok {
+					continue
+				}
+				rec := []string{fmt.Sprintf("%d", el.Num), fmt.Sprintf("%d", en.Num), p}
+				for i := 0; i < 6; i++ {
+					rec = append(rec, csvFloat(s[i]))
+				}
+				records = append(records, rec)
+			}
+		}
+	}
+	return writeCsv(fn, records)
+}
+
+// WriteCsvRate writes maximum section rates of elems read from .rat/.rat2 or calculated by SectionRateCalculation.
+func (frame *Frame) WriteCsvRate(fn string, elems []*Elem) error {
+	header := append([]string{"ELEM", "ETYPE", "SECT"}, CsvRates...)
+	header = append(header, "MAX")
+	records := [][]string{header}
+	for _, el := range sortedElems(frame, elems) {
+		if el.MaxRate == nil {
+			continue
+		}
+		rec := []string{fmt.Sprintf("%d", el.Num), ETYPES[el.Etype], fmt.Sprintf("%d", el.Sect.Num)}
+		for i := range CsvRates {
+			if i < len(el.MaxRate) {
+				rec = append(rec, csvFloat(el.MaxRate[i]))
+			} else {
+				rec = append(rec, "")
+			}
+		}
+		max, err := el.RateMax(nil)
+		if err != nil {
+			rec = append(rec, "")
+		} else {
+			rec = append(rec, csvFloat(max))
+		}
+		records = append(records, rec)
+	}
+	return writeCsv(fn, records)
+}
+
+// ReadCsv updates the frame from a table written by WriteCsvNode, WriteCsvElem or WriteCsvSect
+// (possibly edited elsewhere) and returns the number of updated rows.
+// Only the columns present in the header are applied and blank cells are left unchanged,
+// so that a table with e.g. only NODE and Z columns moves nodes vertically.
+// Nodes, elems and sects must already exist; rows of unknown numbers are errors.
+func (frame *Frame) ReadCsv(fn string) (int, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.Comma = CsvComma(fn)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return 0, err
+	}
+	if len(records) < 1 {
+		return 0, errors.New("ReadCsv: no header")
+	}
+	header := make(map[string]int)
+	for i, h := range records[0] {
+		header[strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	if _, ok := header["PERIOD"]; ok {
+		return 0, errors.New("ReadCsv: results cannot be read")
+	}
+	var parse func(line int, cell func(string) (string, bool)) error
+	switch strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(records[0][0], "\ufeff"))) {
+	default:
+		return 0, fmt.Errorf("ReadCsv: unknown table %s", records[0][0])
+	case "NODE":
+		parse = frame.parseCsvNode
+	case "ELEM":
+		parse = frame.parseCsvElem
+	case "SECT":
+		parse = frame.parseCsvSect
+	}
+	num := 0
+	for i, rec := range records[1:] {
+		if len(rec) == 0 || (len(rec) == 1 && strings.TrimSpace(rec[0]) == "") {
+			continue
+		}
+		cell := func(name string) (string, bool) {
+			if ind, ok := header[name]; ok && ind < len(rec) {
+				val := strings.TrimSpace(rec[ind])
+				return val, val != ""
+			}
+			return "", false
+		}
+		err := parse(i+2, cell)
+		if err != nil {
+			return num, err
+		}
+		num++
+	}
+	return num, nil
+}
+
+func csvInt(line int, name, val string) (int, error) {
+	tmp, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("ReadCsv: line %d: %s: %s", line, name, err.Error())
+	}
+	return int(tmp), nil
+}
+
+func csvParseFloat(line int, name, val string) (float64, error) {
+	tmp, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0.0, fmt.Errorf("ReadCsv: line %d: %s: %s", line, name, err.Error())
+	}
+	return tmp, nil
+}
+
+func (frame *Frame) parseCsvNode(line int, cell func(string) (string, bool)) error {
+	val, _ := cell("NODE")
+	nnum, err := csvInt(line, "NODE", val)
+	if err != nil {
+		return err
+	}
+	n, ok := frame.Nodes[nnum]
+	if !ok {
+		return fmt.Errorf("ReadCsv: line %d: NODE %d not found", line, nnum)
+	}
+	for i, d := range []string{"X", "Y", "Z"} {
+		if v, ok := cell(d); ok {
+			n.Coord[i], err = csvParseFloat(line, d, v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for i, d := range CsvDirections {
+		if v, ok := cell("CONF" + d); ok {
+			n.Conf[i] = v != "0"
+		}
+		if v, ok := cell("LOAD" + d); ok {
+			n.Load[i], err = csvParseFloat(line, "LOAD"+d, v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (frame *Frame) parseCsvElem(line int, cell func(string) (string, bool)) error {
+	val, _ := cell("ELEM")
+	enum, err := csvInt(line, "ELEM", val)
+	if err != nil {
+		return err
+	}
+	el, ok := frame.Elems[enum]
+	if !ok {
+		return fmt.Errorf("ReadCsv: line %d: ELEM %d not found", line, enum)
+	}
+	if v, ok := cell("SECT"); ok {
+		snum, err := csvInt(line, "SECT", v)
+		if err != nil {
+			return err
+		}
+		sec, ok := frame.Sects[snum]
+		if !ok {
+			return fmt.Errorf("ReadCsv: line %d: SECT %d not found", line, snum)
+		}
+		el.Sect = sec
+	}
+	if v, ok := cell("CANG"); ok {
+		el.Cang, err = csvParseFloat(line, "CANG", v)
+		if err != nil {
+			return err
+		}
+		el.SetPrincipalAxis()
+	}
+	if v, ok := cell("BONDS"); ok && el.IsLineElem() {
+		lis := strings.Fields(v)
+		if len(lis) != len(el.Bonds) {
+			return fmt.Errorf("ReadCsv: line %d: BONDS: %d values are required", line, len(el.Bonds))
+		}
+		for i, b := range lis {
+			bnum, err := csvInt(line, "BONDS", b)
+			if err != nil {
+				return err
+			}
+			if bnum == 0 {
+				el.Bonds[i] = nil
+			} else if bond, ok := frame.Bonds[bnum]; ok {
+				el.Bonds[i] = bond
+			} else if bnum == 1 {
+				frame.Bonds[1] = Pin
+				el.Bonds[i] = Pin
+			} else {
+				return fmt.Errorf("ReadCsv: line %d: BOND %d not found", line, bnum)
+			}
+		}
+	}
+	return nil
+}
+
+func (frame *Frame) parseCsvSect(line int, cell func(string) (string, bool)) error {
+	val, _ := cell("SECT")
+	snum, err := csvInt(line, "SECT", val)
+	if err != nil {
+		return err
+	}
+	sec, ok := frame.Sects[snum]
+	if !ok {
+		return fmt.Errorf("ReadCsv: line %d: SECT %d not found", line, snum)
+	}
+	if v, ok := cell("NAME"); ok {
+		sec.Name = v
+	}
+	fnum := 1
+	if v, ok := cell("FIG"); ok {
+		fnum, err = csvInt(line, "FIG", v)
+		if err != nil {
+			return err
+		}
+	}
+	var fig *Fig
+	for _, f := range sec.Figs {
+		if f.Num == fnum {
+			fig = f
+			break
+		}
+	}
+	if fig == nil {
+		return fmt.Errorf("ReadCsv: line %d: SECT %d has no FIG %d", line, snum, fnum)
+	}
+	if v, ok := cell("PROP"); ok {
+		pnum, err := csvInt(line, "PROP", v)
+		if err != nil {
+			return err
+		}
+		p, ok := frame.Props[pnum]
+		if !ok {
+			return fmt.Errorf("ReadCsv: line %d: PROP %d not found", line, pnum)
+		}
+		fig.Prop = p
+	}
+	if v, ok := cell("SHAPE"); ok {
+		sh, _, err := ParseShape(append(strings.Fields(v), make([]string, 9)...))
+		if err != nil {
+			return fmt.Errorf("ReadCsv: line %d: SHAPE: %s", line, err.Error())
+		}
+		fig.Shape = sh
+	}
+	for k := range fig.Value {
+		if v, ok := cell(k); ok {
+			fig.Value[k], err = csvParseFloat(line, k, v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,805 @@
+package st
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Diff State
+const (
+	DIFF_SAME = iota
+	DIFF_ADDED
+	DIFF_REMOVED
+	DIFF_CHANGED
+)
+
+var (
+	DIFF_ADDED_COLOR   = GREEN
+	DIFF_REMOVED_COLOR = RED
+	DIFF_CHANGED_COLOR = YELLOW
+)
+
+var diffKinds = []string{"PROP", "BOND", "PILE", "SECT", "NODE", "ELEM"}
+
+// DiffItem is an object which differs between two frames.
+// Fields are the names of changed fields (CORD, CONF, SECT, ...) and Details describe their values.
+type DiffItem struct {
+	Kind    string
+	Num     int
+	State   int
+	Fields  []string
+	Details []string
+}
+
+func (item *DiffItem) String() string {
+	var rtn bytes.Buffer
+	switch item.State {
+	case DIFF_ADDED:
+		rtn.WriteString(fmt.Sprintf("+ %s %d", item.Kind, item.Num))
+	case DIFF_REMOVED:
+		rtn.WriteString(fmt.Sprintf("- %s %d", item.Kind, item.Num))
+	case DIFF_CHANGED:
+		rtn.WriteString(fmt.Sprintf("~ %s %d", item.Kind, item.Num))
+		if len(item.Fields) > 0 {
+			rtn.WriteString(fmt.Sprintf(" (%s)", strings.Join(item.Fields, ", ")))
+		}
+	}
+	for _, d := range item.Details {
+		rtn.WriteString(fmt.Sprintf("\n    %s", d))
+	}
+	return rtn.String()
+}
+
+// HasField reports whether name is one of the changed fields.
+func (item *DiffItem) HasField(name string) bool {
+	for _, f := range item.Fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FrameDiff is a structural difference between two frames.
+// Removed holds the elements of the old frame which don't exist in the new one, to be drawn as ghosts.
+type FrameDiff struct {
+	From    string
+	To      string
+	Eps     float64
+	Items   []*DiffItem
+	Removed []*Elem
+	index   map[inpKey]*DiffItem
+}
+
+type nodeDiffField struct {
+	name  string
+	get   func(*Node) string
+	equal func(*Node, *Node, float64) bool
+	set   func(*Frame, *Node, *Node) error
+}
+
+type elemDiffField struct {
+	name string
+	get  func(*Elem) string
+	set  func(*Frame, *Elem, *Elem) error
+}
+
+var nodeDiffFields = []nodeDiffField{
+	{
+		name: "CORD",
+		get: func(n *Node) string {
+			return fmt.Sprintf("%.3f %.3f %.3f", n.Coord[0], n.Coord[1], n.Coord[2])
+		},
+		equal: func(a, b *Node, eps float64) bool {
+			return Distance(a, b) <= eps
+		},
+		set: func(frame *Frame, dst, src *Node) error {
+			for i := 0; i < 3; i++ {
+				dst.Coord[i] = src.Coord[i]
+			}
+			return nil
+		},
+	},
+	{
+		name: "CONF",
+		get: func(n *Node) string {
+			var rtn bytes.Buffer
+			for i := 0; i < 6; i++ {
+				if n.Conf[i] {
+					rtn.WriteString("1")
+				} else {
+					rtn.WriteString("0")
+				}
+			}
+			return rtn.String()
+		},
+		set: func(frame *Frame, dst, src *Node) error {
+			for i := 0; i < 6; i++ {
+				dst.Conf[i] = src.Conf[i]
+			}
+			return nil
+		},
+	},
+	{
+		name: "LOAD",
+		get: func(n *Node) string {
+			return diffFloats(n.Load, "%.3f")
+		},
+		set: func(frame *Frame, dst, src *Node) error {
+			for i := 0; i < 6; i++ {
+				dst.Load[i] = src.Load[i]
+			}
+			return nil
+		},
+	},
+	{
+		name: "PILE",
+		get: func(n *Node) string {
+			if n.Pile == nil {
+				return "-"
+			}
+			return fmt.Sprintf("%d", n.Pile.Num)
+		},
+		set: func(frame *Frame, dst, src *Node) error {
+			if src.Pile == nil {
+				dst.Pile = nil
+				return nil
+			}
+			if p, ok := frame.Piles[src.Pile.Num]; ok {
+				dst.Pile = p
+				return nil
+			}
+			return fmt.Errorf("PILE %d doesn't exist", src.Pile.Num)
+		},
+	},
+}
+
+var elemDiffFields = []elemDiffField{
+	{
+		name: "ETYPE",
+		get: func(el *Elem) string {
+			return ETYPES[el.Etype]
+		},
+		set: func(frame *Frame, dst, src *Elem) error {
+			if dst.IsLineElem() != src.IsLineElem() {
+				return fmt.Errorf("cannot change %s to %s", ETYPES[dst.Etype], ETYPES[src.Etype])
+			}
+			dst.Etype = src.Etype
+			return nil
+		},
+	},
+	{
+		name: "SECT",
+		get: func(el *Elem) string {
+			return fmt.Sprintf("%d", el.Sect.Num)
+		},
+		set: func(frame *Frame, dst, src *Elem) error {
+			if sec, ok := frame.Sects[src.Sect.Num]; ok {
+				dst.Sect = sec
+				return nil
+			}
+			return fmt.Errorf("SECT %d doesn't exist", src.Sect.Num)
+		},
+	},
+	{
+		name: "ENOD",
+		get: func(el *Elem) string {
+			nums := make([]string, len(el.Enod))
+			for i, en := range el.Enod {
+				nums[i] = fmt.Sprintf("%d", en.Num)
+			}
+			return strings.Join(nums, " ")
+		},
+		set: func(frame *Frame, dst, src *Elem) error {
+			if dst.Enods != src.Enods {
+				return fmt.Errorf("ENODS %d != %d", dst.Enods, src.Enods)
+			}
+			enod := make([]*Node, src.Enods)
+			for i, en := range src.Enod {
+				n, ok := frame.Nodes[en.Num]
+				if !ok {
+					return fmt.Errorf("NODE %d doesn't exist", en.Num)
+				}
+				enod[i] = n
+			}
+			dst.Enod = enod
+			if dst.IsLineElem() {
+				return dst.SetPrincipalAxis()
+			}
+			return nil
+		},
+	},
+	{
+		name: "BONDS",
+		get: func(el *Elem) string {
+			nums := make([]string, len(el.Bonds))
+			for i, b := range el.Bonds {
+				if b == nil {
+					nums[i] = "0"
+				} else {
+					nums[i] = fmt.Sprintf("%d", b.Num)
+				}
+			}
+			return strings.Join(nums, " ")
+		},
+		set: func(frame *Frame, dst, src *Elem) error {
+			if len(dst.Bonds) != len(src.Bonds) {
+				return fmt.Errorf("number of BONDS %d != %d", len(dst.Bonds), len(src.Bonds))
+			}
+			for i, b := range src.Bonds {
+				if b == nil {
+					dst.Bonds[i] = nil
+				} else if b.Num == Pin.Num {
+					dst.Bonds[i] = Pin
+				} else if nb, ok := frame.Bonds[b.Num]; ok {
+					dst.Bonds[i] = nb
+				} else {
+					return fmt.Errorf("BOND %d doesn't exist", b.Num)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		name: "CANG",
+		get: func(el *Elem) string {
+			return fmt.Sprintf("%.5f", el.Cang)
+		},
+		set: func(frame *Frame, dst, src *Elem) error {
+			dst.Cang = src.Cang
+			if dst.IsLineElem() {
+				return dst.SetPrincipalAxis()
+			}
+			return nil
+		},
+	},
+	{
+		name: "CMQ",
+		get: func(el *Elem) string {
+			return diffFloats(el.Cmq, "%.3f")
+		},
+		set: func(frame *Frame, dst, src *Elem) error {
+			dst.Cmq = make([]float64, len(src.Cmq))
+			copy(dst.Cmq, src.Cmq)
+			return nil
+		},
+	},
+	{
+		name: "WRECT",
+		get: func(el *Elem) string {
+			return diffFloats(el.Wrect, "%.3f")
+		},
+		set: func(frame *Frame, dst, src *Elem) error {
+			dst.Wrect = make([]float64, len(src.Wrect))
+			copy(dst.Wrect, src.Wrect)
+			return nil
+		},
+	},
+}
+
+func diffFloats(vals []float64, format string) string {
+	strs := make([]string, len(vals))
+	for i, v := range vals {
+		strs[i] = fmt.Sprintf(format, v)
+	}
+	return strings.Join(strs, " ")
+}
+
+// diffLines returns lines of b differing from a, prefixed by "-" or "+".
+func diffLines(a, b string) []string {
+	la := strings.Split(strings.TrimRight(a, "\n"), "\n")
+	lb := strings.Split(strings.TrimRight(b, "\n"), "\n")
+	rtn := make([]string, 0)
+	for i := 0; i < len(la) || i < len(lb); i++ {
+		var sa, sb string
+		if i < len(la) {
+			sa = la[i]
+		}
+		if i < len(lb) {
+			sb = lb[i]
+		}
+		if sa == sb {
+			continue
+		}
+		if sa != "" {
+			rtn = append(rtn, "- "+strings.TrimSpace(sa))
+		}
+		if sb != "" {
+			rtn = append(rtn, "+ "+strings.TrimSpace(sb))
+		}
+	}
+	return rtn
+}
+
+// diffNums returns the sorted numbers of kind in frame. Elements and sections not written to .inp are excluded.
+func diffNums(frame *Frame, kind string) []int {
+	rtn := make([]int, 0)
+	switch kind {
+	case "PROP":
+		for num := range frame.Props {
+			rtn = append(rtn, num)
+		}
+	case "BOND":
+		for num := range frame.Bonds {
+			rtn = append(rtn, num)
+		}
+	case "PILE":
+		for num := range frame.Piles {
+			rtn = append(rtn, num)
+		}
+	case "SECT":
+		for num := range frame.Sects {
+			if num < 100 || num > 900 {
+				continue
+			}
+			rtn = append(rtn, num)
+		}
+	case "NODE":
+		for num := range frame.Nodes {
+			rtn = append(rtn, num)
+		}
+	case "ELEM":
+		for num, el := range frame.Elems {
+			if el.Etype == WBRACE || el.Etype == SBRACE {
+				continue
+			}
+			rtn = append(rtn, num)
+		}
+	}
+	sort.Ints(rtn)
+	return rtn
+}
+
+func diffString(frame *Frame, kind string, num int) string {
+	switch kind {
+	case "PROP":
+		return frame.Props[num].InpString()
+	case "BOND":
+		return frame.Bonds[num].InpString()
+	case "PILE":
+		return frame.Piles[num].InpString()
+	case "SECT":
+		return frame.Sects[num].InpString()
+	case "NODE":
+		return frame.Nodes[num].InpString()
+	case "ELEM":
+		return frame.Elems[num].InpString()
+	}
+	return ""
+}
+
+// diffObject compares objects with the same number and returns changed fields and their details.
+func diffObject(a, b *Frame, kind string, num int, eps float64) ([]string, []string) {
+	fields := make([]string, 0)
+	details := make([]string, 0)
+	switch kind {
+	case "NODE":
+		na, nb := a.Nodes[num], b.Nodes[num]
+		for _, f := range nodeDiffFields {
+			va, vb := f.get(na), f.get(nb)
+			if f.equal != nil {
+				if f.equal(na, nb, eps) {
+					continue
+				}
+			} else if va == vb {
+				continue
+			}
+			fields = append(fields, f.name)
+			details = append(details, fmt.Sprintf("%s: %s -> %s", f.name, va, vb))
+		}
+	case "ELEM":
+		ea, eb := a.Elems[num], b.Elems[num]
+		for _, f := range elemDiffFields {
+			va, vb := f.get(ea), f.get(eb)
+			if va == vb {
+				continue
+			}
+			fields = append(fields, f.name)
+			details = append(details, fmt.Sprintf("%s: %s -> %s", f.name, va, vb))
+		}
+	default:
+		sa, sb := diffString(a, kind, num), diffString(b, kind, num)
+		if sa != sb {
+			details = diffLines(sa, sb)
+		}
+	}
+	return fields, details
+}
+
+// DiffFrame compares frame a (old) with b (new) by number.
+// Nodes are taken as moved if the distance is larger than eps.
+func DiffFrame(a, b *Frame, eps float64) *FrameDiff {
+	d := &FrameDiff{
+		From:    a.Name,
+		To:      b.Name,
+		Eps:     eps,
+		Items:   make([]*DiffItem, 0),
+		Removed: make([]*Elem, 0),
+		index:   make(map[inpKey]*DiffItem),
+	}
+	for _, kind := range diffKinds {
+		na := diffNums(a, kind)
+		nb := diffNums(b, kind)
+		inb := make(map[int]bool, len(nb))
+		for _, num := range nb {
+			inb[num] = true
+		}
+		ina := make(map[int]bool, len(na))
+		for _, num := range na {
+			ina[num] = true
+			if !inb[num] {
+				d.add(&DiffItem{Kind: kind, Num: num, State: DIFF_REMOVED})
+				if kind == "ELEM" {
+					d.Removed = append(d.Removed, a.Elems[num])
+				}
+				continue
+			}
+			fields, details := diffObject(a, b, kind, num, eps)
+			if len(details) > 0 {
+				d.add(&DiffItem{Kind: kind, Num: num, State: DIFF_CHANGED, Fields: fields, Details: details})
+			}
+		}
+		for _, num := range nb {
+			if !ina[num] {
+				d.add(&DiffItem{Kind: kind, Num: num, State: DIFF_ADDED})
+			}
+		}
+	}
+	sort.SliceStable(d.Items, func(i, j int) bool {
+		if d.Items[i].Kind != d.Items[j].Kind {
+			return diffKindIndex(d.Items[i].Kind) < diffKindIndex(d.Items[j].Kind)
+		}
+		return d.Items[i].Num < d.Items[j].Num
+	})
+	return d
+}
+
+func diffKindIndex(kind string) int {
+	for i, k := range diffKinds {
+		if k == kind {
+			return i
+		}
+	}
+	return len(diffKinds)
+}
+
+func (d *FrameDiff) add(item *DiffItem) {
+	d.Items = append(d.Items, item)
+	d.index[inpKey{item.Kind, item.Num}] = item
+}
+
+// Item returns the DiffItem of the object, or nil if it is the same in both frames.
+func (d *FrameDiff) Item(kind string, num int) *DiffItem {
+	if d == nil {
+		return nil
+	}
+	return d.index[inpKey{kind, num}]
+}
+
+// State returns DIFF_SAME, DIFF_ADDED, DIFF_REMOVED or DIFF_CHANGED.
+func (d *FrameDiff) State(kind string, num int) int {
+	if item := d.Item(kind, num); item != nil {
+		return item.State
+	}
+	return DIFF_SAME
+}
+
+// ElemState returns the state of elem. An element whose nodes have been moved is taken as changed.
+func (d *FrameDiff) ElemState(elem *Elem) int {
+	if st := d.State("ELEM", elem.Num); st != DIFF_SAME {
+		return st
+	}
+	for _, en := range elem.Enod {
+		if item := d.Item("NODE", en.Num); item != nil && item.HasField("CORD") {
+			return DIFF_CHANGED
+		}
+	}
+	return DIFF_SAME
+}
+
+// Count returns the number of added, removed and changed objects.
+func (d *FrameDiff) Count() (int, int, int) {
+	var added, removed, changed int
+	for _, item := range d.Items {
+		switch item.State {
+		case DIFF_ADDED:
+			added++
+		case DIFF_REMOVED:
+			removed++
+		case DIFF_CHANGED:
+			changed++
+		}
+	}
+	return added, removed, changed
+}
+
+// String returns a report of the difference.
+func (d *FrameDiff) String() string {
+	var rtn bytes.Buffer
+	rtn.WriteString(fmt.Sprintf("DIFF: %s -> %s\n", d.From, d.To))
+	for _, item := range d.Items {
+		rtn.WriteString(item.String())
+		rtn.WriteString("\n")
+	}
+	added, removed, changed := d.Count()
+	rtn.WriteString(fmt.Sprintf("%d added, %d removed, %d changed", added, removed, changed))
+	return rtn.String()
+}
+
+// DiffColor returns the color to draw objects of state.
+func DiffColor(state int) int {
+	switch state {
+	case DIFF_ADDED:
+		return DIFF_ADDED_COLOR
+	case DIFF_REMOVED:
+		return DIFF_REMOVED_COLOR
+	case DIFF_CHANGED:
+		return DIFF_CHANGED_COLOR
+	default:
+		return DARK_GRAY
+	}
+}
+
+// MergeConflict is a change which cannot be merged automatically.
+type MergeConflict struct {
+	Kind    string
+	Num     int
+	Message string
+}
+
+func (c *MergeConflict) String() string {
+	return fmt.Sprintf("CONFLICT: %s %d: %s", c.Kind, c.Num, c.Message)
+}
+
+// MergeFrame merges the changes from base to theirs into ours, and returns the merged frame (ours is not modified).
+// Fields of nodes and elements changed on only one side are merged; an object changed differently on both sides,
+// or removed on one side and changed on the other, is a conflict and ours is kept.
+func MergeFrame(base, ours, theirs *Frame, eps float64) (*Frame, []*MergeConflict) {
+	rtn := ours.Snapshot()
+	conflicts := make([]*MergeConflict, 0)
+	conflict := func(item *DiffItem, format string, a ...interface{}) {
+		conflicts = append(conflicts, &MergeConflict{Kind: item.Kind, Num: item.Num, Message: fmt.Sprintf(format, a...)})
+	}
+	dours := DiffFrame(base, ours, eps)
+	dtheirs := DiffFrame(base, theirs, eps)
+	for _, t := range dtheirs.Items {
+		o := dours.Item(t.Kind, t.Num)
+		switch t.State {
+		case DIFF_ADDED:
+			if o == nil {
+				if err := mergeCopy(rtn, theirs, t.Kind, t.Num); err != nil {
+					conflict(t, "%s", err.Error())
+				}
+			} else if diffString(ours, t.Kind, t.Num) != diffString(theirs, t.Kind, t.Num) {
+				conflict(t, "added differently on both sides")
+			}
+		case DIFF_CHANGED:
+			if o == nil {
+				if err := mergeChange(rtn, theirs, t, nil, eps); err != nil {
+					conflict(t, "%s", err.Error())
+				}
+				break
+			}
+			switch o.State {
+			case DIFF_REMOVED:
+				conflict(t, "removed in ours but changed in theirs")
+			case DIFF_CHANGED:
+				if err := mergeChange(rtn, theirs, t, o, eps); err != nil {
+					conflict(t, "%s", err.Error())
+				}
+			}
+		}
+	}
+	// remove elements before nodes and sections
+	for i := len(dtheirs.Items) - 1; i >= 0; i-- {
+		t := dtheirs.Items[i]
+		if t.State != DIFF_REMOVED {
+			continue
+		}
+		o := dours.Item(t.Kind, t.Num)
+		if o != nil {
+			if o.State == DIFF_CHANGED {
+				conflict(t, "changed in ours but removed in theirs")
+			}
+			continue
+		}
+		if user := mergeUser(rtn, t.Kind, t.Num); user != "" {
+			conflict(t, "removed in theirs but used by %s", user)
+			continue
+		}
+		switch t.Kind {
+		case "PROP":
+			delete(rtn.Props, t.Num)
+		case "BOND":
+			delete(rtn.Bonds, t.Num)
+		case "PILE":
+			delete(rtn.Piles, t.Num)
+		case "SECT":
+			rtn.DeleteSect(t.Num)
+		case "NODE":
+			rtn.DeleteNode(t.Num)
+		case "ELEM":
+			rtn.DeleteElem(t.Num)
+		}
+	}
+	rtn.relink()
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		if conflicts[i].Kind != conflicts[j].Kind {
+			return diffKindIndex(conflicts[i].Kind) < diffKindIndex(conflicts[j].Kind)
+		}
+		return conflicts[i].Num < conflicts[j].Num
+	})
+	return rtn, conflicts
+}
+
+// mergeCopy copies an object of src into frame.
+func mergeCopy(frame, src *Frame, kind string, num int) error {
+	switch kind {
+	case "PROP":
+		frame.Props[num] = src.Props[num].Snapshot()
+	case "BOND":
+		frame.Bonds[num] = src.Bonds[num].Snapshot()
+	case "PILE":
+		frame.Piles[num] = src.Piles[num].Snapshot()
+	case "SECT":
+		for _, f := range src.Sects[num].Figs {
+			if _, ok := frame.Props[f.Prop.Num]; !ok {
+				return fmt.Errorf("PROP %d doesn't exist", f.Prop.Num)
+			}
+		}
+		frame.Sects[num] = src.Sects[num].Snapshot(frame)
+		if num > frame.Maxsnum {
+			frame.Maxsnum = num
+		}
+	case "NODE":
+		n := src.Nodes[num]
+		if n.Pile != nil {
+			if _, ok := frame.Piles[n.Pile.Num]; !ok {
+				return fmt.Errorf("PILE %d doesn't exist", n.Pile.Num)
+			}
+		}
+		frame.Nodes[num] = n.Snapshot(frame)
+		if num > frame.Maxnnum {
+			frame.Maxnnum = num
+		}
+	case "ELEM":
+		el := src.Elems[num]
+		for _, en := range el.Enod {
+			if _, ok := frame.Nodes[en.Num]; !ok {
+				return fmt.Errorf("NODE %d doesn't exist", en.Num)
+			}
+		}
+		if _, ok := frame.Sects[el.Sect.Num]; !ok {
+			return fmt.Errorf("SECT %d doesn't exist", el.Sect.Num)
+		}
+		frame.Elems[num] = el.Snapshot(frame)
+		if num > frame.Maxenum {
+			frame.Maxenum = num
+		}
+	}
+	return nil
+}
+
+// mergeChange applies the change t of src to frame. o is the change of the other side (may be nil).
+func mergeChange(frame, src *Frame, t, o *DiffItem, eps float64) error {
+	switch t.Kind {
+	case "NODE":
+		dst, sn := frame.Nodes[t.Num], src.Nodes[t.Num]
+		for _, f := range nodeDiffFields {
+			if !t.HasField(f.name) {
+				continue
+			}
+			if o != nil && o.HasField(f.name) {
+				if f.equal != nil && f.equal(dst, sn, eps) || f.equal == nil && f.get(dst) == f.get(sn) {
+					continue
+				}
+				return fmt.Errorf("%s changed on both sides: %s, %s", f.name, f.get(dst), f.get(sn))
+			}
+			if err := f.set(frame, dst, sn); err != nil {
+				return err
+			}
+		}
+	case "ELEM":
+		dst, se := frame.Elems[t.Num], src.Elems[t.Num]
+		for _, f := range elemDiffFields {
+			if !t.HasField(f.name) {
+				continue
+			}
+			if o != nil && o.HasField(f.name) {
+				if f.get(dst) == f.get(se) {
+					continue
+				}
+				return fmt.Errorf("%s changed on both sides: %s, %s", f.name, f.get(dst), f.get(se))
+			}
+			if err := f.set(frame, dst, se); err != nil {
+				return err
+			}
+		}
+	default:
+		if o != nil && diffString(frame, t.Kind, t.Num) != diffString(src, t.Kind, t.Num) {
+			return fmt.Errorf("changed differently on both sides")
+		}
+		return mergeCopy(frame, src, t.Kind, t.Num)
+	}
+	return nil
+}
+
+// mergeUser returns an object of frame which refers to the object, or "" if there is none.
+func mergeUser(frame *Frame, kind string, num int) string {
+	switch kind {
+	case "PROP":
+		for _, sec := range frame.Sects {
+			for _, f := range sec.Figs {
+				if f.Prop != nil && f.Prop.Num == num {
+					return fmt.Sprintf("SECT %d", sec.Num)
+				}
+			}
+		}
+	case "BOND":
+		for _, el := range frame.Elems {
+			for _, b := range el.Bonds {
+				if b != nil && b.Num == num {
+					return fmt.Sprintf("ELEM %d", el.Num)
+				}
+			}
+		}
+	case "PILE":
+		for _, n := range frame.Nodes {
+			if n.Pile != nil && n.Pile.Num == num {
+				return fmt.Sprintf("NODE %d", n.Num)
+			}
+		}
+	case "SECT":
+		for _, el := range frame.Elems {
+			if el.Sect.Num == num {
+				return fmt.Sprintf("ELEM %d", el.Num)
+			}
+		}
+	case "NODE":
+		for _, el := range frame.Elems {
+			for _, en := range el.Enod {
+				if en.Num == num {
+					return fmt.Sprintf("ELEM %d", el.Num)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// relink points references of elements, sections and nodes to the objects of frame with the same number.
+func (frame *Frame) relink() {
+	for _, sec := range frame.Sects {
+		for _, f := range sec.Figs {
+			if f.Prop == nil {
+				continue
+			}
+			if p, ok := frame.Props[f.Prop.Num]; ok {
+				f.Prop = p
+			}
+		}
+	}
+	for _, n := range frame.Nodes {
+		if n.Pile == nil {
+			continue
+		}
+		if p, ok := frame.Piles[n.Pile.Num]; ok {
+			n.Pile = p
+		}
+	}
+	for _, el := range frame.Elems {
+		if sec, ok := frame.Sects[el.Sect.Num]; ok {
+			el.Sect = sec
+		}
+		for i, b := range el.Bonds {
+			if b == nil || b == Pin {
+				continue
+			}
+			if nb, ok := frame.Bonds[b.Num]; ok {
+				el.Bonds[i] = nb
+			}
+		}
+	}
+}
@@ -231,6 +231,13 @@ func DrawElem(stw Drawer, elem *Elem, show *Show) {
 			oncap = true
 		}
 	}
+	if show.Service {
+		val, err := elem.ServiceRate()
+		if err == nil {
+			ecap.WriteString(fmt.Sprintf(fmt.Sprintf("%s\n", show.Formats["RATE"]), val))
+			oncap = true
+		}
+	}
 	if elem.IsLineElem() {
 		var icoord, jcoord []float64
 		if show.PlotState&PLOT_UNDEFORMED != 0 {
@@ -962,6 +969,13 @@ func DrawNode(stw Drawer, node *Node, show *Show) {
 			oncap = true
 		}
 	}
+	if show.StrengthRatio {
+		if val, ok := node.Values["STRENGTHRATIO"]; ok {
+			stw.FilledCircle(coord[0], coord[1], show.ConfSize)
+			ncap.WriteString(fmt.Sprintf("%.2f\n", val))
+			oncap = true
+		}
+	}
 	if oncap {
 		stw.Text(coord[0], coord[1], strings.TrimSuffix(ncap.String(), "\n"))
 	}
@@ -1172,6 +1186,16 @@ func DrawFrame(stw Drawer, frame *Frame, color uint, flush bool) {
 					stw.Foreground(CYAN)
 				}
 			}
+			if color == ECOLOR_DIFF {
+				if state := show.Diff.State("NODE", n.Num); state != DIFF_SAME {
+					stw.Foreground(DiffColor(state))
+				}
+			}
+			if show.StrengthRatio {
+				if val, ok := n.Values["STRENGTHRATE"]; ok {
+					stw.Foreground(Rainbow(val, RateBoundary))
+				}
+			}
 			for _, j := range stw.SelectedNodes() {
 				if j == n {
 					stw.SelectNodeStyle()
@@ -1285,10 +1309,22 @@ func DrawFrame(stw Drawer, frame *Frame, color uint, flush bool) {
 					} else {
 						stw.Foreground(Rainbow(val, EnergyBoundary))
 					}
+				case ECOLOR_SERVICE:
+					val, err := el.ServiceRate()
+					if err != nil {
+						stw.Foreground(DARK_GRAY)
+					} else {
+						stw.Foreground(Rainbow(val, RateBoundary))
+					}
+				case ECOLOR_DIFF:
+					stw.Foreground(DiffColor(show.Diff.ElemState(el)))
 				}
 			}
 			DrawElem(stw, el, show)
 		}
+		if color == ECOLOR_DIFF {
+			DrawDiffRemoved(stw, frame, show)
+		}
 	}
 	nomv := show.NoMomentValue
 	nosv := show.NoShearValue
@@ -1338,6 +1374,15 @@ func DrawFrame(stw Drawer, frame *Frame, color uint, flush bool) {
 				} else {
 					stw.Foreground(Rainbow(val, EnergyBoundary))
 				}
+			case ECOLOR_SERVICE:
+				val, err := el.ServiceRate()
+				if err != nil {
+					stw.Foreground(DARK_GRAY)
+				} else {
+					stw.Foreground(Rainbow(val, RateBoundary))
+				}
+			case ECOLOR_DIFF:
+				stw.Foreground(DiffColor(show.Diff.ElemState(el)))
 			}
 		}
 		DrawElem(stw, el, show)
@@ -1367,6 +1412,27 @@ func DrawFrame(stw Drawer, frame *Frame, color uint, flush bool) {
 	}
 }
 
+// DrawDiffRemoved draws elements removed from the frame compared by :diff as dotted lines.
+func DrawDiffRemoved(stw Drawer, frame *Frame, show *Show) {
+	if show.Diff == nil {
+		return
+	}
+	stw.LineStyle(DOTTED)
+	stw.Foreground(DiffColor(DIFF_REMOVED))
+	for _, el := range show.Diff.Removed {
+		coords := make([][]float64, len(el.Enod))
+		for i, en := range el.Enod {
+			coords[i] = frame.View.ProjectCoord(en.Coord)
+		}
+		if el.IsLineElem() {
+			stw.Line(coords[0][0], coords[0][1], coords[1][0], coords[1][1])
+		} else {
+			stw.Polyline(append(coords, coords[0]))
+		}
+	}
+	stw.DefaultStyle()
+}
+
 func DrawFrameNode(stw Drawer, frame *Frame, color uint, flush bool) {
 	if frame == nil {
 		if flush {
@@ -1473,7 +1539,7 @@ func DrawPrintRange(stw Drawer) {
 }
 
 func DrawLegend(stw Drawer, show *Show) {
-	if !show.NoLegend && show.ColorMode == ECOLOR_RATE {
+	if !show.NoLegend && (show.ColorMode == ECOLOR_RATE || show.ColorMode == ECOLOR_SERVICE || show.StrengthRatio) {
 		d := 1.0
 		if stw.CanvasDirection() == 1 {
 			d = -1.0
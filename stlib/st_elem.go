@@ -61,6 +61,8 @@ type Elem struct {
 	Cmq   []float64
 	Wrect []float64
 
+	EffectiveCmq []float64 // CMQ of L including slab and self weight, set by ExtractArclm
+
 	Skip []bool
 
 	Rate    map[string][]float64
@@ -158,6 +160,10 @@ func (elem *Elem) Snapshot(frame *Frame) *Elem {
 			el.Bonds[i] = elem.Bonds[i]
 			el.Cmq[i] = elem.Cmq[i]
 		}
+		if elem.EffectiveCmq != nil {
+			el.EffectiveCmq = make([]float64, 12)
+			copy(el.EffectiveCmq, elem.EffectiveCmq)
+		}
 		el.MaxRate = make([]float64, len(elem.MaxRate))
 		for i, r := range elem.MaxRate {
 			el.MaxRate[i] = r
@@ -686,23 +692,18 @@ func (elem *Elem) OutputRateInformation(long, x1, x2, y1, y2 string, sign float6
 	case COLUMN, GIRDER:
 		var isrc bool
 		switch al.(type) {
-		case *RCColumn, *RCGirder:
+		case *RCColumn, *RCGirder, *CFTColumn, *SRCColumn, *SRCGirder:
 			isrc = true
 		default:
 			isrc = false
 		}
-		if isrc && elem.Condition.Qfact < 1.5 {
-			for i := 0; i < 4; i++ {
-				factor[i][1] = 1.5
-				factor[i][2] = 1.5
-			}
-		} else {
-			for i := 0; i < 4; i++ {
-				factor[i][1] = elem.Condition.Qfact
-				factor[i][2] = elem.Condition.Qfact
-			}
+		qfact := elem.Condition.DesignCode().Amplify(al, "Q", elem.Condition.Qfact)
+		for i := 0; i < 4; i++ {
+			factor[i][1] = qfact
+			factor[i][2] = qfact
 		}
 		elem.Condition.Length = elem.Length() * 100.0 // [cm]
+		elem.SetLateralBuckling()
 		otp.WriteString(strings.Repeat("-", 202))
 		otp.WriteString(fmt.Sprintf("\n部材:%d 始端:%d 終端:%d 断面:%d=%s 材長=%.1f[cm] Mx内法=%.1f[cm] My内法=%.1f[cm]", elem.Num, elem.Enod[0].Num, elem.Enod[1].Num, elem.Sect.Num, strings.Replace(al.TypeString(), "　", "", -1), elem.Condition.Length, elem.Condition.Length, elem.Condition.Length))
 		tex.WriteString(fmt.Sprintf("\\multicolumn{11}{l}{\\textsb{部材:%d 始端:%d 終端:%d 断面:%d=%s 材長=%.1f[cm] Mx内法=%.1f[cm] My内法=%.1f[cm]}}\\\\\n", elem.Num, elem.Enod[0].Num, elem.Enod[1].Num, elem.Sect.Num, strings.Replace(al.TypeString(), "　", "", -1), elem.Condition.Length, elem.Condition.Length, elem.Condition.Length))
@@ -887,29 +888,19 @@ func (elem *Elem) OutputRateInformation(long, x1, x2, y1, y2 string, sign float6
 		tex.WriteString(fmt.Sprintf("\\\\\n\\multicolumn{11}{l}{MAX:$Q/Q_{aL}=%.5f, Q/Q_{aS}=%.5f, M/M_{aL}=%.5f, M/M_{aS}=%.5f$}\\\\\n\\\\ \\hline\n\\\\\n", qlrate, qsrate, mlrate, msrate))
 		elem.MaxRate = []float64{qlrate, qsrate, qurate, mlrate, msrate, murate}
 	case BRACE, WBRACE, SBRACE:
-		var isrc bool
-		switch al.(type) {
-		case *RCWall, *RCGirder:
-			isrc = true
-		default:
-			isrc = false
-		}
 		var qlrate, qsrate, qurate float64
 		var fact float64
 		if elem.Etype == BRACE {
-			fact = elem.Condition.Bfact
+			fact = elem.Condition.DesignCode().Amplify(al, "B", elem.Condition.Bfact)
 			elem.Condition.Length = elem.Length() * 100.0 // [cm]
 		} else {
-			fact = elem.Condition.Wfact
+			fact = elem.Condition.DesignCode().Amplify(al, "W", elem.Condition.Wfact)
 			if bros, ok := elem.Brother(); ok {
 				elem.Condition.Length = 0.5 * (elem.Length() + bros.Length()) * 100.0 // [cm]
 			} else {
 				elem.Condition.Length = elem.Length() * 100.0 // [cm]
 			}
 		}
-		if isrc && fact < 2.0 {
-			fact = 2.0
-		}
 		elem.Condition.Width = elem.Width() * 100.0 // [cm]
 		elem.Condition.Height = elem.Height() * 100.0 // [cm]
 		otp.WriteString(strings.Repeat("-", 202))
@@ -3176,6 +3167,13 @@ func (elem *Elem) CurrentValue(show *Show, max, abs bool) float64 {
 		}
 		return val
 	}
+	if show.Service {
+		val, err := elem.ServiceRate()
+		if err != nil {
+			return 0.0
+		}
+		return val
+	}
 	if show.YieldFunction {
 		f, err := elem.YieldFunction(show.Period)
 		if err[0] != nil || err[1] != nil {
@@ -41,15 +41,18 @@ var (
 		"cw/eak":           complete.MustCompile(":cweak _ _ _ _", nil),
 		"pla/te":           complete.MustCompile(":plate _ _", nil),
 		"ang/le":           complete.MustCompile(":angle _ _ _ _", nil),
+		"stee/l":           complete.MustCompile(":steel _", nil),
+		"catal/og":         complete.MustCompile(":catalog _", nil),
+		"polyg/on":         complete.MustCompile(":polygon [layer:_] [scale:_] %g", nil),
 		"fixr/otate":       complete.MustCompile(":fixrotate", nil),
 		"fixm/ove":         complete.MustCompile(":fixmove", nil),
 		"noun/do":          complete.MustCompile(":noundo", nil),
 		"un/do":            complete.MustCompile(":undo", nil),
 		"w/rite":           complete.MustCompile(":write %g", nil),
-		"sav/e":            complete.MustCompile(":save [mkdir:] %g", nil),
+		"sav/e":            complete.MustCompile(":save [mkdir:] [results:] [flatten:] %g", nil),
 		"inc/rement":       complete.MustCompile(":increment [times:_] _", nil),
 		"c/heck":           complete.MustCompile(":check", nil),
-		"r/ead":            complete.MustCompile(":read %g", nil),
+		"r/ead":            complete.MustCompile(":read [strict:] %g", nil),
 		"ins/ert":          complete.MustCompile(":insert %g", nil),
 		"p/rop/s/ect":      complete.MustCompile(":propsect %g", nil),
 		"w/rite/o/utput":   complete.MustCompile(":writeoutput _", nil),
@@ -69,6 +72,13 @@ var (
 			map[string][]string{
 				"DNUM": []string{"2", "3"},
 			}),
+		"csv/": complete.MustCompile(":csv $TYPE [period:_] _",
+			map[string][]string{
+				"TYPE": []string{"node", "elem", "sect", "disp", "reaction", "stress", "rate", "read"},
+			}),
+		"imp/ort":           complete.MustCompile(":import [pattern:_] [eps:_] %g", nil),
+		"diff/":             complete.MustCompile(":diff [eps:_] [off:] _ _", nil),
+		"mer/ge":            complete.MustCompile(":merge [eps:_] _ _", nil),
 		"plan/":             complete.MustCompile(":plan [floor:_]", nil),
 		"jiku/":             complete.MustCompile(":jiku [name:_]", nil),
 		"crosssec/tion":     complete.MustCompile(":crosssection [axis:_] [min:_] [max:_]", nil),
@@ -108,17 +118,21 @@ var (
 		"e/lem/dup/lication": complete.MustCompile(":elemduplication [ignoresect:]", nil),
 		"n/ode/n/oreference": complete.MustCompile(":nodenoreference", nil),
 		"i/ntersect/a/ll":    complete.MustCompile(":intersectall", nil),
-		"src/al":             complete.MustCompile(":srcal [fbold:] [noreload:] [qfact:_] [wfact:_] [bfact:_] [skipshort:] [temporary:] [moeshiro:]", nil),
+		"src/al":             complete.MustCompile(":srcal [fbold:] [joint:_] [noreload:] [qfact:_] [wfact:_] [bfact:_] [skipshort:] [temporary:] [moeshiro:]", nil),
+		"desi/gncode":        complete.MustCompile(":designcode _", nil),
+		"siz/ing":            complete.MustCompile(":sizing [target:_] [maxiter:_] [noreload:] %g", nil),
+		"serv/iceability":    complete.MustCompile(":serviceability [ratio:_] [absolute:_] [cantilever:_] [frequency:_] %g", nil),
+		"str/engthratio":     complete.MustCompile(":strengthratio [ratio:_] [period:_] %g", nil),
+		"ult/imatestrength":  complete.MustCompile(":ultimatestrength [drift:_] [period:_] %g", nil),
 		"co/nf":              complete.MustCompile(":conf", nil),
 		"pi/le":              complete.MustCompile(":pile", nil),
 		"sec/tion":           complete.MustCompile(":section [nodisp:]_", nil),
 		"c/urrent/v/alue":    complete.MustCompile(":currentvalue [abs:]", nil),
 		"len/gth":            complete.MustCompile(":length [deformed:]", nil),
 		"are/a":              complete.MustCompile(":area [deformed:]", nil),
-		"an/alysis": complete.MustCompile(":analysis [period:$PERIOD] [all:] [solver:$SOLVER] [eps:_] [nlgeom:] [nlmat:] [step:_] [noinit:] [wait:] [post:_] [sects:_] [comp:_] [z:_] _",
+		"lo/ad/p/eriod": complete.MustCompile(":loadperiod $SUB _ _ _",
 			map[string][]string{
-				"PERIOD": []string{"l", "x", "y"},
-				"SOLVER": []string{"LLS", "CRS", "CG", "PCG"},
+				"SUB": []string{"list", "add", "delete"},
 			}),
 		"f/ilter": complete.MustCompile(":filter $CONDITION",
 			map[string][]string{
@@ -570,6 +584,64 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 		if pipe {
 			sender = []interface{}{al}
 		}
+	case "steel":
+		if usage {
+			return Usage(":steel designation")
+		}
+		if narg < 2 {
+			return NotEnoughArgs(":steel")
+		}
+		e, err := LookupSteel(args[1])
+		if err != nil {
+			return err
+		}
+		al := e.Shape()
+		stw.ShapeData(al)
+		if pipe {
+			sender = []interface{}{al}
+		}
+	case "catalog":
+		if usage {
+			return Usage(":catalog [prefix]")
+		}
+		prefix := ""
+		if narg >= 2 {
+			prefix = args[1]
+		}
+		es, err := SearchSteel(prefix)
+		if err != nil {
+			return err
+		}
+		if len(es) == 0 {
+			return fmt.Errorf("no shape: %s", prefix)
+		}
+		for _, e := range es {
+			stw.History(e.String())
+		}
+		return Message(fmt.Sprintf("%d shapes", len(es)))
+	case "polygon":
+		if usage {
+			return Usage(":polygon {-layer=name} {-scale=0.1} filename")
+		}
+		if fn == "" {
+			return NotEnoughArgs(":polygon")
+		}
+		scale := 0.1
+		if sc, ok := argdict["SCALE"]; ok {
+			val, err := strconv.ParseFloat(sc, 64)
+			if err != nil {
+				return err
+			}
+			scale = val
+		}
+		al, err := ReadDxfShape(fn, argdict["LAYER"], scale)
+		if err != nil {
+			return err
+		}
+		stw.ShapeData(al)
+		if pipe {
+			sender = []interface{}{al}
+		}
 	case "fixrotate":
 		stw.ToggleFixRotate()
 	case "fixmove":
@@ -655,16 +727,47 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 		}
 	case "save":
 		if usage {
-			return Usage(":save filename {-u=.strc}")
+			return Usage(":save [json] filename {-u=.strc} [-results] [-flatten]")
+		}
+		if narg >= 2 && strings.ToLower(args[1]) == "yaml" {
+			return errors.New(":save: YAML is not supported, use :save json")
+		}
+		if narg >= 2 && strings.ToLower(args[1]) == "json" {
+			if narg < 3 {
+				fn = Ce(frame.Path, ".json")
+			} else {
+				fn = Ce(CompleteFileName(args[2], frame.Path, stw.Recent())[0], ".json")
+				if filepath.Dir(fn) == "." {
+					fn = filepath.Join(stw.Cwd(), fn)
+				}
+			}
 		}
 		if fn == "" {
 			return NotEnoughArgs(":save")
 		}
+		switch strings.ToLower(filepath.Ext(fn)) {
+		case ".yaml", ".yml":
+			return errors.New(":save: YAML is not supported, use :save json")
+		}
+		if _, ok := argdict["RESULTS"]; ok && filepath.Ext(fn) == ".json" {
+			if bang || (!FileExists(fn) || stw.Yn("Save", "上書きしますか")) {
+				err := frame.WriteJson(fn, true)
+				if err != nil {
+					return err
+				}
+				return Message(fmt.Sprintf("SAVE: %s", fn))
+			}
+			return nil
+		}
 		if bang || (!FileExists(fn) || stw.Yn("Save", "上書きしますか")) {
 			if _, ok := argdict["MKDIR"]; ok {
 				os.MkdirAll(filepath.Dir(fn), 0755)
 			}
 			var err error
+			if _, ok := argdict["FLATTEN"]; ok {
+				// write INCLUDEd objects into one file and forget where they came from
+				frame.Source = nil
+			}
 			readrc := true
 			if rc, ok := argdict["U"]; ok {
 				if rc == "NONE" || rc == "" {
@@ -758,9 +861,103 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 			return err
 		}
 		stw.SetFrame(f)
+		SetPeriodCompletion(f.PeriodNames())
+	case "diff":
+		if usage {
+			return Usage(":diff {-eps=val} [-off] {from} [to]")
+		}
+		if _, ok := argdict["OFF"]; ok {
+			frame.Show.Diff = nil
+			stw.SetColorMode(stw.DefaultColorMode())
+			break
+		}
+		if narg < 2 {
+			return NotEnoughArgs(":diff")
+		}
+		eps := stw.EPS()
+		if e, ok := argdict["EPS"]; ok {
+			val, err := strconv.ParseFloat(e, 64)
+			if err != nil {
+				return err
+			}
+			eps = val
+		}
+		from, err := diffSource(stw, args[1])
+		if err != nil {
+			return err
+		}
+		to := frame
+		if narg >= 3 {
+			to, err = diffSource(stw, args[2])
+			if err != nil {
+				return err
+			}
+		}
+		d := DiffFrame(from, to, eps)
+		d.From = args[1]
+		if narg >= 3 {
+			d.To = args[2]
+		}
+		stw.History(d.String())
+		if to == frame {
+			frame.Show.Diff = d
+			stw.SetColorMode(ECOLOR_DIFF)
+		}
+	case "merge":
+		if usage {
+			return Usage(":merge {-eps=val} base theirs")
+		}
+		if narg < 3 {
+			return NotEnoughArgs(":merge")
+		}
+		eps := stw.EPS()
+		if e, ok := argdict["EPS"]; ok {
+			val, err := strconv.ParseFloat(e, 64)
+			if err != nil {
+				return err
+			}
+			eps = val
+		}
+		base, err := diffSource(stw, args[1])
+		if err != nil {
+			return err
+		}
+		theirs, err := diffSource(stw, args[2])
+		if err != nil {
+			return err
+		}
+		merged, conflicts := MergeFrame(base, frame, theirs, eps)
+		d := DiffFrame(frame, merged, eps)
+		d.From = "ours"
+		d.To = "merged"
+		stw.SetFrame(merged)
+		Snapshot(stw)
+		merged.Show.Diff = d
+		stw.SetColorMode(ECOLOR_DIFF)
+		stw.History(d.String())
+		if len(conflicts) > 0 {
+			nodes := make([]*Node, 0)
+			elems := make([]*Elem, 0)
+			for _, c := range conflicts {
+				stw.History(c.String())
+				switch c.Kind {
+				case "NODE":
+					if n, ok := merged.Nodes[c.Num]; ok {
+						nodes = append(nodes, n)
+					}
+				case "ELEM":
+					if el, ok := merged.Elems[c.Num]; ok {
+						elems = append(elems, el)
+					}
+				}
+			}
+			stw.SelectNode(nodes)
+			stw.SelectElem(elems)
+			return fmt.Errorf(":merge: %d conflicts", len(conflicts))
+		}
 	case "read":
 		if usage {
-			return Usage(":read {type} filename")
+			return Usage(":read {-strict} {type} filename")
 		}
 		if narg < 2 {
 			return NotEnoughArgs(":read")
@@ -771,7 +968,7 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 			case "$all":
 				ReadAll(stw)
 			case "$data":
-				for _, ext := range []string{".inl", ".ihx", ".ihy"} {
+				for _, ext := range frame.InputExts() {
 					err := frame.ReadData(Ce(frame.Path, ext))
 					if err != nil {
 						ErrorMessage(stw, err, ERROR)
@@ -785,13 +982,21 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 						mode = AddSearchResult
 					}
 				}
-				for _, ext := range []string{".otl", ".ohx", ".ohy"} {
+				for _, ext := range frame.OutputExts() {
 					err := frame.ReadResult(Ce(frame.Path, ext), uint(mode))
 					if err != nil {
 						ErrorMessage(stw, err, ERROR)
 					}
 				}
 			default:
+				if _, ok := argdict["STRICT"]; ok && filepath.Ext(fn) == ".inp" {
+					err := frame.ReadInpStrict(fn, []float64{0.0, 0.0, 0.0}, 0.0, false)
+					if err != nil {
+						ShowInpDiagnostics(stw, err)
+						return err
+					}
+					return nil
+				}
 				err := ReadFile(stw, fn)
 				if err != nil {
 					return err
@@ -836,6 +1041,13 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 			if err != nil {
 				return err
 			}
+		case abbrev.For("j/son", t):
+			err := OpenFile(stw, Ce(fn, ".json"), true)
+			if err != nil {
+				return err
+			}
+		case abbrev.For("y/aml", t):
+			return errors.New(":read: YAML is not supported, use :read json")
 		case abbrev.For("k/ijun", t):
 			err := frame.ReadKjn(fn)
 			if err != nil {
@@ -933,7 +1145,7 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 		}
 		ns := stw.SelectedNodes()
 		sort.Sort(NodeByNum{ns})
-		err = WriteReaction(fn, ns, int(tmp), frame.Show.Unit[0])
+		err = WriteReaction(fn, ns, int(tmp), frame.Show.Unit[0], frame.ExtraPeriodNames()...)
 		if err != nil {
 			return err
 		}
@@ -1180,6 +1392,124 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 		if err != nil {
 			return err
 		}
+	case "csv":
+		if usage {
+			return Usage(":csv {node|elem|sect|disp|reaction|stress|rate|read} {-period=L,X,Y} filename")
+		}
+		if narg < 2 {
+			return NotEnoughArgs(":csv")
+		}
+		t := strings.ToLower(args[1])
+		if narg < 3 {
+			fn = Ce(frame.Path, fmt.Sprintf("_%s.csv", t))
+		} else {
+			fn = CompleteFileName(args[2], frame.Path, stw.Recent())[0]
+			if filepath.Dir(fn) == "." {
+				fn = filepath.Join(stw.Cwd(), fn)
+			}
+		}
+		periods := frame.PeriodNames()
+		if p, ok := argdict["PERIOD"]; ok && p != "" {
+			periods = strings.Split(strings.ToUpper(p), ",")
+		}
+		var nodes []*Node
+		var elems []*Elem
+		if stw.NodeSelected() {
+			nodes = stw.SelectedNodes()
+		}
+		if stw.ElemSelected() {
+			elems = stw.SelectedElems()
+		}
+		var err error
+		switch t {
+		default:
+			return fmt.Errorf(":csv: unknown type %s", t)
+		case "read":
+			n, err := frame.ReadCsv(fn)
+			if err != nil {
+				return err
+			}
+			Snapshot(stw)
+			stw.Changed(true)
+			return Message(fmt.Sprintf("%d rows updated", n))
+		case "node":
+			err = frame.WriteCsvNode(fn, nodes)
+		case "elem":
+			err = frame.WriteCsvElem(fn, elems)
+		case "sect":
+			var sects []*Sect
+			for _, el := range elems {
+				if el == nil {
+					continue
+				}
+				found := false
+				for _, sec := range sects {
+					if sec == el.Sect {
+						found = true
+						break
+					}
+				}
+				if !found {
+					sects = append(sects, el.Sect)
+				}
+			}
+			err = frame.WriteCsvSect(fn, sects)
+		case "disp":
+			err = frame.WriteCsvDisp(fn, nodes, periods)
+		case "reaction":
+			err = frame.WriteCsvReaction(fn, nodes, periods)
+		case "stress":
+			err = frame.WriteCsvStress(fn, elems, periods)
+		case "rate":
+			err = frame.WriteCsvRate(fn, elems)
+		}
+		if err != nil {
+			return err
+		}
+		return Message(fmt.Sprintf("CSV: %s", fn))
+	case "ifc":
+		if usage {
+			return Usage(":ifc filename {-period=l}")
+		}
+		period := ""
+		if p, ok := argdict["PERIOD"]; ok {
+			period = strings.ToUpper(p)
+		}
+		err := frame.WriteIfc(Ce(fn, ".ifc"), period)
+		if err != nil {
+			return err
+		}
+	case "import":
+		if usage {
+			return Usage(":import {-pattern=name} {-eps=val} filename(.s2k|.$2k|.tcl)")
+		}
+		if narg < 2 {
+			return NotEnoughArgs(":import")
+		}
+		fn = CompleteFileName(args[1], frame.Path, stw.Recent())[0]
+		if filepath.Dir(fn) == "." {
+			fn = filepath.Join(stw.Cwd(), fn)
+		}
+		eps := stw.EPS()
+		if e, ok := argdict["EPS"]; ok {
+			val, err := strconv.ParseFloat(e, 64)
+			if err != nil {
+				return err
+			}
+			eps = val
+		}
+		messages, err := frame.ImportModel(fn, eps, argdict["PATTERN"])
+		for _, m := range messages {
+			stw.History(m)
+		}
+		if err != nil {
+			return err
+		}
+		Snapshot(stw)
+		stw.Redraw()
+		if len(messages) > 0 {
+			return Message(fmt.Sprintf("%s: %d items are not translated", filepath.Base(fn), len(messages)))
+		}
 	case "plan":
 		if usage {
 			return Usage(":plan filename {-floor=1} {-scale=1000} {-height=250} {-axissize=300}")
@@ -1758,9 +2088,22 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 			}
 		}()
 		Snapshot(stw)
+	case "designcode":
+		if usage {
+			return Usage(":designcode {name}")
+		}
+		if narg < 2 {
+			return Message(fmt.Sprintf("DESIGNCODE=%s (%s)", frame.DesignCode.Name(), strings.Join(DesignCodeNames(), ", ")))
+		}
+		code, err := LookupDesignCode(args[1])
+		if err != nil {
+			return err
+		}
+		frame.SetDesignCode(code)
+		return Message(fmt.Sprintf("DESIGNCODE=%s: %s", code.Name(), code.Description()))
 	case "srcal":
 		if usage {
-			return Usage(":srcal {-verbose} {-fbold} {-torsion} {-noreload} {-qfact=2.0} {-wfact=2.0} {-bfact=1.0} {-skipshort} {-temporary} {-moeshiro} {-sekisetsu} filename")
+			return Usage(":srcal {-verbose} {-fbold} {-cb} {-joint=filename} {-torsion} {-noreload} {-qfact=2.0} {-wfact=2.0} {-bfact=1.0} {-skipshort} {-temporary} {-moeshiro} {-sekisetsu} filename")
 		}
 		var m bytes.Buffer
 		cond := NewCondition()
@@ -1768,6 +2111,10 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 			m.WriteString("Fb: old\n")
 			cond.FbOld = true
 		}
+		if _, ok := argdict["CB"]; ok {
+			m.WriteString("Cb: moment gradient\n")
+			cond.Cgrad = true
+		}
 		var otp string
 		if fn == "" {
 			otp = frame.Path
@@ -1784,6 +2131,20 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 				return err
 			}
 		}
+		frame.Joints = nil
+		if jfn, ok := argdict["JOINT"]; ok {
+			if jfn == "" {
+				jfn = Ce(otp, ".jnt")
+			}
+			js, err := ReadJoint(jfn)
+			if err != nil {
+				return err
+			}
+			frame.Joints = js
+			m.WriteString(fmt.Sprintf("JOINT: %s\n", jfn))
+		}
+		cond.SetCode(frame.DesignCode)
+		m.WriteString(fmt.Sprintf("DESIGNCODE: %s\n", cond.Code.Name()))
 		if qf, ok := argdict["QFACT"]; ok {
 			val, err := strconv.ParseFloat(qf, 64)
 			if err == nil {
@@ -1836,6 +2197,132 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 		}
 		frame.SectionRateCalculation(otp, "L", "X", "X", "Y", "Y", -1.0, cond)
 		return Message(m.String())
+	case "sizing":
+		if usage {
+			return Usage(":sizing {-target=0.95} {-maxiter=10} {-noreload} filename")
+		}
+		if fn == "" {
+			return NotEnoughArgs(":sizing")
+		}
+		sz, err := ReadSizing(fn)
+		if err != nil {
+			return err
+		}
+		if t, ok := argdict["TARGET"]; ok {
+			val, err := strconv.ParseFloat(t, 64)
+			if err == nil {
+				sz.Target = val
+			}
+		}
+		if mi, ok := argdict["MAXITER"]; ok {
+			val, err := strconv.ParseInt(mi, 10, 64)
+			if err == nil {
+				sz.Maxiter = int(val)
+			}
+		}
+		if _, ok := argdict["NORELOAD"]; !ok {
+			err := ReadFile(stw, Ce(frame.Path, ".lst"))
+			if err != nil {
+				return err
+			}
+		}
+		cond := NewCondition()
+		cond.SetCode(frame.DesignCode)
+		otp := strings.TrimSuffix(frame.Path, filepath.Ext(frame.Path)) + "_sized"
+		converged, err := frame.Optimise(sz, cond, otp)
+		if err != nil {
+			return err
+		}
+		stw.Changed(true)
+		stw.Redraw()
+		if !converged {
+			return Message(fmt.Sprintf("NOT CONVERGED: %s", Ce(otp, ".szr")))
+		}
+		return Message(fmt.Sprintf("CONVERGED: %s", Ce(otp, ".szr")))
+	case "serviceability":
+		if usage {
+			return Usage(":serviceability {-ratio=300} {-absolute=2.0[cm]} {-cantilever=2.0} {-frequency=8.0[Hz]} filename")
+		}
+		sv := NewServiceability()
+		for key, val := range map[string]*float64{"RATIO": &sv.Ratio, "ABSOLUTE": &sv.Absolute, "CANTILEVER": &sv.Cantilever, "FREQUENCY": &sv.Frequency} {
+			if v, ok := argdict[key]; ok {
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return err
+				}
+				*val = f
+			}
+		}
+		var otpfn string
+		if fn == "" {
+			otpfn = frame.Path
+		} else {
+			otpfn = fn
+		}
+		err := frame.ServiceabilityCheck(otpfn, sv)
+		if err != nil {
+			return err
+		}
+		stw.SetColorMode(ECOLOR_SERVICE)
+		frame.Show.Service = true
+		stw.Redraw()
+		return Message(fmt.Sprintf("OUTPUT: %s", Ce(otpfn, ".svc")))
+	case "strengthratio":
+		if usage {
+			return Usage(":strengthratio {-ratio=1.5} {-period=L} filename")
+		}
+		required := 1.5
+		if r, ok := argdict["RATIO"]; ok {
+			val, err := strconv.ParseFloat(r, 64)
+			if err != nil {
+				return err
+			}
+			required = val
+		}
+		period := "L"
+		if p, ok := argdict["PERIOD"]; ok && p != "" {
+			period = strings.ToUpper(p)
+		}
+		var otpfn string
+		if fn == "" {
+			otpfn = frame.Path
+		} else {
+			otpfn = fn
+		}
+		err := frame.StrengthRatioCheck(otpfn, period, required)
+		if err != nil {
+			return err
+		}
+		frame.Show.StrengthRatio = true
+		stw.Redraw()
+		return Message(fmt.Sprintf("OUTPUT: %s", Ce(otpfn, ".cbr")))
+	case "ultimatestrength":
+		if usage {
+			return Usage(":ultimatestrength {-drift=0.01} {-period=X,Y} filename")
+		}
+		drift := 0.01
+		if d, ok := argdict["DRIFT"]; ok {
+			val, err := strconv.ParseFloat(d, 64)
+			if err != nil {
+				return err
+			}
+			drift = val
+		}
+		periods := []string{"X", "Y"}
+		if p, ok := argdict["PERIOD"]; ok && p != "" {
+			periods = strings.Split(strings.ToUpper(p), ",")
+		}
+		var otpfn string
+		if fn == "" {
+			otpfn = frame.Path
+		} else {
+			otpfn = fn
+		}
+		err := frame.UltimateStrengthCheck(otpfn, periods, drift)
+		if err != nil {
+			return err
+		}
+		return Message(fmt.Sprintf("OUTPUT: %s", Ce(otpfn, ".qun")))
 	case "srcalangle":
 		if usage {
 			return Usage(":srcalangle {-angle=0[deg]} {-fact=1.0}")
@@ -1848,6 +2335,14 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 		} else {
 			otpfn = fn
 		}
+		reload := true
+		if _, ok := argdict["NORELOAD"]; ok {
+			reload = false
+		}
+		if reload {
+			ReadFile(stw, Ce(otpfn, ".lst"))
+		}
+		cond.SetCode(frame.DesignCode)
 		angle := 0.0
 		if f, ok := argdict["FACT"]; ok {
 			val, err := strconv.ParseFloat(f, 64)
@@ -1887,13 +2382,6 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 		if _, ok := argdict["VERBOSE"]; ok {
 			cond.Verbose = true
 		}
-		reload := true
-		if _, ok := argdict["NORELOAD"]; ok {
-			reload = false
-		}
-		if reload {
-			ReadFile(stw, Ce(otpfn, ".lst"))
-		}
 		if qf, ok := argdict["QFACT"]; ok {
 			val, err := strconv.ParseFloat(qf, 64)
 			if err == nil {
@@ -2092,6 +2580,7 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 			var otp bytes.Buffer
 			var m bytes.Buffer
 			cond := NewCondition()
+			cond.SetCode(frame.DesignCode)
 			ndiv := 100
 			if nd, ok := argdict["NDIV"]; ok {
 				if nd != "" {
@@ -2175,6 +2664,14 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 						yield[10] = mmax
 						yield[11] = -mmax
 					}
+				case Composite:
+					nmax := al.(Composite).Nmax(cond)
+					nmin := al.(Composite).Nmin(cond)
+					for i := 0; i <= ndiv; i++ {
+						cond.N = nmax - float64(i)*(nmax-nmin)/float64(ndiv)
+						otp.WriteString(fmt.Sprintf("%.5f %.5f\n", cond.N, al.Ma(cond)))
+					}
+					otp.WriteString(fmt.Sprintf("Qu %.5f\n", al.Qa(cond)))
 				}
 			}
 			otp.WriteString(fmt.Sprintf("         NZMAX %9.3f NZMIN %9.3f\n", yield[0], yield[1]))
@@ -2439,6 +2936,7 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 		if reload {
 			ReadFile(stw, Ce(otpfn, ".lst"))
 		}
+		cond.SetCode(frame.DesignCode)
 		els := stw.SelectedElems()
 		sort.Sort(ElemByNum{els})
 		a := 0.25
@@ -5409,60 +5907,23 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 		}
 		frame.SaveAsArclm("")
 	case "all":
-		frame.ExtractArclm(Ce(frame.Path, ".wgt"))
-		frame.SaveAsArclm("")
-		acond := arclm.NewAnalysisCondition()
-		extra := make([][]float64, 2)
-		for i, eper := range []string{"X", "Y"} {
-			eaf := frame.Arclms[eper]
-			_, _, vec, err := eaf.AssemGlobalVector(1.0)
-			if err != nil {
-				return err
-			}
-			extra[i] = vec
-		}
-		acond.SetExtra(extra)
 		var otp string
 		if fn == "" {
 			otp = Ce(frame.Path, ".otp")
 		} else {
 			otp = fn
 		}
-		pers := []string{"L", "X", "Y"}
-		af := frame.Arclms["L"]
-		if af == nil {
-			return fmt.Errorf(":all: frame isn't extracted to period L")
-		}
-		otps := []string{Ce(otp, "otl"), Ce(otp, "ohx"), Ce(otp, "ohy")}
-		acond.SetOutput(otps)
-		ctx, cancel := context.WithCancel(context.Background())
-		go func() {
-			err := af.StaticAnalysis(cancel, acond)
-			af.Endch <- err
-		}()
-		ind := 0
-		retval := 0
-	ex_all:
-		for {
-			select {
-			case <-ctx.Done():
-				retval = 1
-			case <-af.Pivot:
-			case <-af.Lapch:
-				frame.ReadArclmData(af, pers[ind])
-				frame.ResultFileName[pers[ind]] = otps[ind]
-				ind++
-				af.Lapch <- retval
-			case <-af.Endch:
-				break ex_all
-			}
+		err := frame.AnalyseAll(otp)
+		if err != nil {
+			return err
 		}
 		ReadFile(stw, Ce(frame.Path, ".lst"))
 		cond := NewCondition()
+		cond.SetCode(frame.DesignCode)
 		frame.SectionRateCalculation(otp, "L", "X", "X", "Y", "Y", -1.0, cond)
 	case "analysis":
 		if usage {
-			return Usage(":analysis {-period=name} {-all} {-solver=name} {-eps=value} {-nlgeom} {-nlmat} {-step=nlap;delta;start;max} {-noinit} {-wait} filename")
+			return Usage(":analysis {-period=name} {-all} {-solver=name} {-eps=value} {-memory=MB} {-nlgeom} {-nlmat} {-step=nlap;delta;start;max} {-noinit} {-wait} {-dump} {-result} filename")
 		}
 		cond := arclm.NewAnalysisCondition()
 		var otp string
@@ -5485,6 +5946,13 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 				}
 			}
 		}
+		if m, ok := argdict["MEMORY"]; ok { // MB
+			tmp, err := strconv.ParseInt(m, 10, 64)
+			if err == nil && tmp > 0 {
+				cond.SetMemory(tmp << 20)
+			}
+		}
+		cond.SetTempDir(filepath.Dir(otp))
 		if _, ok := argdict["NLGEOM"]; ok {
 			cond.SetNlgeometry(true)
 		}
@@ -5528,24 +5996,34 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 			if cond.NonLinear() {
 				return fmt.Errorf("\":analysis-all\" cannot be used for non-linear analysis")
 			}
-			extra := make([][]float64, 2)
-			pers = []string{"L", "X", "Y"}
-			otps := []string{Ce(otp, ".otl"), Ce(otp, ".ohx"), Ce(otp, ".ohy")}
-			per = "L"
-			for i, eper := range []string{"X", "Y"} {
-				eaf := frame.Arclms[eper]
-				if eaf == nil {
-					return fmt.Errorf(":analysis: frame isn't extracted to period %s", eper)
+			lp := frame.Period(per)
+			if lp == nil {
+				return fmt.Errorf(":analysis: period %s isn't registered", per)
+			}
+			extra := make([][]float64, 0)
+			otps := []string{Ce(otp, lp.Output)}
+			arclmpers, err := frame.ArclmPeriods()
+			if err != nil {
+				return err
+			}
+			for _, p := range arclmpers {
+				if p.Name == per {
+					continue
 				}
-				_, _, vec, err := eaf.AssemGlobalVector(1.0)
+				_, _, vec, err := frame.Arclms[p.Name].AssemGlobalVector(1.0)
 				if err != nil {
 					return err
 				}
-				extra[i] = vec
+				pers = append(pers, p.Name)
+				otps = append(otps, Ce(otp, p.Output))
+				extra = append(extra, vec)
 			}
 			cond.SetOutput(otps)
 			cond.SetExtra(extra)
 		}
+		if _, ok := argdict["RESULT"]; ok {
+			cond.SetResult(Ce(otp, ResultExt), pers)
+		}
 		af := frame.Arclms[per]
 		if af == nil {
 			return fmt.Errorf(":analysis: frame isn't extracted to period %s", per)
@@ -5554,6 +6032,15 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 		if af.Running() {
 			return fmt.Errorf("analysis is running")
 		}
+		if _, ok := argdict["DUMP"]; ok {
+			fns, err := af.DumpMatrix(otp, 1.0)
+			if err != nil {
+				return err
+			}
+			for _, f := range fns {
+				stw.History(fmt.Sprintf("DUMP: %s", f))
+			}
+		}
 		var m, m2 bytes.Buffer
 		m.WriteString(fmt.Sprintf("PERIOD      : %s\n", per))
 		if pp, ok := argdict["POST"]; ok {
@@ -5704,6 +6191,72 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 			<-wch
 		}
 		return ArclmStart(m.String())
+	case "loadperiod":
+		if usage {
+			return Usage(":loadperiod [list|add name {input} {output}|delete name]")
+		}
+		sub := "list"
+		if narg >= 2 {
+			sub = strings.ToLower(args[1])
+		}
+		switch sub {
+		default:
+			return fmt.Errorf(":loadperiod: unknown subcommand %s", sub)
+		case "list":
+			var m bytes.Buffer
+			for _, p := range frame.Periods {
+				m.WriteString(p.String())
+				m.WriteString("\n")
+			}
+			return Message(m.String())
+		case "add":
+			if narg < 3 {
+				return NotEnoughArgs(":loadperiod add")
+			}
+			input, output := "", ""
+			if narg >= 5 {
+				input, output = args[3], args[4]
+			}
+			p, err := frame.AddPeriod(args[2], input, output)
+			if err != nil {
+				return err
+			}
+			SetPeriodCompletion(frame.PeriodNames())
+			stw.Changed(true)
+			return Message(fmt.Sprintf("PERIOD %s", p))
+		case "delete":
+			if narg < 3 {
+				return NotEnoughArgs(":loadperiod delete")
+			}
+			err := frame.DeletePeriod(args[2])
+			if err != nil {
+				return err
+			}
+			SetPeriodCompletion(frame.PeriodNames())
+			stw.Changed(true)
+		}
+	case "diagnose":
+		if usage {
+			return Usage(":diagnose {-period=name} {-tol=value}")
+		}
+		per := "L"
+		if p, ok := argdict["PERIOD"]; ok {
+			if p != "" {
+				per = strings.ToUpper(p)
+			}
+		}
+		tol := 1e-8
+		if t, ok := argdict["TOL"]; ok {
+			val, err := strconv.ParseFloat(t, 64)
+			if err == nil {
+				tol = val
+			}
+		}
+		report, err := CheckSingularity(stw, per, tol)
+		if err != nil {
+			return err
+		}
+		return Message(strings.TrimSuffix(report, "\n"))
 	case "stop":
 		if usage {
 			return Usage(":stop period")
@@ -5746,7 +6299,7 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 		return Usage("DEPRECATED: use :analysis -nlgeom -pp=floor {-z=val} {-period=name} {-solver=name} {-eps=value} {-step=nlap;delta;start;max} {-noinit} {-wait} filename")
 	case "arclm101":
 		if usage {
-			return Usage(":arclm101 {-period=name} {-eps=val} {-step=nlap;delta;start;max} filename")
+			return Usage(":arclm101 {-period=name} {-eps=val} {-step=nlap;delta;start;max} {-result} filename")
 		}
 		cond := arclm.NewAnalysisCondition()
 		if e, ok := argdict["EPS"]; ok {
@@ -5805,6 +6358,9 @@ func exCommand(stw ExModer, command string, pipe bool, exmodech chan interface{}
 				cond.SetMax(tmp)
 			}
 		}
+		if _, ok := argdict["RESULT"]; ok {
+			cond.SetResult(Ce(otp, ResultExt), pers)
+		}
 		af := frame.Arclms[per]
 		if _, ok := argdict["NOINIT"]; ok {
 			af.RestoreState(frame.Arclms[frame.Show.Period].SaveState())
@@ -6405,3 +6961,43 @@ func currentnode(stw ExModer, exmodech chan interface{}, exmodeend chan int) []*
 	}
 	return ns
 }
+
+// diffSource returns a frame to be compared by :diff and :merge.
+// name is "@n" (n steps back in the undo stack), a tag, or a .inp/.json file.
+func diffSource(stw ExModer, name string) (*Frame, error) {
+	if strings.HasPrefix(name, "@") {
+		num := 1
+		if len(name) > 1 {
+			val, err := strconv.ParseInt(name[1:], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			num = int(val)
+		}
+		return stw.UndoFrame(num)
+	}
+	if f, err := stw.Tag(name); err == nil {
+		return f, nil
+	}
+	frame := stw.Frame()
+	fn := CompleteFileName(name, frame.Path, stw.Recent())[0]
+	if filepath.Dir(fn) == "." {
+		fn = filepath.Join(stw.Cwd(), fn)
+	}
+	f := NewFrame()
+	switch filepath.Ext(fn) {
+	case ".inp":
+		err := f.ReadInp(fn, []float64{0.0, 0.0, 0.0}, 0.0, false)
+		if err != nil {
+			return nil, err
+		}
+	case ".json":
+		err := f.ReadJson(fn)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%s: neither a tag nor a file", name)
+	}
+	return f, nil
+}
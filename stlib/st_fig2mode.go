@@ -53,27 +53,8 @@ var (
 		"sr/can/col/or": complete.MustCompile("'srcancolor", nil),
 		"sr/can/ra/te":  complete.MustCompile("'srcanrate", nil),
 		"en/ergy":       complete.MustCompile("'energy", nil),
-		"st/ress": complete.MustCompile("'stress $ETYPE $PERIOD $NAME",
-			map[string][]string{
-				"ETYPE":  []string{"column", "girder", "brace", "wall", "wbrace", "slab", "sbrace"},
-				"PERIOD": []string{"l", "x", "y"},
-				"NAME":   []string{"n", "qx", "qy", "mz", "mx", "my"},
-			}),
 		"prest/ress": complete.MustCompile("'prestress", nil),
 		"stiff/":     complete.MustCompile("'stiff", nil),
-		"def/ormation": complete.MustCompile("'deformation $PERIOD",
-			map[string][]string{
-				"PERIOD": []string{"l", "x", "y"},
-			}),
-		"def/orme/d/": complete.MustCompile("'deformed $PERIOD",
-			map[string][]string{
-				"PERIOD": []string{"l", "x", "y"},
-			}),
-		"dis/p": complete.MustCompile("'disp $PERIOD $DIRECTION",
-			map[string][]string{
-				"PERIOD":    []string{"l", "x", "y"},
-				"DIRECTION": []string{"z", "x", "y"},
-			}),
 		"ecc/entric": complete.MustCompile("'eccentric", nil),
 		"dr/aw": complete.MustCompile("'draw $ETYPE...",
 			map[string][]string{
@@ -784,6 +765,20 @@ func Fig2Keyword(stw Fig2Moder, lis []string, un bool) error {
 			stw.SetColorMode(ECOLOR_ENERGY)
 			frame.Show.Energy = true
 		}
+	case "service":
+		if un {
+			stw.SetColorMode(stw.DefaultColorMode())
+			frame.Show.Service = false
+		} else {
+			stw.SetColorMode(ECOLOR_SERVICE)
+			frame.Show.Service = true
+		}
+	case "strengthratio":
+		if un {
+			frame.Show.StrengthRatio = false
+		} else {
+			frame.Show.StrengthRatio = true
+		}
 	case "stress":
 		if usage {
 			stw.History("'stress [etype/sectcode] [period] [stressname]")
@@ -3,6 +3,7 @@ package st
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"embed"
 	"errors"
 	"fmt"
@@ -28,10 +29,6 @@ import (
 	"github.com/yofu/unit"
 )
 
-var (
-	PeriodExt = map[string]string{".inl": "L", ".otl": "L", ".ihx": "X", ".ohx": "X", ".ihy": "Y", ".ohy": "Y"}
-)
-
 // SI unit
 const SI = 9.80665
 
@@ -40,12 +37,6 @@ var (
 	PlasticThreshold = arclm.RADIUS
 )
 
-// Data Extentions
-var (
-	InputExt  = []string{".inl", ".ihx", ".ihy"}
-	OutputExt = []string{".otl", ".ohx", ".ohy"}
-)
-
 const (
 	// DefaultWgt is the name of default .wgt file
 	DefaultWgt = "hogtxt.wgt"
@@ -91,7 +82,9 @@ type Frame struct {
 	NodeSet map[string][]*Node
 	ElemSet map[string][]*Elem
 
-	Arclms map[string]*arclm.Frame
+	Arclms  map[string]*arclm.Frame
+	Periods []*LoadPeriod
+	Source  *InpSource
 
 	Eigenvalue map[int]float64
 
@@ -105,12 +98,17 @@ type Frame struct {
 	Maxnnum int
 	Maxsnum int
 
-	Nlap map[string]int
+	Nlap    map[string]int
+	Results *ResultStore
 
 	Ai   *Aiparameter
 	Wind *Windparameter
 	Fes  *Fact
 
+	DesignCode DesignCode
+	fixedcode  bool // DesignCode is selected by SetDesignCode
+	Joints     *JointSet
+
 	Show *Show
 
 	DataFileName   map[string]string
@@ -135,6 +133,7 @@ func NewFrame() *Frame {
 	f.NodeSet = make(map[string][]*Node)
 	f.ElemSet = make(map[string][]*Elem)
 	f.Arclms = make(map[string]*arclm.Frame)
+	f.Periods = defaultPeriods()
 	f.Eigenvalue = make(map[int]float64)
 	f.Kijuns = make(map[string]*Kijun)
 	f.Measures = make([]*Measure, 0)
@@ -145,6 +144,7 @@ func NewFrame() *Frame {
 	f.Maxsnum = 900
 	f.Nlap = make(map[string]int)
 	f.Ai = NewAiparameter()
+	f.DesignCode = DefaultDesignCode
 	f.Wind = NewWindparameter()
 	f.Show = NewShow(f)
 	f.DataFileName = make(map[string]string)
@@ -416,7 +416,13 @@ func (frame *Frame) Snapshot() *Frame {
 	for k, v := range frame.Nlap {
 		f.Nlap[k] = v
 	}
+	f.Results = frame.Results
 	f.Ai = frame.Ai.Snapshot()
+	f.Source = frame.Source.Snapshot()
+	f.Periods = make([]*LoadPeriod, len(frame.Periods))
+	for i, p := range frame.Periods {
+		f.Periods[i] = NewLoadPeriod(p.Name, p.Input, p.Output)
+	}
 	f.Show = frame.Show
 	for k, v := range frame.DataFileName {
 		f.DataFileName[k] = v
@@ -425,6 +431,9 @@ func (frame *Frame) Snapshot() *Frame {
 		f.ResultFileName[k] = v
 	}
 	f.LstFileName = frame.LstFileName
+	f.DesignCode = frame.DesignCode
+	f.fixedcode = frame.fixedcode
+	f.Joints = frame.Joints
 	return f
 }
 
@@ -507,23 +516,101 @@ func (frame *Frame) readInp(scanner *bufio.Scanner, filename string, coord []flo
 		coord = []float64{0.0, 0.0, 0.0}
 	}
 	var chain *Chain
-	err := ParseScanner(scanner, func(words []string) error {
+	src := NewInpSource(filename)
+	file := src.Main
+	// flush parses the block in tmp after evaluating parameters
+	flush := func() error {
+		if len(tmp) == 0 {
+			return nil
+		}
+		lis, err := file.Expand(tmp)
+		if err != nil {
+			return err
+		}
+		existed := false
+		if len(lis) > 1 && lis[0] == "ELEM" {
+			if num, err := strconv.ParseInt(lis[1], 10, 64); err == nil {
+				_, existed = frame.Elems[int(num)]
+			}
+		}
+		nodemap, err = frame.ParseInp(lis, coord, angle, nodemap, overwrite, chain)
+		if err != nil {
+			return err
+		}
+		src.Record(frame, file, tmp, lis, nodemap, existed)
+		return nil
+	}
+	var parse func(words []string) error
+	parse = func(words []string) error {
 		var err error
 		first := words[0]
 		if strings.HasPrefix(first, "\"") {
-			frame.Title = strings.Join(words, " ")
+			if file == src.Main {
+				frame.Title = strings.Join(words, " ")
+			}
 			return nil
 		} else if strings.HasPrefix(first, "#") {
 			return nil
 		}
+		raw := words
+		switch first {
+		case "PROP", "SECT", "PILE", "BOND", "NODE", "ELEM", "PARAM", "INCLUDE":
+		default:
+			if _, ok := inpGlobals[first]; ok {
+				words, err = file.Expand(words)
+				if err != nil {
+					return err
+				}
+			}
+		}
 		switch first {
 		default:
 			tmp = append(tmp, words...)
 		case "PROP", "SECT", "PILE", "BOND", "NODE", "ELEM":
-			nodemap, err = frame.ParseInp(tmp, coord, angle, nodemap, overwrite, chain)
-			tmp = words
+			err = flush()
+			tmp = raw
+		case "PARAM":
+			err = file.Param(raw)
+		case "INCLUDE":
+			err = flush()
+			tmp = make([]string, 0)
+			if err != nil {
+				break
+			}
+			if chain != nil {
+				return errors.New("ReadInp: INCLUDE: cannot be used in CHAIN")
+			}
+			var child *InpFile
+			child, err = file.Include(words)
+			if err != nil {
+				break
+			}
+			var f *os.File
+			f, err = os.Open(child.Path)
+			if err != nil {
+				return fmt.Errorf("ReadInp: INCLUDE: %s", err.Error())
+			}
+			// the included file sees the nodes read so far, and its nodes are added unless the numbers are already used
+			parent, parentmap := file, nodemap
+			file = child
+			nodemap = make(map[int]int, len(parentmap))
+			for k, v := range parentmap {
+				nodemap[k] = v
+			}
+			err = ParseScanner(bufio.NewScanner(f), parse)
+			f.Close()
+			if err == nil {
+				err = flush()
+				tmp = make([]string, 0)
+			}
+			for k, v := range nodemap {
+				if _, ok := parentmap[k]; !ok {
+					parentmap[k] = v
+				}
+			}
+			file, nodemap = parent, parentmap
 		case "CHAIN":
-			nodemap, err = frame.ParseInp(tmp, coord, angle, nodemap, overwrite, chain)
+			err = flush()
 			tmp = make([]string, 0)
 			chain = NewChain(frame, nil, nil, nil, func(c *Chain) bool { return true }, nil, nil)
 		case "NODESET":
@@ -559,7 +646,7 @@ func (frame *Frame) readInp(scanner *bufio.Scanner, filename string, coord []flo
 			elems = elems[:ind]
 			frame.AddElemSet(name, elems)
 		case "}":
-			nodemap, err = frame.ParseInp(tmp, coord, angle, nodemap, overwrite, chain)
+			err = flush()
 			tmp = make([]string, 0)
 			if chain != nil && chain.Size() > 0 {
 				frame.Chains[chain.Elems()[0].Num] = chain
@@ -612,6 +699,9 @@ func (frame *Frame) readInp(scanner *bufio.Scanner, filename string, coord []flo
 			// 	frame.Ai.Hi[d] = make([]float64, frame.Ai.Nfloor)
 			// }
 		case "HEIGHT":
+			if file == src.Main {
+				src.SetHeight(raw)
+			}
 			if frame.Ai.Nfloor == 0 {
 				frame.Ai.Nfloor = len(words) - 2
 				frame.Ai.Boundary = make([]float64, len(words)-1)
@@ -689,16 +779,26 @@ func (frame *Frame) readInp(scanner *bufio.Scanner, filename string, coord []flo
 			for i := 0; i < 2; i++ {
 				frame.View.Dists[i], err = strconv.ParseFloat(words[i+1], 64)
 			}
+		case "LOADPERIOD":
+			if len(words) < 2 {
+				return errors.New("ReadInp: LOADPERIOD: no name")
+			}
+			input, output := "", ""
+			if len(words) > 3 {
+				input, output = words[2], words[3]
+			}
+			_, err = frame.AddPeriod(words[1], input, output)
 		}
 		if err != nil {
 			return err
 		}
 		return nil
-	})
+	}
+	err := ParseScanner(scanner, parse)
 	if err != nil {
 		return err
 	}
-	nodemap, err = frame.ParseInp(tmp, coord, angle, nodemap, overwrite, chain)
+	err = flush()
 	if err != nil {
 		return err
 	}
@@ -708,6 +808,10 @@ func (frame *Frame) readInp(scanner *bufio.Scanner, filename string, coord []flo
 			el.Chain = chain
 		}
 	}
+	if src.used {
+		src.Finish(frame)
+		frame.Source = src
+	}
 	frame.Name = filepath.Base(filename)
 	frame.Project = ProjectName(filename)
 	path, err := filepath.Abs(filename)
@@ -1460,13 +1564,7 @@ func (frame *Frame) ParseLevel(lis []string) (err error) {
 
 // ReadData reads an input file for arclm frame.
 func (frame *Frame) ReadData(filename string) error {
-	ext := filepath.Ext(filename)
-	var period string
-	if p, ok := PeriodExt[ext]; ok {
-		period = p
-	} else {
-		period = strings.ToUpper(ext[1:])
-	}
+	period := frame.PeriodOfFile(filename)
 	af := arclm.NewFrame()
 	af.ReadInput(filename)
 	frame.Arclms[period] = af
@@ -1640,13 +1738,7 @@ func (frame *Frame) ReadResult(filename string, mode uint) error {
 	if err != nil {
 		return err
 	}
-	ext := filepath.Ext(filename)
-	var period string
-	if p, ok := PeriodExt[ext]; ok {
-		period = p
-	} else {
-		period = strings.ToUpper(ext[1:])
-	}
+	period := frame.PeriodOfFile(filename)
 	var lis []string
 	if ok := strings.HasSuffix(string(f), "\r\n"); ok {
 		lis = strings.Split(string(f), "\r\n")
@@ -2153,8 +2245,21 @@ func (frame *Frame) ParseZoubunForm(lis [][]string, period string) error {
 }
 
 // ReadLst reads an input file for section list.
+// SetDesignCode selects the design code of the model (:designcode).
+// It takes precedence over DESIGNCODE of .lst files read afterwards, and is kept with the model when .lst is written.
+func (frame *Frame) SetDesignCode(code DesignCode) {
+	if code == nil {
+		code = DefaultDesignCode
+	}
+	frame.DesignCode = code
+	frame.fixedcode = true
+}
+
 func (frame *Frame) ReadLst(filename string) error {
 	tmp := make([][]string, 0)
+	if !frame.fixedcode {
+		frame.DesignCode = DefaultDesignCode // DESIGNCODE is written only when it differs from the default
+	}
 	err := ParseFile(filename, func(words []string) error {
 		var err error
 		first := strings.ToUpper(words[0])
@@ -2167,6 +2272,17 @@ func (frame *Frame) ReadLst(filename string) error {
 		case "CODE":
 			err = frame.ParseLst(tmp)
 			tmp = [][]string{words}
+		case "DESIGNCODE":
+			if len(words) < 2 {
+				return fmt.Errorf("ReadLst: DESIGNCODE: not enough data")
+			}
+			code, err := LookupDesignCode(words[1])
+			if err != nil {
+				return err
+			}
+			if !frame.fixedcode {
+				frame.DesignCode = code
+			}
 		}
 		if err != nil {
 			return err
@@ -2201,6 +2317,8 @@ func (frame *Frame) ParseLst(lis [][]string) error {
 			err = frame.ParseLstRC(lis)
 		case "WOOD":
 			err = frame.ParseLstWood(lis)
+		case "CFT", "SRC":
+			err = frame.ParseLstComposite(lis)
 		}
 	}
 	return err
@@ -2253,7 +2371,16 @@ func ParseShape(data []string) (Shape, int, error) {
 	case "THICK":
 		size = 1
 		shape, err = NewTHICK(data[1 : 1+size])
+	case "POLYGON":
+		shape, size, err = NewGeneralShapeFromList(data[1:])
 	default:
+		if IsCatalogDesignation(data[0]) {
+			e, err := LookupSteel(data[0])
+			if err != nil {
+				return nil, 0, err
+			}
+			return e.Shape(), 0, nil
+		}
 		return nil, 0, fmt.Errorf("unknown shape name: %s", data[0])
 	}
 	return shape, size, err
@@ -2409,6 +2536,115 @@ func (frame *Frame) ParseLstRC(lis [][]string) error {
 	return nil
 }
 
+// ParseLstComposite parses CFT and SRC sections.
+// The second line is the steel shape and the rest is the concrete (RC part).
+func (frame *Frame) ParseLstComposite(lis [][]string) error {
+	var num int
+	var sr SectionRate
+	var err error
+	tmp, err := strconv.ParseInt(lis[0][1], 10, 64)
+	if err != nil {
+		return err
+	}
+	num = int(tmp)
+	if _, ok := frame.Sects[num]; !ok {
+		return nil
+	}
+	if len(lis[0]) < 4 {
+		return fmt.Errorf("ParseLstComposite: no element type: CODE %d", num)
+	}
+	if len(lis) < 2 {
+		return fmt.Errorf("ParseLstComposite: no steel shape: CODE %d", num)
+	}
+	shape, size, err := ParseShape(lis[1])
+	if err != nil {
+		return err
+	}
+	if len(lis[1]) < 2+size {
+		return fmt.Errorf("ParseLstComposite: no steel material: CODE %d", num)
+	}
+	material, err := materialname(lis[1][1+size])
+	if err != nil {
+		return err
+	}
+	if _, ok := material.(Steel); !ok {
+		return fmt.Errorf("material is not Steel")
+	}
+	var rc *RCColumn
+	switch strings.ToUpper(lis[0][2]) {
+	case "CFT":
+		if lis[0][3] != "COLUMN" {
+			return nil
+		}
+		sr, err = NewCFTColumn(num, shape, material.(Steel), FC24)
+		if err != nil {
+			return err
+		}
+	case "SRC":
+		switch lis[0][3] {
+		case "COLUMN":
+			src := NewSRCColumn(num, shape, material.(Steel))
+			rc = src.RCPart
+			sr = src
+		case "GIRDER":
+			src := NewSRCGirder(num, shape, material.(Steel))
+			rc = src.RCPart
+			sr = src
+		default:
+			return nil
+		}
+	}
+	for _, words := range lis[2:] {
+		first := strings.ToUpper(words[0])
+		switch first {
+		case "CONCRETE":
+			if cf, ok := sr.(*CFTColumn); ok && len(words) > 1 {
+				c, err := materialname(words[1])
+				if err != nil {
+					return err
+				}
+				if _, ok := c.(Concrete); !ok {
+					return fmt.Errorf("material is not Concrete")
+				}
+				cf.Concrete = c.(Concrete)
+			}
+		case "REINS":
+			if rc != nil {
+				err = rc.AddReins(words[1:])
+			}
+		case "HOOPS":
+			if rc != nil {
+				err = rc.SetHoops(words[1:])
+			}
+		case "CRECT":
+			if rc != nil {
+				err = rc.SetConcrete(words)
+			}
+		case "XFACE", "YFACE", "BBLEN", "BTLEN", "BBFAC", "BTFAC":
+			if len(words) < 3 {
+				return fmt.Errorf("ParseLstComposite: %s: not enough data: CODE %d", first, num)
+			}
+			vals := make([]float64, 2)
+			for i := 0; i < 2; i++ {
+				val, err := strconv.ParseFloat(words[1+i], 64)
+				if err != nil {
+					return err
+				}
+				vals[i] = val
+			}
+			sr.SetValue(first, vals)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(lis[0]) > 4 {
+		sr.SetName(strings.Trim(lis[0][4], "\""))
+	}
+	frame.Sects[num].Allow = sr
+	return nil
+}
+
 // ParseLstSteel parses wood sections.
 func (frame *Frame) ParseLstWood(lis [][]string) error {
 	var num int
@@ -2734,7 +2970,10 @@ func (frame *Frame) WriteInp(fn string) error {
 	sort.Slice(chains, func(i, j int) bool {
 		return chains[i].Elems()[0].Num < chains[j].Elems()[0].Num
 	})
-	return writeinp(fn, frame.Title, frame.View, frame.Ai, frame.Wind, bonds, props, sects, piles, nodes, elems, chains, frame.NodeSet, frame.ElemSet)
+	if frame.Source != nil && frame.Source.IsMain(fn) {
+		return frame.writeSource(fn, bonds, props, sects, piles, nodes, elems, chains)
+	}
+	return writeinp(fn, frame.Title, frame.View, frame.Ai, frame.Wind, frame.Periods, nil, bonds, props, sects, piles, nodes, elems, chains, frame.NodeSet, frame.ElemSet)
 }
 
 // WriteOutput writes an output file of analysis.
@@ -2791,7 +3030,7 @@ func (frame *Frame) WriteReaction(fn string, direction int, unit float64) error
 		ns[nnum] = n
 	}
 	sort.Sort(NodeByNum{ns})
-	return WriteReaction(fn, ns, direction, frame.Show.Unit[0])
+	return WriteReaction(fn, ns, direction, frame.Show.Unit[0], frame.ExtraPeriodNames()...)
 }
 
 func (frame *Frame) ZoubunSummary(fn string, period string, cond *arclm.AnalysisCondition) error {
@@ -2814,29 +3053,11 @@ func (frame *Frame) ZoubunSummary(fn string, period string, cond *arclm.Analysis
 	elems := frame.FloorElems([]int{COLUMN, GIRDER, BRACE, WBRACE, SBRACE}, nil, nil)
 	for lap := 0; lap < nlap; lap++ {
 		nper := fmt.Sprintf("%s@%d", period, lap+1)
-		avedisp := make([]float64, frame.Ai.Nfloor)
-		aveheight := make([]float64, frame.Ai.Nfloor)
-		storydrift := make([]float64, frame.Ai.Nfloor-1)
-		shear := make([]float64, frame.Ai.Nfloor-1)
+		frame.LoadLap(nper)
+		storydrift, shear := frame.StoryDriftShear(nper, ind, nodes, elems)
 		otp.WriteString(fmt.Sprintf("%4d", lap+1))
-		for i := 0; i < frame.Ai.Nfloor; i++ {
-			for _, n := range nodes[i] {
-				avedisp[i] += n.Disp[nper][ind]
-				aveheight[i] += n.Coord[2]
-			}
-			avedisp[i] /= float64(len(nodes[i]))
-			aveheight[i] /= float64(len(nodes[i]))
-		}
 		for i := 0; i < frame.Ai.Nfloor-1; i++ {
-			storydrift[i] = (avedisp[i+1] - avedisp[i])/(aveheight[i+1]-aveheight[i])
-			for _, el := range elems[i] {
-				if ind == 0 {
-					shear[i] -= el.VectorStress(nper, 0, XAXIS)*frame.Show.Unit[0]
-				} else {
-					shear[i] -= el.VectorStress(nper, 0, YAXIS)*frame.Show.Unit[0]
-				}
-			}
-			otp.WriteString(fmt.Sprintf(" %10.6f %10.6f", storydrift[i], shear[i]))
+			otp.WriteString(fmt.Sprintf(" %10.6f %10.6f", storydrift[i], shear[i]*frame.Show.Unit[0]))
 		}
 		otp.WriteString("\n")
 	}
@@ -2850,6 +3071,33 @@ func (frame *Frame) ZoubunSummary(fn string, period string, cond *arclm.Analysis
 	return nil
 }
 
+// StoryDriftShear returns the story drift angles and the story shears [tf] in the direction ind (0: X, 1: Y) of period.
+func (frame *Frame) StoryDriftShear(period string, ind int, nodes [][]*Node, elems [][]*Elem) ([]float64, []float64) {
+	avedisp := make([]float64, frame.Ai.Nfloor)
+	aveheight := make([]float64, frame.Ai.Nfloor)
+	storydrift := make([]float64, frame.Ai.Nfloor-1)
+	shear := make([]float64, frame.Ai.Nfloor-1)
+	for i := 0; i < frame.Ai.Nfloor; i++ {
+		for _, n := range nodes[i] {
+			avedisp[i] += n.ReturnDisp(period, ind)
+			aveheight[i] += n.Coord[2]
+		}
+		avedisp[i] /= float64(len(nodes[i]))
+		aveheight[i] /= float64(len(nodes[i]))
+	}
+	for i := 0; i < frame.Ai.Nfloor-1; i++ {
+		storydrift[i] = (avedisp[i+1] - avedisp[i]) / (aveheight[i+1] - aveheight[i])
+		for _, el := range elems[i] {
+			if ind == 0 {
+				shear[i] -= el.VectorStress(period, 0, XAXIS)
+			} else {
+				shear[i] -= el.VectorStress(period, 0, YAXIS)
+			}
+		}
+	}
+	return storydrift, shear
+}
+
 // ReportZoubunDisp writes an output file which reports displacement data of push-over analysis.
 func (frame *Frame) ReportZoubunDisp(fn string, ns []*Node, pers []string, direction int) error {
 	var otp bytes.Buffer
@@ -2866,6 +3114,7 @@ func (frame *Frame) ReportZoubunDisp(fn string, ns []*Node, pers []string, direc
 			otp.WriteString("\n")
 			for i := 0; i < nlap; i++ {
 				nper := fmt.Sprintf("%s@%d", per, i+1)
+				frame.LoadLap(nper)
 				otp.WriteString(fmt.Sprintf("%8s", nper))
 				for _, n := range ns {
 					if d, ok := n.Disp[nper]; ok {
@@ -2907,6 +3156,7 @@ func (frame *Frame) ReportZoubunReaction(fn string, ns []*Node, pers []string, d
 			otp.WriteString("\n")
 			for i := 0; i < nlap; i++ {
 				nper := fmt.Sprintf("%s@%d", per, i+1)
+				frame.LoadLap(nper)
 				otp.WriteString(fmt.Sprintf("%8s", nper))
 				for _, n := range ns {
 					if r, ok := n.Reaction[nper]; ok {
@@ -3022,7 +3272,7 @@ func (frame *Frame) DrawDxfSection(d *drawing.Drawing, el *Elem, position []floa
 	case *SColumn:
 		sh := al.Shape
 		switch sh.(type) {
-		case HKYOU, HWEAK, CROSS, RPIPE, PLATE, ANGLE, TKYOU, TWEAK, CKYOU, CWEAK:
+		case HKYOU, HWEAK, CROSS, RPIPE, PLATE, ANGLE, TKYOU, TWEAK, CKYOU, CWEAK, GeneralShape:
 			vertices := sh.Vertices()
 			el.DrawDxfSection(d, position, scale, vertices)
 		case CPIPE:
@@ -3039,7 +3289,7 @@ func (frame *Frame) DrawDxfSection(d *drawing.Drawing, el *Elem, position []floa
 	case *SGirder:
 		sh := al.Shape
 		switch sh.(type) {
-		case HKYOU, HWEAK, CROSS, RPIPE, PLATE, ANGLE, TKYOU, TWEAK, CKYOU, CWEAK:
+		case HKYOU, HWEAK, CROSS, RPIPE, PLATE, ANGLE, TKYOU, TWEAK, CKYOU, CWEAK, GeneralShape:
 			vertices := sh.Vertices()
 			el.DrawDxfSection(d, position, scale, vertices)
 		case CPIPE:
@@ -4831,6 +5081,8 @@ func (frame *Frame) SetBoundary(num int, eps float64) error {
 	return nil
 }
 
+// ExtractArclm creates an arclm frame for every registered period.
+// L takes the weight and CMQ, X and Y take the seismic forces, and the other periods take the nodal loads only with short-term stiffness.
 func (frame *Frame) ExtractArclm(fn string) error {
 	cmqs := make(map[int][]float64)
 	for _, el := range frame.Elems {
@@ -4859,6 +5111,12 @@ func (frame *Frame) ExtractArclm(fn string) error {
 	if err != nil {
 		return err
 	}
+	for _, el := range frame.Elems {
+		if el.IsLineElem() {
+			el.EffectiveCmq = make([]float64, 12)
+			copy(el.EffectiveCmq, el.Cmq)
+		}
+	}
 	for _, el := range SortedElem(frame.Elems, func(e *Elem) float64 { return float64(e.Num) }) {
 		if !el.IsLineElem() {
 			brs := el.RectToBrace(2, 1.0)
@@ -4921,7 +5179,8 @@ func (frame *Frame) ExtractArclm(fn string) error {
 			}
 		}
 	}
-	for pi, p := range []string{"L", "X", "Y"} {
+	for _, lp := range frame.Periods {
+		p := lp.Name
 		af := arclm.NewFrame()
 		af.Sects = make([]*arclm.Sect, snum+bnum)
 		arclmsects := make(map[int]int)
@@ -5044,7 +5303,7 @@ func (frame *Frame) ExtractArclm(fn string) error {
 		af.Elems = make([]*arclm.Elem, enum)
 		ind := 0
 		for _, el := range elems {
-			if el.Skip[pi] {
+			if el.IsSkipPeriod(p) {
 				continue
 			}
 			ae := arclm.NewElem()
@@ -5792,17 +6051,86 @@ func (frame *Frame) SaveAsArclm(name string) error {
 	if name == "" {
 		name = frame.Path
 	}
-	for i, p := range []string{"L", "X", "Y"} {
-		fn := Ce(name, InputExt[i])
-		err := frame.Arclms[p].SaveInput(fn)
+	pers, err := frame.ArclmPeriods()
+	if err != nil {
+		return err
+	}
+	for _, p := range pers {
+		fn := Ce(name, p.Input)
+		err := frame.Arclms[p.Name].SaveInput(fn)
 		if err != nil {
 			return err
 		}
-		frame.DataFileName[p] = fn
+		frame.DataFileName[p.Name] = fn
 	}
 	return nil
 }
 
+// AnalyseAll extracts the frame and runs linear analysis of all the periods at once, writing the results to otp.
+// It returns after the results are read.
+func (frame *Frame) AnalyseAll(otp string) error {
+	return frame.analyseAll(frame.Path, otp)
+}
+
+// analyseAll is AnalyseAll which writes the weight and the input files of analysis to Ce(base, ...).
+func (frame *Frame) analyseAll(base, otp string) error {
+	err := frame.ExtractArclm(Ce(base, ".wgt"))
+	if err != nil {
+		return err
+	}
+	err = frame.SaveAsArclm(base)
+	if err != nil {
+		return err
+	}
+	acond := arclm.NewAnalysisCondition()
+	af := frame.Arclms["L"]
+	if af == nil {
+		return fmt.Errorf("AnalyseAll: frame isn't extracted to period L")
+	}
+	pers := []string{"L"}
+	otps := []string{Ce(otp, frame.Period("L").Output)}
+	extra := make([][]float64, 0)
+	arclmpers, err := frame.ArclmPeriods()
+	if err != nil {
+		return err
+	}
+	for _, p := range arclmpers {
+		if p.Name == "L" {
+			continue
+		}
+		_, _, vec, err := frame.Arclms[p.Name].AssemGlobalVector(1.0)
+		if err != nil {
+			return err
+		}
+		pers = append(pers, p.Name)
+		otps = append(otps, Ce(otp, p.Output))
+		extra = append(extra, vec)
+	}
+	acond.SetExtra(extra)
+	acond.SetOutput(otps)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		err := af.StaticAnalysis(cancel, acond)
+		af.Endch <- err
+	}()
+	ind := 0
+	retval := 0
+	for {
+		select {
+		case <-ctx.Done():
+			retval = 1
+		case <-af.Pivot:
+		case <-af.Lapch:
+			frame.ReadArclmData(af, pers[ind])
+			frame.ResultFileName[pers[ind]] = otps[ind]
+			ind++
+			af.Lapch <- retval
+		case err := <-af.Endch:
+			return err
+		}
+	}
+}
+
 func (frame *Frame) ReadArclmData(af *arclm.Frame, per string) {
 	for _, an := range af.Nodes {
 		if n, ok := frame.Nodes[an.Num]; ok {
@@ -5875,11 +6203,21 @@ func (frame *Frame) SectionRateCalculation(fn string, long, x1, x2, y1, y2 strin
 	maxrateelem := make(map[int][]*Elem)
 	for _, el := range elems {
 		el.Condition = cond.Snapshot()
+		for _, key := range []string{"JOINTL", "JOINTS", "JOINTU"} {
+			delete(el.Values, key)
+		}
 		al, str, err := el.OutputRateInformation(long, x1, x2, y1, y2, sign, nil, 0.0)
 		if err != nil {
 			continue
 		}
 		otp.WriteString(str)
+		if frame.Joints != nil {
+			jstr, err := frame.Joints.OutputJointRate(el, al, long, x1, x2, y1, y2, sign)
+			if err != nil {
+				jstr += fmt.Sprintf("接合部: %s\n", err.Error())
+			}
+			otp.WriteString(jstr)
+		}
 		rat.WriteString(el.OutputMaxRate())
 		for _, r := range el.MaxRate {
 			if r >= 1.0 {
@@ -5999,6 +6337,17 @@ func (frame *Frame) SectionRateCalculation(fn string, long, x1, x2, y1, y2 strin
 	}
 	tex.WriteString("\\end{tabular}\n}\n")
 	otp.WriteString(fmt.Sprintf("\n安全率の最大値\n Q/QaL=%7.5f Q/QaS=%7.5f\n M/MaL=%7.5f M/MaS=%7.5f\n", maxql, maxqs, maxml, maxms))
+	if frame.Joints != nil {
+		maxj := make([]float64, 3)
+		for _, el := range elems {
+			for i, key := range []string{"JOINTL", "JOINTS", "JOINTU"} {
+				if val, ok := el.Values[key]; ok && val > maxj[i] {
+					maxj[i] = val
+				}
+			}
+		}
+		otp.WriteString(fmt.Sprintf(" J/JaL=%7.5f J/JaS=%7.5f J/Ju =%7.5f\n", maxj[0], maxj[1], maxj[2]))
+	}
 	tex.WriteString(fmt.Sprintf("\\vspace{10mm}\n\\paragraph{安全率の最大値}\n{\\small\n\\begin{tabular}{l l}\\\\\n $Q/Q_{aL}$=%7.5f & $Q/Q_{aS}$=%7.5f\\\\\n $M/M_{aL}$=%7.5f & $M/M_{aS}$=%7.5f\\\\\n\\end{tabular}\n}\n\\newpage\n", maxql, maxqs, maxml, maxms))
 	otp.WriteString("==========================================================================================================================================================================================================\n各断面種別の入力情報の確認\n\n")
 	otp.WriteString("                              A[cm2]      Ix[cm4]      Iy[cm4]       J[cm4]\n")
@@ -7229,10 +7578,10 @@ func WriteInp(fn string, view *View, ai *Aiparameter, wind *Windparameter, els [
 	}
 	piles = piles[:inum]
 	sort.Sort(PileByNum{piles})
-	return writeinp(fn, "\"CREATED ORGAN FRAME.\"", view, ai, wind, bonds, props, sects, piles, nodes, elems, nil, nil, nil)
+	return writeinp(fn, "\"CREATED ORGAN FRAME.\"", view, ai, wind, nil, nil, bonds, props, sects, piles, nodes, elems, nil, nil, nil)
 }
 
-func writeinp(fn, title string, view *View, ai *Aiparameter, wind *Windparameter, bonds []*Bond, props []*Prop, sects []*Sect, piles []*Pile, nodes []*Node, elems []*Elem, chains []*Chain, nodeset map[string][]*Node, elemset map[string][]*Elem) error {
+func writeinp(fn, title string, view *View, ai *Aiparameter, wind *Windparameter, periods []*LoadPeriod, file *InpFile, bonds []*Bond, props []*Prop, sects []*Sect, piles []*Pile, nodes []*Node, elems []*Elem, chains []*Chain, nodeset map[string][]*Node, elemset map[string][]*Elem) error {
 	var otp bytes.Buffer
 	inum := len(piles)
 	// Frame
@@ -7240,7 +7589,7 @@ func writeinp(fn, title string, view *View, ai *Aiparameter, wind *Windparameter
 	for _, c := range chains {
 		nelem += c.Size()
 	}
-	if len(bonds) == 0 {
+	if len(bonds) == 0 && file == nil {
 		bonds = []*Bond{Pin}
 	}
 	otp.WriteString(fmt.Sprintf("%s\n", title))
@@ -7253,17 +7602,24 @@ func writeinp(fn, title string, view *View, ai *Aiparameter, wind *Windparameter
 		otp.WriteString(fmt.Sprintf("NPILE %d\n", inum))
 	}
 	otp.WriteString("\n")
+	if file != nil {
+		otp.WriteString(file.paramString())
+	}
 	otp.WriteString(fmt.Sprintf("BASE    %5.3f %5.3f\n", ai.Base[0], ai.Base[1]))
 	otp.WriteString(fmt.Sprintf("LOCATE  %5.3f\n", ai.Locate))
 	otp.WriteString(fmt.Sprintf("TFACT   %5.3f\n", ai.Tfact))
 	otp.WriteString(fmt.Sprintf("GPERIOD %5.3f\n", ai.Gperiod))
 	if ai.Nfloor > 0 {
 		otp.WriteString(fmt.Sprintf("NFLOOR %d\n", ai.Nfloor))
-		otp.WriteString("HEIGHT")
-		for i := 0; i < ai.Nfloor+1; i++ {
-			otp.WriteString(fmt.Sprintf(" %.3f", ai.Boundary[i]))
+		if h := file.heightString(ai); h != "" {
+			otp.WriteString(h)
+		} else {
+			otp.WriteString("HEIGHT")
+			for i := 0; i < ai.Nfloor+1; i++ {
+				otp.WriteString(fmt.Sprintf(" %.3f", ai.Boundary[i]))
+			}
+			otp.WriteString("\n")
 		}
-		otp.WriteString("\n")
 	}
 	otp.WriteString(fmt.Sprintf("NOKIHEIGHT %5.3f\n", ai.H0))
 	if ai.Nfloor > 1 {
@@ -7283,27 +7639,73 @@ func writeinp(fn, title string, view *View, ai *Aiparameter, wind *Windparameter
 	otp.WriteString(fmt.Sprintf("VELOCITY  %5.3f\n", wind.Velocity))
 	otp.WriteString(fmt.Sprintf("WFACT     %5.3f\n", wind.Factor))
 	otp.WriteString("\n")
+	nper := 0
+	for _, p := range periods {
+		if p.IsDefault() {
+			continue
+		}
+		otp.WriteString(p.InpString())
+		nper++
+	}
+	if nper > 0 {
+		otp.WriteString("\n")
+	}
 	otp.WriteString(fmt.Sprintf("GFACT %.1f\n", view.Gfact))
 	otp.WriteString(fmt.Sprintf("FOCUS %.1f %.1f %.1f\n", view.Focus[0], view.Focus[1], view.Focus[2]))
 	otp.WriteString(fmt.Sprintf("ANGLE %.1f %.1f\n", view.Angle[0], view.Angle[1]))
 	otp.WriteString(fmt.Sprintf("DISTS %.1f %.1f\n\n", view.Dists[0], view.Dists[1]))
+	writeinpbody(&otp, file, bonds, props, sects, piles, nodes, elems, chains)
+	for _, ns := range slices.Sorted(maps.Keys(nodeset)) {
+		otp.WriteString(fmt.Sprintf("NODESET %s", ns))
+		for _, n := range nodeset[ns] {
+			otp.WriteString(fmt.Sprintf(" %d", n.Num))
+		}
+		otp.WriteString("\n")
+	}
+	for _, els := range slices.Sorted(maps.Keys(elemset)) {
+		otp.WriteString(fmt.Sprintf("ELEMSET %s", els))
+		for _, el := range elemset[els] {
+			otp.WriteString(fmt.Sprintf(" %d", el.Num))
+		}
+		otp.WriteString("\n")
+	}
+	// Write
+	w, err := os.Create(fn)
+	defer w.Close()
+	if err != nil {
+		return err
+	}
+	otp = AddCR(otp)
+	otp.WriteTo(w)
+	return nil
+}
+
+// writeinpbody writes bonds, props, sects, piles, nodes and elems.
+// If file is not nil, its INCLUDE lines are written after nodes.
+func writeinpbody(otp *bytes.Buffer, file *InpFile, bonds []*Bond, props []*Prop, sects []*Sect, piles []*Pile, nodes []*Node, elems []*Elem, chains []*Chain) {
+	// empty sections are omitted in included files
+	blank := func(n int) {
+		if file == nil || n > 0 {
+			otp.WriteString("\n")
+		}
+	}
 	// Bond
 	for _, b := range bonds {
 		otp.WriteString(b.InpString())
 	}
-	otp.WriteString("\n")
+	blank(len(bonds))
 	// Prop
 	for _, p := range props {
 		otp.WriteString(p.InpString())
 	}
-	otp.WriteString("\n")
+	blank(len(props))
 	// Sect
 	for _, sec := range sects {
 		otp.WriteString(sec.InpString())
 	}
-	otp.WriteString("\n")
+	blank(len(sects))
 	// Pile
-	if inum >= 1 {
+	if len(piles) >= 1 {
 		for _, i := range piles {
 			otp.WriteString(i.InpString())
 		}
@@ -7311,9 +7713,13 @@ func writeinp(fn, title string, view *View, ai *Aiparameter, wind *Windparameter
 	}
 	// Node
 	for _, n := range nodes {
-		otp.WriteString(n.InpString())
+		otp.WriteString(file.nodeString(n))
+	}
+	blank(len(nodes))
+	// Include
+	if file != nil {
+		otp.WriteString(file.includeString())
 	}
-	otp.WriteString("\n")
 	// Elem
 	for _, el := range elems {
 		otp.WriteString(el.InpString())
@@ -7326,29 +7732,6 @@ func writeinp(fn, title string, view *View, ai *Aiparameter, wind *Windparameter
 		}
 		otp.WriteString("}\n")
 	}
-	for _, ns := range slices.Sorted(maps.Keys(nodeset)) {
-		otp.WriteString(fmt.Sprintf("NODESET %s", ns))
-		for _, n := range nodeset[ns] {
-			otp.WriteString(fmt.Sprintf(" %d", n.Num))
-		}
-		otp.WriteString("\n")
-	}
-	for _, els := range slices.Sorted(maps.Keys(elemset)) {
-		otp.WriteString(fmt.Sprintf("ELEMSET %s", els))
-		for _, el := range elemset[els] {
-			otp.WriteString(fmt.Sprintf(" %d", el.Num))
-		}
-		otp.WriteString("\n")
-	}
-	// Write
-	w, err := os.Create(fn)
-	defer w.Close()
-	if err != nil {
-		return err
-	}
-	otp = AddCR(otp)
-	otp.WriteTo(w)
-	return nil
 }
 
 func WriteOutput(fn string, p string, els []*Elem) error {
@@ -7409,10 +7792,15 @@ func WriteOutput(fn string, p string, els []*Elem) error {
 	return nil
 }
 
-func WriteReaction(fn string, ns []*Node, direction int, unit float64) error {
+// WriteReaction writes the reactions of L, X, Y and their combinations, followed by those of extra periods.
+func WriteReaction(fn string, ns []*Node, direction int, unit float64, extra ...string) error {
 	var otp bytes.Buffer
 	r := make([]float64, 3)
-	otp.WriteString(" NODE   XCOORD   YCOORD   ZCOORD     WEIGHT       LONG      XSEIS      YSEIS        W+L      W+L+X      W+L-X      W+L+Y      W+L-Y PILE\n")
+	otp.WriteString(" NODE   XCOORD   YCOORD   ZCOORD     WEIGHT       LONG      XSEIS      YSEIS        W+L      W+L+X      W+L-X      W+L+Y      W+L-Y")
+	for _, per := range extra {
+		otp.WriteString(fmt.Sprintf(" %10s", per))
+	}
+	otp.WriteString(" PILE\n")
 	for _, n := range ns {
 		if n == nil {
 			continue
@@ -7430,6 +7818,13 @@ func WriteReaction(fn string, ns []*Node, direction int, unit float64) error {
 			}
 		}
 		otp.WriteString(fmt.Sprintf(" %10.3f %10.3f %10.3f %10.3f %10.3f %10.3f %10.3f %10.3f %10.3f", wgt, r[0], r[1], r[2], wgt+r[0], wgt+r[0]+r[1], wgt+r[0]-r[1], wgt+r[0]+r[2], wgt+r[0]-r[2]))
+		for _, per := range extra {
+			val := 0.0
+			if rea, ok := n.Reaction[per]; ok {
+				val = rea[direction] * unit
+			}
+			otp.WriteString(fmt.Sprintf(" %10.3f", val))
+		}
 		if n.Pile != nil {
 			otp.WriteString(fmt.Sprintf(" %4d\n", n.Pile.Num))
 		} else {
@@ -7511,7 +7906,7 @@ func WriteLst(fn string, sects []*Sect) error {
 	prefix := `仮定断面の入力データ
 
 "INPUT DATA"
-"MATERIAL TYPE:S,RC,SRC,PC"
+"MATERIAL TYPE:S,RC,SRC,CFT,PC"
 "MEMBER TYPE  :COLUMN,GIRDER,WALL,SLAB,BRACE"
 "SRECT:STEEL.                                    LEFT,BOTTOM,TOP,RIGHT[cm]"
 "HKYOU:STEEL H, x=STRONG AXIS.                               H,B,tw,tf[cm]"
@@ -7529,6 +7924,11 @@ func WriteLst(fn string, sects []*Sect) error {
 "YFACE:FACE FOR My,FACE FOR WALL HEIGHT.                     HEAD,TAIL[cm]"
 `
 	otp.WriteString(prefix)
+	if len(sects) > 0 && sects[0].Frame != nil {
+		if code := sects[0].Frame.DesignCode; code != nil && code.Name() != DefaultDesignCode.Name() {
+			otp.WriteString(fmt.Sprintf("\nDESIGNCODE %s\n", code.Name()))
+		}
+	}
 	for _, sec := range sects {
 		var al SectionRate
 		if a := sec.Allow; a != nil {
@@ -0,0 +1,914 @@
+package st
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yofu/dxf"
+	dxfentity "github.com/yofu/dxf/entity"
+)
+
+// GeneralShape is a section bounded by arbitrary polygons with holes (e.g. built-up and welded sections).
+// Coordinates are in [cm].
+// Section properties are calculated when the shape is created:
+// A, Ix, Iy, Zx, Zy, Zpx, Zpy exactly from the polygons, shear areas from the distribution of shear stress,
+// and St. Venant torsional constant, warping constant and shear center by finite differences on a grid.
+//
+// In .lst files, it is written as
+//
+//	POLYGON name nring n1 x y x y ... n2 x y ...
+//
+// where the number of vertices is negative for holes.
+type GeneralShape struct {
+	Name  string
+	Outer [][][]float64
+	Holes [][][]float64
+	prop  *generalProperty
+}
+
+type generalProperty struct {
+	a, cx, cy     float64
+	ix, iy, ixy   float64
+	iu, iv, theta float64
+	zx, zy        float64
+	zpx, zpy      float64
+	asx, asy      float64
+	j, iw, zt     float64
+	xs, ys        float64
+	min, max      []float64
+}
+
+// cells in the grid used to calculate torsional and warping constants
+var GeneralShapeCells = 20000
+
+// NewGeneralShape calculates section properties of the region bounded by outer polygons with holes.
+func NewGeneralShape(name string, outer, holes [][][]float64) (GeneralShape, error) {
+	gs := GeneralShape{
+		Name:  name,
+		Outer: outer,
+		Holes: holes,
+	}
+	if len(outer) == 0 {
+		return gs, fmt.Errorf("NewGeneralShape: no polygon")
+	}
+	for _, rings := range [][][][]float64{outer, holes} {
+		for _, r := range rings {
+			if len(r) < 3 {
+				return gs, fmt.Errorf("NewGeneralShape: polygon with %d vertices", len(r))
+			}
+		}
+	}
+	rg := region{outer, holes}
+	a, sx, sy, ixx, iyy, ixy := rg.moments()
+	if a <= 0.0 {
+		return gs, fmt.Errorf("NewGeneralShape: area is not positive")
+	}
+	p := &generalProperty{a: a}
+	p.cx = sy / a
+	p.cy = sx / a
+	p.ix = ixx - a*p.cy*p.cy
+	p.iy = iyy - a*p.cx*p.cx
+	p.ixy = ixy - a*p.cx*p.cy
+	p.iu, p.iv, p.theta = principal(p.ix, p.iy, p.ixy)
+	p.min, p.max = rg.bounds()
+	p.zx = p.ix / math.Max(p.max[1]-p.cy, p.cy-p.min[1])
+	p.zy = p.iy / math.Max(p.max[0]-p.cx, p.cx-p.min[0])
+	p.zpx = rg.plasticModulus(1)
+	p.zpy = rg.plasticModulus(0)
+	p.asx = rg.shearArea(0, p.cx, p.iy)
+	p.asy = rg.shearArea(1, p.cy, p.ix)
+	j, zt, err := rg.torsion(GeneralShapeCells)
+	if err != nil {
+		return gs, err
+	}
+	p.j = j
+	p.zt = zt
+	iw, xs, ys, err := rg.warping(GeneralShapeCells, p.cx, p.cy)
+	if err != nil {
+		return gs, err
+	}
+	p.iw = iw
+	p.xs = xs
+	p.ys = ys
+	gs.prop = p
+	return gs, nil
+}
+
+// NewGeneralShapeFromList parses "name nring n1 x y ... n2 x y ..." and returns the shape and the number of words used.
+func NewGeneralShapeFromList(lis []string) (GeneralShape, int, error) {
+	if len(lis) < 2 {
+		return GeneralShape{}, 0, NotEnoughArgs("NewGeneralShape")
+	}
+	nring, err := strconv.ParseInt(lis[1], 10, 64)
+	if err != nil {
+		return GeneralShape{}, 0, err
+	}
+	outer := make([][][]float64, 0)
+	holes := make([][][]float64, 0)
+	ind := 2
+	for i := 0; i < int(nring); i++ {
+		if len(lis) <= ind {
+			return GeneralShape{}, 0, NotEnoughArgs("NewGeneralShape")
+		}
+		n, err := strconv.ParseInt(lis[ind], 10, 64)
+		if err != nil {
+			return GeneralShape{}, 0, err
+		}
+		ind++
+		hole := n < 0
+		if hole {
+			n = -n
+		}
+		if len(lis) < ind+2*int(n) {
+			return GeneralShape{}, 0, NotEnoughArgs("NewGeneralShape")
+		}
+		ring := make([][]float64, n)
+		for j := 0; j < int(n); j++ {
+			ring[j] = make([]float64, 2)
+			for k := 0; k < 2; k++ {
+				val, err := strconv.ParseFloat(lis[ind], 64)
+				if err != nil {
+					return GeneralShape{}, 0, err
+				}
+				ring[j][k] = val
+				ind++
+			}
+		}
+		if hole {
+			holes = append(holes, ring)
+		} else {
+			outer = append(outer, ring)
+		}
+	}
+	gs, err := NewGeneralShape(lis[0], outer, holes)
+	return gs, ind, err
+}
+
+// ReadDxfShape reads closed LWPOLYLINEs and CIRCLEs on layer (all layers if layer is empty) of a dxf file as a GeneralShape.
+// Coordinates are multiplied by scale to convert them into [cm] (0.1 for drawings in [mm]).
+// Polygons inside an odd number of other polygons are holes.
+func ReadDxfShape(filename string, layer string, scale float64) (GeneralShape, error) {
+	d, err := dxf.FromFile(filename)
+	if err != nil {
+		return GeneralShape{}, err
+	}
+	rings := make([][][]float64, 0)
+	for _, en := range d.Entities() {
+		if layer != "" && (en.Layer() == nil || en.Layer().Name() != layer) {
+			continue
+		}
+		switch e := en.(type) {
+		case *dxfentity.LwPolyline:
+			vs := e.Vertices
+			if len(vs) > 1 && vs[0][0] == vs[len(vs)-1][0] && vs[0][1] == vs[len(vs)-1][1] {
+				vs = vs[:len(vs)-1]
+			} else if !e.Closed {
+				continue
+			}
+			if len(vs) < 3 {
+				continue
+			}
+			ring := make([][]float64, len(vs))
+			for i, v := range vs {
+				ring[i] = []float64{v[0] * scale, v[1] * scale}
+			}
+			rings = append(rings, ring)
+		case *dxfentity.Circle:
+			ndiv := 64
+			ring := make([][]float64, ndiv)
+			for i := 0; i < ndiv; i++ {
+				theta := 2.0 * math.Pi * float64(i) / float64(ndiv)
+				ring[i] = []float64{(e.Center[0] + e.Radius*math.Cos(theta)) * scale, (e.Center[1] + e.Radius*math.Sin(theta)) * scale}
+			}
+			rings = append(rings, ring)
+		}
+	}
+	if len(rings) == 0 {
+		return GeneralShape{}, fmt.Errorf("ReadDxfShape: no closed polyline in %s", filename)
+	}
+	outer := make([][][]float64, 0)
+	holes := make([][][]float64, 0)
+	for i, r := range rings {
+		depth := 0
+		for j, other := range rings {
+			if i != j && insideRing(r[0], other) {
+				depth++
+			}
+		}
+		if depth%2 == 1 {
+			holes = append(holes, r)
+		} else {
+			outer = append(outer, r)
+		}
+	}
+	name := layer
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	return NewGeneralShape(strings.Replace(name, " ", "_", -1), outer, holes)
+}
+
+func formatCoord(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e4)/1e4, 'f', -1, 64)
+}
+
+func (gs GeneralShape) String() string {
+	var rtn strings.Builder
+	name := gs.Name
+	if name == "" {
+		name = "_"
+	}
+	rtn.WriteString(fmt.Sprintf("POLYGON %s %d", name, len(gs.Outer)+len(gs.Holes)))
+	for k, rings := range [][][][]float64{gs.Outer, gs.Holes} {
+		for _, r := range rings {
+			if k == 0 {
+				rtn.WriteString(fmt.Sprintf(" %d", len(r)))
+			} else {
+				rtn.WriteString(fmt.Sprintf(" %d", -len(r)))
+			}
+			for _, v := range r {
+				rtn.WriteString(fmt.Sprintf(" %s %s", formatCoord(v[0]), formatCoord(v[1])))
+			}
+		}
+	}
+	return rtn.String()
+}
+func (gs GeneralShape) Description() string {
+	return fmt.Sprintf("%s(POLYGON)[cm]", gs.Name)
+}
+func (gs GeneralShape) A() float64 {
+	return gs.prop.a
+}
+func (gs GeneralShape) Asx() float64 {
+	return gs.prop.asx
+}
+func (gs GeneralShape) Asy() float64 {
+	return gs.prop.asy
+}
+func (gs GeneralShape) Ix() float64 {
+	return gs.prop.ix
+}
+func (gs GeneralShape) Iy() float64 {
+	return gs.prop.iy
+}
+func (gs GeneralShape) Ixy() float64 {
+	return gs.prop.ixy
+}
+
+// Centroid returns the coordinate of the centroid in the coordinate system of the polygons.
+func (gs GeneralShape) Centroid() []float64 {
+	return []float64{gs.prop.cx, gs.prop.cy}
+}
+
+// Principal returns the principal moments of inertia (major, minor) and the angle of the major axis from x axis [rad].
+func (gs GeneralShape) Principal() (float64, float64, float64) {
+	return gs.prop.iu, gs.prop.iv, gs.prop.theta
+}
+func (gs GeneralShape) Imin() float64 {
+	return gs.prop.iv
+}
+
+// ShearCenter returns the coordinate of the shear center relative to the centroid.
+func (gs GeneralShape) ShearCenter() []float64 {
+	return []float64{gs.prop.xs, gs.prop.ys}
+}
+func (gs GeneralShape) J() float64 {
+	return gs.prop.j
+}
+func (gs GeneralShape) Iw() float64 {
+	return gs.prop.iw
+}
+func (gs GeneralShape) Torsion() float64 {
+	return gs.prop.zt
+}
+func (gs GeneralShape) Zx() float64 {
+	return gs.prop.zx
+}
+func (gs GeneralShape) Zy() float64 {
+	return gs.prop.zy
+}
+func (gs GeneralShape) Zpx() float64 {
+	return gs.prop.zpx
+}
+func (gs GeneralShape) Zpy() float64 {
+	return gs.prop.zpy
+}
+func (gs GeneralShape) BT_ratio() []float64 {
+	return []float64{0.0}
+}
+
+// BT_ratio_category cannot be judged from polygons, so the lowest rank is returned.
+func (gs GeneralShape) BT_ratio_category(material Steel) int {
+	return P_IV
+}
+
+// Vertices returns the first outer polygon relative to the centroid.
+func (gs GeneralShape) Vertices() [][]float64 {
+	vertices := make([][]float64, len(gs.Outer[0]))
+	for i, v := range gs.Outer[0] {
+		vertices[i] = []float64{v[0] - gs.prop.cx, v[1] - gs.prop.cy}
+	}
+	return vertices
+}
+
+func (gs GeneralShape) PgfString(cx, cy, scale float64) string {
+	var rtn strings.Builder
+	for _, rings := range [][][][]float64{gs.Outer, gs.Holes} {
+		for _, r := range rings {
+			rtn.WriteString("\\draw ")
+			for _, v := range r {
+				rtn.WriteString(fmt.Sprintf("(%.3f,%.3f) -- ", cx+(v[0]-gs.prop.cx)*scale, cy+(v[1]-gs.prop.cy)*scale))
+			}
+			rtn.WriteString("cycle;\n")
+		}
+	}
+	return rtn.String()
+}
+
+func (gs GeneralShape) Breadth(strong bool) float64 {
+	if strong {
+		return gs.prop.max[0] - gs.prop.min[0]
+	} else {
+		return gs.prop.max[1] - gs.prop.min[1]
+	}
+}
+
+// principal returns the principal moments of inertia and the angle of the major axis.
+func principal(ix, iy, ixy float64) (float64, float64, float64) {
+	c := 0.5 * (ix + iy)
+	r := math.Sqrt(math.Pow(0.5*(ix-iy), 2.0) + ixy*ixy)
+	theta := 0.5 * math.Atan2(-2.0*ixy, ix-iy)
+	return c + r, c - r, theta
+}
+
+// insideRing reports whether p is inside ring (even-odd rule).
+func insideRing(p []float64, ring [][]float64) bool {
+	rtn := false
+	n := len(ring)
+	for i := 0; i < n; i++ {
+		a := ring[i]
+		b := ring[(i+1)%n]
+		if (a[1] > p[1]) != (b[1] > p[1]) {
+			if p[0] < a[0]+(p[1]-a[1])*(b[0]-a[0])/(b[1]-a[1]) {
+				rtn = !rtn
+			}
+		}
+	}
+	return rtn
+}
+
+// ringMoments returns the area, the first moments (about x and y axes),
+// the second moments (about x and y axes) and the product of inertia of a polygon.
+// The polygon may be either clockwise or counter-clockwise.
+func ringMoments(ring [][]float64) (a, sx, sy, ixx, iyy, ixy float64) {
+	n := len(ring)
+	for i := 0; i < n; i++ {
+		x1, y1 := ring[i][0], ring[i][1]
+		x2, y2 := ring[(i+1)%n][0], ring[(i+1)%n][1]
+		c := x1*y2 - x2*y1
+		a += 0.5 * c
+		sx += (y1 + y2) * c / 6.0
+		sy += (x1 + x2) * c / 6.0
+		ixx += (y1*y1 + y1*y2 + y2*y2) * c / 12.0
+		iyy += (x1*x1 + x1*x2 + x2*x2) * c / 12.0
+		ixy += (x1*y2 + 2.0*x1*y1 + 2.0*x2*y2 + x2*y1) * c / 24.0
+	}
+	if a < 0.0 {
+		return -a, -sx, -sy, -ixx, -iyy, -ixy
+	}
+	return
+}
+
+// clipRing returns the part of a polygon where the coordinate of axis (0: x, 1: y) is not less than val.
+func clipRing(ring [][]float64, axis int, val float64) [][]float64 {
+	n := len(ring)
+	rtn := make([][]float64, 0, n+2)
+	for i := 0; i < n; i++ {
+		p := ring[i]
+		q := ring[(i+1)%n]
+		pin := p[axis] >= val
+		qin := q[axis] >= val
+		if pin {
+			rtn = append(rtn, p)
+		}
+		if pin != qin {
+			t := (val - p[axis]) / (q[axis] - p[axis])
+			rtn = append(rtn, []float64{p[0] + t*(q[0]-p[0]), p[1] + t*(q[1]-p[1])})
+		}
+	}
+	return rtn
+}
+
+// region is bounded by outer polygons with holes.
+type region struct {
+	outer, holes [][][]float64
+}
+
+func (rg region) each(f func(ring [][]float64, sign float64)) {
+	for _, r := range rg.outer {
+		f(r, 1.0)
+	}
+	for _, r := range rg.holes {
+		f(r, -1.0)
+	}
+}
+
+func (rg region) moments() (a, sx, sy, ixx, iyy, ixy float64) {
+	rg.each(func(r [][]float64, sign float64) {
+		ra, rsx, rsy, rixx, riyy, rixy := ringMoments(r)
+		a += sign * ra
+		sx += sign * rsx
+		sy += sign * rsy
+		ixx += sign * rixx
+		iyy += sign * riyy
+		ixy += sign * rixy
+	})
+	return
+}
+
+func (rg region) bounds() ([]float64, []float64) {
+	min := []float64{math.Inf(1), math.Inf(1)}
+	max := []float64{math.Inf(-1), math.Inf(-1)}
+	rg.each(func(r [][]float64, sign float64) {
+		for _, p := range r {
+			for i := 0; i < 2; i++ {
+				min[i] = math.Min(min[i], p[i])
+				max[i] = math.Max(max[i], p[i])
+			}
+		}
+	})
+	return min, max
+}
+
+// above returns the area and the first moment (coordinate of axis times area) of the part where the coordinate of axis is not less than val.
+func (rg region) above(axis int, val float64) (a, s float64) {
+	rg.each(func(r [][]float64, sign float64) {
+		r = clipRing(r, axis, val)
+		if len(r) < 3 {
+			return
+		}
+		ra, rsx, rsy, _, _, _ := ringMoments(r)
+		a += sign * ra
+		if axis == 1 {
+			s += sign * rsx
+		} else {
+			s += sign * rsy
+		}
+	})
+	return
+}
+
+// chord returns the total length of the section cut by the line where the coordinate of axis is val.
+func (rg region) chord(axis int, val float64) float64 {
+	other := 1 - axis
+	rtn := 0.0
+	rg.each(func(r [][]float64, sign float64) {
+		xs := make([]float64, 0)
+		n := len(r)
+		for i := 0; i < n; i++ {
+			p := r[i]
+			q := r[(i+1)%n]
+			if (p[axis] > val) != (q[axis] > val) {
+				t := (val - p[axis]) / (q[axis] - p[axis])
+				xs = append(xs, p[other]+t*(q[other]-p[other]))
+			}
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			rtn += sign * (xs[i+1] - xs[i])
+		}
+	})
+	return rtn
+}
+
+// plasticModulus returns the plastic section modulus for bending about the axis perpendicular to axis
+// (1: about x axis, 0: about y axis).
+func (rg region) plasticModulus(axis int) float64 {
+	a, sx, sy, _, _, _ := rg.moments()
+	s := sy
+	if axis == 1 {
+		s = sx
+	}
+	min, max := rg.bounds()
+	lo, hi := min[axis], max[axis]
+	for i := 0; i < 60; i++ {
+		mid := 0.5 * (lo + hi)
+		if am, _ := rg.above(axis, mid); am > 0.5*a {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	c := 0.5 * (lo + hi)
+	au, su := rg.above(axis, c)
+	return (su - c*au) - ((s - su) - c*(a-au))
+}
+
+// shearArea returns V/τmax for shear along axis, where τ = V Q / (I b) on cuts perpendicular to axis.
+// c is the centroid and i is the moment of inertia about the neutral axis.
+func (rg region) shearArea(axis int, c, i float64) float64 {
+	min, max := rg.bounds()
+	ndiv := 200
+	rtn := math.Inf(1)
+	for k := 0; k <= ndiv; k++ {
+		val := min[axis] + (max[axis]-min[axis])*float64(k)/float64(ndiv)
+		if k == ndiv/2 {
+			val = c
+		}
+		b := rg.chord(axis, val)
+		if b <= 0.0 {
+			continue
+		}
+		au, su := rg.above(axis, val)
+		q := math.Abs(su - c*au)
+		if q <= 0.0 {
+			continue
+		}
+		if as := i * b / q; as < rtn {
+			rtn = as
+		}
+	}
+	if math.IsInf(rtn, 1) {
+		return 0.0
+	}
+	return rtn
+}
+
+// grid is a raster of a region used by finite differences.
+// Cells outside the region are labeled -1 (connected to the outside of the grid) or -2-k (k-th hole).
+type grid struct {
+	nx, ny   int
+	h        float64
+	x0, y0   float64
+	label    []int
+	ncell    int
+	nhole    int
+	holesize []int
+}
+
+func (rg region) grid(ncell int) *grid {
+	a, _, _, _, _, _ := rg.moments()
+	min, max := rg.bounds()
+	h := math.Sqrt(a / float64(ncell))
+	if d := math.Max(max[0]-min[0], max[1]-min[1]) / 2000.0; h < d {
+		h = d
+	}
+	g := &grid{
+		nx: int(math.Ceil((max[0]-min[0])/h)) + 2,
+		ny: int(math.Ceil((max[1]-min[1])/h)) + 2,
+		h:  h,
+		x0: min[0] - h,
+		y0: min[1] - h,
+	}
+	g.label = make([]int, g.nx*g.ny)
+	for i := range g.label {
+		g.label[i] = -1
+	}
+	for j := 0; j < g.ny; j++ {
+		y := g.y0 + (float64(j)+0.5)*h
+		xs := make([]float64, 0)
+		rg.each(func(r [][]float64, sign float64) {
+			n := len(r)
+			for i := 0; i < n; i++ {
+				p := r[i]
+				q := r[(i+1)%n]
+				if (p[1] > y) != (q[1] > y) {
+					xs = append(xs, p[0]+(y-p[1])*(q[0]-p[0])/(q[1]-p[1]))
+				}
+			}
+		})
+		sort.Float64s(xs)
+		for k := 0; k+1 < len(xs); k += 2 {
+			for i := 0; i < g.nx; i++ {
+				x := g.x0 + (float64(i)+0.5)*h
+				if x >= xs[k] && x < xs[k+1] {
+					g.label[j*g.nx+i] = 0
+				}
+			}
+		}
+	}
+	for i, l := range g.label {
+		if l == 0 {
+			g.label[i] = g.ncell
+			g.ncell++
+		}
+	}
+	// holes: cells outside the region which are not connected to the border of the grid
+	visited := make([]bool, len(g.label))
+	fill := func(start int, val int) int {
+		stack := []int{start}
+		visited[start] = true
+		num := 0
+		for len(stack) > 0 {
+			c := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			g.label[c] = val
+			num++
+			for _, nb := range g.neighbors(c) {
+				if nb >= 0 && !visited[nb] && g.label[nb] < 0 {
+					visited[nb] = true
+					stack = append(stack, nb)
+				}
+			}
+		}
+		return num
+	}
+	fill(0, -1)
+	g.holesize = make([]int, 0)
+	for i, l := range g.label {
+		if l < 0 && !visited[i] {
+			g.holesize = append(g.holesize, fill(i, -2-g.nhole))
+			g.nhole++
+		}
+	}
+	return g
+}
+
+// neighbors returns the right, left, upper and lower cells (-1 if out of the grid).
+func (g *grid) neighbors(c int) [4]int {
+	i := c % g.nx
+	j := c / g.nx
+	rtn := [4]int{-1, -1, -1, -1}
+	if i+1 < g.nx {
+		rtn[0] = c + 1
+	}
+	if i > 0 {
+		rtn[1] = c - 1
+	}
+	if j+1 < g.ny {
+		rtn[2] = c + g.nx
+	}
+	if j > 0 {
+		rtn[3] = c - g.nx
+	}
+	return rtn
+}
+
+// center returns the coordinate of the center of cell c.
+func (g *grid) center(c int) (float64, float64) {
+	return g.x0 + (float64(c%g.nx)+0.5)*g.h, g.y0 + (float64(c/g.nx)+0.5)*g.h
+}
+
+type sparseEntry struct {
+	col int
+	val float64
+}
+
+// conjugateGradient solves a symmetric positive (semi-)definite system with Jacobi preconditioner.
+func conjugateGradient(rows [][]sparseEntry, b []float64, tol float64) ([]float64, error) {
+	n := len(b)
+	mul := func(x, y []float64) {
+		for i, row := range rows {
+			sum := 0.0
+			for _, e := range row {
+				sum += e.val * x[e.col]
+			}
+			y[i] = sum
+		}
+	}
+	diag := make([]float64, n)
+	for i, row := range rows {
+		for _, e := range row {
+			if e.col == i {
+				diag[i] = e.val
+			}
+		}
+		if diag[i] == 0.0 {
+			diag[i] = 1.0
+		}
+	}
+	x := make([]float64, n)
+	r := make([]float64, n)
+	z := make([]float64, n)
+	p := make([]float64, n)
+	ap := make([]float64, n)
+	copy(r, b)
+	bnorm := 0.0
+	for i := range b {
+		bnorm += b[i] * b[i]
+		z[i] = r[i] / diag[i]
+	}
+	if bnorm == 0.0 {
+		return x, nil
+	}
+	copy(p, z)
+	rz := Dot(r, z, n)
+	for iter := 0; iter < 10*n+100; iter++ {
+		mul(p, ap)
+		alpha := rz / Dot(p, ap, n)
+		rr := 0.0
+		for i := 0; i < n; i++ {
+			x[i] += alpha * p[i]
+			r[i] -= alpha * ap[i]
+			rr += r[i] * r[i]
+		}
+		if rr <= tol*tol*bnorm {
+			return x, nil
+		}
+		for i := 0; i < n; i++ {
+			z[i] = r[i] / diag[i]
+		}
+		rznew := Dot(r, z, n)
+		beta := rznew / rz
+		rz = rznew
+		for i := 0; i < n; i++ {
+			p[i] = z[i] + beta*p[i]
+		}
+	}
+	return x, fmt.Errorf("conjugateGradient: not converged")
+}
+
+// torsion returns St. Venant torsional constant and torsional section modulus
+// solving Prandtl's stress function (-∇²φ = 2, φ = 0 on the outer boundary and constant on each hole boundary).
+func (rg region) torsion(ncell int) (float64, float64, error) {
+	g := rg.grid(ncell)
+	n := g.ncell + g.nhole
+	rows := make([][]sparseEntry, n)
+	b := make([]float64, n)
+	h2 := g.h * g.h
+	for c, l := range g.label {
+		if l < 0 {
+			continue
+		}
+		diag := 0.0
+		row := make([]sparseEntry, 0, 5)
+		for _, nb := range g.neighbors(c) {
+			switch nl := g.label[nb]; {
+			case nl >= 0:
+				diag += 1.0
+				row = append(row, sparseEntry{nl, -1.0})
+			case nl == -1:
+				diag += 2.0
+			default:
+				k := g.ncell - 2 - nl
+				diag += 2.0
+				row = append(row, sparseEntry{k, -2.0})
+				rows[k] = append(rows[k], sparseEntry{l, -2.0})
+			}
+		}
+		rows[l] = append(row, sparseEntry{l, diag})
+		b[l] = 2.0 * h2
+	}
+	for k := 0; k < g.nhole; k++ {
+		ind := g.ncell + k
+		diag := 0.0
+		for _, e := range rows[ind] {
+			diag -= e.val
+		}
+		rows[ind] = append(rows[ind], sparseEntry{ind, diag})
+		b[ind] = 2.0 * float64(g.holesize[k]) * h2
+	}
+	phi, err := conjugateGradient(rows, b, 1e-10)
+	if err != nil {
+		return 0.0, 0.0, err
+	}
+	j := 0.0
+	for i := 0; i < g.ncell; i++ {
+		j += 2.0 * phi[i] * h2
+	}
+	for k := 0; k < g.nhole; k++ {
+		j += 2.0 * phi[g.ncell+k] * float64(g.holesize[k]) * h2
+	}
+	value := func(c int) float64 {
+		switch l := g.label[c]; {
+		case l >= 0:
+			return phi[l]
+		case l == -1:
+			return 0.0
+		default:
+			return phi[g.ncell-2-l]
+		}
+	}
+	// the stress at re-entrant corners of polygons is singular,
+	// so the maximum shear stress is taken on straight parts of the boundary.
+	straight := func(c, dir int) bool {
+		step := g.nx
+		if dir >= 2 {
+			step = 1
+		}
+		for _, sgn := range []int{1, -1} {
+			cur := c
+			for s := 0; s < 5; s++ {
+				i := cur % g.nx
+				if step == 1 && (i+sgn < 0 || i+sgn >= g.nx) {
+					return false
+				}
+				cur += sgn * step
+				if cur < 0 || cur >= len(g.label) || g.label[cur] < 0 {
+					return false
+				}
+				if nb := g.neighbors(cur)[dir]; nb < 0 || g.label[nb] >= 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	tmax := 0.0
+	tall := 0.0
+	for c, l := range g.label {
+		if l < 0 {
+			continue
+		}
+		for i, nb := range g.neighbors(c) {
+			if g.label[nb] >= 0 {
+				continue
+			}
+			t := 2.0 * math.Abs(phi[l]-value(nb)) / g.h
+			tall = math.Max(tall, t)
+			if straight(c, i) {
+				tmax = math.Max(tmax, t)
+			}
+		}
+	}
+	if tmax == 0.0 {
+		tmax = tall
+	}
+	if tmax == 0.0 {
+		return j, 0.0, nil
+	}
+	return j, j / tmax, nil
+}
+
+// warping returns warping constant about the shear center and the coordinate of the shear center relative to the centroid (cx, cy)
+// solving the warping function (∇²ω = 0, ∂ω/∂n = y nx - x ny on the boundary).
+func (rg region) warping(ncell int, cx, cy float64) (float64, float64, float64, error) {
+	g := rg.grid(ncell)
+	n := g.ncell
+	rows := make([][]sparseEntry, n)
+	b := make([]float64, n)
+	normal := [4][2]float64{{1.0, 0.0}, {-1.0, 0.0}, {0.0, 1.0}, {0.0, -1.0}}
+	for c, l := range g.label {
+		if l < 0 {
+			continue
+		}
+		x, y := g.center(c)
+		x -= cx
+		y -= cy
+		diag := 0.0
+		row := make([]sparseEntry, 0, 5)
+		for i, nb := range g.neighbors(c) {
+			if nl := g.label[nb]; nl >= 0 {
+				diag += 1.0
+				row = append(row, sparseEntry{nl, -1.0})
+			} else {
+				fx := x + 0.5*g.h*normal[i][0]
+				fy := y + 0.5*g.h*normal[i][1]
+				b[l] += g.h * (fy*normal[i][0] - fx*normal[i][1])
+			}
+		}
+		if diag == 0.0 {
+			return 0.0, 0.0, 0.0, fmt.Errorf("warping: isolated cell")
+		}
+		rows[l] = append(row, sparseEntry{l, diag})
+	}
+	mean := 0.0
+	for i := range b {
+		mean += b[i]
+	}
+	mean /= float64(n)
+	for i := range b {
+		b[i] -= mean
+	}
+	omega, err := conjugateGradient(rows, b, 1e-10)
+	if err != nil {
+		return 0.0, 0.0, 0.0, err
+	}
+	var iwx, iwy, ixx, iyy, ixy float64
+	xs := make([]float64, n)
+	ys := make([]float64, n)
+	for c, l := range g.label {
+		if l < 0 {
+			continue
+		}
+		x, y := g.center(c)
+		xs[l] = x - cx
+		ys[l] = y - cy
+		iwx += omega[l] * xs[l]
+		iwy += omega[l] * ys[l]
+		ixx += ys[l] * ys[l]
+		iyy += xs[l] * xs[l]
+		ixy += xs[l] * ys[l]
+	}
+	det := ixx*iyy - ixy*ixy
+	if det == 0.0 {
+		return 0.0, 0.0, 0.0, fmt.Errorf("warping: singular section")
+	}
+	sx := (iwx*ixy - iyy*iwy) / det
+	sy := (ixx*iwx - ixy*iwy) / det
+	sum := 0.0
+	for i := range omega {
+		omega[i] += -sy*xs[i] + sx*ys[i]
+		sum += omega[i]
+	}
+	sum /= float64(n)
+	iw := 0.0
+	for i := range omega {
+		iw += math.Pow(omega[i]-sum, 2.0) * g.h * g.h
+	}
+	return iw, sx, sy, nil
+}
@@ -0,0 +1,1534 @@
+package st
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// IFC (ISO 10303-21 STEP physical file) import.
+// Structural analysis members (IfcStructuralCurveMember, IfcStructuralSurfaceMember) are used if any;
+// otherwise axes of IfcColumn, IfcBeam, IfcMember, IfcSlab and IfcWall are taken from their representation.
+
+type ifcRef int
+type ifcEnum string
+
+type ifcTyped struct {
+	Type string
+	Args []interface{}
+}
+
+type ifcEntity struct {
+	Num  int
+	Type string
+	Args []interface{}
+}
+
+type ifcModel struct {
+	entities map[int]*ifcEntity
+	bytype   map[string][]*ifcEntity
+	unit     float64
+	material map[int]string
+	profile  map[int]*ifcEntity
+}
+
+// ifcPlacement maps a local point p to origin + axis[0]*p[0] + axis[1]*p[1] + axis[2]*p[2].
+type ifcPlacement struct {
+	origin []float64
+	axis   [][]float64
+}
+
+var (
+	ifcIdentity = &ifcPlacement{
+		origin: []float64{0.0, 0.0, 0.0},
+		axis:   [][]float64{{1.0, 0.0, 0.0}, {0.0, 1.0, 0.0}, {0.0, 0.0, 1.0}},
+	}
+)
+
+func (p *ifcPlacement) apply(v []float64) []float64 {
+	rtn := make([]float64, 3)
+	for i := 0; i < 3; i++ {
+		rtn[i] = p.origin[i]
+		for j := 0; j < 3 && j < len(v); j++ {
+			rtn[i] += p.axis[j][i] * v[j]
+		}
+	}
+	return rtn
+}
+
+func (p *ifcPlacement) direction(v []float64) []float64 {
+	rtn := make([]float64, 3)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3 && j < len(v); j++ {
+			rtn[i] += p.axis[j][i] * v[j]
+		}
+	}
+	return rtn
+}
+
+// compose returns the placement of local placed in p.
+func (p *ifcPlacement) compose(local *ifcPlacement) *ifcPlacement {
+	rtn := &ifcPlacement{
+		origin: p.apply(local.origin),
+		axis:   make([][]float64, 3),
+	}
+	for i := 0; i < 3; i++ {
+		rtn.axis[i] = p.direction(local.axis[i])
+	}
+	return rtn
+}
+
+// ReadIfc reads an IFC file and adds nodes, elements, sections and supports to frame.
+func (frame *Frame) ReadIfc(filename string, eps float64) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	m, err := parseIfc(data)
+	if err != nil {
+		return fmt.Errorf("ReadIfc: %s", err.Error())
+	}
+	err = frame.addIfcModel(m, eps)
+	if err != nil {
+		return fmt.Errorf("ReadIfc: %s", err.Error())
+	}
+	if frame.Name == "" {
+		frame.Name = filepath.Base(filename)
+	}
+	return nil
+}
+
+func parseIfc(data []byte) (*ifcModel, error) {
+	m := &ifcModel{
+		entities: make(map[int]*ifcEntity),
+		bytype:   make(map[string][]*ifcEntity),
+		unit:     1.0,
+		material: make(map[int]string),
+		profile:  make(map[int]*ifcEntity),
+	}
+	start := bytes.Index(data, []byte("DATA;"))
+	if start < 0 {
+		return nil, fmt.Errorf("DATA section not found")
+	}
+	for _, stmt := range ifcStatements(data[start+5:]) {
+		if stmt == "ENDSEC" {
+			break
+		}
+		if !strings.HasPrefix(stmt, "#") {
+			continue
+		}
+		eq := strings.Index(stmt, "=")
+		if eq < 0 {
+			continue
+		}
+		num, err := strconv.ParseInt(strings.TrimSpace(stmt[1:eq]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entity name: %s", stmt[:eq])
+		}
+		body := strings.TrimSpace(stmt[eq+1:])
+		if strings.HasPrefix(body, "(") { // complex entity instance is not supported
+			continue
+		}
+		p := &ifcParser{str: body}
+		v, err := p.value()
+		if err != nil {
+			return nil, fmt.Errorf("#%d: %s", num, err.Error())
+		}
+		t, ok := v.(ifcTyped)
+		if !ok {
+			return nil, fmt.Errorf("#%d: entity type not found", num)
+		}
+		ent := &ifcEntity{
+			Num:  int(num),
+			Type: t.Type,
+			Args: t.Args,
+		}
+		m.entities[ent.Num] = ent
+		m.bytype[ent.Type] = append(m.bytype[ent.Type], ent)
+	}
+	for _, u := range m.bytype["IFCSIUNIT"] {
+		if m.enum(u, 1) != "LENGTHUNIT" || m.enum(u, 3) != "METRE" {
+			continue
+		}
+		switch m.enum(u, 2) {
+		case "MILLI":
+			m.unit = 0.001
+		case "CENTI":
+			m.unit = 0.01
+		case "DECI":
+			m.unit = 0.1
+		case "KILO":
+			m.unit = 1000.0
+		}
+	}
+	return m, nil
+}
+
+// ifcStatements splits the DATA section into statements, skipping comments and semicolons in strings.
+func ifcStatements(data []byte) []string {
+	rtn := make([]string, 0)
+	var b strings.Builder
+	instr := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case instr:
+			b.WriteByte(c)
+			if c == '\'' {
+				if i+1 < len(data) && data[i+1] == '\'' {
+					b.WriteByte('\'')
+					i++
+				} else {
+					instr = false
+				}
+			}
+		case c == '\'':
+			instr = true
+			b.WriteByte(c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(data)
+			} else {
+				i += end + 3
+			}
+		case c == ';':
+			rtn = append(rtn, strings.TrimSpace(b.String()))
+			b.Reset()
+		case c == '\r' || c == '\n':
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return rtn
+}
+
+type ifcParser struct {
+	str string
+	pos int
+}
+
+func (p *ifcParser) skip() {
+	for p.pos < len(p.str) && (p.str[p.pos] == ' ' || p.str[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *ifcParser) value() (interface{}, error) {
+	p.skip()
+	if p.pos >= len(p.str) {
+		return nil, fmt.Errorf("unexpected end")
+	}
+	c := p.str[p.pos]
+	switch {
+	case c == '$' || c == '*':
+		p.pos++
+		return nil, nil
+	case c == '\'':
+		return p.stringvalue()
+	case c == '.':
+		end := strings.IndexByte(p.str[p.pos+1:], '.')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated enumeration")
+		}
+		rtn := ifcEnum(p.str[p.pos+1 : p.pos+1+end])
+		p.pos += end + 2
+		return rtn, nil
+	case c == '#':
+		p.pos++
+		st := p.pos
+		for p.pos < len(p.str) && unicode.IsDigit(rune(p.str[p.pos])) {
+			p.pos++
+		}
+		num, err := strconv.ParseInt(p.str[st:p.pos], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return ifcRef(num), nil
+	case c == '(':
+		return p.list()
+	case unicode.IsLetter(rune(c)):
+		st := p.pos
+		for p.pos < len(p.str) && (unicode.IsLetter(rune(p.str[p.pos])) || unicode.IsDigit(rune(p.str[p.pos])) || p.str[p.pos] == '_') {
+			p.pos++
+		}
+		t := strings.ToUpper(p.str[st:p.pos])
+		p.skip()
+		if p.pos >= len(p.str) || p.str[p.pos] != '(' {
+			return nil, fmt.Errorf("'(' expected after %s", t)
+		}
+		args, err := p.list()
+		if err != nil {
+			return nil, err
+		}
+		return ifcTyped{Type: t, Args: args}, nil
+	default:
+		st := p.pos
+		for p.pos < len(p.str) && p.str[p.pos] != ',' && p.str[p.pos] != ')' {
+			p.pos++
+		}
+		word := strings.TrimSpace(p.str[st:p.pos])
+		val, err := strconv.ParseFloat(word, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value: %s", word)
+		}
+		return val, nil
+	}
+}
+
+func (p *ifcParser) list() ([]interface{}, error) {
+	rtn := make([]interface{}, 0)
+	p.pos++ // '('
+	for {
+		p.skip()
+		if p.pos >= len(p.str) {
+			return nil, fmt.Errorf("unterminated list")
+		}
+		if p.str[p.pos] == ')' {
+			p.pos++
+			return rtn, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		rtn = append(rtn, v)
+		p.skip()
+		if p.pos < len(p.str) && p.str[p.pos] == ',' {
+			p.pos++
+		}
+	}
+}
+
+// stringvalue reads a string decoding \X2\...\X0\ (UTF-16) and \X\hh (ISO 8859-1) escapes.
+func (p *ifcParser) stringvalue() (string, error) {
+	var b strings.Builder
+	p.pos++
+	for p.pos < len(p.str) {
+		c := p.str[p.pos]
+		switch {
+		case c == '\'':
+			if p.pos+1 < len(p.str) && p.str[p.pos+1] == '\'' {
+				b.WriteByte('\'')
+				p.pos += 2
+				continue
+			}
+			p.pos++
+			return b.String(), nil
+		case strings.HasPrefix(p.str[p.pos:], "\\X2\\"):
+			end := strings.Index(p.str[p.pos+4:], "\\X0\\")
+			if end < 0 {
+				return "", fmt.Errorf("unterminated \\X2\\")
+			}
+			h := p.str[p.pos+4 : p.pos+4+end]
+			units := make([]uint16, 0, len(h)/4)
+			for i := 0; i+4 <= len(h); i += 4 {
+				v, err := strconv.ParseUint(h[i:i+4], 16, 16)
+				if err != nil {
+					return "", err
+				}
+				units = append(units, uint16(v))
+			}
+			b.WriteString(string(utf16.Decode(units)))
+			p.pos += end + 8
+		case strings.HasPrefix(p.str[p.pos:], "\\X\\") && p.pos+5 <= len(p.str):
+			v, err := hex.DecodeString(p.str[p.pos+3 : p.pos+5])
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(rune(v[0]))
+			p.pos += 5
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+func (m *ifcModel) ref(v interface{}) *ifcEntity {
+	if r, ok := v.(ifcRef); ok {
+		return m.entities[int(r)]
+	}
+	return nil
+}
+
+func (m *ifcModel) arg(ent *ifcEntity, ind int) interface{} {
+	if ent == nil || ind >= len(ent.Args) {
+		return nil
+	}
+	return ent.Args[ind]
+}
+
+func (m *ifcModel) entity(ent *ifcEntity, ind int) *ifcEntity {
+	return m.ref(m.arg(ent, ind))
+}
+
+func (m *ifcModel) list(ent *ifcEntity, ind int) []interface{} {
+	if l, ok := m.arg(ent, ind).([]interface{}); ok {
+		return l
+	}
+	return nil
+}
+
+func (m *ifcModel) float(ent *ifcEntity, ind int) float64 {
+	switch v := m.arg(ent, ind).(type) {
+	case float64:
+		return v
+	case ifcTyped:
+		if len(v.Args) > 0 {
+			if f, ok := v.Args[0].(float64); ok {
+				return f
+			}
+		}
+	}
+	return 0.0
+}
+
+func (m *ifcModel) str(ent *ifcEntity, ind int) string {
+	if s, ok := m.arg(ent, ind).(string); ok {
+		return s
+	}
+	return ""
+}
+
+func (m *ifcModel) enum(ent *ifcEntity, ind int) string {
+	if e, ok := m.arg(ent, ind).(ifcEnum); ok {
+		return string(e)
+	}
+	return ""
+}
+
+func (m *ifcModel) floats(v interface{}) []float64 {
+	l, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	rtn := make([]float64, len(l))
+	for i, x := range l {
+		if f, ok := x.(float64); ok {
+			rtn[i] = f
+		}
+	}
+	return rtn
+}
+
+// point returns the coordinates of IfcCartesianPoint in metre.
+func (m *ifcModel) point(ent *ifcEntity) []float64 {
+	rtn := []float64{0.0, 0.0, 0.0}
+	if ent == nil {
+		return rtn
+	}
+	for i, v := range m.floats(m.arg(ent, 0)) {
+		if i < 3 {
+			rtn[i] = v * m.unit
+		}
+	}
+	return rtn
+}
+
+func (m *ifcModel) direction(ent *ifcEntity, def []float64) []float64 {
+	if ent == nil {
+		return def
+	}
+	rtn := []float64{0.0, 0.0, 0.0}
+	for i, v := range m.floats(m.arg(ent, 0)) {
+		if i < 3 {
+			rtn[i] = v
+		}
+	}
+	return Normalize(rtn)
+}
+
+// axis2placement reads IfcAxis2Placement2D/3D.
+func (m *ifcModel) axis2placement(ent *ifcEntity) *ifcPlacement {
+	if ent == nil {
+		return ifcIdentity
+	}
+	origin := m.point(m.entity(ent, 0))
+	var z, x []float64
+	switch ent.Type {
+	case "IFCAXIS2PLACEMENT3D":
+		z = m.direction(m.entity(ent, 1), []float64{0.0, 0.0, 1.0})
+		x = m.direction(m.entity(ent, 2), []float64{1.0, 0.0, 0.0})
+	case "IFCAXIS2PLACEMENT2D":
+		z = []float64{0.0, 0.0, 1.0}
+		x = m.direction(m.entity(ent, 1), []float64{1.0, 0.0, 0.0})
+	default:
+		return &ifcPlacement{origin: origin, axis: ifcIdentity.axis}
+	}
+	d := Dot(x, z, 3)
+	for i := 0; i < 3; i++ {
+		x[i] -= d * z[i]
+	}
+	x = Normalize(x)
+	return &ifcPlacement{
+		origin: origin,
+		axis:   [][]float64{x, Cross(z, x), z},
+	}
+}
+
+// placement resolves IfcLocalPlacement into the global coordinate system.
+func (m *ifcModel) placement(ent *ifcEntity, depth int) *ifcPlacement {
+	if ent == nil || depth > 64 {
+		return ifcIdentity
+	}
+	switch ent.Type {
+	case "IFCLOCALPLACEMENT":
+		parent := m.placement(m.entity(ent, 0), depth+1)
+		return parent.compose(m.axis2placement(m.entity(ent, 1)))
+	default:
+		return m.axis2placement(ent)
+	}
+}
+
+// representations returns the representation items of a product, ones with identifier "Axis" first.
+func (m *ifcModel) representations(product *ifcEntity) []*ifcEntity {
+	shape := m.entity(product, 6)
+	if shape == nil {
+		return nil
+	}
+	reps := make([]*ifcEntity, 0)
+	for _, r := range m.list(shape, 2) {
+		if rep := m.ref(r); rep != nil {
+			reps = append(reps, rep)
+		}
+	}
+	sort.SliceStable(reps, func(i, j int) bool {
+		return strings.EqualFold(m.str(reps[i], 1), "Axis") && !strings.EqualFold(m.str(reps[j], 1), "Axis")
+	})
+	rtn := make([]*ifcEntity, 0)
+	for _, rep := range reps {
+		for _, it := range m.list(rep, 3) {
+			if item := m.ref(it); item != nil {
+				rtn = append(rtn, item)
+			}
+		}
+	}
+	return rtn
+}
+
+// curve returns the points of a curve item in the coordinate system of the product.
+func (m *ifcModel) curve(item *ifcEntity) [][]float64 {
+	if item == nil {
+		return nil
+	}
+	switch item.Type {
+	case "IFCPOLYLINE":
+		rtn := make([][]float64, 0)
+		for _, p := range m.list(item, 0) {
+			rtn = append(rtn, m.point(m.ref(p)))
+		}
+		return rtn
+	case "IFCVERTEXPOINT":
+		return [][]float64{m.point(m.entity(item, 0))}
+	case "IFCEDGE":
+		s := m.curve(m.entity(item, 0))
+		e := m.curve(m.entity(item, 1))
+		if len(s) == 0 || len(e) == 0 {
+			return nil
+		}
+		return [][]float64{s[0], e[0]}
+	case "IFCORIENTEDEDGE":
+		rtn := m.curve(m.entity(item, 2))
+		if m.enum(item, 3) == "F" && len(rtn) == 2 {
+			rtn[0], rtn[1] = rtn[1], rtn[0]
+		}
+		return rtn
+	case "IFCEDGELOOP":
+		rtn := make([][]float64, 0)
+		for _, e := range m.list(item, 0) {
+			if c := m.curve(m.ref(e)); len(c) > 0 {
+				rtn = append(rtn, c[0])
+			}
+		}
+		return rtn
+	case "IFCPOLYLOOP":
+		rtn := make([][]float64, 0)
+		for _, p := range m.list(item, 0) {
+			rtn = append(rtn, m.point(m.ref(p)))
+		}
+		return rtn
+	case "IFCFACESURFACE", "IFCFACE":
+		for _, b := range m.list(item, 0) {
+			bound := m.ref(b)
+			if bound == nil {
+				continue
+			}
+			if bound.Type == "IFCFACEOUTERBOUND" || len(m.list(item, 0)) == 1 {
+				return m.curve(m.entity(bound, 0))
+			}
+		}
+	case "IFCMAPPEDITEM":
+		src := m.entity(item, 0)
+		target := m.entity(item, 1)
+		rep := m.entity(src, 1)
+		rtn := make([][]float64, 0)
+		for _, it := range m.list(rep, 3) {
+			if sub := m.ref(it); sub != nil {
+				rtn = append(rtn, m.curve(sub)...)
+			}
+		}
+		if target != nil {
+			o := m.point(m.entity(target, 2))
+			for i := range rtn {
+				for j := 0; j < 3; j++ {
+					rtn[i][j] += o[j]
+				}
+			}
+		}
+		return rtn
+	}
+	return nil
+}
+
+// profilePolygon returns the outline of a profile in its 2D coordinate system.
+func (m *ifcModel) profilePolygon(profile *ifcEntity) [][]float64 {
+	if profile == nil {
+		return nil
+	}
+	switch profile.Type {
+	case "IFCARBITRARYCLOSEDPROFILEDEF", "IFCARBITRARYPROFILEDEFWITHVOIDS":
+		rtn := m.curve(m.entity(profile, 2))
+		if l := len(rtn); l > 1 && distance2(rtn[0], rtn[l-1]) < 1e-9 {
+			rtn = rtn[:l-1]
+		}
+		return rtn
+	case "IFCRECTANGLEPROFILEDEF":
+		pos := m.axis2placement(m.entity(profile, 2))
+		x := 0.5 * m.float(profile, 3) * m.unit
+		y := 0.5 * m.float(profile, 4) * m.unit
+		return [][]float64{
+			pos.apply([]float64{-x, -y, 0.0}),
+			pos.apply([]float64{x, -y, 0.0}),
+			pos.apply([]float64{x, y, 0.0}),
+			pos.apply([]float64{-x, y, 0.0}),
+		}
+	}
+	return nil
+}
+
+// distance2 returns the squared distance between two points.
+func distance2(p1, p2 []float64) float64 {
+	rtn := 0.0
+	for i := 0; i < len(p1) && i < len(p2); i++ {
+		rtn += (p1[i] - p2[i]) * (p1[i] - p2[i])
+	}
+	return rtn
+}
+
+// lineGeometry returns the points of the axis of a product in the global coordinate system.
+func (m *ifcModel) lineGeometry(product *ifcEntity) [][]float64 {
+	pl := m.placement(m.entity(product, 5), 0)
+	items := m.representations(product)
+	for _, item := range items {
+		if c := m.curve(item); len(c) >= 2 {
+			rtn := make([][]float64, len(c))
+			for i, p := range c {
+				rtn[i] = pl.apply(p)
+			}
+			return rtn
+		}
+	}
+	for _, item := range items {
+		if item.Type != "IFCEXTRUDEDAREASOLID" {
+			continue
+		}
+		pos := pl.compose(m.axis2placement(m.entity(item, 1)))
+		dir := m.direction(m.entity(item, 2), []float64{0.0, 0.0, 1.0})
+		depth := m.float(item, 3) * m.unit
+		return [][]float64{
+			pos.apply([]float64{0.0, 0.0, 0.0}),
+			pos.apply([]float64{dir[0] * depth, dir[1] * depth, dir[2] * depth}),
+		}
+	}
+	return nil
+}
+
+// plateGeometry returns the outline of a surface member, a slab or a wall in the global coordinate system.
+// A slab is taken at the bottom of the extrusion, and a wall at its centre line.
+func (m *ifcModel) plateGeometry(product *ifcEntity, etype int) ([][]float64, float64) {
+	pl := m.placement(m.entity(product, 5), 0)
+	items := m.representations(product)
+	var axis [][]float64
+	for _, item := range items {
+		c := m.curve(item)
+		if len(c) >= 3 {
+			rtn := make([][]float64, len(c))
+			for i, p := range c {
+				rtn[i] = pl.apply(p)
+			}
+			return rtn, 0.0
+		} else if len(c) == 2 && axis == nil {
+			axis = c
+		}
+	}
+	for _, item := range items {
+		if item.Type != "IFCEXTRUDEDAREASOLID" {
+			continue
+		}
+		profile := m.entity(item, 0)
+		pos := pl.compose(m.axis2placement(m.entity(item, 1)))
+		dir := m.direction(m.entity(item, 2), []float64{0.0, 0.0, 1.0})
+		depth := m.float(item, 3) * m.unit
+		poly := m.profilePolygon(profile)
+		if len(poly) < 3 {
+			continue
+		}
+		if etype == SLAB {
+			rtn := make([][]float64, len(poly))
+			for i, p := range poly {
+				rtn[i] = pos.apply(p)
+			}
+			return rtn, math.Abs(depth * dir[2])
+		}
+		var start, end []float64
+		thick := 0.0
+		if axis != nil {
+			start = pl.apply(axis[0])
+			end = pl.apply(axis[1])
+		} else {
+			start, end, thick = centreLine(poly)
+			start = pos.apply(start)
+			end = pos.apply(end)
+		}
+		if profile.Type == "IFCRECTANGLEPROFILEDEF" {
+			thick = math.Min(m.float(profile, 3), m.float(profile, 4)) * m.unit
+		}
+		d := pos.direction([]float64{dir[0] * depth, dir[1] * depth, dir[2] * depth})
+		top := func(p []float64) []float64 {
+			return []float64{p[0] + d[0], p[1] + d[1], p[2] + d[2]}
+		}
+		return [][]float64{start, end, top(end), top(start)}, thick
+	}
+	return nil, 0.0
+}
+
+// centreLine returns the centre line of a polygon along its longest edge and the width across it.
+func centreLine(poly [][]float64) ([]float64, []float64, float64) {
+	l := len(poly)
+	imax := 0
+	lmax := 0.0
+	for i := 0; i < l; i++ {
+		if d := distance2(poly[i], poly[(i+1)%l]); d > lmax {
+			lmax = d
+			imax = i
+		}
+	}
+	p0 := poly[imax]
+	u := Normalize([]float64{poly[(imax+1)%l][0] - p0[0], poly[(imax+1)%l][1] - p0[1], 0.0})
+	v := []float64{-u[1], u[0], 0.0}
+	smin, smax := math.Inf(1), math.Inf(-1)
+	tmin, tmax := math.Inf(1), math.Inf(-1)
+	for _, p := range poly {
+		d := []float64{p[0] - p0[0], p[1] - p0[1], 0.0}
+		s, t := Dot(d, u, 3), Dot(d, v, 3)
+		smin, smax = math.Min(smin, s), math.Max(smax, s)
+		tmin, tmax = math.Min(tmin, t), math.Max(tmax, t)
+	}
+	tmid := 0.5 * (tmin + tmax)
+	at := func(s float64) []float64 {
+		return []float64{p0[0] + u[0]*s + v[0]*tmid, p0[1] + u[1]*s + v[1]*tmid, 0.0}
+	}
+	return at(smin), at(smax), tmax - tmin
+}
+
+// associate collects materials and profiles assigned to products by relationships.
+func (m *ifcModel) associate() {
+	for _, rel := range m.bytype["IFCRELASSOCIATESMATERIAL"] {
+		mat := m.entity(rel, 5)
+		name := m.materialName(mat, 0)
+		prof := m.findProfile(mat, 0)
+		for _, o := range m.list(rel, 4) {
+			if r, ok := o.(ifcRef); ok {
+				if name != "" {
+					m.material[int(r)] = name
+				}
+				if prof != nil {
+					m.profile[int(r)] = prof
+				}
+			}
+		}
+	}
+	for _, rel := range m.bytype["IFCRELASSOCIATESPROFILEPROPERTIES"] {
+		prof := m.findProfile(m.entity(rel, 5), 0)
+		if prof == nil {
+			continue
+		}
+		for _, o := range m.list(rel, 4) {
+			if r, ok := o.(ifcRef); ok {
+				m.profile[int(r)] = prof
+			}
+		}
+	}
+}
+
+func (m *ifcModel) materialName(ent *ifcEntity, depth int) string {
+	if ent == nil || depth > 8 {
+		return ""
+	}
+	if ent.Type == "IFCMATERIAL" {
+		return m.str(ent, 0)
+	}
+	for _, a := range ent.Args {
+		switch v := a.(type) {
+		case ifcRef:
+			if name := m.materialName(m.ref(v), depth+1); name != "" {
+				return name
+			}
+		case []interface{}:
+			for _, x := range v {
+				if name := m.materialName(m.ref(x), depth+1); name != "" {
+					return name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func (m *ifcModel) findProfile(ent *ifcEntity, depth int) *ifcEntity {
+	if ent == nil || depth > 8 {
+		return nil
+	}
+	if strings.HasSuffix(ent.Type, "PROFILEDEF") {
+		return ent
+	}
+	if ent.Type == "IFCMATERIAL" || strings.HasPrefix(ent.Type, "IFCCARTESIAN") || strings.HasPrefix(ent.Type, "IFCAXIS2") {
+		return nil
+	}
+	for _, a := range ent.Args {
+		switch v := a.(type) {
+		case ifcRef:
+			if p := m.findProfile(m.ref(v), depth+1); p != nil {
+				return p
+			}
+		case []interface{}:
+			for _, x := range v {
+				if p := m.findProfile(m.ref(x), depth+1); p != nil {
+					return p
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// productProfile returns the profile of a linear product.
+func (m *ifcModel) productProfile(product *ifcEntity) *ifcEntity {
+	if p, ok := m.profile[product.Num]; ok {
+		return p
+	}
+	for _, item := range m.representations(product) {
+		if item.Type == "IFCEXTRUDEDAREASOLID" {
+			return m.ref(m.arg(item, 0))
+		}
+	}
+	return nil
+}
+
+// IfcShape converts an IFC profile definition into Shape.
+// Dimensions are converted into cm.
+func (m *ifcModel) shape(profile *ifcEntity) Shape {
+	if profile == nil {
+		return nil
+	}
+	cm := func(ind int) float64 {
+		return m.float(profile, ind) * m.unit * 100.0
+	}
+	switch profile.Type {
+	case "IFCISHAPEPROFILEDEF":
+		return HKYOU{H: cm(4), B: cm(3), Tw: cm(5), Tf: cm(6)}
+	case "IFCRECTANGLEHOLLOWPROFILEDEF":
+		return RPIPE{H: cm(4), B: cm(3), Tw: cm(5), Tf: cm(5)}
+	case "IFCCIRCLEHOLLOWPROFILEDEF":
+		return CPIPE{D: 2.0 * cm(3), T: cm(4)}
+	case "IFCTSHAPEPROFILEDEF":
+		return TKYOU{H: cm(3), B: cm(4), Tw: cm(5), Tf: cm(6)}
+	case "IFCUSHAPEPROFILEDEF":
+		return CKYOU{H: cm(3), B: cm(4), Tw: cm(5), Tf: cm(6)}
+	case "IFCLSHAPEPROFILEDEF":
+		return ANGLE{H: cm(3), B: cm(4), Tw: cm(5), Tf: cm(5)}
+	case "IFCRECTANGLEPROFILEDEF":
+		return PLATE{H: cm(4), B: cm(3)}
+	case "IFCARBITRARYCLOSEDPROFILEDEF", "IFCARBITRARYPROFILEDEFWITHVOIDS":
+		// profiles written by WriteIfc are named after Shape.String()
+		words := append(strings.Fields(m.str(profile, 1)), make([]string, 9)...) // padding for ParseShape
+		if sh, _, err := ParseShape(words); err == nil {
+			return sh
+		}
+	}
+	return nil
+}
+
+// ifcProp returns PROP for the material name, adding it to frame if necessary.
+// Known material names (SN400, FC24 etc.) are used as they are;
+// otherwise the values of steel or concrete are set according to the name and the shape.
+func (frame *Frame) ifcProp(name string, steel bool) *Prop {
+	for _, p := range frame.Props {
+		if p.Name == name {
+			return p
+		}
+	}
+	num := 101
+	for {
+		if _, exists := frame.Props[num]; !exists {
+			break
+		}
+		num++
+	}
+	p := &Prop{
+		Num:     num,
+		Name:    name,
+		HFactor: 1.0,
+		EFactor: 1.0,
+	}
+	upper := strings.ToUpper(strings.Replace(name, " ", "", -1))
+	if mat, err := materialname(upper); err == nil {
+		p.Material = mat
+	}
+	if strings.Contains(upper, "CONC") || strings.HasPrefix(upper, "FC") || strings.HasPrefix(upper, "RC") {
+		steel = false
+	} else if strings.Contains(upper, "STEEL") || strings.HasPrefix(upper, "S") {
+		steel = true
+	}
+	if steel {
+		p.hiju = 7.8
+		p.eL = 2.1e7
+		p.poi = 1.0 / 3.0
+		p.Color = ColorInt("0 255 255")
+	} else {
+		p.hiju = 2.4
+		p.eL = 2.1e6
+		p.poi = 1.0 / 6.0
+		p.Color = ColorInt("255 255 0")
+	}
+	p.eS = p.eL
+	frame.Props[p.Num] = p
+	return p
+}
+
+func (frame *Frame) addIfcModel(m *ifcModel, eps float64) error {
+	m.associate()
+	structural := len(m.bytype["IFCSTRUCTURALCURVEMEMBER"])+len(m.bytype["IFCSTRUCTURALSURFACEMEMBER"]) > 0
+	sects := make(map[string]*Sect)
+	nextsect := make(map[int]int)
+	for k, v := range sectStart {
+		nextsect[k] = v
+	}
+	sectgroup := func(etype int) int {
+		switch etype {
+		case TRUSS:
+			return BRACE
+		}
+		return etype
+	}
+	getsect := func(etype int, name string, prop *Prop, shape Shape, thick float64) *Sect {
+		g := sectgroup(etype)
+		key := fmt.Sprintf("%d:%s:%d", g, name, prop.Num)
+		if shape != nil {
+			key += ":" + shape.String()
+		}
+		if thick > 0.0 {
+			key += fmt.Sprintf(":%.4f", thick)
+		}
+		if s, ok := sects[key]; ok {
+			return s
+		}
+		num := nextsect[g]
+		for {
+			if _, exists := frame.Sects[num]; !exists {
+				break
+			}
+			num++
+		}
+		nextsect[g] = num + 1
+		s := frame.AddSect(num)
+		s.Frame = frame
+		s.Original = num
+		s.Name = name
+		f := NewFig()
+		f.Prop = prop
+		if shape != nil {
+			f.SetShapeProperty(shape)
+			if s.Name == "" {
+				s.Name = shape.Description()
+			}
+		} else if thick > 0.0 {
+			f.Value["THICK"] = thick
+			if s.Name == "" {
+				s.Name = fmt.Sprintf("t%.0f", thick*1000)
+			}
+		}
+		if s.Name == "" {
+			s.Name = prop.Name
+		}
+		s.Name = strings.Replace(s.Name, " ", "_", -1)
+		if shape != nil || thick > 0.0 {
+			s.Figs = append(s.Figs, f)
+		}
+		sects[key] = s
+		return s
+	}
+	addline := func(product *ifcEntity, etype int) error {
+		pts := m.lineGeometry(product)
+		if len(pts) < 2 {
+			return nil
+		}
+		profile := m.productProfile(product)
+		shape := m.shape(profile)
+		name := m.str(profile, 1)
+		mat := m.material[product.Num]
+		if mat == "" {
+			mat = "STEEL"
+			if _, ok := shape.(PLATE); ok {
+				mat = "CONCRETE"
+			}
+		}
+		_, isplate := shape.(PLATE)
+		prop := frame.ifcProp(mat, !isplate)
+		for i := 0; i < len(pts)-1; i++ {
+			et := etype
+			if et == NULL {
+				et = lineElemType([]float64{pts[i+1][0] - pts[i][0], pts[i+1][1] - pts[i][1], pts[i+1][2] - pts[i][2]})
+				if et == NULL {
+					continue
+				}
+			}
+			n1, _ := frame.CoordNode(pts[i][0], pts[i][1], pts[i][2], eps)
+			n2, _ := frame.CoordNode(pts[i+1][0], pts[i+1][1], pts[i+1][2], eps)
+			if n1 == n2 {
+				continue
+			}
+			frame.AddLineElem(-1, []*Node{n1, n2}, getsect(et, name, prop, shape, 0.0), et)
+		}
+		return nil
+	}
+	addplate := func(product *ifcEntity, etype int) error {
+		pts, thick := m.plateGeometry(product, etype)
+		if len(pts) < 3 {
+			return nil
+		}
+		if th := m.float(product, 8); product.Type == "IFCSTRUCTURALSURFACEMEMBER" && th > 0.0 {
+			thick = th * m.unit
+		}
+		if etype == NULL {
+			etype = plateElemType(pts)
+		}
+		mat := m.material[product.Num]
+		if mat == "" {
+			mat = "CONCRETE"
+		}
+		prop := frame.ifcProp(mat, false)
+		ns := make([]*Node, 0, len(pts))
+		for _, p := range pts {
+			n, _ := frame.CoordNode(p[0], p[1], p[2], eps)
+			if len(ns) > 0 && (ns[len(ns)-1] == n || ns[0] == n) {
+				continue
+			}
+			ns = append(ns, n)
+		}
+		if len(ns) < 3 {
+			return nil
+		}
+		frame.AddPlateElem(-1, ns, getsect(etype, "", prop, nil, thick), etype)
+		return nil
+	}
+	var err error
+	if structural {
+		for _, ent := range m.bytype["IFCSTRUCTURALCURVEMEMBER"] {
+			err = addline(ent, NULL)
+			if err != nil {
+				return err
+			}
+		}
+		for _, ent := range m.bytype["IFCSTRUCTURALSURFACEMEMBER"] {
+			err = addplate(ent, NULL)
+			if err != nil {
+				return err
+			}
+		}
+	} else {
+		for _, t := range []struct {
+			name  string
+			etype int
+		}{
+			{"IFCCOLUMN", COLUMN},
+			{"IFCBEAM", NULL},
+			{"IFCMEMBER", BRACE},
+		} {
+			for _, ent := range m.bytype[t.name] {
+				err = addline(ent, t.etype)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		for _, t := range []struct {
+			name  string
+			etype int
+		}{
+			{"IFCSLAB", SLAB},
+			{"IFCWALL", WALL},
+			{"IFCWALLSTANDARDCASE", WALL},
+		} {
+			for _, ent := range m.bytype[t.name] {
+				err = addplate(ent, t.etype)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, ent := range m.bytype["IFCSTRUCTURALPOINTCONNECTION"] {
+		pl := m.placement(m.entity(ent, 5), 0)
+		p := pl.apply([]float64{0.0, 0.0, 0.0})
+		for _, item := range m.representations(ent) {
+			if c := m.curve(item); len(c) >= 1 {
+				p = pl.apply(c[0])
+				break
+			}
+		}
+		n, _ := frame.CoordNode(p[0], p[1], p[2], eps)
+		cond := m.entity(ent, 7)
+		if cond == nil {
+			continue
+		}
+		for i := 0; i < 6; i++ {
+			n.Conf[i] = m.fixed(m.arg(cond, i+1))
+		}
+	}
+	return nil
+}
+
+// fixed reports whether a stiffness of IfcBoundaryNodeCondition means fixed.
+// IFC4 gives IFCBOOLEAN(.T.); in IFC2x3 a negative or a huge stiffness is taken as rigid.
+func (m *ifcModel) fixed(v interface{}) bool {
+	switch val := v.(type) {
+	case float64:
+		return val < 0.0 || val >= 1e10
+	case ifcTyped:
+		if len(val.Args) == 0 {
+			return false
+		}
+		switch a := val.Args[0].(type) {
+		case ifcEnum:
+			return a == "T"
+		case float64:
+			return a < 0.0 || a >= 1e10
+		}
+	}
+	return false
+}
+
+// IFC export.
+
+const ifcGuidChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz_$"
+
+type ifcWriter struct {
+	buf   bytes.Buffer
+	num   int
+	seed  string
+	owner int
+	ctx   int
+}
+
+// add writes an entity and returns its number.
+func (w *ifcWriter) add(format string, a ...interface{}) int {
+	w.num++
+	w.buf.WriteString(fmt.Sprintf("#%d=", w.num))
+	w.buf.WriteString(fmt.Sprintf(format, a...))
+	w.buf.WriteString(";\n")
+	return w.num
+}
+
+// guid returns a compressed GlobalId which is determined by the seed and the key,
+// so that the same model is always written with the same ids.
+func (w *ifcWriter) guid(key string) string {
+	sum := md5.Sum([]byte(w.seed + "/" + key))
+	var b strings.Builder
+	b.WriteByte(ifcGuidChars[sum[0]>>6])
+	acc := uint(sum[0] & 0x3f)
+	nbits := uint(6)
+	for _, c := range sum[1:] {
+		acc = acc<<8 | uint(c)
+		nbits += 8
+		for nbits >= 6 {
+			nbits -= 6
+			b.WriteByte(ifcGuidChars[(acc>>nbits)&0x3f])
+		}
+		acc &= (1 << nbits) - 1
+	}
+	return fmt.Sprintf("'%s'", b.String())
+}
+
+func ifcFloat(v float64) string {
+	if v == 0.0 {
+		return "0."
+	}
+	s := strconv.FormatFloat(v, 'G', 12, 64)
+	if !strings.ContainsAny(s, ".E") {
+		s += "."
+	} else if i := strings.Index(s, "E"); i >= 0 && !strings.Contains(s[:i], ".") {
+		s = s[:i] + "." + s[i:]
+	}
+	return s
+}
+
+func ifcFloats(v []float64) string {
+	rtn := make([]string, len(v))
+	for i, x := range v {
+		rtn[i] = ifcFloat(x)
+	}
+	return fmt.Sprintf("(%s)", strings.Join(rtn, ","))
+}
+
+// ifcString quotes str encoding non-ASCII characters with \X2\...\X0\.
+func ifcString(str string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range str {
+		switch {
+		case r == '\'':
+			b.WriteString("''")
+		case r == '\\':
+			b.WriteString("\\\\")
+		case r < 0x80:
+			b.WriteRune(r)
+		default:
+			b.WriteString("\\X2\\")
+			for _, u := range utf16.Encode([]rune{r}) {
+				b.WriteString(fmt.Sprintf("%04X", u))
+			}
+			b.WriteString("\\X0\\")
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+func ifcRefs(nums []int) string {
+	rtn := make([]string, len(nums))
+	for i, n := range nums {
+		rtn[i] = fmt.Sprintf("#%d", n)
+	}
+	return fmt.Sprintf("(%s)", strings.Join(rtn, ","))
+}
+
+func ifcBool(b bool) string {
+	if b {
+		return "IFCBOOLEAN(.T.)"
+	}
+	return "IFCBOOLEAN(.F.)"
+}
+
+// topology writes IfcTopologyRepresentation and IfcProductDefinitionShape for an item.
+func (w *ifcWriter) topology(kind string, item int) int {
+	rep := w.add("IFCTOPOLOGYREPRESENTATION(#%d,'Reference','%s',(#%d))", w.ctx, kind, item)
+	return w.add("IFCPRODUCTDEFINITIONSHAPE($,$,(#%d))", rep)
+}
+
+// profile writes the outline of shape given by Vertices as an arbitrary profile in metre.
+// Loops in Vertices are separated by nil; the first one is the outer boundary.
+func (w *ifcWriter) profile(shape Shape) int {
+	loops := make([][]int, 0)
+	current := make([]int, 0)
+	closeloop := func() {
+		if len(current) >= 3 {
+			current = append(current, current[0])
+			loops = append(loops, current)
+		}
+		current = make([]int, 0)
+	}
+	for _, v := range shape.Vertices() {
+		if v == nil {
+			closeloop()
+			continue
+		}
+		current = append(current, w.add("IFCCARTESIANPOINT(%s)", ifcFloats([]float64{v[0] * 0.01, v[1] * 0.01})))
+	}
+	closeloop()
+	if len(loops) == 0 {
+		return 0
+	}
+	curves := make([]int, len(loops))
+	for i, l := range loops {
+		curves[i] = w.add("IFCPOLYLINE(%s)", ifcRefs(l))
+	}
+	if len(curves) == 1 {
+		return w.add("IFCARBITRARYCLOSEDPROFILEDEF(.AREA.,%s,#%d)", ifcString(shape.String()), curves[0])
+	}
+	return w.add("IFCARBITRARYPROFILEDEFWITHVOIDS(.AREA.,%s,#%d,%s)", ifcString(shape.String()), curves[0], ifcRefs(curves[1:]))
+}
+
+// WriteIfc writes frame as an IFC4 structural analysis view.
+// Nodes are written as IfcStructuralPointConnection with their boundary conditions,
+// line elements as IfcStructuralCurveMember with the profile derived from Shape.Vertices
+// and plate elements as IfcStructuralSurfaceMember.
+// Nodal forces of each period (or NODE VCON if no period is loaded) are written as load cases,
+// whose action source is dead load for L, earthquake for X and Y, wind for W, snow for S and not defined otherwise.
+// If period is not empty, member end forces of the period are written as a result group.
+// Forces are written in kN and kN*m.
+func (frame *Frame) WriteIfc(fn string, period string) error {
+	w := &ifcWriter{seed: frame.Name}
+	if w.seed == "" {
+		w.seed = fn
+	}
+	name := frame.Name
+	if name == "" {
+		name = filepath.Base(fn)
+	}
+	// Project
+	person := w.add("IFCPERSON($,$,'',$,$,$,$,$)")
+	org := w.add("IFCORGANIZATION($,'st',$,$,$)")
+	po := w.add("IFCPERSONANDORGANIZATION(#%d,#%d,$)", person, org)
+	app := w.add("IFCAPPLICATION(#%d,'1.0','st','st')", org)
+	w.owner = w.add("IFCOWNERHISTORY(#%d,#%d,$,.NOCHANGE.,$,$,$,0)", po, app)
+	origin := w.add("IFCCARTESIANPOINT((0.,0.,0.))")
+	wcs := w.add("IFCAXIS2PLACEMENT3D(#%d,$,$)", origin)
+	w.ctx = w.add("IFCGEOMETRICREPRESENTATIONCONTEXT($,'Model',3,1.E-05,#%d,$)", wcs)
+	length := w.add("IFCSIUNIT(*,.LENGTHUNIT.,$,.METRE.)")
+	force := w.add("IFCSIUNIT(*,.FORCEUNIT.,.KILO.,.NEWTON.)")
+	moment := w.add("IFCDERIVEDUNIT((#%d,#%d),.MOMENTUNIT.,$)", w.add("IFCDERIVEDUNITELEMENT(#%d,1)", force), w.add("IFCDERIVEDUNITELEMENT(#%d,1)", length))
+	units := w.add("IFCUNITASSIGNMENT((#%d,#%d,#%d))", length, force, moment)
+	project := w.add("IFCPROJECT(%s,#%d,%s,$,$,$,$,(#%d),#%d)", w.guid("project"), w.owner, ifcString(name), w.ctx, units)
+	placement := w.add("IFCLOCALPLACEMENT($,#%d)", wcs)
+	// Nodes
+	nodes := make([]*Node, 0, len(frame.Nodes))
+	for _, n := range frame.Nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Sort(NodeByNum{nodes})
+	vertex := make(map[int]int)
+	connection := make(map[int]int)
+	products := make([]int, 0)
+	for _, n := range nodes {
+		pt := w.add("IFCCARTESIANPOINT(%s)", ifcFloats(n.Coord))
+		vertex[n.Num] = w.add("IFCVERTEXPOINT(#%d)", pt)
+		cond := "$"
+		if n.ConfState() != 0 {
+			b := make([]string, 6)
+			for i := 0; i < 6; i++ {
+				b[i] = ifcBool(n.Conf[i])
+			}
+			cond = fmt.Sprintf("#%d", w.add("IFCBOUNDARYNODECONDITION(%s,%s)", ifcString(fmt.Sprintf("CONF%d", n.Num)), strings.Join(b, ",")))
+		}
+		connection[n.Num] = w.add("IFCSTRUCTURALPOINTCONNECTION(%s,#%d,'%d',$,$,#%d,#%d,%s,$)", w.guid(fmt.Sprintf("node%d", n.Num)), w.owner, n.Num, placement, w.topology("Vertex", vertex[n.Num]), cond)
+		products = append(products, connection[n.Num])
+	}
+	// Materials and profiles
+	materials := make(map[int]int)
+	material := func(p *Prop) int {
+		if m, ok := materials[p.Num]; ok {
+			return m
+		}
+		materials[p.Num] = w.add("IFCMATERIAL(%s,$,$)", ifcString(p.Name))
+		return materials[p.Num]
+	}
+	sectmembers := make(map[int][]int)
+	// Elements
+	elems := make([]*Elem, 0, len(frame.Elems))
+	for _, el := range frame.Elems {
+		if el.Etype == WBRACE || el.Etype == SBRACE {
+			continue
+		}
+		elems = append(elems, el)
+	}
+	sort.Sort(ElemByNum{elems})
+	members := make(map[int]int)
+	pin := 0
+	for _, el := range elems {
+		if el.IsLineElem() {
+			edge := w.add("IFCEDGE(#%d,#%d)", vertex[el.Enod[0].Num], vertex[el.Enod[1].Num])
+			axis := w.add("IFCDIRECTION(%s)", ifcFloats(el.Weak))
+			tp := "RIGID_JOINED_MEMBER"
+			if el.Etype == TRUSS {
+				tp = "PIN_JOINED_MEMBER"
+			}
+			members[el.Num] = w.add("IFCSTRUCTURALCURVEMEMBER(%s,#%d,'%d',$,%s,#%d,#%d,.%s.,#%d)", w.guid(fmt.Sprintf("elem%d", el.Num)), w.owner, el.Num, ifcString(ETYPES[el.Etype]), placement, w.topology("Edge", edge), tp, axis)
+			for i := 0; i < 2; i++ {
+				cond := "$"
+				if el.IsPin(i) {
+					if pin == 0 {
+						pin = w.add("IFCBOUNDARYNODECONDITION('PIN',%s,%s,%s,%s,%s,%s)", ifcBool(true), ifcBool(true), ifcBool(true), ifcBool(true), ifcBool(false), ifcBool(false))
+					}
+					cond = fmt.Sprintf("#%d", pin)
+				}
+				w.add("IFCRELCONNECTSSTRUCTURALMEMBER(%s,#%d,$,$,#%d,#%d,%s,$,$,$)", w.guid(fmt.Sprintf("elem%d/%d", el.Num, i)), w.owner, members[el.Num], connection[el.Enod[i].Num], cond)
+			}
+		} else {
+			bound := make([]int, el.Enods)
+			for i, en := range el.Enod {
+				bound[i] = w.add("IFCCARTESIANPOINT(%s)", ifcFloats(en.Coord))
+			}
+			loop := w.add("IFCPOLYLOOP(%s)", ifcRefs(bound))
+			outer := w.add("IFCFACEOUTERBOUND(#%d,.T.)", loop)
+			face := w.add("IFCFACE((#%d))", outer)
+			thick := "$"
+			if t, err := el.Sect.Thick(0); err == nil {
+				thick = ifcFloat(t)
+			}
+			members[el.Num] = w.add("IFCSTRUCTURALSURFACEMEMBER(%s,#%d,'%d',$,%s,#%d,#%d,.SHELL.,%s)", w.guid(fmt.Sprintf("elem%d", el.Num)), w.owner, el.Num, ifcString(ETYPES[el.Etype]), placement, w.topology("Face", face), thick)
+			for i, en := range el.Enod {
+				w.add("IFCRELCONNECTSSTRUCTURALMEMBER(%s,#%d,$,$,#%d,#%d,$,$,$,$)", w.guid(fmt.Sprintf("elem%d/%d", el.Num, i)), w.owner, members[el.Num], connection[en.Num])
+			}
+		}
+		products = append(products, members[el.Num])
+		sectmembers[el.Sect.Num] = append(sectmembers[el.Sect.Num], members[el.Num])
+	}
+	snums := make([]int, 0, len(sectmembers))
+	for snum := range sectmembers {
+		snums = append(snums, snum)
+	}
+	sort.Ints(snums)
+	for _, snum := range snums {
+		sec := frame.Sects[snum]
+		if len(sec.Figs) == 0 || sec.Figs[0].Prop == nil {
+			continue
+		}
+		fig := sec.Figs[0]
+		mat := material(fig.Prop)
+		relating := mat
+		if fig.Shape != nil {
+			if prof := w.profile(fig.Shape); prof != 0 {
+				mp := w.add("IFCMATERIALPROFILE(%s,$,#%d,#%d,$,$)", ifcString(sec.Name), mat, prof)
+				mps := w.add("IFCMATERIALPROFILESET(%s,$,(#%d),$)", ifcString(fmt.Sprintf("%d", sec.Num)), mp)
+				relating = w.add("IFCMATERIALPROFILESETUSAGE(#%d,5,$)", mps)
+			}
+		}
+		w.add("IFCRELASSOCIATESMATERIAL(%s,#%d,$,$,%s,#%d)", w.guid(fmt.Sprintf("sect%d", sec.Num)), w.owner, ifcRefs(sectmembers[snum]), relating)
+	}
+	// Load cases
+	periods := make([]string, 0)
+	for _, n := range nodes {
+		for p := range n.Force {
+			found := false
+			for _, q := range periods {
+				if q == p {
+					found = true
+					break
+				}
+			}
+			if !found {
+				periods = append(periods, p)
+			}
+		}
+	}
+	sort.Strings(periods)
+	if len(periods) == 0 {
+		periods = []string{"L"}
+	}
+	loadgroups := make([]int, 0)
+	loadcase := make(map[string]int)
+	for _, p := range periods {
+		action := "VARIABLE_Q"
+		var source string
+		switch p {
+		case "L":
+			action = "PERMANENT_G"
+			source = "DEAD_LOAD_G"
+		case "X", "Y":
+			source = "EARTHQUAKE_E"
+		case "W":
+			source = "WIND_W"
+		case "S":
+			source = "SNOW_S"
+		default:
+			action = "NOTDEFINED"
+			source = "NOTDEFINED"
+		}
+		loadcase[p] = w.add("IFCSTRUCTURALLOADCASE(%s,#%d,%s,$,$,.LOAD_CASE.,.%s.,.%s.,1.,$,$)", w.guid("case"+p), w.owner, ifcString(p), action, source)
+		loadgroups = append(loadgroups, loadcase[p])
+		actions := make([]int, 0)
+		for _, n := range nodes {
+			f, ok := n.Force[p]
+			if !ok {
+				if p != "L" || len(n.Force) != 0 {
+					continue
+				}
+				f = n.Load
+			}
+			zero := true
+			for i := 0; i < 6; i++ {
+				if f[i] != 0.0 {
+					zero = false
+					break
+				}
+			}
+			if zero {
+				continue
+			}
+			val := make([]float64, 6)
+			for i := 0; i < 6; i++ {
+				val[i] = f[i] * SI
+			}
+			load := w.add("IFCSTRUCTURALLOADSINGLEFORCE($,%s,%s,%s,%s,%s,%s)", ifcFloat(val[0]), ifcFloat(val[1]), ifcFloat(val[2]), ifcFloat(val[3]), ifcFloat(val[4]), ifcFloat(val[5]))
+			act := w.add("IFCSTRUCTURALPOINTACTION(%s,#%d,'%d',$,$,#%d,#%d,#%d,.GLOBAL_COORDS.,$)", w.guid(fmt.Sprintf("load%s%d", p, n.Num)), w.owner, n.Num, placement, w.topology("Vertex", vertex[n.Num]), load)
+			w.add("IFCRELCONNECTSSTRUCTURALACTIVITY(%s,#%d,$,$,#%d,#%d)", w.guid(fmt.Sprintf("loadrel%s%d", p, n.Num)), w.owner, connection[n.Num], act)
+			actions = append(actions, act)
+		}
+		if len(actions) > 0 {
+			w.add("IFCRELASSIGNSTOGROUP(%s,#%d,$,$,%s,$,#%d)", w.guid("loads"+p), w.owner, ifcRefs(actions), loadcase[p])
+		}
+	}
+	// Results
+	results := "$"
+	if period != "" {
+		lc, ok := loadcase[period]
+		lcref := "$"
+		if ok {
+			lcref = fmt.Sprintf("#%d", lc)
+		}
+		rg := w.add("IFCSTRUCTURALRESULTGROUP(%s,#%d,%s,$,$,.FIRST_ORDER_THEORY.,%s,.T.)", w.guid("result"+period), w.owner, ifcString(period), lcref)
+		reactions := make([]int, 0)
+		for _, el := range elems {
+			if !el.IsLineElem() {
+				continue
+			}
+			st, ok := el.Stress[period]
+			if !ok {
+				continue
+			}
+			for i, en := range el.Enod {
+				s, ok := st[en.Num]
+				if !ok {
+					continue
+				}
+				load := w.add("IFCSTRUCTURALLOADSINGLEFORCE($,%s,%s,%s,%s,%s,%s)", ifcFloat(s[0]*SI), ifcFloat(s[1]*SI), ifcFloat(s[2]*SI), ifcFloat(s[3]*SI), ifcFloat(s[4]*SI), ifcFloat(s[5]*SI))
+				r := w.add("IFCSTRUCTURALPOINTREACTION(%s,#%d,'%d/%d',$,$,#%d,#%d,#%d,.LOCAL_COORDS.)", w.guid(fmt.Sprintf("stress%s%d/%d", period, el.Num, i)), w.owner, el.Num, en.Num, placement, w.topology("Vertex", vertex[en.Num]), load)
+				w.add("IFCRELCONNECTSSTRUCTURALACTIVITY(%s,#%d,$,$,#%d,#%d)", w.guid(fmt.Sprintf("stressrel%s%d/%d", period, el.Num, i)), w.owner, members[el.Num], r)
+				reactions = append(reactions, r)
+			}
+		}
+		if len(reactions) > 0 {
+			w.add("IFCRELASSIGNSTOGROUP(%s,#%d,$,$,%s,$,#%d)", w.guid("stresses"+period), w.owner, ifcRefs(reactions), rg)
+		}
+		results = fmt.Sprintf("(#%d)", rg)
+	}
+	model := w.add("IFCSTRUCTURALANALYSISMODEL(%s,#%d,%s,$,$,.LOADING_3D.,$,%s,%s,#%d)", w.guid("model"), w.owner, ifcString(name), ifcRefs(loadgroups), results, placement)
+	w.add("IFCRELASSIGNSTOGROUP(%s,#%d,$,$,%s,$,#%d)", w.guid("members"), w.owner, ifcRefs(products), model)
+	w.add("IFCRELASSIGNSTOGROUP(%s,#%d,$,$,%s,$,#%d)", w.guid("loadgroups"), w.owner, ifcRefs(loadgroups), model)
+	w.add("IFCRELDECLARES(%s,#%d,$,$,#%d,(#%d))", w.guid("declares"), w.owner, project, model)
+	// Write
+	var otp bytes.Buffer
+	otp.WriteString("ISO-10303-21;\n")
+	otp.WriteString("HEADER;\n")
+	otp.WriteString("FILE_DESCRIPTION(('ViewDefinition [StructuralAnalysisView]'),'2;1');\n")
+	otp.WriteString(fmt.Sprintf("FILE_NAME(%s,'',(''),(''),'st','st','');\n", ifcString(filepath.Base(fn))))
+	otp.WriteString("FILE_SCHEMA(('IFC4'));\n")
+	otp.WriteString("ENDSEC;\n")
+	otp.WriteString("DATA;\n")
+	w.buf.WriteTo(&otp)
+	otp.WriteString("ENDSEC;\n")
+	otp.WriteString("END-ISO-10303-21;\n")
+	return os.WriteFile(fn, otp.Bytes(), 0644)
+}
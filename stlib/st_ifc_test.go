@@ -0,0 +1,84 @@
+package st
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testIfcBrokenEdge has two structural curve members; the edge of the second refers to #99, which does not exist.
+const testIfcBrokenEdge = `ISO-10303-21;
+HEADER;
+FILE_DESCRIPTION((''),'2;1');
+ENDSEC;
+DATA;
+#1=IFCCARTESIANPOINT((0.,0.,0.));
+#2=IFCCARTESIANPOINT((0.,0.,3.));
+#3=IFCVERTEXPOINT(#1);
+#4=IFCVERTEXPOINT(#2);
+#5=IFCEDGE(#3,#4);
+#6=IFCTOPOLOGYREPRESENTATION($,'Reference','Edge',(#5));
+#7=IFCPRODUCTDEFINITIONSHAPE($,$,(#6));
+#8=IFCSTRUCTURALCURVEMEMBER('0',$,'C1',$,$,$,#7,.RIGID_JOINED_MEMBER.,$);
+#13=IFCEDGE(#4,#99);
+#14=IFCTOPOLOGYREPRESENTATION($,'Reference','Edge',(#13));
+#15=IFCPRODUCTDEFINITIONSHAPE($,$,(#14));
+#16=IFCSTRUCTURALCURVEMEMBER('1',$,'G1',$,$,$,#15,.RIGID_JOINED_MEMBER.,$);
+ENDSEC;
+END-ISO-10303-21;
+`
+
+func TestReadIfcBrokenReference(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "broken.ifc")
+	if err := os.WriteFile(fn, []byte(testIfcBrokenEdge), 0644); err != nil {
+		t.Fatal(err)
+	}
+	frame := NewFrame()
+	if err := frame.ReadIfc(fn, 1e-3); err != nil {
+		return
+	}
+	if len(frame.Elems) != 1 {
+		t.Errorf("%d elems are read, want 1 (the member with the broken edge is skipped)", len(frame.Elems))
+	}
+}
+
+func TestWriteIfcLoadCase(t *testing.T) {
+	dir := t.TempDir()
+	frame := NewFrame()
+	if err := frame.ReadInpString(testSlabFrame, filepath.Join(dir, "test.inp"), []float64{0.0, 0.0, 0.0}, 0.0, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"L", "X", "Y", "W", "S", "T"} {
+		frame.Nodes[5].Force[p] = []float64{1.0, 0.0, 0.0, 0.0, 0.0, 0.0}
+	}
+	fn := filepath.Join(dir, "test.ifc")
+	if err := frame.WriteIfc(fn, ""); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range map[string]string{
+		"L": ".PERMANENT_G.,.DEAD_LOAD_G.",
+		"X": ".VARIABLE_Q.,.EARTHQUAKE_E.",
+		"Y": ".VARIABLE_Q.,.EARTHQUAKE_E.",
+		"W": ".VARIABLE_Q.,.WIND_W.",
+		"S": ".VARIABLE_Q.,.SNOW_S.",
+		"T": ".NOTDEFINED.,.NOTDEFINED.",
+	} {
+		found := false
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.Contains(line, "IFCSTRUCTURALLOADCASE(") && strings.Contains(line, "'"+p+"'") {
+				found = true
+				if !strings.Contains(line, want) {
+					t.Errorf("load case %s: %s, want %s", p, line, want)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("load case %s is not written", p)
+		}
+	}
+}
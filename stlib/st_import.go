@@ -0,0 +1,146 @@
+package st
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// Import of models from other analysis programs (SAP2000, OpenSees).
+// Items which cannot be translated don't stop reading; they are collected as messages
+// and returned to the caller together with the number of the source line.
+
+var sectStart = map[int]int{COLUMN: 101, GIRDER: 301, BRACE: 601, SLAB: 701, WALL: 801}
+
+type importer struct {
+	frame    *Frame
+	eps      float64
+	sects    map[string]*Sect
+	nextsect map[int]int
+	messages []string
+}
+
+func newImporter(frame *Frame, eps float64) *importer {
+	im := &importer{
+		frame:    frame,
+		eps:      eps,
+		sects:    make(map[string]*Sect),
+		nextsect: make(map[int]int),
+		messages: make([]string, 0),
+	}
+	for k, v := range sectStart {
+		im.nextsect[k] = v
+	}
+	return im
+}
+
+// skip records an item which could not be translated.
+func (im *importer) skip(line int, format string, a ...interface{}) {
+	if line > 0 {
+		im.messages = append(im.messages, fmt.Sprintf("line %d: %s", line, fmt.Sprintf(format, a...)))
+	} else {
+		im.messages = append(im.messages, fmt.Sprintf(format, a...))
+	}
+}
+
+func (im *importer) node(coord []float64) *Node {
+	n, _ := im.frame.CoordNode(coord[0], coord[1], coord[2], im.eps)
+	return n
+}
+
+// prop returns PROP named name. Values which are not positive are left as default.
+func (im *importer) prop(name string, steel bool, hiju, e, poi float64) *Prop {
+	p := im.frame.ifcProp(name, steel)
+	if hiju > 0.0 {
+		p.hiju = hiju
+	}
+	if e > 0.0 {
+		p.eL = e
+		p.eS = e
+	}
+	if poi > 0.0 {
+		p.poi = poi
+	}
+	return p
+}
+
+// sect returns SECT for the group of etype and key. set is called when SECT is created.
+func (im *importer) sect(etype int, key string, name string, set func(*Sect)) *Sect {
+	g := etype
+	if g == TRUSS {
+		g = BRACE
+	}
+	k := fmt.Sprintf("%d:%s", g, key)
+	if s, ok := im.sects[k]; ok {
+		return s
+	}
+	num := im.nextsect[g]
+	for {
+		if _, exists := im.frame.Sects[num]; !exists {
+			break
+		}
+		num++
+	}
+	im.nextsect[g] = num + 1
+	s := im.frame.AddSect(num)
+	s.Frame = im.frame
+	s.Original = num
+	s.Name = strings.Replace(name, " ", "_", -1)
+	set(s)
+	im.sects[k] = s
+	return s
+}
+
+// orient sets CANG of el so that its strong axis lies along axis.
+// The sign of axis is ignored, then CANG is in (-90, 90] degrees.
+func (im *importer) orient(el *Elem, axis []float64) error {
+	cang, err := el.AxisToCang(axis, true)
+	if err != nil {
+		return err
+	}
+	if cang > 0.5*math.Pi+1e-6 {
+		cang -= math.Pi
+	} else if cang <= -0.5*math.Pi+1e-6 {
+		cang += math.Pi
+	}
+	el.Cang = cang
+	return el.SetPrincipalAxis()
+}
+
+// lineElemType judges COLUMN, GIRDER or BRACE from the direction of an element.
+func lineElemType(d []float64) int {
+	l := math.Sqrt(Dot(d, d, 3))
+	switch {
+	case l == 0.0:
+		return NULL
+	case math.Abs(d[2])/l > 0.999:
+		return COLUMN
+	case math.Abs(d[2])/l < 0.01:
+		return GIRDER
+	default:
+		return BRACE
+	}
+}
+
+// plateElemType judges SLAB or WALL from the normal of a plate.
+func plateElemType(pts [][]float64) int {
+	normal := Cross([]float64{pts[1][0] - pts[0][0], pts[1][1] - pts[0][1], pts[1][2] - pts[0][2]},
+		[]float64{pts[2][0] - pts[0][0], pts[2][1] - pts[0][1], pts[2][2] - pts[0][2]})
+	if math.Abs(Normalize(normal)[2]) > 0.5 {
+		return SLAB
+	}
+	return WALL
+}
+
+// ImportModel reads a model of another analysis program according to the extension of filename.
+// It returns the list of items which could not be translated.
+func (frame *Frame) ImportModel(filename string, eps float64, pattern string) ([]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".s2k", ".$2k":
+		return frame.ReadS2k(filename, eps, pattern)
+	case ".tcl":
+		return frame.ReadOpenSees(filename, eps, pattern)
+	}
+	return nil, fmt.Errorf("ImportModel: unknown format: %s", filename)
+}
@@ -0,0 +1,733 @@
+package st
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// INCLUDE and PARAM of .inp files.
+//
+//	PARAM SPAN 6.0
+//	PARAM H1 3.5
+//	PARAM H2 H1+3.0
+//	INCLUDE block_a.inp
+//	INCLUDE bay.inp X0=0.0
+//	INCLUDE bay.inp X0=SPAN
+//	NODE 101 CORD ${X0+SPAN} 0.0 ${H2} ...
+//
+// ${EXPR} in a word is replaced with the value of EXPR, an arithmetic expression (+, -, *, /, parentheses)
+// of numbers and parameters (NAME or $NAME). Values of PARAM and of INCLUDE arguments are always expressions.
+// Parameters are visible in the file defining them and in the files it includes.
+// NAME=VALUE of INCLUDE overrides PARAM NAME in the included file, so that a file can be used as a template.
+// INCLUDE paths are relative to the including file.
+// Changed objects are written back to the file they were read from. Objects read from a template
+// (a file included with arguments or more than once) cannot be written back and must be unchanged.
+
+// InpParam is a parameter defined by PARAM or by an argument of INCLUDE.
+type InpParam struct {
+	Name  string
+	Expr  string
+	Value float64
+}
+
+// InpFile is one of the files read by an .inp file and its includes.
+type InpFile struct {
+	Name     string
+	Path     string
+	Parent   *InpFile
+	Args     []*InpParam
+	Params   []*InpParam
+	Includes []*InpFile
+	source   *InpSource
+	values   map[string]float64
+}
+
+// InpSource keeps which file each object of the frame was read from, and the parameters used in them,
+// so that WriteInp can write changes back to the right file.
+type InpSource struct {
+	Main    *InpFile
+	files   map[inpKey]*InpFile
+	count   map[string]int
+	cords   map[int][]string
+	height  []string
+	strings map[inpKey]string
+	used    bool
+}
+
+type inpKey struct {
+	kind string
+	num  int
+}
+
+func (k inpKey) String() string {
+	return fmt.Sprintf("%s %d", k.kind, k.num)
+}
+
+// NewInpSource creates an InpSource whose main file is filename.
+func NewInpSource(filename string) *InpSource {
+	src := &InpSource{
+		files:   make(map[inpKey]*InpFile),
+		count:   make(map[string]int),
+		cords:   make(map[int][]string),
+		strings: make(map[inpKey]string),
+	}
+	src.Main = src.newFile(filepath.Base(filename), filename, nil)
+	return src
+}
+
+func (src *InpSource) newFile(name, path string, parent *InpFile) *InpFile {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	src.count[path]++
+	return &InpFile{
+		Name:     name,
+		Path:     path,
+		Parent:   parent,
+		Args:     make([]*InpParam, 0),
+		Params:   make([]*InpParam, 0),
+		Includes: make([]*InpFile, 0),
+		source:   src,
+		values:   make(map[string]float64),
+	}
+}
+
+// IsMain reports whether fn is the main file of src.
+func (src *InpSource) IsMain(fn string) bool {
+	if abs, err := filepath.Abs(fn); err == nil {
+		fn = abs
+	}
+	return fn == src.Main.Path
+}
+
+// IsTemplate reports whether file is included with arguments or more than once (or is included by such a file).
+// Objects read from a template cannot be written back to it.
+func (file *InpFile) IsTemplate() bool {
+	for f := file; f != nil && f.Parent != nil; f = f.Parent {
+		if len(f.Args) > 0 || f.source.count[f.Path] > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// Snapshot returns a deep copy of src, whose files are copied keeping their tree.
+func (src *InpSource) Snapshot() *InpSource {
+	if src == nil {
+		return nil
+	}
+	rtn := &InpSource{
+		files:   make(map[inpKey]*InpFile),
+		count:   make(map[string]int, len(src.count)),
+		cords:   make(map[int][]string),
+		strings: make(map[inpKey]string),
+		used:    src.used,
+	}
+	files := make(map[*InpFile]*InpFile)
+	var cp func(*InpFile, *InpFile) *InpFile
+	cp = func(file, parent *InpFile) *InpFile {
+		f := &InpFile{
+			Name:     file.Name,
+			Path:     file.Path,
+			Parent:   parent,
+			Args:     make([]*InpParam, len(file.Args)),
+			Params:   make([]*InpParam, len(file.Params)),
+			Includes: make([]*InpFile, len(file.Includes)),
+			source:   rtn,
+			values:   make(map[string]float64, len(file.values)),
+		}
+		for i, a := range file.Args {
+			f.Args[i] = &InpParam{Name: a.Name, Expr: a.Expr, Value: a.Value}
+		}
+		for i, p := range file.Params {
+			f.Params[i] = &InpParam{Name: p.Name, Expr: p.Expr, Value: p.Value}
+		}
+		for k, v := range file.values {
+			f.values[k] = v
+		}
+		files[file] = f
+		for i, inc := range file.Includes {
+			f.Includes[i] = cp(inc, f)
+		}
+		return f
+	}
+	rtn.Main = cp(src.Main, nil)
+	for k, f := range src.files {
+		rtn.files[k] = files[f]
+	}
+	for k, v := range src.count {
+		rtn.count[k] = v
+	}
+	for k, v := range src.cords {
+		rtn.cords[k] = append([]string(nil), v...)
+	}
+	rtn.height = append([]string(nil), src.height...)
+	for k, v := range src.strings {
+		rtn.strings[k] = v
+	}
+	return rtn
+}
+
+func (file *InpFile) lookup(name string) (float64, bool) {
+	for f := file; f != nil; f = f.Parent {
+		if val, ok := f.values[name]; ok {
+			return val, true
+		}
+	}
+	return 0.0, false
+}
+
+func validParamName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Param parses a PARAM line: PARAM NAME EXPR.
+func (file *InpFile) Param(words []string) error {
+	if len(words) < 3 {
+		return fmt.Errorf("ReadInp: PARAM: not enough arguments")
+	}
+	name := words[1]
+	if !validParamName(name) {
+		return fmt.Errorf("ReadInp: PARAM: invalid name %s", name)
+	}
+	for _, p := range file.Params {
+		if p.Name == name {
+			return fmt.Errorf("ReadInp: PARAM: %s is already defined in %s", name, file.Name)
+		}
+	}
+	expr := strings.Join(words[2:], "")
+	val, err := file.Eval(expr)
+	if err != nil {
+		return fmt.Errorf("ReadInp: PARAM %s: %s", name, err.Error())
+	}
+	file.Params = append(file.Params, &InpParam{Name: name, Expr: expr, Value: val})
+	file.source.used = true
+	for _, a := range file.Args {
+		if a.Name == name {
+			return nil
+		}
+	}
+	file.values[name] = val
+	return nil
+}
+
+// Include creates the InpFile of an INCLUDE line: INCLUDE PATH [NAME=EXPR ...].
+func (file *InpFile) Include(words []string) (*InpFile, error) {
+	if len(words) < 2 {
+		return nil, fmt.Errorf("ReadInp: INCLUDE: no filename")
+	}
+	path := words[1]
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(file.Path), path)
+	}
+	child := file.source.newFile(words[1], path, file)
+	for f := file; f != nil; f = f.Parent {
+		if f.Path == child.Path {
+			file.source.count[child.Path]--
+			return nil, fmt.Errorf("ReadInp: INCLUDE: %s includes itself", words[1])
+		}
+	}
+	for _, a := range words[2:] {
+		nv := strings.SplitN(a, "=", 2)
+		if len(nv) < 2 || !validParamName(nv[0]) {
+			return nil, fmt.Errorf("ReadInp: INCLUDE %s: invalid argument %s", words[1], a)
+		}
+		val, err := file.Eval(nv[1])
+		if err != nil {
+			return nil, fmt.Errorf("ReadInp: INCLUDE %s: %s: %s", words[1], nv[0], err.Error())
+		}
+		child.Args = append(child.Args, &InpParam{Name: nv[0], Expr: nv[1], Value: val})
+		child.values[nv[0]] = val
+	}
+	file.Includes = append(file.Includes, child)
+	file.source.used = true
+	return child, nil
+}
+
+// Expand replaces ${EXPR} in words with its value and returns a new list.
+func (file *InpFile) Expand(words []string) ([]string, error) {
+	rtn := make([]string, len(words))
+	for i, w := range words {
+		var str strings.Builder
+		for {
+			start := strings.Index(w, "${")
+			if start < 0 {
+				str.WriteString(w)
+				break
+			}
+			end := strings.Index(w[start:], "}")
+			if end < 0 {
+				return nil, fmt.Errorf("ReadInp: %s: %s: } is expected", file.Name, words[i])
+			}
+			val, err := file.Eval(w[start+2 : start+end])
+			if err != nil {
+				return nil, fmt.Errorf("ReadInp: %s: %s", file.Name, err.Error())
+			}
+			str.WriteString(w[:start])
+			str.WriteString(strconv.FormatFloat(val, 'f', -1, 64))
+			w = w[start+end+1:]
+		}
+		rtn[i] = str.String()
+	}
+	return rtn, nil
+}
+
+// hasExpr reports whether any of words has ${EXPR}.
+func hasExpr(words []string) bool {
+	for _, w := range words {
+		if strings.Contains(w, "${") {
+			return true
+		}
+	}
+	return false
+}
+
+// Eval evaluates an arithmetic expression of numbers and parameters (NAME, $NAME or ${NAME}).
+func (file *InpFile) Eval(expr string) (float64, error) {
+	e := &inpExpr{str: expr, file: file}
+	val, err := e.expr()
+	if err != nil {
+		return 0.0, err
+	}
+	if e.pos < len(e.str) {
+		return 0.0, fmt.Errorf("%s: unexpected character %c", expr, e.str[e.pos])
+	}
+	if math.IsNaN(val) || math.IsInf(val, 0) {
+		return 0.0, fmt.Errorf("%s: invalid value", expr)
+	}
+	return val, nil
+}
+
+type inpExpr struct {
+	str  string
+	pos  int
+	file *InpFile
+}
+
+func (e *inpExpr) peek() byte {
+	if e.pos < len(e.str) {
+		return e.str[e.pos]
+	}
+	return 0
+}
+
+func (e *inpExpr) expr() (float64, error) {
+	val, err := e.term()
+	if err != nil {
+		return 0.0, err
+	}
+	for {
+		switch e.peek() {
+		case '+':
+			e.pos++
+			v, err := e.term()
+			if err != nil {
+				return 0.0, err
+			}
+			val += v
+		case '-':
+			e.pos++
+			v, err := e.term()
+			if err != nil {
+				return 0.0, err
+			}
+			val -= v
+		default:
+			return val, nil
+		}
+	}
+}
+
+func (e *inpExpr) term() (float64, error) {
+	val, err := e.factor()
+	if err != nil {
+		return 0.0, err
+	}
+	for {
+		switch e.peek() {
+		case '*':
+			e.pos++
+			v, err := e.factor()
+			if err != nil {
+				return 0.0, err
+			}
+			val *= v
+		case '/':
+			e.pos++
+			v, err := e.factor()
+			if err != nil {
+				return 0.0, err
+			}
+			if v == 0.0 {
+				return 0.0, fmt.Errorf("%s: division by zero", e.str)
+			}
+			val /= v
+		default:
+			return val, nil
+		}
+	}
+}
+
+func (e *inpExpr) factor() (float64, error) {
+	switch c := e.peek(); {
+	case c == '-':
+		e.pos++
+		val, err := e.factor()
+		return -val, err
+	case c == '+':
+		e.pos++
+		return e.factor()
+	case c == '(':
+		e.pos++
+		val, err := e.expr()
+		if err != nil {
+			return 0.0, err
+		}
+		if e.peek() != ')' {
+			return 0.0, fmt.Errorf("%s: ) is expected", e.str)
+		}
+		e.pos++
+		return val, nil
+	case c == '$' || c == '_' || ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z'):
+		if c == '$' {
+			e.pos++
+		}
+		brace := c == '$' && e.peek() == '{'
+		if brace {
+			e.pos++
+		}
+		start := e.pos
+		for e.pos < len(e.str) && validParamName(e.str[start:e.pos+1]) {
+			e.pos++
+		}
+		name := e.str[start:e.pos]
+		if brace {
+			if e.peek() != '}' {
+				return 0.0, fmt.Errorf("%s: } is expected", e.str)
+			}
+			e.pos++
+		}
+		val, ok := e.file.lookup(name)
+		if !ok {
+			return 0.0, fmt.Errorf("%s: PARAM %s is not defined", e.str, name)
+		}
+		return val, nil
+	case c == '.' || ('0' <= c && c <= '9'):
+		start := e.pos
+		for e.pos < len(e.str) {
+			c := e.str[e.pos]
+			if c == '.' || ('0' <= c && c <= '9') {
+				e.pos++
+			} else if (c == 'e' || c == 'E') && e.pos > start {
+				e.pos++
+				if e.peek() == '+' || e.peek() == '-' {
+					e.pos++
+				}
+			} else {
+				break
+			}
+		}
+		return strconv.ParseFloat(e.str[start:e.pos], 64)
+	default:
+		return 0.0, fmt.Errorf("%s: unexpected end of expression", e.str)
+	}
+}
+
+// Record records that the object of lis has been read from file.
+// raw is the list before Expand, existed reports whether the number had been used before reading lis.
+func (src *InpSource) Record(frame *Frame, file *InpFile, raw, lis []string, nodemap map[int]int, existed bool) {
+	if len(lis) < 2 {
+		return
+	}
+	num, err := strconv.ParseInt(lis[1], 10, 64)
+	if err != nil {
+		return
+	}
+	key := inpKey{lis[0], int(num)}
+	switch lis[0] {
+	default:
+		return
+	case "NODE":
+		key.num = nodemap[int(num)]
+		for i, w := range raw {
+			if w == "CORD" && i+3 < len(raw) && hasExpr(raw[i+1:i+4]) {
+				src.cords[key.num] = raw[i+1 : i+4]
+				src.used = true
+			}
+		}
+	case "ELEM":
+		if existed {
+			key.num = frame.Maxenum
+		}
+	case "PROP", "SECT", "PILE", "BOND":
+	}
+	if _, ok := src.files[key]; !ok {
+		src.files[key] = file
+	}
+}
+
+// SetHeight keeps HEIGHT line written with parameters.
+func (src *InpSource) SetHeight(raw []string) {
+	if hasExpr(raw) {
+		src.height = raw
+	}
+}
+
+// Finish is called after reading and keeps the current state of objects read from included files.
+func (src *InpSource) Finish(frame *Frame) {
+	for key, file := range src.files {
+		if file != src.Main {
+			src.strings[key] = src.objString(frame, key)
+		}
+	}
+}
+
+func (src *InpSource) objString(frame *Frame, key inpKey) string {
+	switch key.kind {
+	case "NODE":
+		if n, ok := frame.Nodes[key.num]; ok {
+			return n.InpString()
+		}
+	case "ELEM":
+		if el, ok := frame.Elems[key.num]; ok {
+			return el.InpString()
+		}
+	case "SECT":
+		if sec, ok := frame.Sects[key.num]; ok {
+			return sec.InpString()
+		}
+	case "PROP":
+		if p, ok := frame.Props[key.num]; ok {
+			return p.InpString()
+		}
+	case "PILE":
+		if p, ok := frame.Piles[key.num]; ok {
+			return p.InpString()
+		}
+	case "BOND":
+		if b, ok := frame.Bonds[key.num]; ok {
+			return b.InpString()
+		}
+	}
+	return ""
+}
+
+// FileOf returns the file which the object was read from. Objects added after reading belong to the main file.
+func (src *InpSource) FileOf(kind string, num int) *InpFile {
+	if f, ok := src.files[inpKey{kind, num}]; ok {
+		return f
+	}
+	return src.Main
+}
+
+// nodeString returns NODE line keeping parameterised CORD if the coordinate is unchanged.
+func (file *InpFile) nodeString(n *Node) string {
+	str := n.InpString()
+	if file == nil {
+		return str
+	}
+	raw, ok := file.source.cords[n.Num]
+	if !ok {
+		return str
+	}
+	f := file.source.FileOf("NODE", n.Num)
+	vals, err := f.Expand(raw)
+	if err != nil {
+		return str
+	}
+	for i := 0; i < 3; i++ {
+		val, err := strconv.ParseFloat(vals[i], 64)
+		if err != nil || math.Abs(val-n.Coord[i]) > 1e-6 {
+			return str
+		}
+	}
+	cord := fmt.Sprintf("CORD %7.3f %7.3f %7.3f", n.Coord[0], n.Coord[1], n.Coord[2])
+	return strings.Replace(str, cord, fmt.Sprintf("CORD %s", strings.Join(raw, " ")), 1)
+}
+
+// heightString returns HEIGHT line keeping parameters if the boundaries are unchanged.
+func (file *InpFile) heightString(ai *Aiparameter) string {
+	if file == nil || len(file.source.height) != ai.Nfloor+2 {
+		return ""
+	}
+	vals, err := file.Expand(file.source.height)
+	if err != nil {
+		return ""
+	}
+	for i := 0; i < ai.Nfloor+1; i++ {
+		val, err := strconv.ParseFloat(vals[1+i], 64)
+		if err != nil || math.Abs(val-ai.Boundary[i]) > 1e-6 {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%s\n", strings.Join(file.source.height, " "))
+}
+
+func (file *InpFile) paramString() string {
+	var rtn bytes.Buffer
+	for _, p := range file.Params {
+		rtn.WriteString(fmt.Sprintf("PARAM %s %s\n", p.Name, p.Expr))
+	}
+	if len(file.Params) > 0 {
+		rtn.WriteString("\n")
+	}
+	return rtn.String()
+}
+
+func (file *InpFile) includeString() string {
+	var rtn bytes.Buffer
+	for _, inc := range file.Includes {
+		rtn.WriteString(fmt.Sprintf("INCLUDE %s", inc.Name))
+		for _, a := range inc.Args {
+			rtn.WriteString(fmt.Sprintf(" %s=%s", a.Name, a.Expr))
+		}
+		rtn.WriteString("\n")
+	}
+	if len(file.Includes) > 0 {
+		rtn.WriteString("\n")
+	}
+	return rtn.String()
+}
+
+// inpParts are the objects written to one file.
+type inpParts struct {
+	bonds  []*Bond
+	props  []*Prop
+	sects  []*Sect
+	piles  []*Pile
+	nodes  []*Node
+	elems  []*Elem
+	chains []*Chain
+}
+
+// writeSource writes the frame back to the files it was read from.
+// An included file is rewritten only if its objects have been changed; objects read from templates must be unchanged.
+func (frame *Frame) writeSource(fn string, bonds []*Bond, props []*Prop, sects []*Sect, piles []*Pile, nodes []*Node, elems []*Elem, chains []*Chain) error {
+	src := frame.Source
+	keys := make([]inpKey, 0, len(src.strings))
+	for key := range src.strings {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind < keys[j].kind
+		}
+		return keys[i].num < keys[j].num
+	})
+	dirty := make(map[*InpFile]bool)
+	for _, key := range keys {
+		str := src.objString(frame, key)
+		if str == src.strings[key] {
+			continue
+		}
+		f := src.files[key]
+		if f.IsTemplate() {
+			if str == "" {
+				return fmt.Errorf("WriteInp: %s of %s has been deleted; save with -flatten", key, f.Name)
+			}
+			return fmt.Errorf("WriteInp: %s of %s has been changed; save with -flatten", key, f.Name)
+		}
+		dirty[f] = true
+	}
+	parts := make(map[*InpFile]*inpParts)
+	part := func(kind string, num int) *inpParts {
+		f := src.FileOf(kind, num)
+		if _, ok := parts[f]; !ok {
+			parts[f] = new(inpParts)
+		}
+		return parts[f]
+	}
+	for _, b := range bonds {
+		p := part("BOND", b.Num)
+		p.bonds = append(p.bonds, b)
+	}
+	for _, pr := range props {
+		p := part("PROP", pr.Num)
+		p.props = append(p.props, pr)
+	}
+	for _, sec := range sects {
+		p := part("SECT", sec.Num)
+		p.sects = append(p.sects, sec)
+	}
+	for _, pl := range piles {
+		p := part("PILE", pl.Num)
+		p.piles = append(p.piles, pl)
+	}
+	for _, n := range nodes {
+		p := part("NODE", n.Num)
+		p.nodes = append(p.nodes, n)
+	}
+	for _, el := range elems {
+		p := part("ELEM", el.Num)
+		p.elems = append(p.elems, el)
+	}
+	for _, c := range chains {
+		p := part("ELEM", c.Elems()[0].Num)
+		p.chains = append(p.chains, c)
+	}
+	m, ok := parts[src.Main]
+	if !ok {
+		m = new(inpParts)
+	}
+	err := writeinp(fn, frame.Title, frame.View, frame.Ai, frame.Wind, frame.Periods, src.Main, m.bonds, m.props, m.sects, m.piles, m.nodes, m.elems, m.chains, frame.NodeSet, frame.ElemSet)
+	if err != nil {
+		return err
+	}
+	var write func(*InpFile) error
+	write = func(file *InpFile) error {
+		for _, inc := range file.Includes {
+			if dirty[inc] {
+				p, ok := parts[inc]
+				if !ok {
+					p = new(inpParts)
+				}
+				var otp bytes.Buffer
+				otp.WriteString(inc.paramString())
+				writeinpbody(&otp, inc, p.bonds, p.props, p.sects, p.piles, p.nodes, p.elems, p.chains)
+				w, err := os.Create(inc.Path)
+				if err != nil {
+					return err
+				}
+				otp = AddCR(otp)
+				otp.WriteTo(w)
+				w.Close()
+				delete(dirty, inc)
+			}
+			err := write(inc)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err = write(src.Main)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if str := src.objString(frame, key); str != "" {
+			src.strings[key] = str
+		} else {
+			delete(src.strings, key)
+			delete(src.files, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package st
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testIncludeFrame is a column whose top node and element are read from block.inp and whose brace is read from bay.inp with an argument.
+const testIncludeFrame = `"TEST"
+BASE    0.200 0.200
+LOCATE  1.000
+TFACT   0.020
+GPERIOD 0.600
+NOKIHEIGHT 3.000
+PROP 101 PNAME STEEL
+         HIJU       7.80000
+         E      21000000.000
+         POI        0.33333
+         PCOLOR   0   0   0
+SECT 101 SNAME C1
+         NFIG 1
+         FIG   1 FPROP 101
+                 AREA 0.0100
+                 IXX  0.00010000
+                 IYY  0.00010000
+                 VEN  0.00020000
+         COLOR   0   0   0
+NODE 1 CORD 0.0 0.0 0.0 ICON 1 1 1 1 1 1 VCON 0 0 0 0 0 0
+INCLUDE block.inp
+INCLUDE bay.inp X0=6.0
+`
+
+const testIncludeBlock = `NODE 2 CORD 0.0 0.0 3.0 ICON 0 0 0 0 0 0 VCON 0 0 0 0 0 0
+ELEM 101 ESECT 101 ENODS 2 ENOD 1 2 BONDS 0 0 0 0 0 0 0 0 0 0 0 0
+           CANG 0.0
+           CMQ 0 0 0 0 0 0 0 0 0 0 0 0
+           TYPE COLUMN
+`
+
+const testIncludeBay = `PARAM X0 0.0
+NODE 3 CORD ${X0} 0.0 3.0 ICON 0 0 0 0 0 0 VCON 0 0 0 0 0 0
+ELEM 201 ESECT 101 ENODS 2 ENOD 2 3 BONDS 0 0 0 0 0 0 0 0 0 0 0 0
+           CANG 0.0
+           CMQ 0 0 0 0 0 0 0 0 0 0 0 0
+           TYPE GIRDER
+`
+
+func TestWriteInpInclude(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "main.inp")
+	for name, str := range map[string]string{"main.inp": testIncludeFrame, "block.inp": testIncludeBlock, "bay.inp": testIncludeBay} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(str), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	frame := NewFrame()
+	if err := frame.ReadInp(fn, []float64{0.0, 0.0, 0.0}, 0.0, false); err != nil {
+		t.Fatal(err)
+	}
+	frame.Nodes[2].Coord[2] = 3.5
+	if err := frame.WriteInp(fn); err != nil {
+		t.Fatal(err)
+	}
+	block, err := os.ReadFile(filepath.Join(dir, "block.inp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(block), "3.500") {
+		t.Errorf("block.inp has no changed NODE 2:\n%s", block)
+	}
+	bay, err := os.ReadFile(filepath.Join(dir, "bay.inp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bay) != testIncludeBay {
+		t.Errorf("bay.inp has been overwritten:\n%s", bay)
+	}
+	reread := NewFrame()
+	if err := reread.ReadInp(fn, []float64{0.0, 0.0, 0.0}, 0.0, false); err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := reread.Nodes[2]; !ok || math.Abs(n.Coord[2]-3.5) > 1e-6 {
+		t.Errorf("NODE 2 is not written back to block.inp")
+	}
+	if n, ok := reread.Nodes[3]; !ok || math.Abs(n.Coord[0]-6.0) > 1e-6 {
+		t.Errorf("NODE 3 of bay.inp is not read")
+	}
+	if src := reread.Source; src.FileOf("NODE", 2).Name != "block.inp" {
+		t.Errorf("NODE 2 is read from %s, want block.inp", src.FileOf("NODE", 2).Name)
+	}
+	reread.Nodes[3].Coord[2] = 3.5
+	if err := reread.WriteInp(fn); err == nil {
+		t.Errorf("changed NODE 3 of template bay.inp is saved")
+	}
+}
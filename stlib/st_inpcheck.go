@@ -0,0 +1,845 @@
+package st
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Strict check of .inp files.
+// readInp skips unknown words and reports errors without position;
+// CheckInp goes through the same structure token by token and reports every problem with file:line:column.
+
+// InpDiagnostic is a problem found in an .inp file.
+type InpDiagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (d *InpDiagnostic) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+}
+
+// InpDiagnostics is returned as an error by ReadInpStrict.
+type InpDiagnostics []*InpDiagnostic
+
+func (ds InpDiagnostics) Error() string {
+	switch len(ds) {
+	case 0:
+		return "no diagnostics"
+	case 1:
+		return ds[0].Error()
+	default:
+		return fmt.Sprintf("%s (and %d more)", ds[0].Error(), len(ds)-1)
+	}
+}
+
+// DiagnosticsViewer is implemented by windows which can list diagnostics and jump to their positions.
+type DiagnosticsViewer interface {
+	ShowDiagnostics([]*InpDiagnostic)
+}
+
+// ShowInpDiagnostics shows diagnostics in err by DiagnosticsViewer if stw implements it, or writes them to the history.
+func ShowInpDiagnostics(stw Window, err error) {
+	diags, ok := err.(InpDiagnostics)
+	if !ok {
+		return
+	}
+	if dv, ok := stw.(DiagnosticsViewer); ok {
+		dv.ShowDiagnostics(diags)
+		return
+	}
+	for _, d := range diags {
+		stw.History(d.Error())
+	}
+}
+
+type inpToken struct {
+	word   string
+	file   string
+	line   int
+	column int
+	order  int
+}
+
+// at returns the position of t for messages; the file name is omitted if it is the same as that of other.
+func (t inpToken) at(other inpToken) string {
+	if t.file == other.file {
+		return fmt.Sprintf("line %d", t.line)
+	}
+	return fmt.Sprintf("%s:%d", t.file, t.line)
+}
+
+type inpArg int
+
+const (
+	inpInt inpArg = iota
+	inpFloat
+	inpWord
+	inpFlag
+)
+
+// inpKeyword describes the arguments of a keyword.
+// The arguments are args repeated times*(value of repeat) if repeat is given,
+// followed by any number of restargs until the next keyword if rest is true.
+type inpKeyword struct {
+	args     []inpArg
+	repeat   string
+	times    int
+	rest     bool
+	restarg  inpArg
+	empty    bool
+	min, max float64
+	ranged   bool
+	check    func(c *inpChecker, kw inpToken, args []inpToken) int
+}
+
+var (
+	inpYieldKeys = []string{"NZMAX", "NZMIN", "QXMAX", "QXMIN", "QYMAX", "QYMIN", "MZMAX", "MZMIN", "MXMAX", "MXMIN", "MYMAX", "MYMIN"}
+	inpFigKeys   = []string{"FIG", "FPROP", "FNAME", "SHAPE", "TREIN", "BREIN", "RREIN", "LREIN", "CREIN", "HOOP", "KABURI", "WREIN", "AREA", "IXX", "IYY", "VEN", "THICK", "KFACT", "SREIN", "SIGMA", "XFACE", "YFACE"}
+)
+
+func inpArgs(kind inpArg, n int) []inpArg {
+	rtn := make([]inpArg, n)
+	for i := 0; i < n; i++ {
+		rtn[i] = kind
+	}
+	return rtn
+}
+
+func inpNumber(kind string) inpKeyword {
+	return inpKeyword{args: []inpArg{inpInt}, min: 1, max: math.Inf(1), ranged: true, check: func(c *inpChecker, kw inpToken, args []inpToken) int {
+		c.define(kind, args[0])
+		return 0
+	}}
+}
+
+func inpRef(kind string, n int) inpKeyword {
+	return inpKeyword{args: inpArgs(inpInt, n), check: func(c *inpChecker, kw inpToken, args []inpToken) int {
+		for _, a := range args {
+			c.refer(kind, a)
+		}
+		return 0
+	}}
+}
+
+func inpRange(kind inpArg, n int, min, max float64) inpKeyword {
+	return inpKeyword{args: inpArgs(kind, n), min: min, max: max, ranged: true}
+}
+
+var (
+	inpWordKey   = inpKeyword{args: []inpArg{inpWord}}
+	inpNameKey   = inpKeyword{args: []inpArg{inpWord}, empty: true}
+	inpFloatKey  = inpKeyword{args: []inpArg{inpFloat}}
+	inpColorKey  = inpRange(inpInt, 3, 0, 255)
+	inpNonNeg    = inpRange(inpFloat, 1, 0, math.Inf(1))
+	inpNonNegDim = inpKeyword{args: []inpArg{inpFloat}, min: 0, max: math.Inf(1), ranged: true, rest: true, restarg: inpWord}
+	inpReins     = inpKeyword{rest: true, restarg: inpWord}
+)
+
+// inpBlocks are the keywords of PROP, SECT, PILE, BOND, NODE and ELEM blocks.
+var inpBlocks = map[string]map[string]inpKeyword{
+	"PROP": {
+		"PROP":  inpNumber("PROP"),
+		"PNAME": inpNameKey,
+		"MATERIAL": {args: []inpArg{inpWord}, check: func(c *inpChecker, kw inpToken, args []inpToken) int {
+			if _, err := materialname(args[0].word); err != nil {
+				c.report(args[0], "MATERIAL: %s", err.Error())
+			}
+			return 0
+		}},
+		"HFACT":  inpFloatKey,
+		"EFACT":  inpFloatKey,
+		"HIJU":   inpNonNeg,
+		"E":      inpRange(inpFloat, 1, 0, math.Inf(1)),
+		"ES":     inpNonNeg,
+		"POI":    inpRange(inpFloat, 1, -1.0, 0.5),
+		"PCOLOR": inpColorKey,
+	},
+	"PILE": {
+		"PILE":   inpNumber("PILE"),
+		"INAME":  inpNameKey,
+		"MOMENT": inpFloatKey,
+	},
+	"BOND": {
+		"BOND":  inpNumber("BOND"),
+		"BNAME": inpNameKey,
+		"KR":    inpRange(inpFloat, 2, 0, math.Inf(1)),
+	},
+	"NODE": {
+		"NODE":  inpNumber("NODE"),
+		"CORD":  {args: inpArgs(inpFloat, 3)},
+		"ICON":  {args: inpArgs(inpFlag, 6)},
+		"VCON":  {args: inpArgs(inpFloat, 6)},
+		"PHASE": {args: inpArgs(inpFloat, 2)},
+		"PCON":  inpRef("PILE", 1),
+	},
+	"ELEM": {
+		"ELEM":  inpNumber("ELEM"),
+		"ESECT": inpRef("SECT", 1),
+		"ENODS": inpRange(inpInt, 1, 2, 4),
+		"ENOD": {args: []inpArg{inpInt}, repeat: "ENODS", times: 1, check: func(c *inpChecker, kw inpToken, args []inpToken) int {
+			for i, a := range args {
+				c.refer("NODE", a)
+				for _, b := range args[:i] {
+					if a.word == b.word {
+						c.report(a, "ENOD: NODE %s appears twice", a.word)
+					}
+				}
+			}
+			return 0
+		}},
+		"BONDS": {args: []inpArg{inpInt}, repeat: "ENODS", times: 6, check: func(c *inpChecker, kw inpToken, args []inpToken) int {
+			for _, a := range args {
+				if a.word != "0" && a.word != "1" {
+					c.refer("BOND", a)
+				}
+			}
+			return 0
+		}},
+		"CMQ":   {args: []inpArg{inpFloat}, repeat: "ENODS", times: 6},
+		"CANG":  inpFloatKey,
+		"WRECT": inpRange(inpFloat, 2, 0, math.Inf(1)),
+		"PREST": inpFloatKey,
+		"TYPE": {args: []inpArg{inpWord}, check: func(c *inpChecker, kw inpToken, args []inpToken) int {
+			for _, et := range ETYPES[1:] {
+				if et == args[0].word {
+					return 0
+				}
+			}
+			c.report(args[0], "TYPE: unknown element type %s", args[0].word)
+			return 0
+		}},
+		"SKIP": {args: []inpArg{inpWord}, check: func(c *inpChecker, kw inpToken, args []inpToken) int {
+			if len(args[0].word) < 3 || strings.Trim(args[0].word, "01") != "" {
+				c.report(args[0], "SKIP: %s should be 3 digits of 0 or 1", args[0].word)
+			}
+			return 0
+		}},
+		"EBANS": {args: []inpArg{inpInt}},
+		"EBAN":  {args: []inpArg{inpInt}},
+		"BNODS": inpRange(inpInt, 1, 2, 4),
+		"BNOD":  {args: []inpArg{inpInt}, repeat: "BNODS", times: 1},
+	},
+	"SECT": {
+		"SECT":  inpNumber("SECT"),
+		"SNAME": inpNameKey,
+		"NFIG":  inpRange(inpInt, 1, 0, math.Inf(1)),
+		"FIG":   inpRange(inpInt, 1, 1, math.Inf(1)),
+		"FPROP": inpRef("PROP", 1),
+		"FNAME": inpNameKey,
+		"SHAPE": {rest: true, restarg: inpWord, check: func(c *inpChecker, kw inpToken, args []inpToken) int {
+			words := make([]string, len(args), len(args)+9)
+			for i, a := range args {
+				words[i] = a.word
+			}
+			_, size, err := ParseShape(append(words, make([]string, 9)...))
+			if err != nil {
+				c.report(kw, "SHAPE: %s", err.Error())
+				return 0
+			}
+			if size+1 < len(args) {
+				c.report(args[size+1], "SHAPE: unexpected token %s", args[size+1].word)
+			}
+			return 0
+		}},
+		"TREIN":  inpReins,
+		"BREIN":  inpReins,
+		"RREIN":  inpReins,
+		"LREIN":  inpReins,
+		"CREIN":  inpReins,
+		"HOOP":   inpReins,
+		"KABURI": inpReins,
+		"WREIN":  inpReins,
+		"AREA":   inpNonNegDim,
+		"IXX":    inpNonNegDim,
+		"IYY":    inpNonNegDim,
+		"VEN":    inpNonNegDim,
+		"THICK":  inpNonNegDim,
+		"KFACT":  inpFloatKey,
+		"SREIN":  inpNonNeg,
+		"SIGMA": {args: inpArgs(inpWord, 6), check: func(c *inpChecker, kw inpToken, args []inpToken) int {
+			for i, prefix := range []string{"FC", "SD", "D", "", "@", ""} {
+				if _, err := strconv.ParseFloat(strings.TrimPrefix(args[i].word, prefix), 64); err != nil {
+					c.report(args[i], "SIGMA: invalid value %s", args[i].word)
+				}
+			}
+			return 0
+		}},
+		"XFACE": {args: inpArgs(inpFloat, 2)},
+		"YFACE": {args: inpArgs(inpFloat, 2)},
+		"LLOAD": {args: inpArgs(inpFloat, 3)},
+		"PERPL": {args: inpArgs(inpFloat, 3)},
+		"EXP":   inpNonNeg,
+		"EXQ":   inpNonNeg,
+		"NZMAX": {args: inpArgs(inpWord, 23), check: func(c *inpChecker, kw inpToken, args []inpToken) int {
+			for i, a := range args {
+				if i%2 == 1 {
+					if a.word != inpYieldKeys[(i+1)/2] {
+						c.report(a, "NZMAX: %s is expected instead of %s", inpYieldKeys[(i+1)/2], a.word)
+					}
+				} else {
+					c.number(a, inpFloat)
+				}
+			}
+			return 0
+		}},
+		"BSECT": {args: []inpArg{inpInt}},
+		"COLOR": inpColorKey,
+		"SROLE": inpWordKey,
+	},
+}
+
+// inpGlobals are the keywords which are recognized at the head of a line.
+var inpGlobals = map[string]inpKeyword{
+	"NNODE":      {args: []inpArg{inpInt}},
+	"NELEM":      {args: []inpArg{inpInt}},
+	"NPROP":      {args: []inpArg{inpInt}},
+	"NSECT":      {args: []inpArg{inpInt}},
+	"NBOND":      {args: []inpArg{inpInt}},
+	"NPILE":      {args: []inpArg{inpInt}},
+	"BASE":       {args: []inpArg{inpFloat}, rest: true, restarg: inpFloat, min: 0, max: math.Inf(1), ranged: true},
+	"LOCATE":     inpNonNeg,
+	"TFACT":      inpNonNeg,
+	"GPERIOD":    inpNonNeg,
+	"NFLOOR":     inpRange(inpInt, 1, 0, math.Inf(1)),
+	"HEIGHT":     {rest: true, restarg: inpFloat},
+	"NOKIHEIGHT": inpFloatKey,
+	"BETAX":      {rest: true, restarg: inpFloat},
+	"BETAY":      {rest: true, restarg: inpFloat},
+	"ROUGHNESS":  inpRange(inpInt, 1, 1, 5),
+	"VELOCITY":   inpNonNeg,
+	"WFACT":      inpFloatKey,
+	"GFACT":      inpFloatKey,
+	"FOCUS":      {args: inpArgs(inpFloat, 3)},
+	"ANGLE":      {args: inpArgs(inpFloat, 2)},
+	"DISTS":      {args: inpArgs(inpFloat, 2)},
+	"LOADPERIOD": {args: []inpArg{inpWord}, rest: true, restarg: inpWord},
+	"NODESET": {args: []inpArg{inpWord}, rest: true, restarg: inpInt, check: func(c *inpChecker, kw inpToken, args []inpToken) int {
+		for _, a := range args[1:] {
+			c.refer("NODE", a)
+		}
+		return 0
+	}},
+	"ELEMSET": {args: []inpArg{inpWord}, rest: true, restarg: inpInt, check: func(c *inpChecker, kw inpToken, args []inpToken) int {
+		for _, a := range args[1:] {
+			c.refer("ELEM", a)
+		}
+		return 0
+	}},
+	"CHAIN":   {rest: true, restarg: inpWord},
+	"}":       {},
+	"PARAM":   {rest: true, restarg: inpWord},
+	"INCLUDE": {rest: true, restarg: inpWord},
+}
+
+type inpChecker struct {
+	frame      *Frame
+	files      []string
+	stack      []string
+	diags      InpDiagnostics
+	defined    map[string]map[int]inpToken
+	refs       []inpReference
+	tmp        []inpToken
+	nfloor     int
+	order      int
+	chain      bool
+	chaintoken inpToken
+}
+
+type inpReference struct {
+	kind  string
+	token inpToken
+}
+
+func (c *inpChecker) report(t inpToken, format string, a ...interface{}) {
+	c.diags = append(c.diags, &InpDiagnostic{
+		File:    t.file,
+		Line:    t.line,
+		Column:  t.column,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+func (c *inpChecker) define(kind string, t inpToken) {
+	num, err := strconv.ParseInt(t.word, 10, 64)
+	if err != nil {
+		return
+	}
+	if c.defined[kind] == nil {
+		c.defined[kind] = make(map[int]inpToken)
+	}
+	if d, ok := c.defined[kind][int(num)]; ok {
+		if d.file == t.file && d.line == t.line && d.column == t.column {
+			// a template INCLUDEd more than once; renumbered when reading
+			return
+		}
+		c.report(t, "%s %d is already defined at %s", kind, num, d.at(t))
+		return
+	}
+	c.defined[kind][int(num)] = t
+}
+
+func (c *inpChecker) refer(kind string, t inpToken) {
+	c.refs = append(c.refs, inpReference{kind, t})
+}
+
+// number checks that t is a number of kind and returns its value.
+func (c *inpChecker) number(t inpToken, kind inpArg) (float64, bool) {
+	if strings.Contains(t.word, "$") {
+		// evaluated by PARAM when reading
+		return 0.0, false
+	}
+	switch kind {
+	case inpInt:
+		val, err := strconv.ParseInt(t.word, 10, 64)
+		if err != nil {
+			c.report(t, "invalid integer %s", t.word)
+			return 0.0, false
+		}
+		return float64(val), true
+	case inpFloat:
+		val, err := strconv.ParseFloat(t.word, 64)
+		if err != nil || math.IsNaN(val) || math.IsInf(val, 0) {
+			c.report(t, "invalid number %s", t.word)
+			return 0.0, false
+		}
+		return val, true
+	case inpFlag:
+		if t.word != "0" && t.word != "1" {
+			c.report(t, "%s should be 0 or 1", t.word)
+			return 0.0, false
+		}
+		if t.word == "1" {
+			return 1.0, true
+		}
+		return 0.0, true
+	}
+	return 0.0, true
+}
+
+// keyword checks the arguments of lis[0] by kw and returns the number of tokens consumed.
+// values holds the first argument of keywords already read in the block (used for ENODS etc.).
+func (c *inpChecker) keyword(lis []inpToken, kw inpKeyword, iskey func(string) bool, values map[string]float64) int {
+	key := lis[0]
+	n := len(kw.args)
+	if kw.repeat != "" {
+		val, ok := values[kw.repeat]
+		if !ok {
+			c.report(key, "%s: %s is not given before", key.word, kw.repeat)
+			return 1
+		}
+		n = int(val) * kw.times * len(kw.args)
+	}
+	if kw.empty && (len(lis) < 2 || iskey(lis[1].word)) {
+		// written as "SNAME " by WriteInp when the name is empty
+		return 1
+	}
+	args := make([]inpToken, 0, n)
+	for i := 0; i < n; i++ {
+		if 1+i >= len(lis) {
+			c.report(key, "%s: %d values are required, %d given", key.word, n, i)
+			return len(lis)
+		}
+		t := lis[1+i]
+		kind := kw.args[i%len(kw.args)]
+		if kind != inpWord {
+			val, ok := c.number(t, kind)
+			if ok && kw.ranged && (val < kw.min || val > kw.max) {
+				c.report(t, "%s: %s is out of range [%g, %g]", key.word, t.word, kw.min, kw.max)
+			}
+			if i == 0 {
+				values[key.word] = val
+			}
+		}
+		args = append(args, t)
+	}
+	if kw.rest {
+		for 1+len(args) < len(lis) && !iskey(lis[1+len(args)].word) {
+			t := lis[1+len(args)]
+			if kw.restarg != inpWord {
+				val, ok := c.number(t, kw.restarg)
+				if ok && kw.ranged && (val < kw.min || val > kw.max) {
+					c.report(t, "%s: %s is out of range [%g, %g]", key.word, t.word, kw.min, kw.max)
+				}
+			}
+			args = append(args, t)
+		}
+	}
+	if kw.check != nil && len(args) >= n {
+		c.check(kw, key, args)
+	}
+	return 1 + len(args)
+}
+
+func (c *inpChecker) check(kw inpKeyword, key inpToken, args []inpToken) {
+	kw.check(c, key, args)
+}
+
+// block checks a PROP, SECT, PILE, BOND, NODE or ELEM block.
+func (c *inpChecker) block(lis []inpToken) {
+	if len(lis) == 0 {
+		return
+	}
+	head := lis[0]
+	schema, ok := inpBlocks[head.word]
+	if !ok {
+		for _, t := range lis {
+			c.report(t, "unknown keyword %s", t.word)
+		}
+		return
+	}
+	iskey := func(word string) bool {
+		_, ok := schema[word]
+		return ok
+	}
+	values := make(map[string]float64)
+	seen := make(map[string]inpToken)
+	for i := 0; i < len(lis); {
+		t := lis[i]
+		kw, ok := schema[t.word]
+		if !ok {
+			if strings.Contains(t.word, "\t") {
+				c.report(t, "tab is not a separator: %q", t.word)
+			} else {
+				c.report(t, "unknown keyword %s in %s block", t.word, head.word)
+			}
+			i++
+			continue
+		}
+		if t.word == "FIG" {
+			for _, k := range inpFigKeys {
+				delete(seen, k)
+			}
+		}
+		if d, ok := seen[t.word]; ok && t.word != "NZMAX" {
+			c.report(t, "%s is already given at line %d", t.word, d.line)
+		}
+		seen[t.word] = t
+		i += c.keyword(lis[i:], kw, iskey, values)
+	}
+	switch head.word {
+	case "NODE":
+		if _, ok := seen["CORD"]; !ok {
+			c.report(head, "NODE: CORD is missing")
+		}
+	case "ELEM":
+		for _, k := range []string{"ESECT", "ENODS", "ENOD"} {
+			if _, ok := seen[k]; !ok {
+				c.report(head, "ELEM: %s is missing", k)
+			}
+		}
+		if t, ok := seen["TYPE"]; ok {
+			ind := indexOfToken(lis, t) + 1
+			if enods, ok := values["ENODS"]; ok && ind < len(lis) {
+				for i, et := range ETYPES {
+					if et == lis[ind].word {
+						if i <= TRUSS && enods != 2 {
+							c.report(t, "ELEM: %s must have 2 nodes", et)
+						} else if i >= WBRACE && i <= SBRACE && enods != 2 {
+							c.report(t, "ELEM: %s must have 2 nodes", et)
+						} else if i >= WALL && enods < 3 {
+							c.report(t, "ELEM: %s must have 3 or 4 nodes", et)
+						}
+					}
+				}
+			}
+		}
+	case "SECT":
+		nfig := 0
+		for _, t := range lis {
+			if t.word == "FIG" {
+				nfig++
+			}
+		}
+		if t, ok := seen["NFIG"]; ok && int(values["NFIG"]) != nfig {
+			c.report(t, "NFIG: %d is given but %d FIG found", int(values["NFIG"]), nfig)
+		}
+		fig := false
+		for i, t := range lis {
+			switch t.word {
+			case "FIG":
+				if fig {
+					c.report(t, "FIG: FPROP is missing in the previous FIG")
+				}
+				fig = true
+			case "FPROP":
+				fig = false
+			}
+			if i == len(lis)-1 && fig {
+				c.report(head, "SECT: FPROP is missing in the last FIG")
+			}
+		}
+	}
+}
+
+func indexOfToken(lis []inpToken, t inpToken) int {
+	for i, l := range lis {
+		if l == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// global checks a line beginning with one of inpGlobals.
+func (c *inpChecker) global(lis []inpToken, nfloor int) {
+	kw := inpGlobals[lis[0].word]
+	values := make(map[string]float64)
+	n := c.keyword(lis, kw, func(string) bool { return false }, values)
+	switch lis[0].word {
+	case "HEIGHT":
+		if nfloor > 0 && len(lis)-1 != nfloor+1 {
+			c.report(lis[0], "HEIGHT: %d boundaries are required for NFLOOR %d, %d given", nfloor+1, nfloor, len(lis)-1)
+		}
+	case "BETAX", "BETAY":
+		if nfloor > 1 && len(lis)-1 < nfloor-1 {
+			c.report(lis[0], "%s: %d values are required for NFLOOR %d, %d given", lis[0].word, nfloor-1, nfloor, len(lis)-1)
+		}
+	case "BASE":
+		if len(lis) > 3 {
+			c.report(lis[3], "unexpected token %s", lis[3].word)
+		}
+	case "LOADPERIOD":
+		if len(lis) != 2 && len(lis) != 4 {
+			c.report(lis[0], "LOADPERIOD: name or name, input and output extensions are required")
+		}
+	case "CHAIN":
+		if len(lis) > 1 && lis[1].word != "{" {
+			c.report(lis[1], "unexpected token %s", lis[1].word)
+		}
+	}
+	for _, t := range lis[n:] {
+		c.report(t, "unexpected token %s", t.word)
+	}
+}
+
+// references checks references to nodes, sects, props, piles and bonds.
+// They must be defined in the file before they are used, or exist in the frame.
+func (c *inpChecker) references() {
+	for _, r := range c.refs {
+		num, err := strconv.ParseInt(r.token.word, 10, 64)
+		if err != nil {
+			continue
+		}
+		if d, ok := c.defined[r.kind][int(num)]; ok {
+			if d.order > r.token.order {
+				c.report(r.token, "%s %d is used before its definition at %s", r.kind, num, d.at(r.token))
+			}
+			continue
+		}
+		if c.frame != nil {
+			var exists bool
+			switch r.kind {
+			case "NODE":
+				_, exists = c.frame.Nodes[int(num)]
+			case "ELEM":
+				_, exists = c.frame.Elems[int(num)]
+			case "SECT":
+				_, exists = c.frame.Sects[int(num)]
+			case "PROP":
+				_, exists = c.frame.Props[int(num)]
+			case "PILE":
+				_, exists = c.frame.Piles[int(num)]
+			case "BOND":
+				_, exists = c.frame.Bonds[int(num)]
+			}
+			if exists {
+				continue
+			}
+		}
+		c.report(r.token, "%s %d is not defined", r.kind, num)
+	}
+}
+
+// inpTokens splits a line into words separated by spaces as ParseScanner does, keeping their columns.
+func inpTokens(text string, file string, line int, order int) []inpToken {
+	rtn := make([]inpToken, 0)
+	start := -1
+	for i := 0; i <= len(text); i++ {
+		if i == len(text) || text[i] == ' ' {
+			if start >= 0 {
+				rtn = append(rtn, inpToken{text[start:i], file, line, start + 1, order})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	return rtn
+}
+
+// read checks filename and the files it includes.
+func (c *inpChecker) read(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	c.files = append(c.files, filename)
+	c.stack = append(c.stack, filename)
+	defer func() {
+		c.stack = c.stack[:len(c.stack)-1]
+	}()
+	s := bufio.NewScanner(f)
+	line := 0
+	for s.Scan() {
+		line++
+		c.order++
+		words := inpTokens(s.Text(), filename, line, c.order)
+		if len(words) == 0 {
+			continue
+		}
+		first := words[0].word
+		if strings.HasPrefix(first, "\"") || strings.HasPrefix(first, "#") {
+			continue
+		}
+		if _, ok := inpBlocks[first]; ok {
+			c.block(c.tmp)
+			c.tmp = words
+			continue
+		}
+		if _, ok := inpGlobals[first]; !ok {
+			if len(c.tmp) == 0 {
+				for _, t := range words {
+					c.report(t, "unknown keyword %s", t.word)
+				}
+				continue
+			}
+			c.tmp = append(c.tmp, words...)
+			continue
+		}
+		switch first {
+		case "CHAIN":
+			c.block(c.tmp)
+			c.tmp = make([]inpToken, 0)
+			if c.chain {
+				c.report(words[0], "CHAIN: previous CHAIN at %s isn't closed", c.chaintoken.at(words[0]))
+			}
+			c.chain = true
+			c.chaintoken = words[0]
+		case "}":
+			c.block(c.tmp)
+			c.tmp = make([]inpToken, 0)
+			if !c.chain {
+				c.report(words[0], "} without CHAIN")
+			}
+			c.chain = false
+		case "NODESET", "ELEMSET":
+			// NODESET and ELEMSET refer to nodes and elems read so far
+			c.block(c.tmp)
+			c.tmp = make([]inpToken, 0)
+		case "NFLOOR":
+			if len(words) > 1 {
+				if val, err := strconv.ParseInt(words[1].word, 10, 64); err == nil {
+					c.nfloor = int(val)
+				}
+			}
+		case "PARAM":
+			if len(words) < 3 {
+				c.report(words[0], "PARAM: name and value are required")
+			} else if !validParamName(words[1].word) {
+				c.report(words[1], "PARAM: invalid name %s", words[1].word)
+			}
+			continue
+		case "INCLUDE":
+			c.block(c.tmp)
+			c.tmp = make([]inpToken, 0)
+			c.include(words)
+			continue
+		}
+		c.global(words, c.nfloor)
+	}
+	return s.Err()
+}
+
+// include checks the file of an INCLUDE line.
+func (c *inpChecker) include(words []inpToken) {
+	if len(words) < 2 {
+		c.report(words[0], "INCLUDE: no filename")
+		return
+	}
+	if c.chain {
+		c.report(words[0], "INCLUDE: cannot be used in CHAIN")
+	}
+	for _, a := range words[2:] {
+		nv := strings.SplitN(a.word, "=", 2)
+		if len(nv) < 2 || !validParamName(nv[0]) {
+			c.report(a, "INCLUDE: invalid argument %s", a.word)
+		}
+	}
+	fn := words[1].word
+	if !filepath.IsAbs(fn) {
+		fn = filepath.Join(filepath.Dir(words[1].file), fn)
+	}
+	for _, f := range c.stack {
+		if f == fn {
+			c.report(words[1], "INCLUDE: %s includes itself", words[1].word)
+			return
+		}
+	}
+	if err := c.read(fn); err != nil {
+		c.report(words[1], "INCLUDE: %s", err.Error())
+	}
+}
+
+// CheckInp checks an .inp file and the files it includes strictly, and returns the diagnostics sorted by position.
+// frame (may be nil) is the frame the file is going to be read into; its nodes, sects etc. can be referred to.
+func CheckInp(filename string, frame *Frame) (InpDiagnostics, error) {
+	c := &inpChecker{
+		frame:   frame,
+		files:   make([]string, 0),
+		stack:   make([]string, 0),
+		diags:   make(InpDiagnostics, 0),
+		defined: make(map[string]map[int]inpToken),
+		refs:    make([]inpReference, 0),
+		tmp:     make([]inpToken, 0),
+	}
+	err := c.read(filename)
+	if err != nil {
+		return nil, err
+	}
+	c.block(c.tmp)
+	if c.chain {
+		c.report(c.chaintoken, "CHAIN isn't closed")
+	}
+	c.references()
+	fileorder := make(map[string]int)
+	for i, f := range c.files {
+		if _, ok := fileorder[f]; !ok {
+			fileorder[f] = i
+		}
+	}
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		if c.diags[i].File != c.diags[j].File {
+			return fileorder[c.diags[i].File] < fileorder[c.diags[j].File]
+		}
+		if c.diags[i].Line != c.diags[j].Line {
+			return c.diags[i].Line < c.diags[j].Line
+		}
+		return c.diags[i].Column < c.diags[j].Column
+	})
+	return c.diags, nil
+}
+
+// ReadInpStrict checks filename by CheckInp before reading it.
+// If any problem is found, the frame is left unchanged and the diagnostics are returned as InpDiagnostics.
+func (frame *Frame) ReadInpStrict(filename string, coord []float64, angle float64, overwrite bool) error {
+	diags, err := CheckInp(filename, frame)
+	if err != nil {
+		return err
+	}
+	if len(diags) > 0 {
+		return diags
+	}
+	return frame.ReadInp(filename, coord, angle, overwrite)
+}
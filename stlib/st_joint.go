@@ -0,0 +1,701 @@
+package st
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Joint design of steel members.
+// Joints are checked at the ends of elements with the stress at the ends,
+// and for the connection of full strength (保有耐力接合) with the plastic capacity of the member.
+//
+// A joint file (.jnt) is written as
+//
+//	JOINT G1 SPLICE                    # girder splice
+//	    FLANGE F10T M22 12 2 2         # grade, size, bolts per flange, shear planes, bolt lines across the flange
+//	    WEB    F10T M22 3 2 6.0 6.0 2  # grade, size, rows, columns, pitch[cm], gauge[cm], shear planes
+//	JOINT P1 SHEARTAB                  # pin joint of small beams
+//	    WEB    S10T M20 3 1 6.0 0.0 1
+//	    ECC    6.0                     # eccentricity of web bolts from the end[cm]
+//	JOINT E1 ENDPLATE
+//	    TENSION F10T M24 4             # bolts on each flange
+//	    SHEAR   F10T M24 8 1           # bolts carrying shear, shear planes
+//	JOINT W1 WELDED                    # flanges: full penetration, web: fillet
+//	    FILLET 0.6                     # leg of fillet welds[cm]
+//	JOINT B1 GUSSET                    # brace
+//	    SHEAR F10T M20 4 1 1
+//	    THICK 0.9                      # thickness of brace at the bolts for its net section[cm]
+//	    ALPHA 1.2                      # factor of full strength (default: 1.2)
+//	SECT 201 G1                        # both ends of elements of SECT 201
+//	SECT 202 P1 G1                     # i-end, j-end ("-" for none)
+//	ELEM 12 W1 W1                      # overrides SECT
+//
+// Units are [tf], [cm].
+// Allowable shear strength of high strength friction bolts is fs=1.5[tf/cm2] per friction plane (long-term),
+// allowable tensile strength is ft=3.1[tf/cm2], both on the nominal area.
+// Maximum shear strength is 0.6 Fu per plane and maximum tensile strength is 0.75 Fu on the nominal area.
+// Web bolts carry the share of moment Iw/I in addition to shear force, distributed elastically over the bolt group.
+// The connection of full strength requires Mu >= αMp and Qu >= QL + 2αMp/L for girders and columns, Nu >= αNy for braces.
+
+const (
+	JOINT_SPLICE = iota
+	JOINT_SHEARTAB
+	JOINT_ENDPLATE
+	JOINT_WELDED
+	JOINT_GUSSET
+)
+
+var (
+	JOINTTYPES     = []string{"SPLICE", "SHEARTAB", "ENDPLATE", "WELDED", "GUSSET"}
+	JOINTTYPENAMES = []string{"継手", "ピン接合", "エンドプレート", "溶接接合", "ガセット"}
+)
+
+// Bolt is a high strength bolt.
+type Bolt struct {
+	Grade string
+	D     float64 // nominal diameter [cm]
+	Fs    float64 // allowable shear stress per friction plane (long-term) [tf/cm2]
+	Ft    float64 // allowable tensile stress (long-term) [tf/cm2]
+	Fu    float64 // tensile strength [tf/cm2]
+}
+
+func NewBolt(grade, size string) (Bolt, error) {
+	var b Bolt
+	switch strings.ToUpper(grade) {
+	default:
+		return b, fmt.Errorf("NewBolt: unknown grade %s", grade)
+	case "F10T", "S10T":
+		b = Bolt{strings.ToUpper(grade), 0.0, 1.5, 3.1, 10.0}
+	case "F8T":
+		b = Bolt{"F8T", 0.0, 1.2, 2.5, 8.0}
+	}
+	if !strings.HasPrefix(strings.ToUpper(size), "M") {
+		return b, fmt.Errorf("NewBolt: unknown size %s", size)
+	}
+	val, err := strconv.ParseFloat(size[1:], 64)
+	if err != nil {
+		return b, err
+	}
+	b.D = val * 0.1
+	return b, nil
+}
+
+func (b Bolt) String() string {
+	return fmt.Sprintf("%s-M%d", b.Grade, int(b.D*10+0.5))
+}
+
+func (b Bolt) A() float64 {
+	return 0.25 * math.Pi * b.D * b.D
+}
+
+// Hole returns the diameter of the bolt hole [cm].
+func (b Bolt) Hole() float64 {
+	if b.D < 2.7 {
+		return b.D + 0.2
+	}
+	return b.D + 0.3
+}
+
+// Factor returns the factor of allowable stresses for period "L" (long-term) or "S" (short-term).
+func (b Bolt) Factor(p string) (float64, error) {
+	switch p {
+	default:
+		return 0.0, fmt.Errorf("Bolt: unknown period: %s", p)
+	case "L":
+		return 1.0, nil
+	case "S":
+		return 1.5, nil
+	}
+}
+
+// Qa returns the allowable shear strength of a bolt [tf].
+func (b Bolt) Qa(period string, planes int) (float64, error) {
+	fact, err := b.Factor(period)
+	if err != nil {
+		return 0.0, err
+	}
+	return b.Fs * b.A() * float64(planes) * fact, nil
+}
+
+// Ta returns the allowable tensile strength of a bolt [tf].
+func (b Bolt) Ta(period string) (float64, error) {
+	fact, err := b.Factor(period)
+	if err != nil {
+		return 0.0, err
+	}
+	return b.Ft * b.A() * fact, nil
+}
+
+// Qu returns the maximum shear strength of a bolt [tf].
+func (b Bolt) Qu(planes int) float64 {
+	return 0.6 * b.Fu * b.A() * float64(planes)
+}
+
+// Tu returns the maximum tensile strength of a bolt [tf].
+func (b Bolt) Tu() float64 {
+	return 0.75 * b.Fu * b.A()
+}
+
+// BoltGroup is a group of bolts.
+// Bolts of a web are arranged in Rows x Cols with Pitch (vertical) and Gauge (horizontal).
+type BoltGroup struct {
+	Bolt
+	Num    int
+	Planes int
+	Lines  int // number of bolt lines across the member, which reduce its net section
+	Rows   int
+	Cols   int
+	Pitch  float64
+	Gauge  float64
+}
+
+func (bg *BoltGroup) String() string {
+	if bg.Rows > 0 {
+		return fmt.Sprintf("%s %dx%d本 %d面", bg.Bolt.String(), bg.Rows, bg.Cols, bg.Planes)
+	}
+	return fmt.Sprintf("%s %d本 %d面", bg.Bolt.String(), bg.Num, bg.Planes)
+}
+
+// Ip returns the polar moment of the bolt group about its centre [cm2].
+func (bg *BoltGroup) Ip() float64 {
+	rtn := 0.0
+	for i := 0; i < bg.Rows; i++ {
+		y := bg.Pitch * (float64(i) - 0.5*float64(bg.Rows-1))
+		for j := 0; j < bg.Cols; j++ {
+			x := bg.Gauge * (float64(j) - 0.5*float64(bg.Cols-1))
+			rtn += x*x + y*y
+		}
+	}
+	return rtn
+}
+
+func (bg *BoltGroup) corner() (float64, float64) {
+	return 0.5 * bg.Gauge * float64(bg.Cols-1), 0.5 * bg.Pitch * float64(bg.Rows-1)
+}
+
+// Resultant returns the maximum force on a bolt [tf] under shear q, axial force n [tf] and moment m [tfcm].
+func (bg *BoltGroup) Resultant(q, n, m float64) float64 {
+	num := float64(bg.Num)
+	ip := bg.Ip()
+	x, y := bg.corner()
+	v := math.Abs(q) / num
+	h := math.Abs(n) / num
+	if ip > 0.0 {
+		v += math.Abs(m) * x / ip
+		h += math.Abs(m) * y / ip
+	}
+	return math.Sqrt(v*v + h*h)
+}
+
+// Mu returns the maximum moment of the bolt group [tfcm] when the farthest bolt reaches its maximum strength.
+func (bg *BoltGroup) Mu() float64 {
+	x, y := bg.corner()
+	r := math.Sqrt(x*x + y*y)
+	if r == 0.0 {
+		return 0.0
+	}
+	return bg.Qu(bg.Planes) * bg.Ip() / r
+}
+
+// Joint is a connection at the end of elements.
+type Joint struct {
+	Name    string
+	Type    int
+	Flange  *BoltGroup
+	Web     *BoltGroup
+	Tension *BoltGroup
+	Shear   *BoltGroup
+	Fillet  float64 // leg of fillet welds of web [cm]
+	Ecc     float64 // eccentricity of web bolts [cm]
+	Thick   float64 // thickness of brace at the bolts [cm]
+	Alpha   float64
+}
+
+func NewJoint(name string, jtype int) *Joint {
+	return &Joint{
+		Name:  name,
+		Type:  jtype,
+		Alpha: 1.2,
+	}
+}
+
+func (j *Joint) String() string {
+	return fmt.Sprintf("%s=%s", j.Name, JOINTTYPENAMES[j.Type])
+}
+
+// IsMomentJoint reports whether j transmits bending moment.
+func (j *Joint) IsMomentJoint() bool {
+	switch j.Type {
+	case JOINT_SPLICE, JOINT_ENDPLATE, JOINT_WELDED:
+		return true
+	}
+	return false
+}
+
+func (j *Joint) check() error {
+	switch j.Type {
+	case JOINT_SPLICE:
+		if j.Flange == nil || j.Web == nil {
+			return fmt.Errorf("JOINT %s: FLANGE and WEB are needed", j.Name)
+		}
+	case JOINT_SHEARTAB:
+		if j.Web == nil {
+			return fmt.Errorf("JOINT %s: WEB is needed", j.Name)
+		}
+	case JOINT_ENDPLATE:
+		if j.Tension == nil || j.Shear == nil {
+			return fmt.Errorf("JOINT %s: TENSION and SHEAR are needed", j.Name)
+		}
+	case JOINT_WELDED:
+		if j.Fillet <= 0.0 {
+			return fmt.Errorf("JOINT %s: FILLET is needed", j.Name)
+		}
+	case JOINT_GUSSET:
+		if j.Shear == nil {
+			return fmt.Errorf("JOINT %s: SHEAR is needed", j.Name)
+		}
+	}
+	return nil
+}
+
+// JointSet holds joints and their assignment to the ends of elements.
+type JointSet struct {
+	Joints map[string]*Joint
+	Sects  map[int][]*Joint
+	Elems  map[int][]*Joint
+}
+
+func NewJointSet() *JointSet {
+	return &JointSet{
+		Joints: make(map[string]*Joint),
+		Sects:  make(map[int][]*Joint),
+		Elems:  make(map[int][]*Joint),
+	}
+}
+
+// ReadJoint reads a joint file.
+func ReadJoint(filename string) (*JointSet, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseJoint(f)
+}
+
+func parseBoltGroup(words []string, web bool) (*BoltGroup, error) {
+	if len(words) < 4 {
+		return nil, NotEnoughArgs("parseBoltGroup")
+	}
+	b, err := NewBolt(words[1], words[2])
+	if err != nil {
+		return nil, err
+	}
+	bg := &BoltGroup{Bolt: b, Planes: 1}
+	vals := make([]float64, len(words)-3)
+	for i, w := range words[3:] {
+		val, err := strconv.ParseFloat(w, 64)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = val
+	}
+	if web {
+		if len(vals) < 5 {
+			return nil, NotEnoughArgs("parseBoltGroup")
+		}
+		bg.Rows = int(vals[0])
+		bg.Cols = int(vals[1])
+		bg.Pitch = vals[2]
+		bg.Gauge = vals[3]
+		bg.Planes = int(vals[4])
+		bg.Num = bg.Rows * bg.Cols
+		bg.Lines = bg.Rows
+	} else {
+		bg.Num = int(vals[0])
+		if len(vals) >= 2 {
+			bg.Planes = int(vals[1])
+		}
+		if len(vals) >= 3 {
+			bg.Lines = int(vals[2])
+		}
+	}
+	if bg.Num <= 0 || bg.Planes <= 0 {
+		return nil, errors.New("parseBoltGroup: number of bolts = 0")
+	}
+	return bg, nil
+}
+
+func ParseJoint(r io.Reader) (*JointSet, error) {
+	js := NewJointSet()
+	var current *Joint
+	assign := func(words []string) ([]*Joint, error) {
+		rtn := make([]*Joint, 2)
+		for i := 0; i < 2; i++ {
+			name := words[len(words)-1]
+			if i+2 < len(words) {
+				name = words[i+2]
+			}
+			if name == "-" {
+				continue
+			}
+			j, ok := js.Joints[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown joint %s", name)
+			}
+			rtn[i] = j
+		}
+		return rtn, nil
+	}
+	s := bufio.NewScanner(r)
+	nline := 0
+	for s.Scan() {
+		nline++
+		line := s.Text()
+		if ind := strings.Index(line, "#"); ind >= 0 {
+			line = line[:ind]
+		}
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			continue
+		}
+		key := strings.ToUpper(words[0])
+		switch key {
+		case "JOINT":
+			if len(words) < 3 {
+				return nil, fmt.Errorf("ParseJoint: line %d: not enough data", nline)
+			}
+			jtype := -1
+			for i, t := range JOINTTYPES {
+				if strings.ToUpper(words[2]) == t {
+					jtype = i
+					break
+				}
+			}
+			if jtype < 0 {
+				return nil, fmt.Errorf("ParseJoint: line %d: unknown joint type %s", nline, words[2])
+			}
+			if current != nil {
+				if err := current.check(); err != nil {
+					return nil, fmt.Errorf("ParseJoint: %s", err.Error())
+				}
+			}
+			current = NewJoint(words[1], jtype)
+			js.Joints[current.Name] = current
+			continue
+		case "SECT", "ELEM":
+			if current != nil {
+				if err := current.check(); err != nil {
+					return nil, fmt.Errorf("ParseJoint: %s", err.Error())
+				}
+				current = nil
+			}
+			if len(words) < 3 {
+				return nil, fmt.Errorf("ParseJoint: line %d: not enough data", nline)
+			}
+			js2, err := assign(words)
+			if err != nil {
+				return nil, fmt.Errorf("ParseJoint: line %d: %s", nline, err.Error())
+			}
+			for _, num := range SplitNums(words[1]) {
+				if key == "SECT" {
+					js.Sects[num] = js2
+				} else {
+					js.Elems[num] = js2
+				}
+			}
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("ParseJoint: line %d: %s outside JOINT", nline, words[0])
+		}
+		var err error
+		switch key {
+		default:
+			return nil, fmt.Errorf("ParseJoint: line %d: unknown keyword %s", nline, words[0])
+		case "FLANGE":
+			current.Flange, err = parseBoltGroup(words, false)
+		case "WEB":
+			current.Web, err = parseBoltGroup(words, true)
+		case "TENSION":
+			current.Tension, err = parseBoltGroup(words, false)
+		case "SHEAR":
+			current.Shear, err = parseBoltGroup(words, false)
+		case "FILLET", "ECC", "THICK", "ALPHA":
+			if len(words) < 2 {
+				return nil, fmt.Errorf("ParseJoint: line %d: not enough data", nline)
+			}
+			var val float64
+			val, err = strconv.ParseFloat(words[1], 64)
+			switch key {
+			case "FILLET":
+				current.Fillet = val
+			case "ECC":
+				current.Ecc = val
+			case "THICK":
+				current.Thick = val
+			case "ALPHA":
+				current.Alpha = val
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ParseJoint: line %d: %s", nline, err.Error())
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if current != nil {
+		if err := current.check(); err != nil {
+			return nil, fmt.Errorf("ParseJoint: %s", err.Error())
+		}
+	}
+	return js, nil
+}
+
+// Joint returns the joints at the ends of elem.
+func (js *JointSet) Joint(elem *Elem) []*Joint {
+	if j, ok := js.Elems[elem.Num]; ok {
+		return j
+	}
+	if j, ok := js.Sects[elem.Sect.Num]; ok {
+		return j
+	}
+	if j, ok := js.Sects[elem.OriginalSection().Num]; ok {
+		return j
+	}
+	return nil
+}
+
+func jointSteel(al SectionRate) (*SColumn, error) {
+	switch sr := al.(type) {
+	case *SColumn:
+		return sr, nil
+	case *SGirder:
+		return &sr.SColumn, nil
+	case *SBrace:
+		return &sr.SColumn, nil
+	}
+	return nil, fmt.Errorf("joint of %s is not supported", al.TypeString())
+}
+
+// Allowable returns the rates of the components of j under n, q [tf], m [tfcm].
+func (j *Joint) Allowable(sc *SColumn, period string, n, q, m float64) ([]float64, []string, error) {
+	if j.Type == JOINT_GUSSET {
+		qa, err := j.Shear.Qa(period, j.Shear.Planes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []float64{math.Abs(n) / (float64(j.Shear.Num) * qa)}, []string{"ボルト"}, nil
+	}
+	hk, ok := sc.Shape.(HKYOU)
+	if !ok {
+		return nil, nil, fmt.Errorf("joint of %s is not supported", sc.Shape.String())
+	}
+	h := hk.H - hk.Tf
+	hw := hk.H - 2.0*hk.Tf
+	af := hk.B * hk.Tf
+	aw := hw * hk.Tw
+	mw := m * hk.Tw * hw * hw * hw / 12.0 / sc.Ix()
+	mf := math.Abs(m - mw)
+	ff := mf/h + math.Abs(n)*af/sc.A()
+	switch j.Type {
+	default:
+		return nil, nil, fmt.Errorf("unknown joint type")
+	case JOINT_SPLICE:
+		fqa, err := j.Flange.Qa(period, j.Flange.Planes)
+		if err != nil {
+			return nil, nil, err
+		}
+		wqa, err := j.Web.Qa(period, j.Web.Planes)
+		if err != nil {
+			return nil, nil, err
+		}
+		rf := ff / (float64(j.Flange.Num) * fqa)
+		rw := j.Web.Resultant(q, n*aw/sc.A(), mw) / wqa
+		return []float64{rf, rw}, []string{"フランジ", "ウェブ"}, nil
+	case JOINT_SHEARTAB:
+		wqa, err := j.Web.Qa(period, j.Web.Planes)
+		if err != nil {
+			return nil, nil, err
+		}
+		rw := j.Web.Resultant(q, n, math.Abs(m)+math.Abs(q)*j.Ecc) / wqa
+		return []float64{rw}, []string{"ウェブ"}, nil
+	case JOINT_ENDPLATE:
+		ta, err := j.Tension.Ta(period)
+		if err != nil {
+			return nil, nil, err
+		}
+		sqa, err := j.Shear.Qa(period, j.Shear.Planes)
+		if err != nil {
+			return nil, nil, err
+		}
+		t := math.Abs(m)/h/float64(j.Tension.Num) + math.Max(-n, 0.0)/float64(2*j.Tension.Num)
+		rt := t / ta
+		rs := math.Abs(q) / (float64(j.Shear.Num) * sqa)
+		return []float64{rt, rs}, []string{"引張", "せん断"}, nil
+	case JOINT_WELDED:
+		fact := sc.Factor(period)
+		if fact == 0.0 {
+			return nil, nil, fmt.Errorf("unknown period: %s", period)
+		}
+		rf := ff / af / (sc.F / 1.5 * fact)
+		a := 0.7 * j.Fillet
+		le := hw - 2.0*j.Fillet
+		sigma := math.Abs(mw) / (2.0 * a * le * le / 6.0)
+		tau := math.Abs(q) / (2.0 * a * le)
+		rw := math.Sqrt(sigma*sigma+tau*tau) / (sc.F / (1.5 * math.Sqrt(3)) * fact)
+		return []float64{rf, rw}, []string{"フランジ", "ウェブ"}, nil
+	}
+}
+
+// Ultimate returns the required and maximum strengths of j for the connection of full strength.
+// The strengths are moment [tfcm] and shear [tf] for girders and columns, and axial force [tf] for braces.
+// ql is the long-term shear force [tf], l is the length of the member [cm].
+func (j *Joint) Ultimate(sc *SColumn, ql, l float64) ([]float64, []float64, []string, error) {
+	if j.Type == JOINT_GUSSET {
+		nu := float64(j.Shear.Num) * j.Shear.Qu(j.Shear.Planes)
+		if j.Thick > 0.0 {
+			nu = math.Min(nu, (sc.A()-float64(j.Shear.Lines)*j.Shear.Hole()*j.Thick)*sc.Fu)
+		}
+		return []float64{j.Alpha * sc.F * sc.A()}, []float64{nu}, []string{"N"}, nil
+	}
+	if j.Type == JOINT_SHEARTAB {
+		return nil, nil, nil, nil
+	}
+	hk, ok := sc.Shape.(HKYOU)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("joint of %s is not supported", sc.Shape.String())
+	}
+	h := hk.H - hk.Tf
+	hw := hk.H - 2.0*hk.Tf
+	var mu, qu float64
+	switch j.Type {
+	case JOINT_SPLICE:
+		fu := float64(j.Flange.Num) * j.Flange.Qu(j.Flange.Planes)
+		fu = math.Min(fu, (hk.B-float64(j.Flange.Lines)*j.Flange.Hole())*hk.Tf*sc.Fu)
+		mu = fu*h + j.Web.Mu()
+		qu = float64(j.Web.Num) * j.Web.Qu(j.Web.Planes)
+		qu = math.Min(qu, (hw-float64(j.Web.Lines)*j.Web.Hole())*hk.Tw*sc.Fu/math.Sqrt(3))
+	case JOINT_ENDPLATE:
+		mu = float64(j.Tension.Num) * j.Tension.Tu() * h
+		qu = float64(j.Shear.Num) * j.Shear.Qu(j.Shear.Planes)
+	case JOINT_WELDED:
+		a := 0.7 * j.Fillet
+		le := hw - 2.0*j.Fillet
+		mu = sc.Fu*hk.B*hk.Tf*h + sc.Fu/math.Sqrt(3)*2.0*a*le*le/4.0
+		qu = sc.Fu / math.Sqrt(3) * 2.0 * a * le
+	}
+	mp := sc.F * sc.Zpx()
+	req := []float64{j.Alpha * mp, math.Abs(ql)}
+	if l > 0.0 {
+		req[1] += 2.0 * j.Alpha * mp / l
+	}
+	return req, []float64{mu, qu}, []string{"M", "Q"}, nil
+}
+
+// OutputJointRate checks the joints at the ends of elem and returns the output.
+// The maximum rates of long-term, short-term and full strength are kept in elem.Values as "JOINTL", "JOINTS" and "JOINTU".
+func (js *JointSet) OutputJointRate(elem *Elem, al SectionRate, long, x1, x2, y1, y2 string, sign float64) (string, error) {
+	joints := js.Joint(elem)
+	if joints == nil {
+		return "", nil
+	}
+	sc, err := jointSteel(al)
+	if err != nil {
+		return "", err
+	}
+	var otp bytes.Buffer
+	rates := make([]float64, 3)
+	for end, j := range joints {
+		if j == nil {
+			continue
+		}
+		otp.WriteString(fmt.Sprintf("接合部:%s端 %s", []string{"i", "j"}[end], j.String()))
+		for _, bg := range []*BoltGroup{j.Flange, j.Web, j.Tension, j.Shear} {
+			if bg != nil {
+				otp.WriteString(fmt.Sprintf(" %s", bg.String()))
+			}
+		}
+		if j.Fillet > 0.0 {
+			otp.WriteString(fmt.Sprintf(" 隅肉S=%.1f[mm]", j.Fillet*10.0))
+		}
+		if elem.IsLineElem() && elem.Etype != BRACE && elem.Etype != WBRACE && elem.Etype != SBRACE {
+			if j.IsMomentJoint() && elem.IsPin(end) {
+				otp.WriteString(" ※ピン端に剛接合")
+			} else if !j.IsMomentJoint() && !elem.IsPin(end) {
+				otp.WriteString(" ※剛接端にピン接合")
+			}
+		}
+		otp.WriteString("\n")
+		stress := func(per string, s float64) []float64 {
+			rtn := make([]float64, 3)
+			for i, ind := range []int{0, 2, 4} {
+				rtn[i] = elem.ReturnStress(long, end, ind) + s*elem.ReturnStress(per, end, ind)
+			}
+			rtn[2] *= 100.0 // [tfcm]
+			if end == 1 {
+				rtn[0] = -rtn[0]
+			}
+			return rtn
+		}
+		for p, per := range []string{long, x1, x2, y1, y2} {
+			period := "S"
+			var st []float64
+			switch p {
+			case 0:
+				period = "L"
+				st = stress(per, 0.0)
+			case 1, 3:
+				st = stress(per, 1.0)
+			default:
+				st = stress(per, sign)
+			}
+			rs, names, err := j.Allowable(sc, period, st[0], st[1], st[2])
+			if err != nil {
+				return otp.String(), err
+			}
+			otp.WriteString(fmt.Sprintf("     %s:", []string{"長期      ", "短期X正加力", "短期X負加力", "短期Y正加力", "短期Y負加力"}[p]))
+			for i, r := range rs {
+				otp.WriteString(fmt.Sprintf(" %s=%.5f", names[i], r))
+				if p == 0 {
+					rates[0] = math.Max(rates[0], r)
+				} else {
+					rates[1] = math.Max(rates[1], r)
+				}
+			}
+			otp.WriteString("\n")
+		}
+		req, str, names, err := j.Ultimate(sc, elem.ReturnStress(long, end, 2), elem.Length()*100.0)
+		if err != nil {
+			return otp.String(), err
+		}
+		if req != nil {
+			otp.WriteString("     保有耐力接合:")
+			for i := range req {
+				r := req[i] / str[i]
+				rates[2] = math.Max(rates[2], r)
+				if names[i] == "M" {
+					otp.WriteString(fmt.Sprintf(" αMp=%.3f Mu=%.3f[tfm] %.5f", req[i]*0.01, str[i]*0.01, r))
+				} else if names[i] == "Q" {
+					otp.WriteString(fmt.Sprintf(" QL+2αMp/L=%.3f Qu=%.3f[tf] %.5f", req[i], str[i], r))
+				} else {
+					otp.WriteString(fmt.Sprintf(" αNy=%.3f Nu=%.3f[tf] %.5f", req[i], str[i], r))
+				}
+			}
+			otp.WriteString(fmt.Sprintf(" (α=%.2f)\n", j.Alpha))
+		}
+	}
+	elem.Values["JOINTL"] = rates[0]
+	elem.Values["JOINTS"] = rates[1]
+	elem.Values["JOINTU"] = rates[2]
+	otp.WriteString(fmt.Sprintf("MAX:J/JaL=%.5f J/JaS=%.5f J/Ju=%.5f\n", rates[0], rates[1], rates[2]))
+	return otp.String(), nil
+}
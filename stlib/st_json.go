@@ -0,0 +1,692 @@
+package st
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mattn/natural"
+)
+
+// JsonFrame is the structured form of Frame used by WriteJson and ReadJson.
+// It holds the same data as .inp so that a model can go through JSON and back to .inp without loss.
+// Results are included only when they are requested.
+// YAML is not supported: no YAML package is among the dependencies, and the schema is plain JSON
+// so that tools preferring YAML can convert it without loss.
+type JsonFrame struct {
+	Title    string           `json:"title"`
+	View     *JsonView        `json:"view,omitempty"`
+	Ai       *JsonAi          `json:"ai,omitempty"`
+	Wind     *JsonWind        `json:"wind,omitempty"`
+	Periods  []*JsonPeriod    `json:"periods,omitempty"`
+	Bonds    []*JsonBond      `json:"bonds"`
+	Props    []*JsonProp      `json:"props"`
+	Sects    []*JsonSect      `json:"sects"`
+	Piles    []*JsonPile      `json:"piles,omitempty"`
+	Nodes    []*JsonNode      `json:"nodes"`
+	Elems    []*JsonElem      `json:"elems"`
+	Chains   [][]int          `json:"chains,omitempty"`
+	NodeSets map[string][]int `json:"nodesets,omitempty"`
+	ElemSets map[string][]int `json:"elemsets,omitempty"`
+	Kijuns   []*JsonKijun     `json:"kijuns,omitempty"`
+	Nlap     map[string]int   `json:"nlap,omitempty"`
+}
+
+type JsonView struct {
+	Gfact float64   `json:"gfact"`
+	Focus []float64 `json:"focus"`
+	Angle []float64 `json:"angle"`
+	Dists []float64 `json:"dists"`
+}
+
+type JsonAi struct {
+	Base     []float64 `json:"base"`
+	Locate   float64   `json:"locate"`
+	Tfact    float64   `json:"tfact"`
+	Gperiod  float64   `json:"gperiod"`
+	Nfloor   int       `json:"nfloor"`
+	Boundary []float64 `json:"boundary,omitempty"`
+	H0       float64   `json:"nokiheight"`
+	BetaX    []float64 `json:"betax,omitempty"`
+	BetaY    []float64 `json:"betay,omitempty"`
+}
+
+type JsonWind struct {
+	Roughness int     `json:"roughness"`
+	Velocity  float64 `json:"velocity"`
+	Factor    float64 `json:"factor"`
+}
+
+type JsonBond struct {
+	Num       int       `json:"num"`
+	Name      string    `json:"name"`
+	Stiffness []float64 `json:"kr"`
+}
+
+type JsonProp struct {
+	Num      int     `json:"num"`
+	Name     string  `json:"name"`
+	Material string  `json:"material,omitempty"`
+	HFactor  float64 `json:"hfact,omitempty"`
+	EFactor  float64 `json:"efact,omitempty"`
+	Hiju     float64 `json:"hiju"`
+	E        float64 `json:"e"`
+	ES       float64 `json:"es"`
+	Poi      float64 `json:"poi"`
+	Color    int     `json:"color"`
+}
+
+type JsonSect struct {
+	Num   int        `json:"num"`
+	Name  string     `json:"name"`
+	Figs  []*JsonFig `json:"figs"`
+	Exp   float64    `json:"exp"`
+	Exq   float64    `json:"exq"`
+	Lload []float64  `json:"lload"`
+	Perpl []float64  `json:"perpl"`
+	Yield []float64  `json:"yield"`
+	Color int        `json:"color"`
+}
+
+type JsonFig struct {
+	Num   int                 `json:"num"`
+	Name  string              `json:"name,omitempty"`
+	Prop  int                 `json:"prop"`
+	Shape string              `json:"shape,omitempty"`
+	Value map[string]float64  `json:"value,omitempty"`
+	Reins map[string][]string `json:"reins,omitempty"`
+}
+
+type JsonPeriod struct {
+	Name   string `json:"name"`
+	Input  string `json:"input"`
+	Output string `json:"output"`
+}
+
+type JsonPile struct {
+	Num    int     `json:"num"`
+	Name   string  `json:"name"`
+	Moment float64 `json:"moment"`
+}
+
+type JsonNode struct {
+	Num      int                  `json:"num"`
+	Coord    []float64            `json:"coord"`
+	Conf     []bool               `json:"conf"`
+	Load     []float64            `json:"load"`
+	Phase    []float64            `json:"phase,omitempty"`
+	Pile     int                  `json:"pile,omitempty"`
+	Disp     map[string][]float64 `json:"disp,omitempty"`
+	Reaction map[string][]float64 `json:"reaction,omitempty"`
+}
+
+type JsonElem struct {
+	Num       int                          `json:"num"`
+	Sect      int                          `json:"sect"`
+	Etype     string                       `json:"etype"`
+	Enod      []int                        `json:"enod"`
+	Bonds     []int                        `json:"bonds,omitempty"`
+	Cang      float64                      `json:"cang"`
+	Cmq       []float64                    `json:"cmq,omitempty"`
+	Wrect     []float64                    `json:"wrect,omitempty"`
+	Prestress float64                      `json:"prestress,omitempty"`
+	Skip      []bool                       `json:"skip,omitempty"`
+	Stress    map[string]map[int][]float64 `json:"stress,omitempty"`
+}
+
+type JsonKijun struct {
+	Name  string    `json:"name"`
+	Start []float64 `json:"start"`
+	End   []float64 `json:"end"`
+}
+
+// JsonFrame converts frame into JsonFrame.
+// As WriteInp does, brace sections and brace elements generated from walls and slabs are omitted.
+func (frame *Frame) JsonFrame(results bool) *JsonFrame {
+	jf := &JsonFrame{
+		Title: frame.Title,
+		Bonds: make([]*JsonBond, 0),
+		Props: make([]*JsonProp, 0),
+		Sects: make([]*JsonSect, 0),
+		Nodes: make([]*JsonNode, 0),
+		Elems: make([]*JsonElem, 0),
+	}
+	if frame.View != nil {
+		jf.View = &JsonView{
+			Gfact: frame.View.Gfact,
+			Focus: frame.View.Focus,
+			Angle: frame.View.Angle,
+			Dists: frame.View.Dists,
+		}
+	}
+	if frame.Ai != nil {
+		jf.Ai = &JsonAi{
+			Base:     frame.Ai.Base,
+			Locate:   frame.Ai.Locate,
+			Tfact:    frame.Ai.Tfact,
+			Gperiod:  frame.Ai.Gperiod,
+			Nfloor:   frame.Ai.Nfloor,
+			Boundary: frame.Ai.Boundary,
+			H0:       frame.Ai.H0,
+			BetaX:    frame.Ai.BetaX,
+			BetaY:    frame.Ai.BetaY,
+		}
+	}
+	if frame.Wind != nil {
+		jf.Wind = &JsonWind{
+			Roughness: frame.Wind.Roughness,
+			Velocity:  frame.Wind.Velocity,
+			Factor:    frame.Wind.Factor,
+		}
+	}
+	bonds := make([]*Bond, 0, len(frame.Bonds))
+	for _, b := range frame.Bonds {
+		bonds = append(bonds, b)
+	}
+	sort.Sort(BondByNum{bonds})
+	for _, b := range bonds {
+		jf.Bonds = append(jf.Bonds, &JsonBond{
+			Num:       b.Num,
+			Name:      b.Name,
+			Stiffness: b.Stiffness,
+		})
+	}
+	props := make([]*Prop, 0, len(frame.Props))
+	for _, p := range frame.Props {
+		props = append(props, p)
+	}
+	sort.Sort(PropByNum{props})
+	for _, p := range props {
+		jp := &JsonProp{
+			Num:   p.Num,
+			Name:  p.Name,
+			Hiju:  p.Hiju(),
+			E:     p.EL(),
+			ES:    p.ES(),
+			Poi:   p.Poi(),
+			Color: p.Color,
+		}
+		if p.Material != nil {
+			jp.Material = p.Material.Name()
+			jp.HFactor = p.HFactor
+			jp.EFactor = p.EFactor
+		}
+		jf.Props = append(jf.Props, jp)
+	}
+	sects := make([]*Sect, 0, len(frame.Sects))
+	for _, sec := range frame.Sects {
+		if sec.Num < 100 || sec.Num > 900 {
+			continue
+		}
+		sects = append(sects, sec)
+	}
+	sort.Sort(SectByNum{sects})
+	for _, sec := range sects {
+		js := &JsonSect{
+			Num:   sec.Num,
+			Name:  sec.Name,
+			Figs:  make([]*JsonFig, len(sec.Figs)),
+			Exp:   sec.Exp,
+			Exq:   sec.Exq,
+			Lload: sec.Lload,
+			Perpl: sec.Perpl,
+			Yield: sec.Yield,
+			Color: sec.Color,
+		}
+		for i, f := range sec.Figs {
+			jfig := &JsonFig{
+				Num:   f.Num,
+				Name:  f.Name,
+				Value: f.Value,
+				Reins: f.Reins,
+			}
+			if f.Prop != nil {
+				jfig.Prop = f.Prop.Num
+			}
+			if f.Shape != nil {
+				jfig.Shape = f.Shape.String()
+			}
+			js.Figs[i] = jfig
+		}
+		jf.Sects = append(jf.Sects, js)
+	}
+	piles := make([]*Pile, 0, len(frame.Piles))
+	for _, p := range frame.Piles {
+		piles = append(piles, p)
+	}
+	sort.Sort(PileByNum{piles})
+	for _, p := range piles {
+		jf.Piles = append(jf.Piles, &JsonPile{
+			Num:    p.Num,
+			Name:   p.Name,
+			Moment: p.Moment,
+		})
+	}
+	nodes := make([]*Node, 0, len(frame.Nodes))
+	for _, n := range frame.Nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Sort(NodeByNum{nodes})
+	for _, n := range nodes {
+		jn := &JsonNode{
+			Num:   n.Num,
+			Coord: n.Coord,
+			Conf:  n.Conf,
+			Load:  n.Load,
+		}
+		if !(n.Phase[0] == 1.0 && n.Phase[1] == 1.0) {
+			jn.Phase = n.Phase
+		}
+		if n.Pile != nil {
+			jn.Pile = n.Pile.Num
+		}
+		if results {
+			jn.Disp = n.Disp
+			jn.Reaction = n.Reaction
+		}
+		jf.Nodes = append(jf.Nodes, jn)
+	}
+	elems := make([]*Elem, 0, len(frame.Elems))
+	for _, el := range frame.Elems {
+		if el.Etype == WBRACE || el.Etype == SBRACE {
+			continue
+		}
+		elems = append(elems, el)
+	}
+	sort.Sort(ElemByNum{elems})
+	for _, el := range elems {
+		je := &JsonElem{
+			Num:   el.Num,
+			Sect:  el.Sect.Num,
+			Etype: ETYPES[el.Etype],
+			Enod:  make([]int, el.Enods),
+		}
+		for i := 0; i < el.Enods; i++ {
+			je.Enod[i] = el.Enod[i].Num
+		}
+		if el.IsLineElem() {
+			je.Bonds = make([]int, 6*el.Enods)
+			for i, b := range el.Bonds {
+				if b != nil {
+					je.Bonds[i] = b.Num
+				}
+			}
+			je.Cang = el.Cang
+			je.Cmq = el.Cmq
+			je.Prestress = el.Prestress
+			if results {
+				je.Stress = el.Stress
+			}
+		} else if el.Wrect != nil && (el.Wrect[0] != 0.0 || el.Wrect[1] != 0.0) {
+			je.Wrect = el.Wrect
+		}
+		if el.IsSkipAny() {
+			je.Skip = el.Skip
+		}
+		jf.Elems = append(jf.Elems, je)
+	}
+	chains := make([]*Chain, 0, len(frame.Chains))
+	for _, c := range frame.Chains {
+		chains = append(chains, c)
+	}
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i].Elems()[0].Num < chains[j].Elems()[0].Num
+	})
+	for _, c := range chains {
+		nums := make([]int, c.Size())
+		for i, el := range c.Elems() {
+			nums[i] = el.Num
+		}
+		jf.Chains = append(jf.Chains, nums)
+	}
+	if len(frame.NodeSet) > 0 {
+		jf.NodeSets = make(map[string][]int)
+		for name, ns := range frame.NodeSet {
+			nums := make([]int, len(ns))
+			for i, n := range ns {
+				nums[i] = n.Num
+			}
+			jf.NodeSets[name] = nums
+		}
+	}
+	if len(frame.ElemSet) > 0 {
+		jf.ElemSets = make(map[string][]int)
+		for name, els := range frame.ElemSet {
+			nums := make([]int, len(els))
+			for i, el := range els {
+				nums[i] = el.Num
+			}
+			jf.ElemSets[name] = nums
+		}
+	}
+	for _, p := range frame.Periods {
+		if p.IsDefault() {
+			continue
+		}
+		jf.Periods = append(jf.Periods, &JsonPeriod{
+			Name:   p.Name,
+			Input:  p.Input,
+			Output: p.Output,
+		})
+	}
+	ks := make([]string, 0, len(frame.Kijuns))
+	for k := range frame.Kijuns {
+		ks = append(ks, k)
+	}
+	natural.Sort(ks)
+	for _, k := range ks {
+		jf.Kijuns = append(jf.Kijuns, &JsonKijun{
+			Name:  frame.Kijuns[k].Name,
+			Start: frame.Kijuns[k].Start,
+			End:   frame.Kijuns[k].End,
+		})
+	}
+	if results && len(frame.Nlap) > 0 {
+		jf.Nlap = frame.Nlap
+	}
+	return jf
+}
+
+// WriteJson writes frame to a JSON file.
+// If results is true, displacements, reactions and stresses of loaded periods are also written.
+func (frame *Frame) WriteJson(fn string, results bool) error {
+	w, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(frame.JsonFrame(results))
+}
+
+// ReadJson reads a JSON file written by WriteJson.
+func (frame *Frame) ReadJson(filename string) error {
+	r, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	jf := new(JsonFrame)
+	err = json.NewDecoder(r).Decode(jf)
+	if err != nil {
+		return fmt.Errorf("ReadJson: %s", err.Error())
+	}
+	err = frame.ParseJsonFrame(jf)
+	if err != nil {
+		return err
+	}
+	frame.Name = filepath.Base(filename)
+	frame.Project = ProjectName(filename)
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		frame.Path = filename
+	} else {
+		frame.Path = path
+	}
+	return nil
+}
+
+// ParseJsonFrame adds the data of jf to frame.
+func (frame *Frame) ParseJsonFrame(jf *JsonFrame) error {
+	frame.Title = jf.Title
+	if jf.View != nil {
+		frame.View.Gfact = jf.View.Gfact
+		copy(frame.View.Focus, jf.View.Focus)
+		copy(frame.View.Angle, jf.View.Angle)
+		copy(frame.View.Dists, jf.View.Dists)
+	}
+	if jf.Ai != nil {
+		if len(jf.Ai.Base) >= 2 {
+			frame.Ai.Base = jf.Ai.Base
+		}
+		frame.Ai.Locate = jf.Ai.Locate
+		frame.Ai.Tfact = jf.Ai.Tfact
+		frame.Ai.Gperiod = jf.Ai.Gperiod
+		frame.Ai.Nfloor = jf.Ai.Nfloor
+		frame.Ai.H0 = jf.Ai.H0
+		if frame.Ai.Nfloor > 0 {
+			if len(jf.Ai.Boundary) < frame.Ai.Nfloor+1 {
+				return fmt.Errorf("ReadJson: not enough boundaries (%d < %d)", len(jf.Ai.Boundary), frame.Ai.Nfloor+1)
+			}
+			frame.Ai.Boundary = jf.Ai.Boundary
+			frame.Ai.BetaX = make([]float64, frame.Ai.Nfloor-1)
+			frame.Ai.BetaY = make([]float64, frame.Ai.Nfloor-1)
+			for i := 0; i < frame.Ai.Nfloor-1; i++ {
+				frame.Ai.BetaX[i] = 1.0
+				frame.Ai.BetaY[i] = 1.0
+			}
+			copy(frame.Ai.BetaX, jf.Ai.BetaX)
+			copy(frame.Ai.BetaY, jf.Ai.BetaY)
+		}
+	}
+	if jf.Wind != nil {
+		frame.Wind.Roughness = jf.Wind.Roughness
+		frame.Wind.Velocity = jf.Wind.Velocity
+		frame.Wind.Factor = jf.Wind.Factor
+	}
+	for _, jb := range jf.Bonds {
+		if jb.Num == 1 {
+			frame.Bonds[1] = Pin
+			continue
+		}
+		b := &Bond{
+			Num:       jb.Num,
+			Name:      jb.Name,
+			Stiffness: make([]float64, 2),
+		}
+		copy(b.Stiffness, jb.Stiffness)
+		frame.Bonds[b.Num] = b
+	}
+	for _, jp := range jf.Props {
+		p := &Prop{
+			Num:     jp.Num,
+			Name:    jp.Name,
+			HFactor: jp.HFactor,
+			EFactor: jp.EFactor,
+			hiju:    jp.Hiju,
+			eL:      jp.E,
+			eS:      jp.ES,
+			poi:     jp.Poi,
+			Color:   jp.Color,
+		}
+		if jp.Material != "" {
+			m, err := materialname(jp.Material)
+			if err != nil {
+				return fmt.Errorf("ReadJson: PROP %d: %s", jp.Num, err.Error())
+			}
+			p.Material = m
+		}
+		frame.Props[p.Num] = p
+	}
+	for _, js := range jf.Sects {
+		s := NewSect()
+		s.Frame = frame
+		s.Num = js.Num
+		s.Original = js.Num
+		s.Name = js.Name
+		s.Exp = js.Exp
+		s.Exq = js.Exq
+		copy(s.Lload, js.Lload)
+		copy(s.Perpl, js.Perpl)
+		copy(s.Yield, js.Yield)
+		s.Color = js.Color
+		for _, jfig := range js.Figs {
+			f := NewFig()
+			f.Num = jfig.Num
+			f.Name = jfig.Name
+			if p, ok := frame.Props[jfig.Prop]; ok {
+				f.Prop = p
+			} else {
+				return fmt.Errorf("ReadJson: SECT %d: PROP %d doesn't exist", js.Num, jfig.Prop)
+			}
+			for k, v := range jfig.Value {
+				f.Value[k] = v
+			}
+			for k, v := range jfig.Reins {
+				f.Reins[k] = v
+			}
+			if jfig.Shape != "" {
+				shape, _, err := ParseShape(strings.Fields(jfig.Shape))
+				if err != nil {
+					return fmt.Errorf("ReadJson: SECT %d: %s", js.Num, err.Error())
+				}
+				if _, ok := f.Value["AREA"]; ok {
+					f.Shape = shape
+				} else {
+					f.SetShapeProperty(shape)
+				}
+			}
+			s.Figs = append(s.Figs, f)
+		}
+		frame.Sects[s.Num] = s
+		frame.Show.Sect[s.Num] = true
+	}
+	for _, jp := range jf.Piles {
+		frame.Piles[jp.Num] = &Pile{
+			Num:    jp.Num,
+			Name:   jp.Name,
+			Moment: jp.Moment,
+		}
+	}
+	for _, jn := range jf.Nodes {
+		if len(jn.Coord) < 3 {
+			return fmt.Errorf("ReadJson: NODE %d: not enough coordinates", jn.Num)
+		}
+		n := NewNode()
+		n.Frame = frame
+		n.Num = jn.Num
+		copy(n.Coord, jn.Coord)
+		copy(n.Conf, jn.Conf)
+		copy(n.Load, jn.Load)
+		copy(n.Phase, jn.Phase)
+		if jn.Pile != 0 {
+			if p, ok := frame.Piles[jn.Pile]; ok {
+				n.Pile = p
+			} else {
+				return fmt.Errorf("ReadJson: NODE %d: PILE %d doesn't exist", jn.Num, jn.Pile)
+			}
+		}
+		for per, v := range jn.Disp {
+			n.Disp[per] = v
+		}
+		for per, v := range jn.Reaction {
+			n.Reaction[per] = v
+		}
+		frame.Nodes[n.Num] = n
+		if n.Num > frame.Maxnnum {
+			frame.Maxnnum = n.Num
+		}
+	}
+	for _, je := range jf.Elems {
+		sec, ok := frame.Sects[je.Sect]
+		if !ok {
+			return fmt.Errorf("ReadJson: ELEM %d: SECT %d doesn't exist", je.Num, je.Sect)
+		}
+		etype := Etype(je.Etype)
+		if etype == NULL {
+			return fmt.Errorf("ReadJson: ELEM %d: unknown etype %s", je.Num, je.Etype)
+		}
+		enod := make([]*Node, len(je.Enod))
+		for i, nnum := range je.Enod {
+			if n, ok := frame.Nodes[nnum]; ok {
+				enod[i] = n
+			} else {
+				return fmt.Errorf("ReadJson: ELEM %d: NODE %d doesn't exist", je.Num, nnum)
+			}
+		}
+		var el *Elem
+		if etype < WALL {
+			if len(enod) < 2 {
+				return fmt.Errorf("ReadJson: ELEM %d: not enough nodes", je.Num)
+			}
+			el = NewLineElem(enod, sec, etype)
+			el.Cang = je.Cang
+			copy(el.Cmq, je.Cmq)
+			for i, bnum := range je.Bonds {
+				if bnum == 0 || i >= len(el.Bonds) {
+					continue
+				}
+				if b, ok := frame.Bonds[bnum]; ok {
+					el.Bonds[i] = b
+				} else {
+					return fmt.Errorf("ReadJson: ELEM %d: BOND %d doesn't exist", je.Num, bnum)
+				}
+			}
+			el.Prestress = je.Prestress
+			el.SetPrincipalAxis()
+			for per, v := range je.Stress {
+				el.Stress[per] = v
+			}
+		} else {
+			el = NewPlateElem(enod, sec, etype)
+			copy(el.Wrect, je.Wrect)
+		}
+		copy(el.Skip, je.Skip)
+		el.Num = je.Num
+		el.Frame = frame
+		frame.Elems[el.Num] = el
+		if el.Num > frame.Maxenum {
+			frame.Maxenum = el.Num
+		}
+	}
+	for _, nums := range jf.Chains {
+		chain := NewChain(frame, nil, nil, nil, func(c *Chain) bool { return true }, nil, nil)
+		for _, enum := range nums {
+			el, ok := frame.Elems[enum]
+			if !ok {
+				return fmt.Errorf("ReadJson: CHAIN: ELEM %d doesn't exist", enum)
+			}
+			err := chain.Append(el)
+			if err != nil {
+				return fmt.Errorf("ReadJson: CHAIN: ELEM %d: %s", enum, err.Error())
+			}
+		}
+		if chain.Size() > 0 {
+			frame.Chains[chain.Elems()[0].Num] = chain
+			for _, el := range chain.Elems() {
+				el.Chain = chain
+			}
+		}
+	}
+	for name, nums := range jf.NodeSets {
+		nodes := make([]*Node, 0, len(nums))
+		for _, nnum := range nums {
+			if n, ok := frame.Nodes[nnum]; ok {
+				nodes = append(nodes, n)
+			}
+		}
+		frame.AddNodeSet(name, nodes)
+	}
+	for name, nums := range jf.ElemSets {
+		elems := make([]*Elem, 0, len(nums))
+		for _, enum := range nums {
+			if el, ok := frame.Elems[enum]; ok {
+				elems = append(elems, el)
+			}
+		}
+		frame.AddElemSet(name, elems)
+	}
+	for _, jk := range jf.Kijuns {
+		if len(jk.Start) < 3 || len(jk.End) < 3 {
+			return fmt.Errorf("ReadJson: KIJUN %s: not enough coordinates", jk.Name)
+		}
+		_, err := frame.AddKijun(jk.Name, jk.Start, jk.End)
+		if err != nil {
+			return fmt.Errorf("ReadJson: %s", err.Error())
+		}
+	}
+	for _, jp := range jf.Periods {
+		_, err := frame.AddPeriod(jp.Name, jp.Input, jp.Output)
+		if err != nil {
+			return fmt.Errorf("ReadJson: %s", err.Error())
+		}
+	}
+	for per, nlap := range jf.Nlap {
+		frame.Nlap[per] = nlap
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package st
+
+import (
+	"math"
+)
+
+// Lateral-torsional buckling of members.
+//
+// The laterally unbraced length Lb is the distance between the lateral restraint points along a member.
+// Starting from an element, collinear elements of the same type are followed in both directions until a node which restrains the lateral displacement.
+// A node is a lateral restraint point when
+//
+//   - it is in the node set RESTRAINT (NODESET RESTRAINT ... in .inp)
+//   - it is fixed in the lateral direction
+//   - another column or girder is connected to it (joint)
+//   - another line element, such as a brace or a small beam, is connected to it in the lateral direction
+//
+// The lateral direction of the strong axis bending is the strong axis, and vice versa.
+//
+// The moment gradient factor C (AIJ Design Standard for Steel Structures) is calculated from the end moments of each stress combination:
+//
+//	C = 1.75 + 1.05(M2/M1) + 0.3(M2/M1)^2 <= 2.3
+//
+// where M2/M1 is positive in double curvature.
+// C is calculated only when Condition.Cgrad is set (:srcal -cb), otherwise C = 1.0.
+// C = 1.0 also when the unbraced length spans several elements, or the element is loaded between its ends
+// or its loads are unknown, judged from the CMQ used by the analysis (Elem.EffectiveCmq).
+
+const RESTRAINT = "RESTRAINT"
+
+// IsLateralRestraint reports whether n restrains elem laterally in the direction of axis.
+func (elem *Elem) IsLateralRestraint(n *Node, axis []float64) bool {
+	frame := elem.Frame
+	if frame == nil {
+		return false
+	}
+	if ns, ok := frame.NodeSet[RESTRAINT]; ok {
+		for _, rn := range ns {
+			if rn == n {
+				return true
+			}
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if n.Conf[i] && math.Abs(axis[i]) >= 0.5 {
+			return true
+		}
+	}
+	d := elem.Direction(true)
+	for _, el := range frame.NodeToElemAny(n) {
+		if el == elem || !el.IsLineElem() {
+			continue
+		}
+		ed := el.Direction(true)
+		if IsParallel(d, ed, 1e-2) {
+			continue
+		}
+		if el.Etype == COLUMN || el.Etype == GIRDER {
+			return true
+		}
+		if math.Abs(Dot(ed, axis, 3)) >= 0.5 {
+			return true
+		}
+	}
+	return false
+}
+
+// UnbracedLength returns the laterally unbraced length [m] of elem and the elements between the restraint points.
+func (elem *Elem) UnbracedLength(strong bool) (float64, []*Elem) {
+	if !elem.IsLineElem() {
+		return 0.0, nil
+	}
+	s, w, err := elem.PrincipalAxis(elem.Cang)
+	if err != nil {
+		return elem.Length(), []*Elem{elem}
+	}
+	axis := s
+	if !strong {
+		axis = w
+	}
+	d := elem.Direction(true)
+	length := elem.Length()
+	els := []*Elem{elem}
+	for i := 0; i < 2; i++ {
+		n := elem.Enod[i]
+		current := elem
+	walk:
+		for !elem.IsLateralRestraint(n, axis) {
+			for _, el := range elem.Frame.NodeToElemAny(n) {
+				if el == current || el.Etype != elem.Etype || !IsParallel(d, el.Direction(true), 1e-2) {
+					continue
+				}
+				for _, e := range els {
+					if e == el {
+						break walk
+					}
+				}
+				current = el
+				n = el.Otherside(n)
+				length += el.Length()
+				els = append(els, el)
+				continue walk
+			}
+			break
+		}
+	}
+	return length, els
+}
+
+// SetLateralBuckling sets the unbraced length of elem to its Condition, and whether C is calculated from the end moments.
+func (elem *Elem) SetLateralBuckling() {
+	if elem.Condition == nil {
+		return
+	}
+	elem.Condition.Lb = make([]float64, 2)
+	elem.Condition.CbAuto = make([]bool, 2)
+	for i, strong := range []bool{true, false} {
+		l, els := elem.UnbracedLength(strong)
+		elem.Condition.Lb[i] = l * 100.0 // [cm]
+		if !elem.Condition.Cgrad || len(els) != 1 || len(elem.EffectiveCmq) < 12 {
+			continue
+		}
+		loaded := false
+		for _, j := range []int{2 - i, 4 + i, 8 - i, 10 + i} {
+			if elem.EffectiveCmq[j] != 0.0 {
+				loaded = true
+				break
+			}
+		}
+		elem.Condition.CbAuto[i] = !loaded
+	}
+}
+
+// MomentGradientFactor returns C from the moments mi, mj at the ends, which have the same sign in single curvature.
+func MomentGradientFactor(mi, mj float64) float64 {
+	m1, m2 := mi, mj
+	if math.Abs(mj) > math.Abs(mi) {
+		m1, m2 = mj, mi
+	}
+	if m1 == 0.0 {
+		return 1.0
+	}
+	r := -m2 / m1
+	c := 1.75 + 1.05*r + 0.3*r*r
+	if c > 2.3 {
+		return 2.3
+	}
+	return c
+}
+
+// UnbracedLength returns Lb [cm] of the bending about the strong or weak axis.
+func (cond *Condition) UnbracedLength(strong bool) float64 {
+	ind := 0
+	if !strong {
+		ind = 1
+	}
+	if cond.Lb != nil && cond.Lb[ind] > 0.0 {
+		return cond.Lb[ind]
+	}
+	return cond.Length
+}
+
+// SetMomentGradient sets C of both axes from the end moments in stress.
+func (cond *Condition) SetMomentGradient(stress []float64) {
+	cond.Cb = []float64{1.0, 1.0}
+	if cond.CbAuto == nil {
+		return
+	}
+	for i := 0; i < 2; i++ {
+		if cond.CbAuto[i] {
+			cond.Cb[i] = MomentGradientFactor(stress[4+i], -stress[10+i])
+		}
+	}
+}
+
+// MomentGradientFactor returns C of the bending in cond.
+func (cond *Condition) MomentGradientFactor() float64 {
+	ind := 0
+	if !cond.Strong {
+		ind = 1
+	}
+	if cond.Cb == nil {
+		return 1.0
+	}
+	return cond.Cb[ind]
+}
@@ -18,8 +18,10 @@ type ExModer interface {
 
 	// UndoStack
 	UseUndo(bool)
+	UndoFrame(int) (*Frame, error)
 	// TagFrame
 	Checkout(string) (*Frame, error)
+	Tag(string) (*Frame, error)
 	AddTag(*Frame, string, bool) error
 
 	LastExCommand() string
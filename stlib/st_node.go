@@ -38,6 +38,8 @@ type Node struct {
 
 	Pile *Pile
 
+	Values map[string]float64
+
 	Pcoord []float64
 	Dcoord []float64
 
@@ -94,7 +96,8 @@ func NewNode() *Node {
 		Phase:    []float64{1.0, 1.0},
 		Disp:     make(map[string][]float64),
 		Force:    make(map[string][]float64),
-		Reaction: make(map[string][]float64)}
+		Reaction: make(map[string][]float64),
+		Values:   make(map[string]float64)}
 }
 
 func (node *Node) Snapshot(frame *Frame) *Node {
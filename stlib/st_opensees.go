@@ -0,0 +1,521 @@
+package st
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// OpenSees Tcl script import.
+// node, fix, geomTransf, section Elastic, section ElasticMembranePlateSection,
+// element elasticBeamColumn/forceBeamColumn/dispBeamColumn/truss/ShellMITC4 and load in a pattern are translated.
+// Variables set by "set" and [expr ...] of arithmetic are evaluated; other Tcl commands are reported.
+// Units of the script are used as they are (m, tf is expected).
+// A 2D model (-ndm 2) is placed on the XZ plane.
+
+type osCommand struct {
+	line  int
+	words []string
+}
+
+// osCommands splits a Tcl script into commands. Braces of blocks are taken as separators.
+func osCommands(filename string) ([]*osCommand, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rtn := make([]*osCommand, 0)
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	num := 0
+	var current []string
+	start := 0
+	for s.Scan() {
+		num++
+		line := s.Text()
+		if current == nil {
+			start = num
+		}
+		if strings.HasSuffix(strings.TrimRight(line, " \t\r"), "\\") {
+			current = append(current, strings.TrimSuffix(strings.TrimRight(line, " \t\r"), "\\"))
+			continue
+		}
+		line = strings.Join(append(current, line), " ")
+		current = nil
+		for _, cmd := range osSplit(line) {
+			words := osWords(cmd)
+			if len(words) == 0 || strings.HasPrefix(words[0], "#") {
+				continue
+			}
+			rtn = append(rtn, &osCommand{line: start, words: words})
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return rtn, nil
+}
+
+// osSplit splits a line at ";", "{" and "}" outside brackets.
+// A comment lasts until the end of the line.
+func osSplit(line string) []string {
+	rtn := make([]string, 0)
+	depth := 0
+	start := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '#':
+			if depth == 0 && strings.TrimSpace(line[start:i]) == "" {
+				rtn = append(rtn, line[start:i])
+				return rtn
+			}
+		case ';', '{', '}':
+			if depth == 0 {
+				rtn = append(rtn, line[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(rtn, line[start:])
+}
+
+// osWords splits a command into words keeping [ ... ] and "..." as one word.
+func osWords(cmd string) []string {
+	rtn := make([]string, 0)
+	var word strings.Builder
+	depth := 0
+	quoted := false
+	for _, c := range cmd {
+		switch {
+		case c == '"' && depth == 0:
+			quoted = !quoted
+		case c == '[':
+			depth++
+			word.WriteRune(c)
+		case c == ']':
+			depth--
+			word.WriteRune(c)
+		case (c == ' ' || c == '\t' || c == '\r') && depth == 0 && !quoted:
+			if word.Len() > 0 {
+				rtn = append(rtn, word.String())
+				word.Reset()
+			}
+		default:
+			word.WriteRune(c)
+		}
+	}
+	if word.Len() > 0 {
+		rtn = append(rtn, word.String())
+	}
+	return rtn
+}
+
+type osModel struct {
+	im     *importer
+	vars   *InpFile
+	ndm    int
+	ndf    int
+	nodes  map[string]*Node
+	vecxz  map[string][]float64
+	sects  map[string][]float64
+	plates map[string][]float64
+}
+
+// value evaluates a word: a number, $variable or [expr ...].
+func (m *osModel) value(word string) (float64, error) {
+	if strings.HasPrefix(word, "[") && strings.HasSuffix(word, "]") {
+		inner := strings.Fields(strings.TrimSpace(word[1 : len(word)-1]))
+		if len(inner) < 2 || inner[0] != "expr" {
+			return 0.0, fmt.Errorf("%s is not translated", word)
+		}
+		return m.vars.Eval(strings.Trim(strings.Join(inner[1:], ""), "{}"))
+	}
+	if val, err := strconv.ParseFloat(word, 64); err == nil {
+		return val, nil
+	}
+	return m.vars.Eval(word)
+}
+
+func (m *osModel) values(words []string) ([]float64, error) {
+	rtn := make([]float64, len(words))
+	for i, w := range words {
+		val, err := m.value(w)
+		if err != nil {
+			return nil, err
+		}
+		rtn[i] = val
+	}
+	return rtn, nil
+}
+
+// name expands a word used as a tag.
+func (m *osModel) name(word string) string {
+	if strings.HasPrefix(word, "$") {
+		if val, err := m.value(word); err == nil {
+			return strconv.FormatFloat(val, 'f', -1, 64)
+		}
+	}
+	return word
+}
+
+// dofs returns the indices of Conf and Load for the degrees of freedom of the model.
+func (m *osModel) dofs() []int {
+	if m.ndm == 2 {
+		return []int{0, 2, 4}
+	}
+	return []int{0, 1, 2, 3, 4, 5}
+}
+
+func (m *osModel) coord(vals []float64) []float64 {
+	if m.ndm == 2 {
+		return []float64{vals[0], 0.0, vals[1]}
+	}
+	return []float64{vals[0], vals[1], vals[2]}
+}
+
+func (m *osModel) node(line int, word string) *Node {
+	n, ok := m.nodes[m.name(word)]
+	if !ok {
+		m.im.skip(line, "node %s is not defined", word)
+	}
+	return n
+}
+
+// ReadOpenSees reads an OpenSees Tcl script and adds its model to frame.
+// Loads of pattern (tag) are read; if pattern is empty, the first pattern is used.
+// It returns the list of items which could not be translated.
+func (frame *Frame) ReadOpenSees(filename string, eps float64, pattern string) ([]string, error) {
+	cmds, err := osCommands(filename)
+	if err != nil {
+		return nil, fmt.Errorf("ReadOpenSees: %s", err.Error())
+	}
+	m := &osModel{
+		im:     newImporter(frame, eps),
+		vars:   &InpFile{values: make(map[string]float64)},
+		ndm:    3,
+		ndf:    6,
+		nodes:  make(map[string]*Node),
+		vecxz:  make(map[string][]float64),
+		sects:  make(map[string][]float64),
+		plates: make(map[string][]float64),
+	}
+	im := m.im
+	currentpattern := ""
+	fail := func(cmd *osCommand, err error) {
+		im.skip(cmd.line, "%s: %s", cmd.words[0], err.Error())
+	}
+	for _, cmd := range cmds {
+		words := cmd.words
+		switch words[0] {
+		default:
+			im.skip(cmd.line, "command %s is not translated", words[0])
+		case "wipe", "puts", "source", "recorder", "system", "numberer", "constraints", "integrator", "algorithm", "analysis", "analyze", "test", "loadConst", "timeSeries", "print", "logFile":
+		case "set":
+			if len(words) < 3 {
+				im.skip(cmd.line, "set: not enough arguments")
+				continue
+			}
+			val, err := m.value(words[2])
+			if err != nil || !validParamName(words[1]) {
+				im.skip(cmd.line, "set %s: value is not translated", words[1])
+				continue
+			}
+			m.vars.values[words[1]] = val
+		case "model":
+			for i := 1; i < len(words)-1; i++ {
+				switch words[i] {
+				case "-ndm":
+					val, _ := strconv.ParseInt(words[i+1], 10, 64)
+					m.ndm = int(val)
+				case "-ndf":
+					val, _ := strconv.ParseInt(words[i+1], 10, 64)
+					m.ndf = int(val)
+				}
+			}
+			if m.ndm != 2 && m.ndm != 3 {
+				return im.messages, fmt.Errorf("ReadOpenSees: -ndm %d is not supported", m.ndm)
+			}
+		case "node":
+			if len(words) < 2+m.ndm {
+				im.skip(cmd.line, "node: not enough arguments")
+				continue
+			}
+			vals, err := m.values(words[2 : 2+m.ndm])
+			if err != nil {
+				fail(cmd, err)
+				continue
+			}
+			if len(words) > 2+m.ndm {
+				im.skip(cmd.line, "node %s: options %s are not translated", words[1], strings.Join(words[2+m.ndm:], " "))
+			}
+			m.nodes[m.name(words[1])] = im.node(m.coord(vals))
+		case "fix":
+			if len(words) < 2 {
+				im.skip(cmd.line, "fix: not enough arguments")
+				continue
+			}
+			n := m.node(cmd.line, words[1])
+			if n == nil {
+				continue
+			}
+			for i, ind := range m.dofs() {
+				if i+2 < len(words) && i < m.ndf {
+					n.Conf[ind] = words[i+2] == "1"
+				}
+			}
+		case "geomTransf":
+			if len(words) < 3 {
+				im.skip(cmd.line, "geomTransf: not enough arguments")
+				continue
+			}
+			if words[1] != "Linear" {
+				im.skip(cmd.line, "geomTransf %s is taken as Linear", words[1])
+			}
+			if m.ndm == 2 {
+				m.vecxz[m.name(words[2])] = []float64{0.0, 1.0, 0.0}
+				continue
+			}
+			if len(words) < 6 {
+				im.skip(cmd.line, "geomTransf: vecxz is not given")
+				continue
+			}
+			vals, err := m.values(words[3:6])
+			if err != nil {
+				fail(cmd, err)
+				continue
+			}
+			m.vecxz[m.name(words[2])] = vals
+		case "section":
+			if len(words) < 3 {
+				im.skip(cmd.line, "section: not enough arguments")
+				continue
+			}
+			switch words[1] {
+			case "Elastic":
+				// 3D: E A Iz Iy G J, 2D: E A Iz -> A E G J Iz Iy
+				vals, err := m.values(words[3:])
+				if err != nil || len(vals) < 3 {
+					im.skip(cmd.line, "section Elastic %s is not translated", words[2])
+					continue
+				}
+				sec := []float64{vals[1], vals[0], 0.0, 0.0, vals[2], 0.0}
+				if len(vals) >= 6 {
+					sec = []float64{vals[1], vals[0], vals[4], vals[5], vals[2], vals[3]}
+				}
+				m.sects[m.name(words[2])] = sec
+			case "ElasticMembranePlateSection":
+				// E nu h rho
+				vals, err := m.values(words[3:])
+				if err != nil || len(vals) < 3 {
+					im.skip(cmd.line, "section ElasticMembranePlateSection %s is not translated", words[2])
+					continue
+				}
+				m.plates[m.name(words[2])] = vals
+			default:
+				im.skip(cmd.line, "section %s %s is not translated", words[1], words[2])
+			}
+		case "pattern":
+			if len(words) < 3 {
+				im.skip(cmd.line, "pattern: not enough arguments")
+				continue
+			}
+			currentpattern = m.name(words[2])
+			if pattern == "" {
+				pattern = currentpattern
+			}
+		case "load":
+			if currentpattern != pattern {
+				im.skip(cmd.line, "load of pattern %s is not translated", currentpattern)
+				continue
+			}
+			if len(words) < 2 {
+				im.skip(cmd.line, "load: not enough arguments")
+				continue
+			}
+			n := m.node(cmd.line, words[1])
+			if n == nil {
+				continue
+			}
+			vals, err := m.values(words[2:])
+			if err != nil {
+				fail(cmd, err)
+				continue
+			}
+			for i, ind := range m.dofs() {
+				if i < len(vals) {
+					n.Load[ind] += vals[i]
+				}
+			}
+		case "element":
+			if len(words) < 3 {
+				im.skip(cmd.line, "element: not enough arguments")
+				continue
+			}
+			m.element(cmd)
+		}
+	}
+	if frame.Name == "" {
+		frame.Name = filepath.Base(filename)
+	}
+	return im.messages, nil
+}
+
+func (m *osModel) element(cmd *osCommand) {
+	im := m.im
+	words := cmd.words
+	etype, tag := words[1], words[2]
+	switch etype {
+	default:
+		im.skip(cmd.line, "element %s %s is not translated", etype, tag)
+	case "elasticBeamColumn", "forceBeamColumn", "dispBeamColumn", "truss", "corotTruss":
+		if len(words) < 5 {
+			im.skip(cmd.line, "element %s %s: not enough arguments", etype, tag)
+			return
+		}
+		n1 := m.node(cmd.line, words[3])
+		n2 := m.node(cmd.line, words[4])
+		if n1 == nil || n2 == nil || n1 == n2 {
+			return
+		}
+		args := words[5:]
+		var sec []float64 // A E G J Iz Iy
+		var key, name, transf string
+		switch etype {
+		case "truss", "corotTruss":
+			// A matTag or -section secTag
+			if len(args) >= 2 && args[0] == "-section" {
+				key = "SECTION:" + m.name(args[1])
+				name = "SEC" + m.name(args[1])
+				sec = m.sects[m.name(args[1])]
+			} else if len(args) >= 1 {
+				a, err := m.value(args[0])
+				if err != nil {
+					im.skip(cmd.line, "element %s %s: %s", etype, tag, err.Error())
+					return
+				}
+				key = fmt.Sprintf("TRUSS:%g", a)
+				name = fmt.Sprintf("A%g", a)
+				sec = []float64{a, 0.0, 0.0, 0.0, 0.0, 0.0}
+				im.skip(cmd.line, "element %s %s: material %s is taken as steel", etype, tag, args[len(args)-1])
+			}
+		case "elasticBeamColumn":
+			if vals, err := m.values(args); err == nil && (len(vals) == 7 || len(vals) == 4) {
+				if len(vals) == 7 { // A E G J Iy Iz transfTag
+					sec = []float64{vals[0], vals[1], vals[2], vals[3], vals[5], vals[4]}
+				} else { // A E Iz transfTag
+					sec = []float64{vals[0], vals[1], 0.0, 0.0, vals[2], 0.0}
+				}
+				key = fmt.Sprintf("ELASTIC:%g", sec)
+				name = "ELASTIC"
+				transf = args[len(args)-1]
+			} else if len(args) >= 2 { // secTag transfTag
+				key = "SECTION:" + m.name(args[0])
+				name = "SEC" + m.name(args[0])
+				sec = m.sects[m.name(args[0])]
+				transf = args[1]
+			}
+		default:
+			// transfTag integration or numIntgrPts secTag transfTag
+			if len(args) >= 3 {
+				if _, err := strconv.ParseInt(m.name(args[0]), 10, 64); err == nil && len(args) >= 3 && !strings.HasPrefix(args[1], "\"") {
+					if _, ok := m.sects[m.name(args[1])]; ok {
+						key = "SECTION:" + m.name(args[1])
+						name = "SEC" + m.name(args[1])
+						sec = m.sects[m.name(args[1])]
+						transf = args[2]
+					}
+				}
+				if sec == nil {
+					transf = args[0]
+					for _, a := range args[1:] {
+						if s, ok := m.sects[m.name(a)]; ok {
+							key = "SECTION:" + m.name(a)
+							name = "SEC" + m.name(a)
+							sec = s
+							break
+						}
+					}
+				}
+			}
+			im.skip(cmd.line, "element %s %s is translated as elastic", etype, tag)
+		}
+		if sec == nil {
+			im.skip(cmd.line, "element %s %s: section is not translated", etype, tag)
+			return
+		}
+		d := []float64{n2.Coord[0] - n1.Coord[0], n2.Coord[1] - n1.Coord[1], n2.Coord[2] - n1.Coord[2]}
+		et := lineElemType(d)
+		if strings.HasSuffix(etype, "russ") && et != COLUMN {
+			et = BRACE
+		}
+		s := im.sect(et, key, name, func(s *Sect) {
+			pname := "STEEL"
+			poi := 0.0
+			if sec[1] > 0.0 {
+				pname = fmt.Sprintf("E%g", sec[1])
+				if sec[2] > 0.0 {
+					poi = sec[1]/(2.0*sec[2]) - 1.0
+				}
+			}
+			f := NewFig()
+			f.Prop = im.prop(pname, true, 0.0, sec[1], poi)
+			f.Value["AREA"] = sec[0]
+			f.Value["IXX"] = sec[4]
+			f.Value["IYY"] = sec[5]
+			f.Value["VEN"] = sec[3]
+			s.Figs = append(s.Figs, f)
+		})
+		el := im.frame.AddLineElem(-1, []*Node{n1, n2}, s, et)
+		if v, ok := m.vecxz[m.name(transf)]; ok && transf != "" {
+			// local z is the component of vecxz perpendicular to the element
+			z := Cross(Cross(d, v), d)
+			if math.Sqrt(Dot(z, z, 3)) > 0.0 {
+				if err := im.orient(el, z); err != nil {
+					im.skip(cmd.line, "element %s %s: %s", etype, tag, err.Error())
+				}
+			}
+		} else if transf != "" {
+			im.skip(cmd.line, "element %s %s: geomTransf %s is not defined", etype, tag, transf)
+		}
+	case "ShellMITC4", "ShellDKGQ", "ShellNLDKGQ", "shell":
+		if len(words) < 8 {
+			im.skip(cmd.line, "element %s %s: not enough arguments", etype, tag)
+			return
+		}
+		ns := make([]*Node, 4)
+		pts := make([][]float64, 4)
+		for i := 0; i < 4; i++ {
+			ns[i] = m.node(cmd.line, words[3+i])
+			if ns[i] == nil {
+				return
+			}
+			pts[i] = ns[i].Coord
+		}
+		vals, ok := m.plates[m.name(words[7])]
+		if !ok {
+			im.skip(cmd.line, "element %s %s: section %s is not translated", etype, tag, words[7])
+			return
+		}
+		et := plateElemType(pts)
+		s := im.sect(et, "PLATE:"+m.name(words[7]), "PLATE_"+m.name(words[7]), func(s *Sect) {
+			hiju := 0.0
+			if len(vals) >= 4 {
+				hiju = vals[3] * 9.80665
+			}
+			f := NewFig()
+			f.Prop = im.prop(fmt.Sprintf("E%g", vals[0]), false, hiju, vals[0], vals[1])
+			f.Value["THICK"] = vals[2]
+			s.Figs = append(s.Figs, f)
+		})
+		im.frame.AddPlateElem(-1, ns, s, et)
+	}
+}
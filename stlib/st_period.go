@@ -0,0 +1,310 @@
+package st
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/yofu/complete"
+)
+
+// LoadPeriod is a load case of the frame.
+// Input and Output are the extensions of its arclm input and output files.
+type LoadPeriod struct {
+	Name   string
+	Input  string
+	Output string
+}
+
+// DefaultPeriods are registered to every frame: long-term (L) and seismic (X, Y).
+var DefaultPeriods = []LoadPeriod{
+	{"L", ".inl", ".otl"},
+	{"X", ".ihx", ".ohx"},
+	{"Y", ".ihy", ".ohy"},
+}
+
+// reservedExt are extensions used by other files of the frame.
+var reservedExt = []string{".inp", ".json", ".ifc", ".conf", ".wgt", ".lst", ".kjn", ".rat", ".rat2", ".otp", ".otx", ".oty", ".inc", ".dxf", ".csv", ".otb", ".s2k", ".$2k", ".tcl"}
+
+// periodCompletions are the commands whose $PERIOD completes the registered periods.
+// They are compiled into ExAbbrev or Fig2Abbrev by SetPeriodCompletion.
+var periodCompletions = []struct {
+	abbrev  map[string]*complete.Complete
+	key     string
+	pattern string
+	dict    map[string][]string
+}{
+	{ExAbbrev, "ifc/", ":ifc [period:$PERIOD]", nil},
+	{ExAbbrev, "an/alysis", ":analysis [period:$PERIOD] [all:] [solver:$SOLVER] [eps:_] [memory:_] [nlgeom:] [nlmat:] [step:_] [noinit:] [wait:] [dump:] [result:] [post:_] [sects:_] [comp:_] [z:_] _",
+		map[string][]string{
+			"SOLVER": []string{"LLS", "CRS", "CG", "PCG", "OOC"},
+		}},
+	{ExAbbrev, "diag/nose", ":diagnose [period:$PERIOD] [tol:_]", nil},
+	{Fig2Abbrev, "st/ress", "'stress $ETYPE $PERIOD $NAME",
+		map[string][]string{
+			"ETYPE": []string{"column", "girder", "brace", "wall", "wbrace", "slab", "sbrace"},
+			"NAME":  []string{"n", "qx", "qy", "mz", "mx", "my"},
+		}},
+	{Fig2Abbrev, "def/ormation", "'deformation $PERIOD", nil},
+	{Fig2Abbrev, "def/orme/d/", "'deformed $PERIOD", nil},
+	{Fig2Abbrev, "dis/p", "'disp $PERIOD $DIRECTION",
+		map[string][]string{
+			"DIRECTION": []string{"z", "x", "y"},
+		}},
+}
+
+func init() {
+	names := make([]string, len(DefaultPeriods))
+	for i, p := range DefaultPeriods {
+		names[i] = p.Name
+	}
+	SetPeriodCompletion(names)
+}
+
+// SetPeriodCompletion compiles the completions of $PERIOD with names.
+// It is called whenever the periods of the current frame change.
+func SetPeriodCompletion(names []string) {
+	periods := make([]string, len(names))
+	for i, n := range names {
+		periods[i] = strings.ToLower(n)
+	}
+	for _, pc := range periodCompletions {
+		dict := map[string][]string{"PERIOD": periods}
+		for k, v := range pc.dict {
+			dict[k] = v
+		}
+		pc.abbrev[pc.key] = complete.MustCompile(pc.pattern, dict)
+	}
+}
+
+// NewLoadPeriod creates a LoadPeriod.
+// If input or output is empty, it is derived from the name as ".in"+name and ".ot"+name (e.g. S: .ins, .ots).
+func NewLoadPeriod(name, input, output string) *LoadPeriod {
+	name = strings.ToUpper(name)
+	if input == "" {
+		input = ".in" + strings.ToLower(name)
+	} else if !strings.HasPrefix(input, ".") {
+		input = "." + input
+	}
+	if output == "" {
+		output = ".ot" + strings.ToLower(name)
+	} else if !strings.HasPrefix(output, ".") {
+		output = "." + output
+	}
+	return &LoadPeriod{
+		Name:   name,
+		Input:  input,
+		Output: output,
+	}
+}
+
+func (lp *LoadPeriod) String() string {
+	return fmt.Sprintf("%s: %s %s", lp.Name, lp.Input, lp.Output)
+}
+
+// InpString returns a LOADPERIOD line of .inp file.
+func (lp *LoadPeriod) InpString() string {
+	return fmt.Sprintf("LOADPERIOD %s %s %s\n", lp.Name, lp.Input, lp.Output)
+}
+
+// IsDefault reports whether lp is one of DefaultPeriods.
+func (lp *LoadPeriod) IsDefault() bool {
+	for _, p := range DefaultPeriods {
+		if p == *lp {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultPeriods() []*LoadPeriod {
+	rtn := make([]*LoadPeriod, len(DefaultPeriods))
+	for i, p := range DefaultPeriods {
+		rtn[i] = NewLoadPeriod(p.Name, p.Input, p.Output)
+	}
+	return rtn
+}
+
+// ExtraPeriodNames returns the names of registered periods other than DefaultPeriods.
+func (frame *Frame) ExtraPeriodNames() []string {
+	rtn := make([]string, 0)
+extra:
+	for _, p := range frame.Periods {
+		for _, d := range DefaultPeriods {
+			if p.Name == d.Name {
+				continue extra
+			}
+		}
+		rtn = append(rtn, p.Name)
+	}
+	return rtn
+}
+
+// IsSkipPeriod reports whether elem is skipped in period.
+// L, X and Y have their own flags, and the other periods skip elem only if it is skipped in both X and Y.
+func (elem *Elem) IsSkipPeriod(period string) bool {
+	switch period {
+	case "L":
+		return elem.Skip[0]
+	case "X":
+		return elem.Skip[1]
+	case "Y":
+		return elem.Skip[2]
+	}
+	return elem.Skip[1] && elem.Skip[2]
+}
+
+// Period returns the registered LoadPeriod named name, or nil.
+func (frame *Frame) Period(name string) *LoadPeriod {
+	name = strings.ToUpper(name)
+	for _, p := range frame.Periods {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// PeriodNames returns the names of registered periods in order of registration.
+func (frame *Frame) PeriodNames() []string {
+	rtn := make([]string, len(frame.Periods))
+	for i, p := range frame.Periods {
+		rtn[i] = p.Name
+	}
+	return rtn
+}
+
+// AddPeriod registers a new period.
+// An existing period with the same name is overwritten unless its extensions are used by another period.
+func (frame *Frame) AddPeriod(name, input, output string) (*LoadPeriod, error) {
+	if name == "" || strings.ContainsAny(name, "@+- \t") {
+		return nil, fmt.Errorf("AddPeriod: invalid name \"%s\"", name)
+	}
+	lp := NewLoadPeriod(name, input, output)
+	if lp.Input == lp.Output {
+		return nil, fmt.Errorf("AddPeriod: %s: input and output have the same extension %s", lp.Name, lp.Input)
+	}
+	for _, ext := range reservedExt {
+		if lp.Input == ext || lp.Output == ext {
+			return nil, fmt.Errorf("AddPeriod: %s: extension %s is reserved", lp.Name, ext)
+		}
+	}
+	for _, p := range frame.Periods {
+		if p.Name == lp.Name {
+			continue
+		}
+		for _, ext := range []string{lp.Input, lp.Output} {
+			if ext == p.Input || ext == p.Output {
+				return nil, fmt.Errorf("AddPeriod: %s: extension %s is used by period %s", lp.Name, ext, p.Name)
+			}
+		}
+	}
+	if p := frame.Period(lp.Name); p != nil {
+		p.Input = lp.Input
+		p.Output = lp.Output
+		return p, nil
+	}
+	frame.Periods = append(frame.Periods, lp)
+	return lp, nil
+}
+
+// DeletePeriod removes a period from the registry. DefaultPeriods cannot be removed.
+func (frame *Frame) DeletePeriod(name string) error {
+	name = strings.ToUpper(name)
+	for i, p := range frame.Periods {
+		if p.Name != name {
+			continue
+		}
+		for _, d := range DefaultPeriods {
+			if d.Name == name {
+				return fmt.Errorf("DeletePeriod: period %s cannot be deleted", name)
+			}
+		}
+		frame.Periods = append(frame.Periods[:i], frame.Periods[i+1:]...)
+		return nil
+	}
+	return fmt.Errorf("DeletePeriod: period %s not found", name)
+}
+
+// PeriodOfFile returns the period of an arclm input/output file judging from its extension.
+// Unregistered extensions are taken as the period name itself (e.g. .abc: ABC).
+func (frame *Frame) PeriodOfFile(filename string) string {
+	ext := filepath.Ext(filename)
+	for _, p := range frame.Periods {
+		if ext == p.Input || ext == p.Output {
+			return p.Name
+		}
+	}
+	return strings.ToUpper(strings.TrimPrefix(ext, "."))
+}
+
+// IsInputFile reports whether filename has the input extension of a registered period.
+func (frame *Frame) IsInputFile(filename string) bool {
+	ext := filepath.Ext(filename)
+	for _, p := range frame.Periods {
+		if ext == p.Input {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOutputFile reports whether filename has the output extension of a registered period.
+func (frame *Frame) IsOutputFile(filename string) bool {
+	ext := filepath.Ext(filename)
+	for _, p := range frame.Periods {
+		if ext == p.Output {
+			return true
+		}
+	}
+	return false
+}
+
+// InputExts returns the input extensions of registered periods.
+func (frame *Frame) InputExts() []string {
+	rtn := make([]string, len(frame.Periods))
+	for i, p := range frame.Periods {
+		rtn[i] = p.Input
+	}
+	return rtn
+}
+
+// OutputExts returns the output extensions of registered periods.
+func (frame *Frame) OutputExts() []string {
+	rtn := make([]string, len(frame.Periods))
+	for i, p := range frame.Periods {
+		rtn[i] = p.Output
+	}
+	return rtn
+}
+
+// ArclmPeriods returns the registered periods with their arclm frames.
+// It returns an error if any of them hasn't been extracted or read.
+func (frame *Frame) ArclmPeriods() ([]*LoadPeriod, error) {
+	rtn := make([]*LoadPeriod, 0, len(frame.Periods))
+	for _, p := range frame.Periods {
+		if af, ok := frame.Arclms[p.Name]; !ok || af == nil {
+			return nil, fmt.Errorf("ArclmPeriods: period %s has no arclm frame", p.Name)
+		}
+		rtn = append(rtn, p)
+	}
+	return rtn, nil
+}
+
+// NextPeriod returns the period num steps away from per in the registry.
+// The sign prefix of per ("-X") is kept. If per isn't registered, it returns per.
+func (frame *Frame) NextPeriod(per string, num int) string {
+	sign := ""
+	if strings.HasPrefix(per, "-") || strings.HasPrefix(per, "+") {
+		sign = per[:1]
+		per = per[1:]
+	}
+	size := len(frame.Periods)
+	for i, p := range frame.Periods {
+		if p.Name == strings.ToUpper(per) {
+			ind := ((i+num)%size + size) % size
+			return sign + frame.Periods[ind].Name
+		}
+	}
+	return sign + per
+}
@@ -242,6 +242,14 @@ var (
 	SN490T40 = Steel{"SN490T40", 3.0, 5.0, 2100.0, 0.3, 7.8}
 	BCR295   = Steel{"BCR295", 3.0, 4.0, 2100.0, 0.3, 7.8}
 	BCR365   = Steel{"BCR365", 3.7, 5.0, 2100.0, 0.3, 7.8}
+	BCP235   = Steel{"BCP235", 2.4, 4.0, 2100.0, 0.3, 7.8}
+	BCP325   = Steel{"BCP325", 3.3, 5.0, 2100.0, 0.3, 7.8}
+	SS400    = Steel{"SS400", 2.4, 4.0, 2100.0, 0.3, 7.8}
+	SM490    = Steel{"SM490", 3.3, 5.0, 2100.0, 0.3, 7.8}
+	STKR400  = Steel{"STKR400", 2.4, 4.0, 2100.0, 0.3, 7.8}
+	STKR490  = Steel{"STKR490", 3.3, 5.0, 2100.0, 0.3, 7.8}
+	STK400   = Steel{"STK400", 2.4, 4.0, 2100.0, 0.3, 7.8}
+	STK490   = Steel{"STK490", 3.3, 5.0, 2100.0, 0.3, 7.8}
 	HT600    = Steel{"HT600", 6.0, 8.0, 2100.0, 0.3, 7.8}
 	HT700    = Steel{"HT700", 7.0, 9.0, 2100.0, 0.3, 7.8}
 
@@ -321,6 +329,22 @@ func materialname(name string) (Material, error) {
 		return BCR295, nil
 	case "BCR365":
 		return BCR365, nil
+	case "BCP235":
+		return BCP235, nil
+	case "BCP325":
+		return BCP325, nil
+	case "SS400":
+		return SS400, nil
+	case "SM490":
+		return SM490, nil
+	case "STKR400":
+		return STKR400, nil
+	case "STKR490":
+		return STKR490, nil
+	case "STK400":
+		return STK400, nil
+	case "STK490":
+		return STK490, nil
 	case "HT600":
 		return HT600, nil
 	case "HT700":
@@ -645,14 +669,7 @@ func (sc *SColumn) String() string {
 	return rtn.String()
 }
 func (sc *SColumn) Factor(p string) float64 {
-	switch p {
-	default:
-		return 0.0
-	case "L", "ML", "MS":
-		return 1.0
-	case "X", "Y", "S":
-		return 1.5
-	}
+	return DefaultDesignCode.AllowableFactor(ALLOW_STEEL, p)
 }
 func (sc *SColumn) Lk(length float64, strong bool) float64 {
 	var ind int
@@ -697,6 +714,14 @@ func (sc *SColumn) Fc(cond *Condition) float64 {
 		}
 		lambda_x = lambda
 		lambda_y = lambda
+	} else if gs, ok := sc.Shape.(GeneralShape); ok {
+		if lx > ly {
+			lambda = lx / math.Sqrt(gs.Imin()/gs.A())
+		} else {
+			lambda = ly / math.Sqrt(gs.Imin()/gs.A())
+		}
+		lambda_x = lambda
+		lambda_y = lambda
 	} else {
 		lambda_x = lx / math.Sqrt(sc.Ix()/sc.A())
 		lambda_y = ly / math.Sqrt(sc.Iy()/sc.A())
@@ -713,7 +738,7 @@ func (sc *SColumn) Fc(cond *Condition) float64 {
 	} else {
 		rtn = 0.277 * sc.F / (val * val)
 	}
-	rtn *= sc.Factor(cond.Period)
+	rtn *= allowableFactor(cond, ALLOW_STEEL)
 	if cond.Verbose {
 		cond.Buffer.WriteString(fmt.Sprintf("#     座屈長さ[cm]: Lkx=%.3f, Lky=%.3f\n", lx, ly))
 		check := ""
@@ -726,19 +751,23 @@ func (sc *SColumn) Fc(cond *Condition) float64 {
 	return rtn
 }
 func (sc *SColumn) Ft(cond *Condition) float64 {
-	return sc.F / 1.5 * sc.Factor(cond.Period)
+	return sc.F / 1.5 * allowableFactor(cond, ALLOW_STEEL)
 }
 func (sc *SColumn) Fs(cond *Condition) float64 {
-	return sc.F / (1.5 * math.Sqrt(3)) * sc.Factor(cond.Period)
+	return sc.F / (1.5 * math.Sqrt(3)) * allowableFactor(cond, ALLOW_STEEL)
 }
 func (sc *SColumn) Fb(cond *Condition) float64 {
-	l := sc.Lb(cond.Length, cond.Strong)
+	l := sc.Lb(cond.UnbracedLength(cond.Strong), cond.Strong)
+	c := cond.MomentGradientFactor()
 	if cond.Verbose {
 		cond.Buffer.WriteString(fmt.Sprintf("#     横座屈長さ[cm]: Lb=%.3f\n", l))
 	}
 	var rtn float64
 	fbnew := func() float64 {
-		me := sc.Me(l, 1.0)
+		me := sc.Me(l, c)
+		if cond.Verbose {
+			cond.Buffer.WriteString(fmt.Sprintf("#     モーメント勾配による補正係数: C=%.3f\n", c))
+		}
 		my := sc.My(cond)
 		lambda_b := math.Sqrt(my / me)
 		nu := 1.5 + math.Pow(lambda_b/E_LAMBDA_B, 2.0)/1.5
@@ -798,7 +827,7 @@ func (sc *SColumn) Fb(cond *Condition) float64 {
 			rtn = sc.F / 1.5
 		}
 	}
-	rtn *= sc.Factor(cond.Period)
+	rtn *= allowableFactor(cond, ALLOW_STEEL)
 	if cond.Verbose {
 		if cond.Strong {
 			cond.Buffer.WriteString(fmt.Sprintf("#     許容曲げ応力度(Mx): Fb=%.3f [tf/cm2]\n", rtn))
@@ -809,16 +838,7 @@ func (sc *SColumn) Fb(cond *Condition) float64 {
 	return rtn
 }
 func (sc *SColumn) Na(cond *Condition) float64 {
-	switch sc.Shape.(type) {
-	case SAREA:
-		return sc.Ft(cond) * sc.A() * sc.multi
-	default:
-		if cond.Compression {
-			return sc.Fc(cond) * sc.A() * sc.multi
-		} else {
-			return sc.Ft(cond) * sc.A() * sc.multi
-		}
-	}
+	return steelCode(cond).SteelNa(sc, cond)
 }
 func (sc *SColumn) Ny() float64 {
 	return sc.F * sc.A()
@@ -877,12 +897,7 @@ func (sc *SColumn) Nc2(cond *Condition) float64 {
 	}
 }
 func (sc *SColumn) Qa(cond *Condition) float64 {
-	f := sc.Fs(cond)
-	if cond.Strong { // for Qy
-		return f * sc.Asy() * sc.multi
-	} else { // for Qx
-		return f * sc.Asx() * sc.multi
-	}
+	return steelCode(cond).SteelQa(sc, cond)
 }
 func (sc *SColumn) Me(length, Cb float64) float64 {
 	E := sc.EL()
@@ -905,15 +920,10 @@ func (sc *SColumn) My(cond *Condition) float64 {
 	}
 }
 func (sc *SColumn) Ma(cond *Condition) float64 {
-	f := sc.Fb(cond)
-	if cond.Strong {
-		return f * sc.Zx() * 0.01 * sc.multi // [tfm]
-	} else {
-		return f * sc.Zy() * 0.01 * sc.multi // [tfm]
-	}
+	return steelCode(cond).SteelMa(sc, cond)
 }
 func (sc *SColumn) Mza(cond *Condition) float64 {
-	return sc.Fs(cond) * sc.Torsion() * 0.01 * sc.multi // [tfm]
+	return steelCode(cond).SteelMza(sc, cond)
 }
 
 func (sc *SColumn) Vertices() [][]float64 {
@@ -928,10 +938,11 @@ func (sc *SColumn) Amount() Amount {
 
 type HKYOU struct {
 	H, B, Tw, Tf float64
+	Catalog      *CatalogEntry
 }
 
 func NewHKYOU(lis []string) (HKYOU, error) {
-	hk := HKYOU{0.0, 0.0, 0.0, 0.0}
+	hk := HKYOU{0.0, 0.0, 0.0, 0.0, nil}
 	if len(lis) < 4 {
 		return hk, NotEnoughArgs("NewHKYOU")
 	}
@@ -960,12 +971,18 @@ func NewHKYOU(lis []string) (HKYOU, error) {
 	return hk, nil
 }
 func (hk HKYOU) String() string {
+	if hk.Catalog != nil {
+		return hk.Catalog.Designation
+	}
 	return fmt.Sprintf("HKYOU %5.1f %5.1f %4.2f %4.1f", hk.H, hk.B, hk.Tw, hk.Tf)
 }
 func (hk HKYOU) Description() string {
 	return fmt.Sprintf("H-%dx%dx%dx%d(KYOU)[mm]", int(hk.H*10), int(hk.B*10), int(hk.Tw*10), int(hk.Tf*10))
 }
 func (hk HKYOU) A() float64 {
+	if hk.Catalog != nil {
+		return hk.Catalog.A
+	}
 	return hk.H*hk.B - (hk.H-2*hk.Tf)*(hk.B-hk.Tw)
 }
 func (hk HKYOU) Asx() float64 {
@@ -975,15 +992,27 @@ func (hk HKYOU) Asy() float64 {
 	return (hk.H - 2*hk.Tf) * hk.Tw
 }
 func (hk HKYOU) Ix() float64 {
+	if hk.Catalog != nil {
+		return hk.Catalog.Ix
+	}
 	return (hk.B*math.Pow(hk.H, 3.0) - (hk.B-hk.Tw)*math.Pow(hk.H-2*hk.Tf, 3.0)) / 12.0
 }
 func (hk HKYOU) Iy() float64 {
+	if hk.Catalog != nil {
+		return hk.Catalog.Iy
+	}
 	return 2.0*hk.Tf*math.Pow(hk.B, 3.0)/12.0 + (hk.H-2*hk.Tf)*math.Pow(hk.Tw, 3.0)/12.0
 }
 func (hk HKYOU) J() float64 {
+	if hk.Catalog != nil {
+		return hk.Catalog.J
+	}
 	return 2.0*hk.B*math.Pow(hk.Tf, 3.0)/3.0 + (hk.H-2*hk.Tf)*math.Pow(hk.Tw, 3.0)/3.0
 }
 func (hk HKYOU) Iw() float64 {
+	if hk.Catalog != nil {
+		return hk.Catalog.Iw
+	}
 	return math.Pow(hk.H, 2.0) * math.Pow(hk.B, 3.0) * hk.Tf / 24.0
 }
 func (hk HKYOU) Torsion() float64 {
@@ -994,15 +1023,27 @@ func (hk HKYOU) Torsion() float64 {
 	}
 }
 func (hk HKYOU) Zx() float64 {
+	if hk.Catalog != nil {
+		return hk.Catalog.Zx
+	}
 	return hk.Ix() / hk.H * 2.0
 }
 func (hk HKYOU) Zy() float64 {
+	if hk.Catalog != nil {
+		return hk.Catalog.Zy
+	}
 	return hk.Iy() / hk.B * 2.0
 }
 func (hk HKYOU) Zpx() float64 {
+	if hk.Catalog != nil {
+		return hk.Catalog.Zpx
+	}
 	return (hk.B*math.Pow(hk.H, 2.0) - (hk.B-hk.Tw)*math.Pow(hk.H-2*hk.Tf, 2.0)) / 4.0
 }
 func (hk HKYOU) Zpy() float64 {
+	if hk.Catalog != nil {
+		return hk.Catalog.Zpy
+	}
 	return 2.0*hk.Tf*math.Pow(hk.B, 2.0)/4.0 + (hk.H-2*hk.Tf)*math.Pow(hk.Tw, 2.0)/4.0
 }
 func (hk HKYOU) BT_ratio() []float64 {
@@ -1229,7 +1270,7 @@ type CROSS struct {
 
 func NewCROSS(lis []string) (CROSS, error) {
 	cr := CROSS{
-		Hkyou: HKYOU{0.0, 0.0, 0.0, 0.0},
+		Hkyou: HKYOU{0.0, 0.0, 0.0, 0.0, nil},
 		Hweak: HWEAK{0.0, 0.0, 0.0, 0.0},
 	}
 	if len(lis) < 8 {
@@ -1345,10 +1386,11 @@ func (cr CROSS) Breadth(strong bool) float64 {
 
 type RPIPE struct {
 	H, B, Tw, Tf float64
+	Catalog      *CatalogEntry
 }
 
 func NewRPIPE(lis []string) (RPIPE, error) {
-	rp := RPIPE{0.0, 0.0, 0.0, 0.0}
+	rp := RPIPE{0.0, 0.0, 0.0, 0.0, nil}
 	if len(lis) < 4 {
 		return rp, NotEnoughArgs("NewRPIPE")
 	}
@@ -1377,12 +1419,18 @@ func NewRPIPE(lis []string) (RPIPE, error) {
 	return rp, nil
 }
 func (rp RPIPE) String() string {
+	if rp.Catalog != nil {
+		return rp.Catalog.Designation
+	}
 	return fmt.Sprintf("RPIPE %5.1f %5.1f %4.2f %4.2f", rp.H, rp.B, rp.Tw, rp.Tf)
 }
 func (rp RPIPE) Description() string {
 	return fmt.Sprintf("BOX-%dx%dx%dx%d[mm]", int(rp.H*10), int(rp.B*10), int(rp.Tw*10), int(rp.Tf*10))
 }
 func (rp RPIPE) A() float64 {
+	if rp.Catalog != nil {
+		return rp.Catalog.A
+	}
 	return rp.H*rp.B - (rp.H-2*rp.Tf)*(rp.B-2*rp.Tw)
 }
 func (rp RPIPE) Asx() float64 {
@@ -1392,15 +1440,27 @@ func (rp RPIPE) Asy() float64 {
 	return 2.0 * (rp.H - 2*rp.Tf) * rp.Tw
 }
 func (rp RPIPE) Ix() float64 {
+	if rp.Catalog != nil {
+		return rp.Catalog.Ix
+	}
 	return (rp.B*math.Pow(rp.H, 3.0) - (rp.B-2*rp.Tw)*math.Pow(rp.H-2*rp.Tf, 3.0)) / 12.0
 }
 func (rp RPIPE) Iy() float64 {
+	if rp.Catalog != nil {
+		return rp.Catalog.Iy
+	}
 	return (rp.H*math.Pow(rp.B, 3.0) - (rp.H-2*rp.Tf)*math.Pow(rp.B-2*rp.Tw, 3.0)) / 12.0
 }
 func (rp RPIPE) J() float64 {
+	if rp.Catalog != nil {
+		return rp.Catalog.J
+	}
 	return 4.0 * math.Pow((rp.H-rp.Tf)*(rp.B-rp.Tw), 2.0) / (2.0 * ((rp.B-rp.Tw)/rp.Tf + (rp.H-rp.Tf)/rp.Tw))
 }
 func (rp RPIPE) Iw() float64 {
+	if rp.Catalog != nil {
+		return rp.Catalog.Iw
+	}
 	return 0.0
 }
 func (rp RPIPE) Torsion() float64 {
@@ -1411,15 +1471,27 @@ func (rp RPIPE) Torsion() float64 {
 	}
 }
 func (rp RPIPE) Zx() float64 {
+	if rp.Catalog != nil {
+		return rp.Catalog.Zx
+	}
 	return rp.Ix() / rp.H * 2.0
 }
 func (rp RPIPE) Zy() float64 {
+	if rp.Catalog != nil {
+		return rp.Catalog.Zy
+	}
 	return rp.Iy() / rp.B * 2.0
 }
 func (rp RPIPE) Zpx() float64 {
+	if rp.Catalog != nil {
+		return rp.Catalog.Zpx
+	}
 	return (rp.B*math.Pow(rp.H, 2.0) - (rp.B-2*rp.Tw)*math.Pow(rp.H-2*rp.Tf, 2.0))/4.0
 }
 func (rp RPIPE) Zpy() float64 {
+	if rp.Catalog != nil {
+		return rp.Catalog.Zpy
+	}
 	return (rp.H*math.Pow(rp.B, 2.0) - (rp.H-2*rp.Tf)*math.Pow(rp.B-2*rp.Tw, 2.0))/4.0
 }
 func (rp RPIPE) BT_ratio() []float64 {
@@ -1471,11 +1543,12 @@ func (rp RPIPE) Breadth(strong bool) float64 {
 }
 
 type CPIPE struct {
-	D, T float64
+	D, T    float64
+	Catalog *CatalogEntry
 }
 
 func NewCPIPE(lis []string) (CPIPE, error) {
-	cp := CPIPE{0.0, 0.0}
+	cp := CPIPE{0.0, 0.0, nil}
 	if len(lis) < 2 {
 		return cp, NotEnoughArgs("NewCPIPE")
 	}
@@ -1494,12 +1567,18 @@ func NewCPIPE(lis []string) (CPIPE, error) {
 	return cp, nil
 }
 func (cp CPIPE) String() string {
+	if cp.Catalog != nil {
+		return cp.Catalog.Designation
+	}
 	return fmt.Sprintf("CPIPE %5.2f %5.2f", cp.D, cp.T)
 }
 func (cp CPIPE) Description() string {
 	return fmt.Sprintf("PIPE-%dx%d[mm]", int(cp.D*10), int(cp.T*10))
 }
 func (cp CPIPE) A() float64 {
+	if cp.Catalog != nil {
+		return cp.Catalog.A
+	}
 	return 0.25 * math.Pi * (math.Pow(cp.D, 2.0) - math.Pow(cp.D-2*cp.T, 2.0))
 }
 func (cp CPIPE) Asx() float64 {
@@ -1509,30 +1588,54 @@ func (cp CPIPE) Asy() float64 {
 	return 0.5 * cp.A()
 }
 func (cp CPIPE) Ix() float64 {
+	if cp.Catalog != nil {
+		return cp.Catalog.Ix
+	}
 	return 0.015625 * math.Pi * (math.Pow(cp.D, 4.0) - math.Pow(cp.D-2*cp.T, 4.0))
 }
 func (cp CPIPE) Iy() float64 {
+	if cp.Catalog != nil {
+		return cp.Catalog.Iy
+	}
 	return 0.015625 * math.Pi * (math.Pow(cp.D, 4.0) - math.Pow(cp.D-2*cp.T, 4.0))
 }
 func (cp CPIPE) J() float64 {
+	if cp.Catalog != nil {
+		return cp.Catalog.J
+	}
 	return 4.0 * math.Pow(0.25*math.Pi*math.Pow(cp.D-cp.T, 2.0), 2.0) * cp.T / (math.Pi * (cp.D - cp.T))
 }
 func (cp CPIPE) Iw() float64 {
+	if cp.Catalog != nil {
+		return cp.Catalog.Iw
+	}
 	return 0.0
 }
 func (cp CPIPE) Torsion() float64 {
 	return 2.0 * 0.25 * math.Pi * math.Pow(cp.D-cp.T, 2.0) * cp.T
 }
 func (cp CPIPE) Zx() float64 {
+	if cp.Catalog != nil {
+		return cp.Catalog.Zx
+	}
 	return cp.Ix() / cp.D * 2.0
 }
 func (cp CPIPE) Zy() float64 {
+	if cp.Catalog != nil {
+		return cp.Catalog.Zy
+	}
 	return cp.Iy() / cp.D * 2.0
 }
 func (cp CPIPE) Zpx() float64 {
+	if cp.Catalog != nil {
+		return cp.Catalog.Zpx
+	}
 	return (math.Pow(cp.D, 3.0) - math.Pow(cp.D-2*cp.T, 3.0))/6.0
 }
 func (cp CPIPE) Zpy() float64 {
+	if cp.Catalog != nil {
+		return cp.Catalog.Zpy
+	}
 	return (math.Pow(cp.D, 3.0) - math.Pow(cp.D-2*cp.T, 3.0))/6.0
 }
 func (cp CPIPE) BT_ratio() []float64 {
@@ -1571,10 +1674,11 @@ func (cp CPIPE) Breadth(strong bool) float64 {
 
 type TKYOU struct {
 	H, B, Tw, Tf float64
+	Catalog      *CatalogEntry
 }
 
 func NewTKYOU(lis []string) (TKYOU, error) {
-	tk := TKYOU{0.0, 0.0, 0.0, 0.0}
+	tk := TKYOU{0.0, 0.0, 0.0, 0.0, nil}
 	if len(lis) < 4 {
 		return tk, NotEnoughArgs("NewTKYOU")
 	}
@@ -1603,12 +1707,18 @@ func NewTKYOU(lis []string) (TKYOU, error) {
 	return tk, nil
 }
 func (tk TKYOU) String() string {
+	if tk.Catalog != nil {
+		return tk.Catalog.Designation
+	}
 	return fmt.Sprintf("TKYOU %5.1f %5.1f %4.2f %4.1f", tk.H, tk.B, tk.Tw, tk.Tf)
 }
 func (tk TKYOU) Description() string {
 	return fmt.Sprintf("T-%dx%dx%dx%d(KYOU)[mm]", int(tk.H*10), int(tk.B*10), int(tk.Tw*10), int(tk.Tf*10))
 }
 func (tk TKYOU) A() float64 {
+	if tk.Catalog != nil {
+		return tk.Catalog.A
+	}
 	return tk.H*tk.B - (tk.H-tk.Tf)*(tk.B-tk.Tw)
 }
 func (tk TKYOU) Asx() float64 {
@@ -1621,16 +1731,28 @@ func (tk TKYOU) Cy() float64 {
 	return ((tk.B-tk.Tw)*tk.Tf*0.5*tk.Tf + tk.H*tk.Tw*0.5*tk.H) / tk.A()
 }
 func (tk TKYOU) Ix() float64 {
+	if tk.Catalog != nil {
+		return tk.Catalog.Ix
+	}
 	cy := tk.Cy()
 	return (tk.B-tk.Tw)*math.Pow(tk.Tf, 3.0)/12.0 + tk.Tw*math.Pow(tk.H, 3.0)/12.0 + (tk.B-tk.Tw)*tk.Tf*math.Pow(cy-0.5*tk.Tf, 2.0) + tk.H*tk.Tw*math.Pow(0.5*tk.H-cy, 2.0)
 }
 func (tk TKYOU) Iy() float64 {
+	if tk.Catalog != nil {
+		return tk.Catalog.Iy
+	}
 	return tk.Tf*math.Pow(tk.B, 3.0)/12.0 + (tk.H-tk.Tf)*math.Pow(tk.Tw, 3.0)/12.0
 }
 func (tk TKYOU) J() float64 {
+	if tk.Catalog != nil {
+		return tk.Catalog.J
+	}
 	return tk.B*math.Pow(tk.Tf, 3.0)/3.0 + (tk.H-tk.Tf)*math.Pow(tk.Tw, 3.0)/3.0
 }
 func (tk TKYOU) Iw() float64 {
+	if tk.Catalog != nil {
+		return tk.Catalog.Iw
+	}
 	return 0.0
 }
 func (tk TKYOU) Torsion() float64 {
@@ -1641,6 +1763,9 @@ func (tk TKYOU) Torsion() float64 {
 	}
 }
 func (tk TKYOU) Zx() float64 {
+	if tk.Catalog != nil {
+		return tk.Catalog.Zx
+	}
 	cy := tk.Cy()
 	if cy >= tk.H*0.5 {
 		return tk.Ix() / cy
@@ -1649,13 +1774,22 @@ func (tk TKYOU) Zx() float64 {
 	}
 }
 func (tk TKYOU) Zy() float64 {
+	if tk.Catalog != nil {
+		return tk.Catalog.Zy
+	}
 	return tk.Iy() / tk.B * 2.0
 }
 func (tk TKYOU) Zpx() float64 {
+	if tk.Catalog != nil {
+		return tk.Catalog.Zpx
+	}
 	cy := tk.Cy()
 	return (tk.B-tk.Tw)*math.Pow(tk.Tf, 2.0)/4.0 + tk.Tw*math.Pow(tk.H, 2.0)/4.0 + (tk.B-tk.Tw)*tk.Tf*math.Abs(cy-0.5*tk.Tf) + tk.H*tk.Tw*math.Abs(0.5*tk.H-cy)
 }
 func (tk TKYOU) Zpy() float64 {
+	if tk.Catalog != nil {
+		return tk.Catalog.Zpy
+	}
 	return tk.Tf*math.Pow(tk.B, 2.0)/4.0 + (tk.H-tk.Tf)*math.Pow(tk.Tw, 2.0)/4.0
 }
 func (tk TKYOU) BT_ratio() []float64 {
@@ -1875,10 +2009,11 @@ func (tk TWEAK) Breadth(strong bool) float64 {
 
 type CKYOU struct {
 	H, B, Tw, Tf float64
+	Catalog      *CatalogEntry
 }
 
 func NewCKYOU(lis []string) (CKYOU, error) {
-	ck := CKYOU{0.0, 0.0, 0.0, 0.0}
+	ck := CKYOU{0.0, 0.0, 0.0, 0.0, nil}
 	if len(lis) < 4 {
 		return ck, NotEnoughArgs("NewCKYOU")
 	}
@@ -1907,12 +2042,18 @@ func NewCKYOU(lis []string) (CKYOU, error) {
 	return ck, nil
 }
 func (ck CKYOU) String() string {
+	if ck.Catalog != nil {
+		return ck.Catalog.Designation
+	}
 	return fmt.Sprintf("CKYOU %5.1f %5.1f %4.2f %4.2f", ck.H, ck.B, ck.Tw, ck.Tf)
 }
 func (ck CKYOU) Description() string {
 	return fmt.Sprintf("C-%dx%dx%dx%d(KYOU)[mm]", int(ck.H*10), int(ck.B*10), int(ck.Tw*10), int(ck.Tf*10))
 }
 func (ck CKYOU) A() float64 {
+	if ck.Catalog != nil {
+		return ck.Catalog.A
+	}
 	return ck.H*ck.B - (ck.H-2*ck.Tf)*(ck.B-ck.Tw)
 }
 func (ck CKYOU) Asx() float64 {
@@ -1922,19 +2063,31 @@ func (ck CKYOU) Asy() float64 {
 	return (ck.H - 2*ck.Tf) * ck.Tw
 }
 func (ck CKYOU) Ix() float64 {
+	if ck.Catalog != nil {
+		return ck.Catalog.Ix
+	}
 	return (ck.B*math.Pow(ck.H, 3.0) - (ck.B-ck.Tw)*math.Pow(ck.H-2*ck.Tf, 3.0)) / 12.0
 }
 func (ck CKYOU) Cx() float64 {
 	return (2.0*ck.B*ck.Tf*0.5*ck.B + (ck.H-2*ck.Tf)*ck.Tw*0.5*ck.Tw) / ck.A()
 }
 func (ck CKYOU) Iy() float64 {
+	if ck.Catalog != nil {
+		return ck.Catalog.Iy
+	}
 	cx := ck.Cx()
 	return 2.0*ck.Tf*math.Pow(ck.B, 3.0)/12.0 + (ck.H-2*ck.Tf)*math.Pow(ck.Tw, 3.0)/12.0 + 2.0*ck.B*ck.Tf*math.Pow(0.5*ck.B-cx, 2.0) + (ck.H-2*ck.Tf)*ck.Tw*math.Pow(cx-0.5*ck.Tw, 2.0)
 }
 func (ck CKYOU) J() float64 {
+	if ck.Catalog != nil {
+		return ck.Catalog.J
+	}
 	return 2.0*ck.B*math.Pow(ck.Tf, 3.0)/3.0 + (ck.H-2*ck.Tf)*math.Pow(ck.Tw, 3.0)/3.0
 }
 func (ck CKYOU) Iw() float64 {
+	if ck.Catalog != nil {
+		return ck.Catalog.Iw
+	}
 	return math.Pow(ck.H, 2.0) * math.Pow(ck.B, 3.0) * ck.Tf * (3.0*ck.B*ck.Tf + 2.0*ck.H*ck.Tw) / (12.0 * (6.0*ck.B*ck.Tf + ck.H*ck.Tw))
 }
 func (ck CKYOU) Torsion() float64 {
@@ -1945,9 +2098,15 @@ func (ck CKYOU) Torsion() float64 {
 	}
 }
 func (ck CKYOU) Zx() float64 {
+	if ck.Catalog != nil {
+		return ck.Catalog.Zx
+	}
 	return ck.Ix() / ck.H * 2.0
 }
 func (ck CKYOU) Zy() float64 {
+	if ck.Catalog != nil {
+		return ck.Catalog.Zy
+	}
 	cx := ck.Cx()
 	if cx >= ck.B*0.5 {
 		return ck.Iy() / cx
@@ -1956,9 +2115,15 @@ func (ck CKYOU) Zy() float64 {
 	}
 }
 func (ck CKYOU) Zpx() float64 {
+	if ck.Catalog != nil {
+		return ck.Catalog.Zpx
+	}
 	return (ck.B*math.Pow(ck.H, 2.0) - (ck.B-ck.Tw)*math.Pow(ck.H-2*ck.Tf, 2.0)) / 4.0
 }
 func (ck CKYOU) Zpy() float64 {
+	if ck.Catalog != nil {
+		return ck.Catalog.Zpy
+	}
 	cx := ck.Cx()
 	return 2.0*ck.Tf*math.Pow(ck.B, 2.0)/4.0 + (ck.H-2*ck.Tf)*math.Pow(ck.Tw, 2.0)/4.0 + 2.0*ck.B*ck.Tf*math.Abs(0.5*ck.B-cx) + (ck.H-2*ck.Tf)*ck.Tw*math.Abs(cx-0.5*ck.Tw)
 }
@@ -2306,10 +2471,11 @@ func (pl PLATE) Breadth(strong bool) float64 {
 
 type ANGLE struct {
 	H, B, Tw, Tf float64
+	Catalog      *CatalogEntry
 }
 
 func NewANGLE(lis []string) (ANGLE, error) {
-	an := ANGLE{0.0, 0.0, 0.0, 0.0}
+	an := ANGLE{0.0, 0.0, 0.0, 0.0, nil}
 	if len(lis) < 4 {
 		return an, NotEnoughArgs("NewANGLE")
 	}
@@ -2338,12 +2504,18 @@ func NewANGLE(lis []string) (ANGLE, error) {
 	return an, nil
 }
 func (an ANGLE) String() string {
+	if an.Catalog != nil {
+		return an.Catalog.Designation
+	}
 	return fmt.Sprintf("ANGLE %5.1f %5.1f %4.1f %4.1f", an.H, an.B, an.Tw, an.Tf)
 }
 func (an ANGLE) Description() string {
 	return fmt.Sprintf("L-%dx%dx%dx%d[mm]", int(an.H*10), int(an.B*10), int(an.Tw*10), int(an.Tf*10))
 }
 func (an ANGLE) A() float64 {
+	if an.Catalog != nil {
+		return an.Catalog.A
+	}
 	return an.H*an.Tw + an.B*an.Tf - an.Tw*an.Tf
 }
 func (an ANGLE) Asx() float64 {
@@ -2359,14 +2531,23 @@ func (an ANGLE) Cy() float64 {
 	return (an.H*an.Tw*0.5*an.H + (an.B-an.Tw)*an.Tf*0.5*an.Tf) / an.A()
 }
 func (an ANGLE) Ix() float64 {
+	if an.Catalog != nil {
+		return an.Catalog.Ix
+	}
 	cy := an.Cy()
 	return (an.B-an.Tw)*math.Pow(an.Tf, 3.0)/12.0 + an.Tw*math.Pow(an.H, 3.0)/12.0 + (an.B-an.Tw)*an.Tf*math.Pow(cy-0.5*an.Tf, 2.0) + an.H*an.Tw*math.Pow(0.5*an.H-cy, 2.0)
 }
 func (an ANGLE) Iy() float64 {
+	if an.Catalog != nil {
+		return an.Catalog.Iy
+	}
 	cx := an.Cx()
 	return (an.H-an.Tf)*math.Pow(an.Tw, 3.0)/12.0 + an.Tf*math.Pow(an.B, 3.0)/12.0 + (an.H-an.Tf)*an.Tw*math.Pow(cx-0.5*an.Tw, 2.0) + an.B*an.Tf*math.Pow(0.5*an.B-cx, 2.0)
 }
 func (an ANGLE) Imin() float64 {
+	if an.Catalog != nil {
+		return an.Catalog.Imin
+	}
 	cx := an.Cx()
 	cy := an.Cy()
 	Ix := an.Ix()
@@ -2387,9 +2568,15 @@ func (an ANGLE) Imin() float64 {
 	}
 }
 func (an ANGLE) J() float64 {
+	if an.Catalog != nil {
+		return an.Catalog.J
+	}
 	return an.B*math.Pow(an.Tf, 3.0)/3.0 + (an.H-an.Tf)*math.Pow(an.Tw, 3.0)/3.0
 }
 func (an ANGLE) Iw() float64 {
+	if an.Catalog != nil {
+		return an.Catalog.Iw
+	}
 	return 0.0
 }
 func (an ANGLE) Torsion() float64 {
@@ -2400,6 +2587,9 @@ func (an ANGLE) Torsion() float64 {
 	}
 }
 func (an ANGLE) Zx() float64 {
+	if an.Catalog != nil {
+		return an.Catalog.Zx
+	}
 	cy := an.Cy()
 	if cy >= an.H*0.5 {
 		return an.Ix() / cy
@@ -2408,6 +2598,9 @@ func (an ANGLE) Zx() float64 {
 	}
 }
 func (an ANGLE) Zy() float64 {
+	if an.Catalog != nil {
+		return an.Catalog.Zy
+	}
 	cx := an.Cx()
 	if cx >= an.B*0.5 {
 		return an.Iy() / cx
@@ -2416,10 +2609,16 @@ func (an ANGLE) Zy() float64 {
 	}
 }
 func (an ANGLE) Zpx() float64 {
+	if an.Catalog != nil {
+		return an.Catalog.Zpx
+	}
 	cy := an.Cy()
 	return (an.B-an.Tw)*math.Pow(an.Tf, 2.0)/4.0 + an.Tw*math.Pow(an.H, 2.0)/4.0 + (an.B-an.Tw)*an.Tf*math.Abs(cy-0.5*an.Tf) + an.H*an.Tw*math.Abs(0.5*an.H-cy)
 }
 func (an ANGLE) Zpy() float64 {
+	if an.Catalog != nil {
+		return an.Catalog.Zpy
+	}
 	cx := an.Cx()
 	return (an.H-an.Tf)*math.Pow(an.Tw, 2.0)/4.0 + an.Tf*math.Pow(an.B, 2.0)/4.0 + (an.H-an.Tf)*an.Tw*math.Abs(cx-0.5*an.Tw) + an.B*an.Tf*math.Abs(0.5*an.B-cx)
 }
@@ -2745,26 +2944,16 @@ func (sw *SWall) SetValue(name string, vals []float64) {
 	}
 }
 func (sw *SWall) Factor(p string) float64 {
-	switch p {
-	default:
-		return 0.0
-	case "L", "ML", "MS":
-		return 1.0
-	case "X", "Y", "S":
-		return 1.5
-	}
+	return DefaultDesignCode.AllowableFactor(ALLOW_STEEL, p)
 }
 func (sw *SWall) Fs(cond *Condition) float64 {
-	return sw.F / (1.5 * math.Sqrt(3)) * sw.Factor(cond.Period)
+	return sw.F / (1.5 * math.Sqrt(3)) * allowableFactor(cond, ALLOW_STEEL)
 }
 func (sw *SWall) Thickness() float64 {
 	return sw.Shape.(THICK).Thickness
 }
 func (sw *SWall) Na(cond *Condition) float64 {
-	fs := sw.Fs(cond)
-	r := 1.0 // TODO: set windowrate
-	Qa := r * sw.Thickness() * cond.Length * fs
-	return 0.5 * Qa
+	return steelCode(cond).SteelWallNa(sw, cond)
 }
 func (sw *SWall) Qa(cond *Condition) float64 {
 	return 0.0
@@ -3190,16 +3379,7 @@ func (rc *RCColumn) SetValue(name string, vals []float64) {
 	}
 }
 func (rc *RCColumn) Factor(p string) float64 {
-	switch p {
-	default:
-		return 0.0
-	case "L", "ML", "MS":
-		return 1.0
-	case "X", "Y", "S":
-		return 2.0
-	case "U":
-		return 3.0
-	}
+	return DefaultDesignCode.AllowableFactor(ALLOW_CONCRETE, p)
 }
 func (rc *RCColumn) Fs(cond *Condition) float64 {
 	var rtn float64
@@ -3210,20 +3390,10 @@ func (rc *RCColumn) Fs(cond *Condition) float64 {
 	} else {
 		rtn = f2
 	}
-	switch cond.Period {
-	default:
-		rtn = 0.0
-	case "L", "ML", "MS":
-		rtn *= 1.0
-	case "X", "Y", "S":
-		rtn *= 1.5
-	case "U":
-		rtn *= 3.0
-	}
-	return rtn
+	return rtn * allowableFactor(cond, ALLOW_CONCRETE_SHEAR)
 }
 func (rc *RCColumn) Fc(cond *Condition) float64 {
-	return rc.fc / 3.0 * rc.Factor(cond.Period)
+	return rc.fc / 3.0 * allowableFactor(cond, ALLOW_CONCRETE)
 }
 func (rc *RCColumn) Ai() float64 {
 	if rc.Reins == nil {
@@ -3476,28 +3646,7 @@ func (rc *RCColumn) Nmin(cond *Condition) float64 {
 	return -ft * rc.Ai()
 }
 func (rc *RCColumn) Na(cond *Condition) float64 {
-	if cond.Compression {
-		if cond.Verbose {
-			cond.Buffer.WriteString(fmt.Sprintf("#     許容圧縮応力度: Fc= %.3f [tf/cm2]\n", rc.Fc(cond)))
-		}
-		return rc.Fc(cond) * rc.Area()
-	} else {
-		if rc.Reins == nil {
-			return 0.0
-		}
-		area := 0.0
-		ft := 0.0
-		rtn := 0.0
-		for _, r := range rc.Reins {
-			area += r.Area
-			ft = r.Ft(cond)
-			rtn += r.Area * r.Ft(cond)
-		}
-		if cond.Verbose {
-			cond.Buffer.WriteString(fmt.Sprintf("#     鉄筋総断面積: at= %.3f [cm2]\n#     鉄筋許容引張応力度 Ft= %.3f [tf/cm2]\n", area, ft))
-		}
-		return rtn
-	}
+	return rcCode(cond).RCNa(rc, cond)
 }
 func (rc *RCColumn) Alpha(d float64, cond *Condition) float64 {
 	alpha := 4.0 / (math.Abs(cond.M*100.0/(cond.Q*d)) + 1.0)
@@ -3509,80 +3658,10 @@ func (rc *RCColumn) Alpha(d float64, cond *Condition) float64 {
 	return alpha
 }
 func (rc *RCColumn) Qa(cond *Condition) float64 {
-	b := rc.Breadth(cond.Strong)
-	d := rc.FarSideReins(cond)
-	fs := rc.Fs(cond)
-	alpha := rc.Alpha(d, cond)
-	if cond.Verbose {
-		cond.Buffer.WriteString(fmt.Sprintf("#     許容せん断応力度: b=%.3f, d=%.3f, α=%.3f, Fs=%.3f [tf/cm2]\n", b, d, alpha, fs))
-	}
-	switch cond.Period {
-	default:
-		fmt.Printf("unknown period: %s\n", cond.Period)
-		return 0.0
-	case "L", "ML", "MS":
-		return 7 / 8.0 * b * d * alpha * fs // RC規準2018 (15.1)式
-	case "X", "Y", "S":
-		var pw float64
-		if cond.Strong { // for Qy
-			pw = rc.Hoops.Ps[1]
-		} else { // for Qx
-			pw = rc.Hoops.Ps[0]
-		}
-		if pw < 0.002 {
-			return 7 / 8.0 * b * d * 2.0 / 3.0 * alpha * fs
-		} else if pw > 0.012 {
-			pw = 0.012
-		}
-		if cond.Verbose {
-			cond.Buffer.WriteString(fmt.Sprintf("#     せん断補強筋比: pw=%.6f\n", pw))
-		}
-		return 7 / 8.0 * b * d * (2.0/3.0*alpha*fs + 0.5*rc.Hoops.Ftw(cond)*(pw-0.002)) // RC規準2018 (15.3)式
-		// return 7 / 8.0 * b * d * (fs + 0.5*rc.Hoops.Ftw(cond)*(pw-0.002)) // RC規準2018 (15.6)式
-	case "U":
-		var pw float64
-		if cond.Strong { // for Qy
-			pw = rc.Hoops.Ps[1]
-		} else { // for Qx
-			pw = rc.Hoops.Ps[0]
-		}
-		if pw > 0.012 {
-			pw = 0.012
-		}
-		pt := rc.Ai()*0.5/(b*d)*100
-		Fc := rc.fc*100 // N/mm2
-		ft := rc.Hoops.Ftw(cond)*98.0665 // N/mm2
-		alpha := 1.0
-		if cond.Q == 0.0 {
-			alpha = 1.0
-		} else {
-			alpha = math.Abs(cond.M*100.0/(cond.Q*d))
-			if alpha < 1.0 {
-				alpha = 1.0
-			} else if alpha > 3.0 {
-				alpha = 3.0
-			}
-		}
-		return (b*7/8.0*d*((0.068*math.Pow(pt, 0.23)*(Fc+18))/(alpha+0.12)+0.85*math.Sqrt(pw*ft)))/9.80665/10 // tf
-	}
+	return rcCode(cond).RCQa(rc, cond)
 }
 func (rc *RCColumn) Ma(cond *Condition) float64 {
-	b := rc.Breadth(cond.Strong)
-	h := rc.Height(cond.Strong)
-	xn, sigma, err := rc.NeutralAxis(cond)
-	if err != nil {
-		return 0.0
-	}
-	if cond.Verbose {
-		cond.Buffer.WriteString(fmt.Sprintf("#     中立軸: xn= %.3f [cm]\n#     許容応力度: σ= %.3f [tf/cm2]\n", xn, sigma))
-	}
-	if xn >= h {
-		return (sigma/xn*(b*h*(3.0*math.Pow(xn, 2.0)-3.0*xn*h+math.Pow(h, 2.0))/3.0+NCS*(math.Pow(xn, 2.0)*rc.Ai()-2.0*xn*rc.LiAi(cond)+rc.Li2Ai(cond))) - cond.N*(xn-h/2.0)) * 0.01 // [tfm]
-	} else if xn <= 0 {
-		return -(NCS*sigma/xn*(math.Pow(xn, 2.0)*rc.Ai()-2.0*xn*rc.LiAi(cond)+rc.Li2Ai(cond)) + cond.N*(xn-h/2.0)) * 0.01 // [tfm]
-	} else {
-		return (sigma*(b*math.Pow(xn, 2.0)/3.0+NCS*(xn*rc.Ai()-2.0*rc.LiAi(cond)+rc.Li2Ai(cond)/xn)) - cond.N*(xn-h/2.0)) * 0.01 // [tfm]
-	}
+	return rcCode(cond).RCMa(rc, cond)
 }
 func (rc *RCColumn) Mu(cond *Condition) float64 {
 	cond.Period = "U"
@@ -3609,46 +3688,7 @@ func (rc *RCColumn) Mu(cond *Condition) float64 {
 	}
 }
 func (rc *RCColumn) Mza(cond *Condition) float64 {
-	if rc.Reins == nil || len(rc.Reins) < 1 {
-		return 0.0
-	}
-	ft := rc.Reins[0].Ft(cond)
-	fs := rc.Fs(cond)
-	wft := rc.Hoops.Ftw(cond)
-	b := rc.Breadth(true)
-	d := rc.Height(true)
-	dw := 1.1 // TODO: set dw, aw, lw & kaburi
-	// aw := 0.7133
-	// lw := 15.0
-	kaburi := 5.0
-	b0 := b - kaburi*2.0 - dw
-	d0 := d - kaburi*2.0 - dw
-	A0 := b0 * d0
-	var T1, T2, T3 float64
-	if b >= d {
-		T1 = b * d * d * fs * 4.0 / 3.0 / 100.0 // [tfm]
-	} else {
-		T1 = b * b * d * fs * 4.0 / 3.0 / 100.0 // [tfm]
-	}
-	// T2 = aw * 2.0 * wft * A0 / lw / 100.0                // [tfm]
-	T2 = wft * A0 * rc.Hoops.Ps[1] * b / 100.0           // [tfm]
-	T3 = rc.Ai() * 2.0 * ft * A0 / (2*b0 + 2*d0) / 100.0 // [tfm]
-	if cond.Verbose {
-		cond.Buffer.WriteString(fmt.Sprintf("#     許容ねじりモーメント: T1= %.3f [tfm] T2= %.3f [tfm] T3= %.3f [tfm]\n", T1, T2, T3))
-	}
-	if T1 <= T2 {
-		if T1 <= T3 {
-			return T1
-		} else {
-			return T3
-		}
-	} else {
-		if T2 <= T3 {
-			return T2
-		} else {
-			return T3
-		}
-	}
+	return rcCode(cond).RCMza(rc, cond)
 }
 func (rc *RCColumn) Amount() Amount {
 	a := NewAmount()
@@ -3695,71 +3735,7 @@ func (rg *RCGirder) Alpha(d float64, cond *Condition) float64 {
 	return alpha
 }
 func (rg *RCGirder) Qa(cond *Condition) float64 {
-	b := rg.Breadth(cond.Strong)
-	d := rg.FarSideReins(cond)
-	fs := rg.Fs(cond)
-	alpha := rg.Alpha(d, cond)
-	if cond.Verbose {
-		cond.Buffer.WriteString(fmt.Sprintf("#     許容せん断応力度: b=%.3f, d=%.3f, α=%.3f, Fs=%.3f [tf/cm2]\n", b, d, alpha, fs))
-	}
-	var pw float64
-	if cond.Strong { // for Qy
-		pw = rg.Hoops.Ps[1]
-	} else { // for Qx
-		pw = rg.Hoops.Ps[0]
-	}
-	switch cond.Period {
-	default:
-		fmt.Printf("unknown period: %s\n", cond.Period)
-		return 0.0
-	case "L", "ML", "MS":
-		if pw < 0.002 {
-			// fmt.Printf("shortage in pw: %.6f\n", pw)
-			return 7 / 8.0 * b * d * fs
-		} else if pw > 0.006 {
-			pw = 0.006
-		}
-		if cond.Verbose {
-			cond.Buffer.WriteString(fmt.Sprintf("#     せん断補強筋比: pw=%.6f\n", pw))
-		}
-		return 7 / 8.0 * b * d * (alpha*fs + 0.5*rg.Hoops.Ftw(cond)*(pw-0.002)) // RC規準2018 (15.2)式
-	case "X", "Y", "S":
-		if pw < 0.002 {
-			// fmt.Printf("shortage in pw: %.6f\n", pw)
-			return 7 / 8.0 * b * d * fs
-		} else if pw > 0.012 {
-			pw = 0.012
-		}
-		if cond.Verbose {
-			cond.Buffer.WriteString(fmt.Sprintf("#     せん断補強筋比: pw=%.6f\n", pw))
-		}
-		return 7 / 8.0 * b * d * (alpha*fs + 0.5*rg.Hoops.Ftw(cond)*(pw-0.002)) // RC規準2018 (15.5)式
-	case "U":
-		var pw float64
-		if cond.Strong { // for Qy
-			pw = rg.Hoops.Ps[1]
-		} else { // for Qx
-			pw = rg.Hoops.Ps[0]
-		}
-		if pw > 0.012 {
-			pw = 0.012
-		}
-		pt := rg.Ai()*0.5/(b*d)*100
-		Fc := rg.fc*100 // N/mm2
-		ft := rg.Hoops.Ftw(cond)*98.0665 // N/mm2
-		alpha := 1.0
-		if cond.Q == 0.0 {
-			alpha = 1.0
-		} else {
-			alpha = math.Abs(cond.M*100.0/(cond.Q*d))
-			if alpha < 1.0 {
-				alpha = 1.0
-			} else if alpha > 3.0 {
-				alpha = 3.0
-			}
-		}
-		return (b*7/8.0*d*((0.068*math.Pow(pt, 0.23)*(Fc+18))/(alpha+0.12)+0.85*math.Sqrt(pw*ft)))/9.80665/10 // tf
-	}
+	return rcCode(cond).RCGirderQa(rg, cond)
 }
 func (rg *RCGirder) Amount() Amount {
 	a := NewAmount()
@@ -3902,14 +3878,7 @@ func (rw *RCWall) SetValue(name string, vals []float64) {
 	}
 }
 func (rw *RCWall) Factor(p string) float64 {
-	switch p {
-	default:
-		return 0.0
-	case "L", "ML", "MS":
-		return 1.0
-	case "X", "Y", "S":
-		return 2.0
-	}
+	return DefaultDesignCode.AllowableFactor(ALLOW_CONCRETE, p)
 }
 func (rw *RCWall) Fs(cond *Condition) float64 {
 	var rtn float64
@@ -3920,66 +3889,10 @@ func (rw *RCWall) Fs(cond *Condition) float64 {
 	} else {
 		rtn = f2
 	}
-	switch cond.Period {
-	default:
-		rtn = 0.0
-	case "L", "ML", "MS":
-		rtn *= 1.0
-	case "X", "Y", "S":
-		rtn *= 1.5
-	}
-	return rtn
+	return rtn * allowableFactor(cond, ALLOW_CONCRETE_SHEAR)
 }
 func (rw *RCWall) Na(cond *Condition) float64 {
-	fs := rw.Fs(cond)
-	var Qc, Qw, Qa float64
-	l0 := cond.Width
-	h0 := cond.Height
-	l1 := rw.Wrect[0]
-	h1 := rw.Wrect[1]
-	r := 1.0
-	if l1 > 0.0 && h1 > 0.0 {
-		r0 := math.Sqrt((h1*l1)/(h0*l0))
-		if r0 > 0.4 {
-			cond.Buffer.WriteString(fmt.Sprintf("r0 > 0.4: r0= %.3f\n", r0))
-		}
-		r = 1.0 - 1.25*r0
-	}
-	Qc = r * rw.Thick * cond.Length * fs
-	switch cond.Period {
-	default:
-		fmt.Printf("unknown period: %s\n", cond.Period)
-		return 0.0
-	case "L", "ML", "MS":
-		Qa = Qc
-		if cond.Verbose {
-			cond.Buffer.WriteString(fmt.Sprintf("#     l=%.3f\n", cond.Length))
-			cond.Buffer.WriteString(fmt.Sprintf("#     t=%.3f\n", rw.Thick))
-			cond.Buffer.WriteString(fmt.Sprintf("#     r=%.3f\n", r))
-			cond.Buffer.WriteString(fmt.Sprintf("#     fcs=%.3f\n", fs))
-			cond.Buffer.WriteString(fmt.Sprintf("#     Qc=%.3f\n", Qc))
-		}
-	case "X", "Y", "S":
-		Qa = Qc
-		le := cond.Width - (rw.XFace[0] + rw.XFace[1])
-		if le >= 0.0 {
-			Qw = r * rw.Thick * rw.Srein * cond.Length * le / l0 * rw.Material.Fs
-			if Qw > Qc {
-				Qa = Qw
-			}
-			if cond.Verbose {
-				cond.Buffer.WriteString(fmt.Sprintf("#     l=%.3f\n", cond.Length))
-				cond.Buffer.WriteString(fmt.Sprintf("#     l0=%.3f, le=%.3f\n", l0, le))
-				cond.Buffer.WriteString(fmt.Sprintf("#     pw=%.6f\n", rw.Srein))
-				cond.Buffer.WriteString(fmt.Sprintf("#     t=%.3f\n", rw.Thick))
-				cond.Buffer.WriteString(fmt.Sprintf("#     r=%.3f\n", r))
-				cond.Buffer.WriteString(fmt.Sprintf("#     fcs=%.3f\n", fs))
-				cond.Buffer.WriteString(fmt.Sprintf("#     frs=%.3f\n", rw.Material.Fs))
-				cond.Buffer.WriteString(fmt.Sprintf("#     Qc=%.3f Qw=%.3f Qa=max{Qw,Qc}=%.3f\n", Qc, Qw, Qa))
-			}
-		}
-	}
-	return 0.5 * Qa
+	return rcCode(cond).RCWallNa(rw, cond)
 }
 func (rw *RCWall) Qa(cond *Condition) float64 {
 	return 0.0
@@ -4165,18 +4078,7 @@ func (wc *WoodColumn) String() string {
 	return rtn.String()
 }
 func (wc *WoodColumn) Factor(p string) float64 {
-	switch p {
-	default:
-		return 0.0
-	case "L":
-		return 1.1 / 3.0
-	case "ML":
-		return 1.43 / 3.0
-	case "MS":
-		return 1.6 / 3.0
-	case "X", "Y", "S":
-		return 2.0 / 3.0
-	}
+	return DefaultDesignCode.AllowableFactor(ALLOW_WOOD, p) / 3.0
 }
 func (wc *WoodColumn) Lk(length float64, strong bool) float64 {
 	var ind int
@@ -4227,7 +4129,7 @@ func (wc *WoodColumn) Fc(cond *Condition) float64 {
 	} else {
 		rtn = 3000.0 / (lambda * lambda)
 	}
-	rtn *= wc.Factor(cond.Period) * wc.fc
+	rtn *= allowableFactor(cond, ALLOW_WOOD) / 3.0 * wc.fc
 	if cond.Verbose {
 		cond.Buffer.WriteString(fmt.Sprintf("#     座屈長さ[cm]: Lkx=%.3f, Lky=%.3f\n", lx, ly))
 		check := ""
@@ -4241,28 +4143,19 @@ func (wc *WoodColumn) Fc(cond *Condition) float64 {
 	return rtn
 }
 func (wc *WoodColumn) Ft(cond *Condition) float64 {
-	return wc.ft * wc.Factor(cond.Period)
+	return wc.ft * allowableFactor(cond, ALLOW_WOOD) / 3.0
 }
 func (wc *WoodColumn) Fs(cond *Condition) float64 {
-	return wc.fs * wc.Factor(cond.Period)
+	return wc.fs * allowableFactor(cond, ALLOW_WOOD) / 3.0
 }
 func (wc *WoodColumn) Fb(cond *Condition) float64 {
-	return wc.fb * wc.Factor(cond.Period)
+	return wc.fb * allowableFactor(cond, ALLOW_WOOD) / 3.0
 }
 func (wc *WoodColumn) Na(cond *Condition) float64 {
-	if cond.Compression {
-		return wc.Fc(cond) * wc.A() * wc.multi
-	} else {
-		return wc.Ft(cond) * wc.A() * wc.multi
-	}
+	return woodCode(cond).WoodNa(wc, cond)
 }
 func (wc *WoodColumn) Qa(cond *Condition) float64 {
-	f := wc.Fs(cond)
-	if cond.Strong { // for Qy
-		return f * wc.Asy() * wc.multi
-	} else { // for Qx
-		return f * wc.Asx() * wc.multi
-	}
+	return woodCode(cond).WoodQa(wc, cond)
 }
 func (wc *WoodColumn) ShapeFactor(strong bool) float64 {
 	alpha := 1.0/9.0
@@ -4283,16 +4176,10 @@ func (wc *WoodColumn) ShapeFactor(strong bool) float64 {
 	}
 }
 func (wc *WoodColumn) Ma(cond *Condition) float64 {
-	f := wc.Fb(cond)
-	shapefactor := wc.ShapeFactor(cond.Strong)
-	if cond.Strong {
-		return shapefactor * f * wc.Zx() * 0.01 * wc.multi // [tfm]
-	} else {
-		return shapefactor * f * wc.Zy() * 0.01 * wc.multi // [tfm]
-	}
+	return woodCode(cond).WoodMa(wc, cond)
 }
 func (wc *WoodColumn) Mza(cond *Condition) float64 {
-	return wc.Fs(cond) * wc.Torsion() * 0.01 * wc.multi // [tfm]
+	return woodCode(cond).WoodMza(wc, cond)
 }
 
 func (wc *WoodColumn) Vertices() [][]float64 {
@@ -4407,45 +4294,13 @@ func (ww *WoodWall) SetValue(name string, vals []float64) {
 	}
 }
 func (ww *WoodWall) Factor(p string) float64 {
-	switch p {
-	default:
-		return 0.0
-	case "L":
-		return 1.1
-	case "ML":
-		return 1.43
-	case "MS":
-		return 1.6
-	case "X", "Y", "S":
-		return 2.0
-	}
+	return DefaultDesignCode.AllowableFactor(ALLOW_WOOD, p)
 }
 func (ww *WoodWall) Fs(cond *Condition) float64 {
-	rtn := ww.fs // [tf/cm2]
-	switch cond.Period {
-	default:
-		rtn = 0.0
-	case "L":
-		rtn *= 1.1
-	case "ML":
-		rtn *= 1.43
-	case "MS":
-		rtn *= 1.6
-	case "X", "Y", "S":
-		rtn *= 2.0
-	}
-	return rtn
+	return ww.fs * allowableFactor(cond, ALLOW_WOOD) // [tf/cm2]
 }
 func (ww *WoodWall) Na(cond *Condition) float64 {
-	r := 1.0 // TODO: set windowrate
-	Qa := 0.0
-	if ww.Kfact != 0.0 {
-		Qa = r * ww.Factor(cond.Period) * ww.Kfact * 0.001 * cond.Length
-	} else if ww.Thick != 0.0 {
-		fs := ww.Fs(cond)
-		Qa = r * ww.Thick * cond.Length * fs
-	}
-	return 0.5 * Qa
+	return woodCode(cond).WoodWallNa(ww, cond)
 }
 func (ww *WoodWall) Qa(cond *Condition) float64 {
 	return 0.0
@@ -4486,6 +4341,10 @@ type Condition struct {
 	Positive    bool
 	FbOld       bool
 	RCTorsion   bool
+	Cgrad       bool      // whether Cb may be calculated from the end moments (:srcal -cb)
+	Lb          []float64 // laterally unbraced length [cm] (strong, weak)
+	Cb          []float64 // moment gradient factor (strong, weak)
+	CbAuto      []bool    // whether Cb is calculated from the end moments (strong, weak)
 	N           float64
 	M           float64
 	Q           float64
@@ -4499,6 +4358,7 @@ type Condition struct {
 	Wfact       float64
 	Skipshort   bool
 	Temporary   string
+	Code        DesignCode
 }
 
 func NewCondition() *Condition {
@@ -4512,20 +4372,43 @@ func NewCondition() *Condition {
 		Positive:    true,
 		FbOld:       false,
 		RCTorsion:   false,
+		Cgrad:       false,
 		N:           0.0,
 		M:           0.0,
 		Q:           0.0,
 		Sign:        1.0,
 		Verbose:     false,
 		Buffer:      new(bytes.Buffer),
-		Nfact:       1.0,
-		Qfact:       2.0,
-		Mfact:       1.0,
-		Bfact:       1.0,
-		Wfact:       2.0,
+		Nfact:       DefaultDesignCode.StressFactor("N"),
+		Qfact:       DefaultDesignCode.StressFactor("Q"),
+		Mfact:       DefaultDesignCode.StressFactor("M"),
+		Bfact:       DefaultDesignCode.StressFactor("B"),
+		Wfact:       DefaultDesignCode.StressFactor("W"),
 		Skipshort:   false,
 		Temporary:   "",
+		Code:        DefaultDesignCode,
+	}
+}
+
+// DesignCode returns the design code which gives the strengths of members.
+func (cond *Condition) DesignCode() DesignCode {
+	if cond.Code == nil {
+		return DefaultDesignCode
 	}
+	return cond.Code
+}
+
+// SetCode selects the design code and resets the stress factors to its defaults.
+func (cond *Condition) SetCode(code DesignCode) {
+	if code == nil {
+		code = DefaultDesignCode
+	}
+	cond.Code = code
+	cond.Nfact = code.StressFactor("N")
+	cond.Qfact = code.StressFactor("Q")
+	cond.Mfact = code.StressFactor("M")
+	cond.Bfact = code.StressFactor("B")
+	cond.Wfact = code.StressFactor("W")
 }
 
 func (cond *Condition) Snapshot() *Condition {
@@ -4538,6 +4421,16 @@ func (cond *Condition) Snapshot() *Condition {
 	c.Strong = cond.Strong
 	c.Positive = cond.Positive
 	c.FbOld = cond.FbOld
+	c.Cgrad = cond.Cgrad
+	if cond.Lb != nil {
+		c.Lb = []float64{cond.Lb[0], cond.Lb[1]}
+	}
+	if cond.Cb != nil {
+		c.Cb = []float64{cond.Cb[0], cond.Cb[1]}
+	}
+	if cond.CbAuto != nil {
+		c.CbAuto = []bool{cond.CbAuto[0], cond.CbAuto[1]}
+	}
 	c.N = cond.N
 	c.M = cond.M
 	c.Q = cond.Q
@@ -4551,6 +4444,7 @@ func (cond *Condition) Snapshot() *Condition {
 	c.Wfact = cond.Wfact
 	c.Skipshort = cond.Skipshort
 	c.Temporary = cond.Temporary
+	c.Code = cond.Code
 	return c
 }
 
@@ -4563,6 +4457,7 @@ func Rate1(sr SectionRate, stress []float64, cond *Condition) ([]float64, string
 	var ind int
 	var otp, tex bytes.Buffer
 	verbose := false
+	cond.SetMomentGradient(stress)
 	for i := 0; i < 2; i++ {
 		if cond.Verbose {
 			if i == 0 {
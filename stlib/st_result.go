@@ -0,0 +1,160 @@
+package st
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yofu/st/arclm"
+)
+
+// ResultExt is the extension of binary result files written by analysis.
+var ResultExt = ".otb"
+
+// ResultStoreKeep is the number of laps kept in memory by ResultStore.
+var ResultStoreKeep = 8
+
+// ResultStore loads laps from a binary result file only when they are displayed.
+type ResultStore struct {
+	Name   string
+	Keep   int
+	reader *arclm.ResultReader
+	loaded []string
+}
+
+// OpenResultStore opens a binary result file and registers the number of laps of its periods.
+// Laps are read by LoadLap.
+func (frame *Frame) OpenResultStore(fn string) error {
+	rr, err := arclm.OpenResult(fn)
+	if err != nil {
+		return err
+	}
+	frame.CloseResultStore()
+	frame.Results = &ResultStore{
+		Name:   fn,
+		Keep:   ResultStoreKeep,
+		reader: rr,
+		loaded: make([]string, 0),
+	}
+	for _, per := range rr.Periods() {
+		frame.Nlap[per] = rr.Nlap(per)
+		frame.ResultFileName[per] = fn
+	}
+	return nil
+}
+
+// CloseResultStore unloads laps read from the binary result file and closes it.
+func (frame *Frame) CloseResultStore() error {
+	if frame.Results == nil {
+		return nil
+	}
+	for _, per := range frame.Results.loaded {
+		frame.unloadLap(per)
+	}
+	err := frame.Results.reader.Close()
+	frame.Results = nil
+	return err
+}
+
+// LoadLap reads the laps which appear in period (e.g. "X@12", "L+X@3") from the binary result file.
+// A period without lap number is read from its last lap.
+// Laps which have not been used recently are unloaded so that at most Keep laps stay in memory.
+func (frame *Frame) LoadLap(period string) error {
+	rs := frame.Results
+	if rs == nil {
+		return nil
+	}
+	current := make(map[string]bool)
+	var err error
+	PeriodValue(strings.ToUpper(period), func(p string, s float64) float64 {
+		if p == "" || err != nil {
+			return 0.0
+		}
+		current[p] = true
+		err = rs.load(frame, p)
+		return 0.0
+	})
+	if err != nil {
+		return err
+	}
+	for len(rs.loaded) > rs.Keep {
+		ind := -1
+		for i, p := range rs.loaded {
+			if !current[p] {
+				ind = i
+				break
+			}
+		}
+		if ind < 0 {
+			break
+		}
+		frame.unloadLap(rs.loaded[ind])
+		rs.loaded = append(rs.loaded[:ind], rs.loaded[ind+1:]...)
+	}
+	return nil
+}
+
+func (rs *ResultStore) load(frame *Frame, per string) error {
+	for i, p := range rs.loaded {
+		if p == per {
+			rs.loaded = append(append(rs.loaded[:i], rs.loaded[i+1:]...), per)
+			return nil
+		}
+	}
+	name := per
+	lap := 0
+	if ind := strings.Index(per, "@"); ind >= 0 {
+		name = per[:ind]
+		tmp, err := strconv.ParseInt(per[ind+1:], 10, 64)
+		if err != nil {
+			return fmt.Errorf("LoadLap: %s: %s", per, err)
+		}
+		lap = int(tmp)
+	}
+	nlap := rs.reader.Nlap(name)
+	if nlap == 0 {
+		return nil
+	}
+	if lap == 0 {
+		lap = nlap
+	}
+	lr, err := rs.reader.Lap(name, lap)
+	if err != nil {
+		return err
+	}
+	frame.setLapResult(lr, per)
+	rs.loaded = append(rs.loaded, per)
+	return nil
+}
+
+func (frame *Frame) setLapResult(lr *arclm.LapResult, per string) {
+	for i, num := range lr.Nodes {
+		if n, ok := frame.Nodes[num]; ok {
+			n.Disp[per] = lr.Disp[i]
+			n.Reaction[per] = lr.Reaction[i]
+		}
+	}
+	for i, num := range lr.Elems {
+		if el, ok := frame.Elems[num]; ok {
+			stress := make(map[int][]float64, 2)
+			phinge := make(map[int]bool, 2)
+			for j, nnum := range lr.Enod[i] {
+				stress[nnum] = lr.Stress[i][6*j : 6*j+6]
+				phinge[nnum] = lr.Phinge[i][j]
+			}
+			el.Stress[per] = stress
+			el.Phinge[per] = phinge
+		}
+	}
+}
+
+func (frame *Frame) unloadLap(per string) {
+	for _, n := range frame.Nodes {
+		delete(n.Disp, per)
+		delete(n.Reaction, per)
+	}
+	for _, el := range frame.Elems {
+		delete(el.Stress, per)
+		delete(el.Phinge, per)
+	}
+}
@@ -0,0 +1,111 @@
+package st
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/yofu/st/arclm"
+)
+
+func TestLoadLap(t *testing.T) {
+	frame := NewFrame()
+	n1 := frame.AddNode(0.0, 0.0, 0.0)
+	n2 := frame.AddNode(0.0, 0.0, 3.0)
+	el := NewLineElem([]*Node{n1, n2}, frame.AddSect(1), COLUMN)
+	frame.AddElem(-1, el)
+
+	af := arclm.NewFrame()
+	for _, n := range []*Node{n1, n2} {
+		an := arclm.NewNode()
+		an.Num = n.Num
+		af.Nodes = append(af.Nodes, an)
+	}
+	ae := arclm.NewElem()
+	ae.Num = el.Num
+	ae.Enod[0] = af.Nodes[0]
+	ae.Enod[1] = af.Nodes[1]
+	af.Elems = []*arclm.Elem{ae}
+	fn := filepath.Join(t.TempDir(), "test"+ResultExt)
+	rw, err := arclm.CreateResult(fn, af)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []struct {
+		period string
+		lap    int
+	}{{"L", 1}, {"X", 1}, {"X", 2}} {
+		val := float64(10*len(l.period)) + float64(l.lap)
+		if l.period == "X" {
+			val = -val
+		}
+		af.Nodes[1].Disp[0] = val
+		af.Nodes[0].Reaction[2] = 2.0 * val
+		ae.Stress[0] = 3.0 * val
+		ae.Stress[6] = 4.0 * val
+		ae.Phinge[1] = l.lap == 2
+		if err := rw.WriteLap(l.period, l.lap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := frame.OpenResultStore(fn); err != nil {
+		t.Fatal(err)
+	}
+	defer frame.CloseResultStore()
+	if frame.Nlap["X"] != 2 || frame.Nlap["L"] != 1 {
+		t.Errorf("nlap = %v", frame.Nlap)
+	}
+	frame.Results.Keep = 2
+	for _, c := range []struct {
+		period string
+		val    float64
+	}{
+		{"X@1", -11.0},
+		{"X", -12.0},
+		{"L@1", 11.0},
+		{"X@2", -12.0},
+	} {
+		if err := frame.LoadLap(c.period); err != nil {
+			t.Fatal(err)
+		}
+		if v := n2.Disp[c.period][0]; v != c.val {
+			t.Errorf("%s: disp = %g, want %g", c.period, v, c.val)
+		}
+		if v := n1.Reaction[c.period][2]; v != 2.0*c.val {
+			t.Errorf("%s: reaction = %g, want %g", c.period, v, 2.0*c.val)
+		}
+		if v := el.Stress[c.period][n1.Num][0]; v != 3.0*c.val {
+			t.Errorf("%s: stress i = %g, want %g", c.period, v, 3.0*c.val)
+		}
+		if v := el.Stress[c.period][n2.Num][0]; v != 4.0*c.val {
+			t.Errorf("%s: stress j = %g, want %g", c.period, v, 4.0*c.val)
+		}
+		if v := el.Phinge[c.period][n2.Num]; v != (c.val == -12.0) {
+			t.Errorf("%s: phinge = %t", c.period, v)
+		}
+	}
+	// X@1 and X are unloaded since Keep is 2
+	for _, per := range []string{"X@1", "X"} {
+		if _, ok := n2.Disp[per]; ok {
+			t.Errorf("%s is not unloaded", per)
+		}
+	}
+	if err := frame.LoadLap("L+X@2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := n2.Disp["L"]; !ok {
+		t.Error("L of L+X@2 is not loaded")
+	}
+	if err := frame.LoadLap("X@9"); err == nil {
+		t.Error("X@9 is loaded")
+	}
+	if err := frame.CloseResultStore(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := n2.Disp["X@2"]; ok {
+		t.Error("X@2 is not unloaded by CloseResultStore")
+	}
+}
@@ -0,0 +1,549 @@
+package st
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SAP2000 text database (.s2k, .$2k) import.
+// Joints, frames, areas, frame and area sections, materials, joint restraints,
+// frame releases of moments and joint loads of one load pattern are translated.
+// Values are converted into m and tf according to CurrUnits of "PROGRAM CONTROL".
+
+var (
+	s2kForceUnit = map[string]float64{
+		"N":    1.0 / 9806.65,
+		"KN":   1.0 / 9.80665,
+		"KGF":  1e-3,
+		"TONF": 1.0,
+		"LB":   4.4482216e-3 / 9.80665,
+		"KIP":  4.4482216 / 9.80665,
+	}
+	s2kLengthUnit = map[string]float64{
+		"MM":     1e-3,
+		"CM":     1e-2,
+		"M":      1.0,
+		"INCHES": 0.0254,
+		"IN":     0.0254,
+		"FEET":   0.3048,
+		"FT":     0.3048,
+	}
+	s2kTables = []string{
+		"PROGRAM CONTROL",
+		"MATERIAL PROPERTIES 01 - GENERAL",
+		"MATERIAL PROPERTIES 02 - BASIC MECHANICAL PROPERTIES",
+		"FRAME SECTION PROPERTIES 01 - GENERAL",
+		"AREA SECTION PROPERTIES",
+		"JOINT COORDINATES",
+		"JOINT RESTRAINT ASSIGNMENTS",
+		"CONNECTIVITY - FRAME",
+		"FRAME SECTION ASSIGNMENTS",
+		"FRAME LOCAL AXES ASSIGNMENTS 1 - TYPICAL",
+		"FRAME RELEASE ASSIGNMENTS 1 - GENERAL",
+		"CONNECTIVITY - AREA",
+		"AREA SECTION ASSIGNMENTS",
+		"JOINT LOADS - FORCE",
+	}
+	// tables which don't affect the model
+	s2kIgnored = []string{
+		"ACTIVE DEGREES OF FREEDOM",
+		"ANALYSIS OPTIONS",
+		"COORDINATE SYSTEMS",
+		"GRID LINES",
+		"LOAD PATTERN DEFINITIONS",
+		"LOAD CASE DEFINITIONS",
+		"MATERIAL PROPERTIES 03A - STEEL DATA",
+		"MATERIAL PROPERTIES 03B - CONCRETE DATA",
+		"PROJECT INFORMATION",
+	}
+)
+
+type s2kRow struct {
+	line   int
+	fields map[string]string
+}
+
+func (r *s2kRow) str(key string) string {
+	return r.fields[strings.ToUpper(key)]
+}
+
+// float returns the value of the first key found.
+func (r *s2kRow) float(keys ...string) (float64, bool) {
+	for _, key := range keys {
+		if v, ok := r.fields[strings.ToUpper(key)]; ok && v != "" {
+			val, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return 0.0, false
+			}
+			return val, true
+		}
+	}
+	return 0.0, false
+}
+
+func (r *s2kRow) yes(key string) bool {
+	switch strings.ToUpper(r.str(key)) {
+	case "YES", "Y", "TRUE":
+		return true
+	}
+	return false
+}
+
+// s2kFields splits a line of a SAP2000 table into key=value pairs.
+// Values may be double quoted.
+func s2kFields(line string) (map[string]string, error) {
+	rtn := make(map[string]string)
+	pos := 0
+	for {
+		for pos < len(line) && (line[pos] == ' ' || line[pos] == '\t') {
+			pos++
+		}
+		if pos >= len(line) {
+			return rtn, nil
+		}
+		eq := strings.IndexByte(line[pos:], '=')
+		if eq < 0 {
+			return rtn, fmt.Errorf("\"=\" is expected: %s", line[pos:])
+		}
+		key := strings.ToUpper(strings.TrimSpace(line[pos : pos+eq]))
+		pos += eq + 1
+		var val string
+		if pos < len(line) && line[pos] == '"' {
+			end := strings.IndexByte(line[pos+1:], '"')
+			if end < 0 {
+				return rtn, fmt.Errorf("unterminated string: %s", line[pos:])
+			}
+			val = line[pos+1 : pos+1+end]
+			pos += end + 2
+		} else {
+			end := strings.IndexAny(line[pos:], " \t")
+			if end < 0 {
+				end = len(line) - pos
+			}
+			val = line[pos : pos+end]
+			pos += end
+		}
+		rtn[key] = val
+	}
+}
+
+func readS2kTables(filename string) (map[string][]*s2kRow, []string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	tables := make(map[string][]*s2kRow)
+	order := make([]string, 0)
+	var table string
+	var current string
+	start := 0
+	num := 0
+	parse := func() error {
+		if strings.TrimSpace(current) == "" {
+			return nil
+		}
+		if table == "" {
+			return fmt.Errorf("line %d: data without TABLE", start)
+		}
+		fields, err := s2kFields(current)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", start, err.Error())
+		}
+		tables[table] = append(tables[table], &s2kRow{line: start, fields: fields})
+		return nil
+	}
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for s.Scan() {
+		num++
+		line := strings.TrimRight(s.Text(), " \t\r")
+		if current == "" {
+			start = num
+		}
+		trimmed := strings.TrimSpace(line)
+		if current == "" && (trimmed == "" || strings.HasPrefix(trimmed, "$") || strings.HasPrefix(trimmed, "File ")) {
+			continue
+		}
+		if strings.HasSuffix(line, " _") {
+			current += line[:len(line)-2] + " "
+			continue
+		}
+		current += line
+		upper := strings.ToUpper(strings.TrimSpace(current))
+		switch {
+		case strings.HasPrefix(upper, "TABLE:"):
+			table = strings.Trim(strings.TrimSpace(strings.TrimSpace(current)[6:]), "\"")
+			table = strings.ToUpper(table)
+			if _, ok := tables[table]; !ok {
+				tables[table] = make([]*s2kRow, 0)
+				order = append(order, table)
+			}
+		case upper == "END TABLE DATA":
+			table = ""
+		default:
+			err := parse()
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		current = ""
+	}
+	if err := s.Err(); err != nil {
+		return nil, nil, err
+	}
+	return tables, order, nil
+}
+
+// s2kUnits returns factors which convert force and length of the file into tf and m.
+func s2kUnits(tables map[string][]*s2kRow, im *importer) (float64, float64) {
+	force, length := s2kForceUnit["KN"], 1.0
+	rows := tables["PROGRAM CONTROL"]
+	if len(rows) == 0 || rows[0].str("CurrUnits") == "" {
+		im.skip(0, "units are not specified; KN, m is assumed")
+		return force, length
+	}
+	units := strings.Split(rows[0].str("CurrUnits"), ",")
+	if len(units) < 2 {
+		im.skip(rows[0].line, "unknown units: %s; KN, m is assumed", rows[0].str("CurrUnits"))
+		return force, length
+	}
+	if f, ok := s2kForceUnit[strings.ToUpper(strings.TrimSpace(units[0]))]; ok {
+		force = f
+	} else {
+		im.skip(rows[0].line, "unknown force unit: %s; KN is assumed", units[0])
+	}
+	if l, ok := s2kLengthUnit[strings.ToUpper(strings.TrimSpace(units[1]))]; ok {
+		length = l
+	} else {
+		im.skip(rows[0].line, "unknown length unit: %s; m is assumed", units[1])
+	}
+	return force, length
+}
+
+// s2kShape converts a SAP2000 frame section into Shape. Dimensions are converted into cm.
+func s2kShape(r *s2kRow, length float64) Shape {
+	cm := func(key string) float64 {
+		val, _ := r.float(key)
+		return val * length * 100.0
+	}
+	switch strings.ToUpper(r.str("Shape")) {
+	case "I/WIDE FLANGE":
+		return HKYOU{H: cm("t3"), B: cm("t2"), Tw: cm("tw"), Tf: cm("tf")}
+	case "BOX/TUBE":
+		return RPIPE{H: cm("t3"), B: cm("t2"), Tw: cm("tw"), Tf: cm("tf")}
+	case "PIPE":
+		return CPIPE{D: cm("t3"), T: cm("tw")}
+	case "CHANNEL":
+		return CKYOU{H: cm("t3"), B: cm("t2"), Tw: cm("tw"), Tf: cm("tf")}
+	case "TEE":
+		return TKYOU{H: cm("t3"), B: cm("t2"), Tw: cm("tw"), Tf: cm("tf")}
+	case "ANGLE":
+		return ANGLE{H: cm("t3"), B: cm("t2"), Tw: cm("tw"), Tf: cm("tf")}
+	case "RECTANGULAR":
+		return PLATE{H: cm("t3"), B: cm("t2")}
+	}
+	return nil
+}
+
+// s2kAxis3 returns the local 3 axis of a SAP2000 frame element rotated by angle [deg].
+// Local 2 is in the vertical plane (+X for vertical elements) and 3 = 1 x 2.
+func s2kAxis3(d []float64, angle float64) []float64 {
+	d = Normalize(d)
+	var ax2 []float64
+	if math.Abs(d[2]) > 0.999 {
+		ax2 = []float64{1.0, 0.0, 0.0}
+	} else {
+		ax2 = Normalize(Cross(Cross(d, []float64{0.0, 0.0, 1.0}), d))
+	}
+	ax3 := Cross(d, ax2)
+	if angle == 0.0 {
+		return ax3
+	}
+	c := math.Cos(angle * math.Pi / 180.0)
+	s := math.Sin(angle * math.Pi / 180.0)
+	rtn := make([]float64, 3)
+	for i := 0; i < 3; i++ {
+		rtn[i] = c*ax3[i] - s*ax2[i]
+	}
+	return rtn
+}
+
+// ReadS2k reads a SAP2000 text database and adds its model to frame.
+// Joint loads of pattern are read; if pattern is empty, the first load pattern is used.
+// It returns the list of items which could not be translated.
+func (frame *Frame) ReadS2k(filename string, eps float64, pattern string) ([]string, error) {
+	tables, order, err := readS2kTables(filename)
+	if err != nil {
+		return nil, fmt.Errorf("ReadS2k: %s", err.Error())
+	}
+	im := newImporter(frame, eps)
+	force, length := s2kUnits(tables, im)
+	// Material
+	mattype := make(map[string]string)
+	for _, r := range tables["MATERIAL PROPERTIES 01 - GENERAL"] {
+		mattype[r.str("Material")] = strings.ToUpper(r.str("Type"))
+	}
+	matvalue := make(map[string]*s2kRow)
+	for _, r := range tables["MATERIAL PROPERTIES 02 - BASIC MECHANICAL PROPERTIES"] {
+		matvalue[r.str("Material")] = r
+	}
+	for _, r := range tables["MATERIAL PROPERTIES 01 - GENERAL"] {
+		if _, ok := matvalue[r.str("Material")]; !ok {
+			matvalue[r.str("Material")] = r
+		}
+	}
+	prop := func(name string, line int) *Prop {
+		steel := mattype[name] != "CONCRETE"
+		r, ok := matvalue[name]
+		if !ok {
+			if name == "" {
+				name = "STEEL"
+			} else {
+				im.skip(line, "material %s is not defined", name)
+			}
+			return im.prop(name, steel, 0.0, 0.0, 0.0)
+		}
+		hiju, _ := r.float("UnitWeight", "UnitWt")
+		e, _ := r.float("E1", "E")
+		poi, _ := r.float("U12", "U")
+		return im.prop(name, steel, hiju*force/math.Pow(length, 3.0), e*force/math.Pow(length, 2.0), poi)
+	}
+	// Section
+	framesects := make(map[string]*s2kRow)
+	for _, r := range tables["FRAME SECTION PROPERTIES 01 - GENERAL"] {
+		framesects[r.str("SectionName")] = r
+	}
+	areasects := make(map[string]*s2kRow)
+	for _, r := range tables["AREA SECTION PROPERTIES"] {
+		areasects[r.str("Section")] = r
+	}
+	framesect := func(etype int, name string, line int) *Sect {
+		return im.sect(etype, "FRAME:"+name, name, func(s *Sect) {
+			r, ok := framesects[name]
+			if !ok {
+				im.skip(line, "frame section %s is not defined", name)
+				return
+			}
+			f := NewFig()
+			f.Prop = prop(r.str("Material"), r.line)
+			if sh := s2kShape(r, length); sh != nil {
+				f.SetShapeProperty(sh)
+			} else {
+				a, aok := r.float("Area")
+				ix, _ := r.float("I33")
+				iy, _ := r.float("I22")
+				j, _ := r.float("TorsConst")
+				if !aok {
+					im.skip(r.line, "frame section %s: shape %s is not translated", name, r.str("Shape"))
+					return
+				}
+				f.Value["AREA"] = a * math.Pow(length, 2.0)
+				f.Value["IXX"] = ix * math.Pow(length, 4.0)
+				f.Value["IYY"] = iy * math.Pow(length, 4.0)
+				f.Value["VEN"] = j * math.Pow(length, 4.0)
+			}
+			s.Figs = append(s.Figs, f)
+		})
+	}
+	areasect := func(etype int, name string, line int) *Sect {
+		return im.sect(etype, "AREA:"+name, name, func(s *Sect) {
+			r, ok := areasects[name]
+			if !ok {
+				im.skip(line, "area section %s is not defined", name)
+				return
+			}
+			if t := strings.ToUpper(r.str("AreaType")); t != "" && t != "SHELL" {
+				im.skip(r.line, "area section %s: %s is translated as shell", name, r.str("AreaType"))
+			}
+			f := NewFig()
+			f.Prop = prop(r.str("Material"), r.line)
+			if th, ok := r.float("Thickness", "MatThick"); ok {
+				f.Value["THICK"] = th * length
+			}
+			s.Figs = append(s.Figs, f)
+		})
+	}
+	// Joint
+	joints := make(map[string]*Node)
+	for _, r := range tables["JOINT COORDINATES"] {
+		coord := make([]float64, 3)
+		if _, ok := r.fields["GLOBALX"]; ok {
+			for i, key := range []string{"GlobalX", "GlobalY", "GlobalZ"} {
+				coord[i], _ = r.float(key)
+			}
+		} else {
+			if t := strings.ToUpper(r.str("CoordType")); t != "" && t != "CARTESIAN" {
+				im.skip(r.line, "joint %s: coordinate type %s is not translated", r.str("Joint"), r.str("CoordType"))
+				continue
+			}
+			if cs := strings.ToUpper(r.str("CoordSys")); cs != "" && cs != "GLOBAL" {
+				im.skip(r.line, "joint %s: coordinate system %s is taken as GLOBAL", r.str("Joint"), r.str("CoordSys"))
+			}
+			for i, key := range []string{"XorR", "Y", "Z"} {
+				coord[i], _ = r.float(key)
+			}
+		}
+		for i := 0; i < 3; i++ {
+			coord[i] *= length
+		}
+		joints[r.str("Joint")] = im.node(coord)
+	}
+	joint := func(r *s2kRow, key string) *Node {
+		n, ok := joints[r.str(key)]
+		if !ok {
+			im.skip(r.line, "joint %s is not defined", r.str(key))
+		}
+		return n
+	}
+	for _, r := range tables["JOINT RESTRAINT ASSIGNMENTS"] {
+		if n := joint(r, "Joint"); n != nil {
+			for i, key := range []string{"U1", "U2", "U3", "R1", "R2", "R3"} {
+				n.Conf[i] = r.yes(key)
+			}
+		}
+	}
+	// Frame
+	assign := make(map[string]*s2kRow)
+	for _, r := range tables["FRAME SECTION ASSIGNMENTS"] {
+		assign[r.str("Frame")] = r
+	}
+	angles := make(map[string]float64)
+	for _, r := range tables["FRAME LOCAL AXES ASSIGNMENTS 1 - TYPICAL"] {
+		angles[r.str("Frame")], _ = r.float("Angle")
+	}
+	releases := make(map[string]*s2kRow)
+	for _, r := range tables["FRAME RELEASE ASSIGNMENTS 1 - GENERAL"] {
+		releases[r.str("Frame")] = r
+	}
+	for _, r := range tables["CONNECTIVITY - FRAME"] {
+		name := r.str("Frame")
+		if r.yes("IsCurved") {
+			im.skip(r.line, "frame %s: curved frame is taken as straight", name)
+		}
+		n1 := joint(r, "JointI")
+		n2 := joint(r, "JointJ")
+		if n1 == nil || n2 == nil || n1 == n2 {
+			continue
+		}
+		d := []float64{n2.Coord[0] - n1.Coord[0], n2.Coord[1] - n1.Coord[1], n2.Coord[2] - n1.Coord[2]}
+		etype := lineElemType(d)
+		a, ok := assign[name]
+		if !ok {
+			im.skip(r.line, "frame %s: section is not assigned", name)
+			continue
+		}
+		sname := a.str("AnalSect")
+		if sname == "" {
+			sname = a.str("SectionName")
+		}
+		if strings.ToUpper(sname) == "NONE" {
+			im.skip(a.line, "frame %s: section None is not translated", name)
+			continue
+		}
+		el := frame.AddLineElem(-1, []*Node{n1, n2}, framesect(etype, sname, a.line), etype)
+		if err := im.orient(el, s2kAxis3(d, angles[name])); err != nil {
+			im.skip(r.line, "frame %s: %s", name, err.Error())
+		}
+		if rel, ok := releases[name]; ok {
+			for i, end := range []string{"I", "J"} {
+				if rel.yes("M3" + end) {
+					el.Bonds[6*i+4] = Pin
+				}
+				if rel.yes("M2" + end) {
+					el.Bonds[6*i+5] = Pin
+				}
+				for _, key := range []string{"P", "V2", "V3", "T"} {
+					if rel.yes(key + end) {
+						im.skip(rel.line, "frame %s: release %s%s is not translated", name, key, end)
+					}
+				}
+			}
+		}
+	}
+	// Area
+	areaassign := make(map[string]*s2kRow)
+	for _, r := range tables["AREA SECTION ASSIGNMENTS"] {
+		areaassign[r.str("Area")] = r
+	}
+	for _, r := range tables["CONNECTIVITY - AREA"] {
+		name := r.str("Area")
+		nj, _ := r.float("NumJoints")
+		if nj < 3 || nj > 4 {
+			im.skip(r.line, "area %s: %d joints are not translated", name, int(nj))
+			continue
+		}
+		ns := make([]*Node, 0, 4)
+		pts := make([][]float64, 0, 4)
+		for i := 1; i <= int(nj); i++ {
+			n := joint(r, fmt.Sprintf("Joint%d", i))
+			if n == nil {
+				break
+			}
+			ns = append(ns, n)
+			pts = append(pts, n.Coord)
+		}
+		if len(ns) < int(nj) {
+			continue
+		}
+		a, ok := areaassign[name]
+		if !ok {
+			im.skip(r.line, "area %s: section is not assigned", name)
+			continue
+		}
+		if strings.ToUpper(a.str("Section")) == "NONE" {
+			continue
+		}
+		etype := plateElemType(pts)
+		frame.AddPlateElem(-1, ns, areasect(etype, a.str("Section"), a.line), etype)
+	}
+	// Load
+	for _, r := range tables["JOINT LOADS - FORCE"] {
+		pat := r.str("LoadPat")
+		if pattern == "" {
+			pattern = pat
+		}
+		if !strings.EqualFold(pat, pattern) {
+			im.skip(r.line, "joint %s: load of pattern %s is not translated", r.str("Joint"), pat)
+			continue
+		}
+		if cs := strings.ToUpper(r.str("CoordSys")); cs != "" && cs != "GLOBAL" {
+			im.skip(r.line, "joint %s: load in %s is taken as GLOBAL", r.str("Joint"), r.str("CoordSys"))
+		}
+		if n := joint(r, "Joint"); n != nil {
+			for i, key := range []string{"F1", "F2", "F3", "M1", "M2", "M3"} {
+				val, _ := r.float(key)
+				if i < 3 {
+					n.Load[i] += val * force
+				} else {
+					n.Load[i] += val * force * length
+				}
+			}
+		}
+	}
+	// Others
+	known := make(map[string]bool)
+	for _, t := range append(s2kTables, s2kIgnored...) {
+		known[t] = true
+	}
+	others := make([]string, 0)
+	for _, t := range order {
+		if !known[t] && len(tables[t]) > 0 {
+			others = append(others, t)
+		}
+	}
+	sort.Strings(others)
+	for _, t := range others {
+		im.skip(tables[t][0].line, "TABLE \"%s\" (%d rows) is not translated", t, len(tables[t]))
+	}
+	if frame.Name == "" {
+		frame.Name = filepath.Base(filename)
+	}
+	return im.messages, nil
+}
@@ -1,5 +1,11 @@
 package st
 
+import (
+	"fmt"
+
+	"github.com/yofu/st/arclm"
+)
+
 const (
 	nodeSelectPixel = 15
 	elemSelectPixel = 5
@@ -44,6 +50,35 @@ func SelectConfed(stw Selector) {
 	stw.SelectNode(nodes[:num])
 }
 
+// CheckSingularity selects nodes whose DOFs have small pivots in the global stiffness of period per,
+// and returns the report of arclm.Frame.Diagnose.
+func CheckSingularity(stw Selector, per string, tol float64) (string, error) {
+	stw.Deselect()
+	frame := stw.Frame()
+	af := frame.Arclms[per]
+	if af == nil {
+		return "", fmt.Errorf("frame isn't extracted to period %s", per)
+	}
+	d, sps, err := af.Diagnose(tol)
+	if err != nil {
+		return "", err
+	}
+	nodes := make([]*Node, 0, len(sps))
+	selected := make(map[int]bool)
+	for _, sp := range sps {
+		if selected[sp.Node.Num] {
+			continue
+		}
+		if n, ok := frame.Nodes[sp.Node.Num]; ok {
+			nodes = append(nodes, n)
+			selected[n.Num] = true
+		}
+	}
+	stw.SelectNode(nodes)
+	stw.Redraw()
+	return arclm.DiagnosisReport(d, sps), nil
+}
+
 func CheckFrame(stw Selector) {
 	stw.Deselect()
 	frame := stw.Frame()
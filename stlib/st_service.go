@@ -0,0 +1,259 @@
+package st
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// Serviceability checks of girders and slabs.
+//
+// Deflection of a girder is measured from the chord between its end nodes under the long-term (L) period.
+// The mid-span deflection is the sum of the one interpolated from the end rotations
+// and the one of a fixed-ended beam under the load of CMQ, assumed to be uniformly distributed.
+// CMQ is the one used by the last analysis (Elem.EffectiveCmq), which includes slab and self weight:
+//
+//	δ = L(θj - θi)/8 + WL^3/(384EI)
+//
+// For a cantilever, the deflection of its tip relative to the root is checked with the span multiplied by Cantilever.
+// Elastic deflection is multiplied by the creep factor (Building Standard Law Notification No.1459: RC 8, wood 2).
+//
+// Natural frequency of a rectangular slab is estimated as the one of a simply supported plate:
+//
+//	f = π/2 (1/a^2 + 1/b^2) √(D g/w),  D = Et^3/12(1-ν^2)
+//
+// where w is the weight per area for seismic load.
+
+// Serviceability holds the limits of serviceability checks.
+type Serviceability struct {
+	Ratio      float64 // deflection <= span / Ratio
+	Absolute   float64 // deflection <= Absolute [cm]
+	Cantilever float64 // span of a cantilever is its length times Cantilever
+	CreepRC    float64
+	CreepWood  float64
+	Frequency  float64 // natural frequency of slabs >= Frequency [Hz]
+}
+
+func NewServiceability() *Serviceability {
+	return &Serviceability{
+		Ratio:      300.0,
+		Absolute:   2.0,
+		Cantilever: 2.0,
+		CreepRC:    8.0,
+		CreepWood:  2.0,
+		Frequency:  8.0,
+	}
+}
+
+// Creep returns the creep factor of elem.
+func (sv *Serviceability) Creep(elem *Elem) float64 {
+	if len(elem.Sect.Figs) == 0 || elem.Sect.Figs[0].Prop == nil {
+		return 1.0
+	}
+	prop := elem.Sect.Figs[0].Prop
+	switch {
+	case prop.IsRc(1e-3):
+		return sv.CreepRC
+	case prop.IsWood(0.0, 1e-3):
+		return sv.CreepWood
+	}
+	return 1.0
+}
+
+// CantileverTip returns the index of the free end of elem if elem is a cantilever.
+// An end is free when it is not restrained vertically and no other line element is connected to it.
+func (elem *Elem) CantileverTip() (int, bool) {
+	if elem.Frame == nil || !elem.IsLineElem() {
+		return -1, false
+	}
+	tip := -1
+	for i := 0; i < 2; i++ {
+		n := elem.Enod[i]
+		if n.Conf[2] {
+			continue
+		}
+		free := true
+		for _, el := range elem.Frame.SearchElem(n) {
+			if el != elem && el.IsLineElem() {
+				free = false
+				break
+			}
+		}
+		if free {
+			if tip >= 0 {
+				return -1, false
+			}
+			tip = i
+		}
+	}
+	return tip, tip >= 0
+}
+
+// Deflection returns the downward deflection [m] of elem relative to the chord between its end nodes, and whether elem is a cantilever.
+func (elem *Elem) Deflection(period string) (float64, bool, error) {
+	if !elem.IsLineElem() {
+		return 0.0, false, fmt.Errorf("Deflection: ELEM %d is not a line element", elem.Num)
+	}
+	l := elem.Length()
+	if l == 0.0 {
+		return 0.0, false, fmt.Errorf("Deflection: ELEM %d: length = 0", elem.Num)
+	}
+	d := elem.Direction(true)
+	v := []float64{-d[2] * d[0], -d[2] * d[1], 1.0 - d[2]*d[2]}
+	if Dot(v, v, 3) < 1e-6 {
+		return 0.0, false, fmt.Errorf("Deflection: ELEM %d is vertical", elem.Num)
+	}
+	v = Normalize(v)
+	b := Cross(d, v)
+	w := make([]float64, 2)
+	s := make([]float64, 2)
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 3; j++ {
+			w[i] += elem.Enod[i].ReturnDisp(period, j) * v[j]
+			s[i] += elem.Enod[i].ReturnDisp(period, j+3) * b[j]
+		}
+	}
+	if tip, ok := elem.CantileverTip(); ok {
+		return w[1-tip] - w[tip], true, nil
+	}
+	ei, err := elem.BendingStiffness(b)
+	if err != nil {
+		return 0.0, false, err
+	}
+	cmq := elem.EffectiveCmq
+	if len(cmq) < 12 {
+		cmq = elem.Cmq
+	}
+	load := 0.0
+	if len(cmq) >= 12 {
+		load = cmq[2] + cmq[8]
+	}
+	return l*(s[1]-s[0])/8.0 + load*l*l*l/(384.0*ei), false, nil
+}
+
+// BendingStiffness returns EI [tfm2] of elem for bending about axis.
+func (elem *Elem) BendingStiffness(axis []float64) (float64, error) {
+	if len(elem.Sect.Figs) == 0 || elem.Sect.Figs[0].Prop == nil {
+		return 0.0, fmt.Errorf("BendingStiffness: SECT %d has no figure", elem.Sect.Num)
+	}
+	ix, err := elem.Sect.Ix(0)
+	if err != nil {
+		return 0.0, err
+	}
+	iy, err := elem.Sect.Iy(0)
+	if err != nil {
+		return 0.0, err
+	}
+	strong, weak, err := elem.PrincipalAxis(elem.Cang)
+	if err != nil {
+		return 0.0, err
+	}
+	cs := Dot(axis, strong, 3)
+	cw := Dot(axis, weak, 3)
+	ei := elem.Sect.Figs[0].Prop.EL() * (ix*cs*cs + iy*cw*cw)
+	if ei <= 0.0 {
+		return 0.0, fmt.Errorf("BendingStiffness: ELEM %d: EI = 0", elem.Num)
+	}
+	return ei, nil
+}
+
+// NaturalFrequency returns the natural frequency [Hz] of a rectangular slab.
+func (elem *Elem) NaturalFrequency() (float64, error) {
+	if elem.Etype != SLAB || elem.Enods != 4 {
+		return 0.0, fmt.Errorf("NaturalFrequency: ELEM %d is not a rectangular slab", elem.Num)
+	}
+	t, err := elem.Sect.Thick(0)
+	if err != nil {
+		return 0.0, err
+	}
+	w := elem.Sect.Weight()[2]
+	if w <= 0.0 {
+		return 0.0, fmt.Errorf("NaturalFrequency: ELEM %d: weight = 0", elem.Num)
+	}
+	a, b := elem.PlateSize()
+	prop := elem.Sect.Figs[0].Prop
+	poi := prop.Poi()
+	dd := prop.ES() * t * t * t / (12.0 * (1.0 - poi*poi))
+	return 0.5 * math.Pi * (1.0/(a*a) + 1.0/(b*b)) * math.Sqrt(dd*9.80665/w), nil
+}
+
+// ServiceRate returns the rate of serviceability set by ServiceabilityCheck.
+func (elem *Elem) ServiceRate() (float64, error) {
+	if val, ok := elem.Values["SERVICE"]; ok {
+		return val, nil
+	}
+	return 0.0, errors.New("no service rate")
+}
+
+// ServiceabilityCheck checks deflection of girders and natural frequency of slabs, and writes the result to Ce(fn, ".svc").
+// The rates are kept in the elements to be shown as ServiceRate.
+func (frame *Frame) ServiceabilityCheck(fn string, sv *Serviceability) error {
+	girders := make([]*Elem, 0)
+	slabs := make([]*Elem, 0)
+	for _, el := range frame.Elems {
+		delete(el.Values, "SERVICE")
+		switch el.Etype {
+		case GIRDER:
+			girders = append(girders, el)
+		case SLAB:
+			slabs = append(slabs, el)
+		}
+	}
+	sort.Sort(ElemByNum{girders})
+	sort.Sort(ElemByNum{slabs})
+	var otp bytes.Buffer
+	otp.WriteString("使用性の検討\n")
+	otp.WriteString(fmt.Sprintf("たわみ: δ×クリープ係数 <= L/%.0f かつ %.1f[cm] (片持ち梁: L×%.1f)\n", sv.Ratio, sv.Absolute, sv.Cantilever))
+	otp.WriteString(fmt.Sprintf("床振動: 固有振動数 >= %.1f[Hz]\n\n", sv.Frequency))
+	otp.WriteString(" ELEM SECT        L[m]   δ[cm] クリープ  δc[cm]  限界[cm]    検定比\n")
+	maxrate := 0.0
+	for _, el := range girders {
+		delta, cantilever, err := el.Deflection("L")
+		if err != nil {
+			continue
+		}
+		l := el.Length()
+		span := l
+		mark := ""
+		if cantilever {
+			span *= sv.Cantilever
+			mark = " 片持ち"
+		}
+		creep := sv.Creep(el)
+		dc := delta * creep * 100.0 // [cm]
+		limit := math.Min(span*100.0/sv.Ratio, sv.Absolute)
+		rate := math.Abs(dc) / limit
+		el.Values["SERVICE"] = rate
+		if rate > maxrate {
+			maxrate = rate
+		}
+		otp.WriteString(fmt.Sprintf("%5d %4d %11.3f %7.3f %8.1f %7.3f %9.3f %9.5f%s\n", el.Num, el.Sect.Num, l, delta*100.0, creep, dc, limit, rate, mark))
+	}
+	otp.WriteString("\n ELEM SECT        a[m]    b[m]   t[cm]  w[tf/m2]   f[Hz]    検定比\n")
+	for _, el := range slabs {
+		f, err := el.NaturalFrequency()
+		if err != nil {
+			continue
+		}
+		a, b := el.PlateSize()
+		t, _ := el.Sect.Thick(0)
+		rate := sv.Frequency / f
+		el.Values["SERVICE"] = rate
+		if rate > maxrate {
+			maxrate = rate
+		}
+		otp.WriteString(fmt.Sprintf("%5d %4d %11.3f %7.3f %7.1f %9.3f %7.2f %9.5f\n", el.Num, el.Sect.Num, a, b, t*100.0, el.Sect.Weight()[2], f, rate))
+	}
+	otp.WriteString(fmt.Sprintf("\n検定比の最大値: %.5f\n", maxrate))
+	w, err := os.Create(Ce(fn, ".svc"))
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	otp = AddCR(otp)
+	otp.WriteTo(w)
+	return nil
+}
@@ -0,0 +1,143 @@
+package st
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testSlabFrame is a one-storey frame of 6m x 6m whose girders 201-204 carry slab 301 and have no CMQ of their own.
+const testSlabFrame = `"TEST"
+BASE    0.200 0.200
+LOCATE  1.000
+TFACT   0.020
+GPERIOD 0.600
+NFLOOR 2
+HEIGHT -100.000 1.500 100.000
+NOKIHEIGHT 3.000
+PROP 101 PNAME STEEL
+         HIJU       7.80000
+         E      21000000.000
+         POI        0.33333
+         PCOLOR   0   0   0
+PROP 201 PNAME CONCRETE
+         HIJU       2.40000
+         E       2100000.000
+         POI        0.16667
+         PCOLOR   0   0   0
+SECT 101 SNAME C1
+         NFIG 1
+         FIG   1 FPROP 101
+                 AREA 0.0100
+                 IXX  0.00010000
+                 IYY  0.00010000
+                 VEN  0.00020000
+         COLOR   0   0   0
+SECT 201 SNAME G1
+         NFIG 1
+         FIG   1 FPROP 101
+                 AREA 0.0060
+                 IXX  0.00020000
+                 IYY  0.00001000
+                 VEN  0.00000100
+         COLOR   0   0   0
+SECT 301 SNAME S1
+         NFIG 1
+         FIG   1 FPROP 201
+                 THICK 0.15000
+         LLOAD 0.600 0.300 0.200
+         COLOR   0   0   0
+NODE 1 CORD 0.0 0.0 0.0 ICON 1 1 1 1 1 1 VCON 0 0 0 0 0 0
+NODE 2 CORD 6.0 0.0 0.0 ICON 1 1 1 1 1 1 VCON 0 0 0 0 0 0
+NODE 3 CORD 6.0 6.0 0.0 ICON 1 1 1 1 1 1 VCON 0 0 0 0 0 0
+NODE 4 CORD 0.0 6.0 0.0 ICON 1 1 1 1 1 1 VCON 0 0 0 0 0 0
+NODE 5 CORD 0.0 0.0 3.0 ICON 0 0 0 0 0 0 VCON 0 0 0 0 0 0
+NODE 6 CORD 6.0 0.0 3.0 ICON 0 0 0 0 0 0 VCON 0 0 0 0 0 0
+NODE 7 CORD 6.0 6.0 3.0 ICON 0 0 0 0 0 0 VCON 0 0 0 0 0 0
+NODE 8 CORD 0.0 6.0 3.0 ICON 0 0 0 0 0 0 VCON 0 0 0 0 0 0
+ELEM 101 ESECT 101 ENODS 2 ENOD 1 5 BONDS 0 0 0 0 0 0 0 0 0 0 0 0
+           CANG 0.0
+           CMQ 0 0 0 0 0 0 0 0 0 0 0 0
+           TYPE COLUMN
+ELEM 102 ESECT 101 ENODS 2 ENOD 2 6 BONDS 0 0 0 0 0 0 0 0 0 0 0 0
+           CANG 0.0
+           CMQ 0 0 0 0 0 0 0 0 0 0 0 0
+           TYPE COLUMN
+ELEM 103 ESECT 101 ENODS 2 ENOD 3 7 BONDS 0 0 0 0 0 0 0 0 0 0 0 0
+           CANG 0.0
+           CMQ 0 0 0 0 0 0 0 0 0 0 0 0
+           TYPE COLUMN
+ELEM 104 ESECT 101 ENODS 2 ENOD 4 8 BONDS 0 0 0 0 0 0 0 0 0 0 0 0
+           CANG 0.0
+           CMQ 0 0 0 0 0 0 0 0 0 0 0 0
+           TYPE COLUMN
+ELEM 201 ESECT 201 ENODS 2 ENOD 5 6 BONDS 0 0 0 0 0 0 0 0 0 0 0 0
+           CANG 0.0
+           CMQ 0 0 0 0 0 0 0 0 0 0 0 0
+           TYPE GIRDER
+ELEM 202 ESECT 201 ENODS 2 ENOD 6 7 BONDS 0 0 0 0 0 0 0 0 0 0 0 0
+           CANG 0.0
+           CMQ 0 0 0 0 0 0 0 0 0 0 0 0
+           TYPE GIRDER
+ELEM 203 ESECT 201 ENODS 2 ENOD 7 8 BONDS 0 0 0 0 0 0 0 0 0 0 0 0
+           CANG 0.0
+           CMQ 0 0 0 0 0 0 0 0 0 0 0 0
+           TYPE GIRDER
+ELEM 204 ESECT 201 ENODS 2 ENOD 8 5 BONDS 0 0 0 0 0 0 0 0 0 0 0 0
+           CANG 0.0
+           CMQ 0 0 0 0 0 0 0 0 0 0 0 0
+           TYPE GIRDER
+ELEM 301 ESECT 301 ENODS 4 ENOD 5 6 7 8 BONDS 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0
+                     EBANS 1 EBAN 1 BNODS 4 BNOD 5 6 7 8
+           TYPE SLAB
+`
+
+func TestDeflectionSlabLoad(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "test.inp")
+	if err := os.WriteFile(fn, []byte(testSlabFrame), 0644); err != nil {
+		t.Fatal(err)
+	}
+	frame := NewFrame()
+	if err := frame.ReadInp(fn, []float64{0.0, 0.0, 0.0}, 0.0, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := frame.AnalyseAll(Ce(fn, ".otp")); err != nil {
+		t.Fatal(err)
+	}
+	elem := frame.Elems[201]
+	for i, v := range elem.Cmq {
+		if v != 0.0 {
+			t.Fatalf("Cmq[%d] = %g, want the original 0", i, v)
+		}
+	}
+	if len(elem.EffectiveCmq) != 12 {
+		t.Fatalf("EffectiveCmq = %v", elem.EffectiveCmq)
+	}
+	// slab: (0.15*2.4 + 0.3 of LLOAD for frames) * 6 * 6 / 4, girder: 0.006 * 7.8 * 6
+	want := (0.15*2.4+0.3)*9.0 + 0.006*7.8*6.0
+	load := elem.EffectiveCmq[2] + elem.EffectiveCmq[8]
+	if math.Abs(load-want) > 1e-3 {
+		t.Errorf("load = %g, want %g", load, want)
+	}
+	d, cantilever, err := elem.Deflection("L")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cantilever {
+		t.Error("girder is a cantilever")
+	}
+	elem.EffectiveCmq = nil
+	d0, _, err := elem.Deflection("L")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ei, err := elem.BendingStiffness(Cross(elem.Direction(true), []float64{0.0, 0.0, 1.0}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := elem.Length()
+	if got, want := d-d0, load*l*l*l/(384.0*ei); math.Abs(got-want) > 1e-9 {
+		t.Errorf("deflection by CMQ = %g, want %g", got, want)
+	}
+}
@@ -81,7 +81,7 @@ var ( // Boundary for Rainbow (length should be <= 6)
 )
 var (
 	// ECOLORS = []string{ "WHITE", "BLACK", "BY SECTION", "BY RATE", "BY HEIGHT", "BY N" }
-	ECOLORS = []string{"WHITE", "BLACK", "BY SECTION", "BY SECTION", "BY RATE", "BY N", "BY STRONG", "BY ENERGY"}
+	ECOLORS = []string{"WHITE", "BLACK", "BY SECTION", "BY SECTION", "BY RATE", "BY N", "BY STRONG", "BY ENERGY", "BY DIFF", "BY SERVICE"}
 	PERIODS = []string{"L", "X", "Y"}
 )
 
@@ -95,6 +95,8 @@ const (
 	ECOLOR_N
 	ECOLOR_STRONG
 	ECOLOR_ENERGY
+	ECOLOR_DIFF
+	ECOLOR_SERVICE
 )
 
 type Show struct {
@@ -109,7 +111,10 @@ type Show struct {
 	ElemCaption uint
 	SrcanRate   uint
 
-	Energy bool
+	Energy        bool
+	Service       bool
+	StrengthRatio bool
+	Diff          *FrameDiff
 
 	GlobalAxis      bool
 	GlobalAxisSize  float64
@@ -227,6 +232,8 @@ func NewShow(frame *Frame) *Show {
 		ElemCaption:          0,
 		SrcanRate:            0,
 		Energy:               false,
+		Service:              false,
+		StrengthRatio:        false,
 		GlobalAxis:           true,
 		GlobalAxisSize:       1.0,
 		ElementAxis:          false,
@@ -303,6 +310,7 @@ func (show *Show) Copy() *Show {
 		s.Zrange[i] = show.Zrange[i]
 	}
 	s.ColorMode = show.ColorMode
+	s.Diff = show.Diff
 	s.NodeCaption = show.NodeCaption
 	s.ElemCaption = show.ElemCaption
 	s.SrcanRate = show.SrcanRate
@@ -442,6 +450,9 @@ func (show *Show) Dataline() []string {
 	if show.SrcanRate != 0 || show.ColorMode == ECOLOR_RATE {
 		first = append(first, "断面検定比")
 	}
+	if show.Service || show.ColorMode == ECOLOR_SERVICE {
+		first = append(first, "使用性検定比")
+	}
 	second := make([]string, 0)
 	num2 := 0
 	for i, _ := range ETYPES {
@@ -0,0 +1,484 @@
+package st
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Automatic member sizing of steel sections.
+// Sections are divided into groups sharing a shape chosen from candidates,
+// and analysis -> section check -> resize is repeated until the rates of all groups are below the target.
+//
+// A sizing file is written as
+//
+//	TARGET  0.95
+//	MAXITER 10
+//	GROUP   101,102  SEARCH BCR-400 BCR-450    # candidates from the catalogue by prefix
+//	GROUP   201      H-400x200x8x13 H-450x200x9x14 H-500x200x10x16
+//	DEPTH   101 201                            # depth of 101 >= depth of 201
+//
+// Candidates are sorted by area, which is proportional to steel weight.
+
+// SizingGroup is a group of sections sharing a shape.
+type SizingGroup struct {
+	Sects      []int
+	Candidates []Shape
+	Current    int
+	lower      int // candidates below lower have failed
+}
+
+// Sizing is the definition of automatic member sizing.
+type Sizing struct {
+	Target  float64
+	Maxiter int
+	Groups  []*SizingGroup
+	Depths  [][]int // {a, b}: depth of a >= depth of b
+}
+
+func NewSizing() *Sizing {
+	return &Sizing{
+		Target:  0.95,
+		Maxiter: 10,
+		Groups:  make([]*SizingGroup, 0),
+		Depths:  make([][]int, 0),
+	}
+}
+
+// ReadSizing reads a sizing file.
+func ReadSizing(filename string) (*Sizing, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseSizing(f)
+}
+
+func ParseSizing(r io.Reader) (*Sizing, error) {
+	sz := NewSizing()
+	s := bufio.NewScanner(r)
+	nline := 0
+	for s.Scan() {
+		nline++
+		line := s.Text()
+		if ind := strings.Index(line, "#"); ind >= 0 {
+			line = line[:ind]
+		}
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			continue
+		}
+		switch strings.ToUpper(words[0]) {
+		default:
+			return nil, fmt.Errorf("ParseSizing: line %d: unknown keyword %s", nline, words[0])
+		case "TARGET":
+			if len(words) < 2 {
+				return nil, fmt.Errorf("ParseSizing: line %d: not enough data", nline)
+			}
+			val, err := strconv.ParseFloat(words[1], 64)
+			if err != nil {
+				return nil, err
+			}
+			sz.Target = val
+		case "MAXITER":
+			if len(words) < 2 {
+				return nil, fmt.Errorf("ParseSizing: line %d: not enough data", nline)
+			}
+			val, err := strconv.ParseInt(words[1], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			sz.Maxiter = int(val)
+		case "GROUP":
+			if len(words) < 3 {
+				return nil, fmt.Errorf("ParseSizing: line %d: not enough data", nline)
+			}
+			g := &SizingGroup{
+				Sects:      SplitNums(words[1]),
+				Candidates: make([]Shape, 0),
+			}
+			if len(g.Sects) == 0 {
+				return nil, fmt.Errorf("ParseSizing: line %d: no section", nline)
+			}
+			if strings.ToUpper(words[2]) == "SEARCH" {
+				for _, prefix := range words[3:] {
+					es, err := SearchSteel(prefix)
+					if err != nil {
+						return nil, err
+					}
+					for _, e := range es {
+						g.Candidates = append(g.Candidates, e.Shape())
+					}
+				}
+			} else {
+				for _, w := range words[2:] {
+					e, err := LookupSteel(w)
+					if err != nil {
+						return nil, fmt.Errorf("ParseSizing: line %d: %s", nline, err.Error())
+					}
+					g.Candidates = append(g.Candidates, e.Shape())
+				}
+			}
+			if len(g.Candidates) == 0 {
+				return nil, fmt.Errorf("ParseSizing: line %d: no candidate", nline)
+			}
+			sort.SliceStable(g.Candidates, func(i, j int) bool {
+				return g.Candidates[i].A() < g.Candidates[j].A()
+			})
+			sz.Groups = append(sz.Groups, g)
+		case "DEPTH":
+			if len(words) < 3 {
+				return nil, fmt.Errorf("ParseSizing: line %d: not enough data", nline)
+			}
+			pair := make([]int, 2)
+			for i := 0; i < 2; i++ {
+				val, err := strconv.ParseInt(words[1+i], 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				pair[i] = int(val)
+			}
+			sz.Depths = append(sz.Depths, pair)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	for _, d := range sz.Depths {
+		for _, snum := range d {
+			if sz.Group(snum) == nil {
+				return nil, fmt.Errorf("ParseSizing: DEPTH: section %d is not in any group", snum)
+			}
+		}
+	}
+	return sz, nil
+}
+
+// Group returns the group which snum belongs to.
+func (sz *Sizing) Group(snum int) *SizingGroup {
+	for _, g := range sz.Groups {
+		for _, s := range g.Sects {
+			if s == snum {
+				return g
+			}
+		}
+	}
+	return nil
+}
+
+func (g *SizingGroup) Shape() Shape {
+	return g.Candidates[g.Current]
+}
+
+func (g *SizingGroup) String() string {
+	sects := make([]string, len(g.Sects))
+	for i, s := range g.Sects {
+		sects[i] = strconv.Itoa(s)
+	}
+	return strings.Join(sects, ",")
+}
+
+// depth returns the depth of a shape for the strong axis.
+func depth(s Shape) float64 {
+	return s.Breadth(false)
+}
+
+// steelShape returns the steel part of SectionRate whose shape is changed by sizing.
+func steelShape(al SectionRate) (*SColumn, bool) {
+	switch al := al.(type) {
+	case *SColumn:
+		return al, true
+	case *SGirder:
+		return &al.SColumn, true
+	case *SBrace:
+		return &al.SColumn, true
+	}
+	return nil, false
+}
+
+// capacity returns the property of shape which the rate of the group is assumed to be inversely proportional to:
+// section modulus for columns and girders, area for braces.
+func (g *SizingGroup) capacity(frame *Frame, s Shape) float64 {
+	if _, ok := frame.Sects[g.Sects[0]].Allow.(*SBrace); ok {
+		return s.A()
+	}
+	return s.Zx()
+}
+
+// SetSizing checks that all the sections of sz are steel and sets the initial shapes.
+// The initial shape of a group is the current one of its first section if it is one of the candidates,
+// otherwise the lightest candidate not lighter than it.
+func (frame *Frame) SetSizing(sz *Sizing) error {
+	for _, g := range sz.Groups {
+		for _, snum := range g.Sects {
+			sec, ok := frame.Sects[snum]
+			if !ok {
+				return fmt.Errorf("SetSizing: SECT %d doesn't exist", snum)
+			}
+			if _, ok := steelShape(sec.Allow); !ok {
+				return fmt.Errorf("SetSizing: SECT %d is not a steel column, girder nor brace", snum)
+			}
+		}
+		sc, _ := steelShape(frame.Sects[g.Sects[0]].Allow)
+		g.Current = len(g.Candidates) - 1
+		for i, c := range g.Candidates {
+			if c.String() == sc.Shape.String() {
+				g.Current = i
+				break
+			}
+			if c.A() >= sc.Shape.A() && i < g.Current {
+				g.Current = i
+			}
+		}
+		g.lower = 0
+		frame.applySizingGroup(g)
+	}
+	return nil
+}
+
+func (frame *Frame) applySizingGroup(g *SizingGroup) {
+	s := g.Shape()
+	for _, snum := range g.Sects {
+		sec := frame.Sects[snum]
+		sc, _ := steelShape(sec.Allow)
+		sc.Shape = s
+		if sec.HasArea(0) {
+			sec.Figs[0].SetShapeProperty(s)
+			sec.Name = s.Description()
+		}
+	}
+}
+
+// SectRates returns the maximum rate of each section after SectionRateCalculation.
+func (frame *Frame) SectRates() map[int]float64 {
+	rtn := make(map[int]float64)
+	for _, el := range frame.Elems {
+		if !el.IsLineElem() {
+			continue
+		}
+		val, err := el.RateMax(nil)
+		if err != nil {
+			continue
+		}
+		snum := el.OriginalSection().Num
+		if val > rtn[snum] {
+			rtn[snum] = val
+		}
+	}
+	return rtn
+}
+
+// SteelWeight returns the weight [tf] of steel of the sections.
+func (frame *Frame) SteelWeight(sects ...int) float64 {
+	rtn := 0.0
+	for _, snum := range sects {
+		if sec, ok := frame.Sects[snum]; ok && sec.Allow != nil && sec.HasArea(0) {
+			if val, ok := sec.Allow.Amount()["STEEL"]; ok {
+				rtn += val * sec.TotalAmount() * 7.8
+			}
+		}
+	}
+	return rtn
+}
+
+// Resize chooses the next shapes of the groups from the rates.
+// A group whose rate r exceeds the target is changed to the lightest candidate whose capacity is not less than
+// r/target times the current one, and the current and lighter candidates are never chosen again.
+// A group below the target is reduced in the same way within the candidates which have not failed.
+// Depth constraints are satisfied by making the deeper group heavier.
+// It returns whether any shape is changed and whether all rates are below the target.
+func (frame *Frame) Resize(sz *Sizing, rates map[int]float64, otp io.Writer) (bool, bool) {
+	changed := false
+	ok := true
+	for _, g := range sz.Groups {
+		r := g.rate(rates)
+		cur := g.Current
+		if r > sz.Target {
+			ok = false
+			if g.Current+1 > g.lower {
+				g.lower = g.Current + 1
+			}
+		}
+		next := cur
+		if r > 0.0 && g.lower < len(g.Candidates) {
+			need := g.capacity(frame, g.Shape()) * r / sz.Target
+			next = len(g.Candidates) - 1
+			for i := g.lower; i < len(g.Candidates); i++ {
+				if g.capacity(frame, g.Candidates[i]) >= need {
+					next = i
+					break
+				}
+			}
+		}
+		if next < g.lower && g.lower < len(g.Candidates) {
+			next = g.lower
+		}
+		fmt.Fprintf(otp, "GROUP %-16s RATE %7.5f %-24s", g, r, g.Shape().String())
+		if next != cur {
+			g.Current = next
+			changed = true
+			fmt.Fprintf(otp, " -> %s", g.Shape().String())
+		} else if r > sz.Target {
+			fmt.Fprintf(otp, " NO LARGER CANDIDATE")
+		}
+		fmt.Fprintf(otp, "\n")
+	}
+	for _, d := range sz.Depths {
+		ga := sz.Group(d[0])
+		gb := sz.Group(d[1])
+		if ga == gb || depth(ga.Shape()) >= depth(gb.Shape()) {
+			continue
+		}
+		c0 := ga.capacity(frame, ga.Shape())
+		found := false
+		for i := ga.lower; i < len(ga.Candidates); i++ {
+			c := ga.Candidates[i]
+			if depth(c) >= depth(gb.Shape()) && ga.capacity(frame, c) >= c0 {
+				fmt.Fprintf(otp, "DEPTH %d >= %d: %s -> %s\n", d[0], d[1], ga.Shape().String(), c.String())
+				ga.Current = i
+				changed = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			ok = false
+			fmt.Fprintf(otp, "DEPTH %d >= %d: NOT SATISFIED (%.1f < %.1f)\n", d[0], d[1], depth(ga.Shape()), depth(gb.Shape()))
+		}
+	}
+	for _, g := range sz.Groups {
+		frame.applySizingGroup(g)
+	}
+	return changed, ok
+}
+
+// rate returns the maximum rate of the sections of g.
+func (g *SizingGroup) rate(rates map[int]float64) float64 {
+	r := 0.0
+	for _, snum := range g.Sects {
+		if val, ok := rates[snum]; ok && val > r {
+			r = val
+		}
+	}
+	return r
+}
+
+// deleteExtractedBraces deletes braces which ExtractArclm has made from walls and slabs, so that they are made again.
+func (frame *Frame) deleteExtractedBraces() {
+	for _, el := range frame.Elems {
+		if el.IsLineElem() || el.Children == nil {
+			continue
+		}
+		for _, c := range el.Children {
+			if c != nil {
+				frame.DeleteElem(c.Num)
+			}
+		}
+		el.Children = nil
+	}
+}
+
+// Optimise repeats analysis, section check and resizing until all rates are below the target and no shape is changed.
+// If shapes are still changed at Maxiter, the final shapes are analysed and checked once more without resizing.
+// The report of every iteration is written to Ce(fn, ".szr"), the final sections to Ce(fn, ".inp") and Ce(fn, ".lst").
+// The input and output files of the analyses are also named after fn, so that those of the model are kept.
+func (frame *Frame) Optimise(sz *Sizing, cond *Condition, fn string) (bool, error) {
+	err := frame.SetSizing(sz)
+	if err != nil {
+		return false, err
+	}
+	sects := make([]int, 0)
+	for _, g := range sz.Groups {
+		sects = append(sects, g.Sects...)
+	}
+	sort.Ints(sects)
+	var otp bytes.Buffer
+	otp.WriteString(fmt.Sprintf("自動断面算定 TARGET=%.3f MAXITER=%d\n", sz.Target, sz.Maxiter))
+	converged := false
+	output := Ce(fn, ".otp")
+	analysed := false
+	check := func() error {
+		if analysed {
+			frame.deleteExtractedBraces()
+		}
+		analysed = true
+		err := frame.analyseAll(fn, output)
+		if err != nil {
+			return err
+		}
+		err = frame.SectionRateCalculation(output, "L", "X", "X", "Y", "Y", -1.0, cond)
+		if err != nil {
+			return err
+		}
+		otp.WriteString(fmt.Sprintf("STEEL WEIGHT %10.4f [tf]\n", frame.SteelWeight(sects...)))
+		return nil
+	}
+	changed := true
+	for iter := 1; iter <= sz.Maxiter && changed; iter++ {
+		otp.WriteString(fmt.Sprintf("\nITERATION %d\n", iter))
+		err := check()
+		if err != nil {
+			return false, err
+		}
+		var ok bool
+		changed, ok = frame.Resize(sz, frame.SectRates(), &otp)
+		if !changed {
+			converged = ok
+		}
+	}
+	if changed {
+		otp.WriteString("\nFINAL CHECK\n")
+		err := check()
+		if err != nil {
+			return false, err
+		}
+		rates := frame.SectRates()
+		for _, g := range sz.Groups {
+			r := g.rate(rates)
+			fmt.Fprintf(&otp, "GROUP %-16s RATE %7.5f %-24s", g, r, g.Shape().String())
+			if r > sz.Target {
+				fmt.Fprintf(&otp, " NG")
+			}
+			fmt.Fprintf(&otp, "\n")
+		}
+	}
+	if converged {
+		otp.WriteString("\nCONVERGED\n")
+	} else {
+		otp.WriteString("\nNOT CONVERGED\n")
+	}
+	otp.WriteString(fmt.Sprintf("STEEL WEIGHT %10.4f [tf]\n", frame.SteelWeight(sects...)))
+	for _, g := range sz.Groups {
+		otp.WriteString(fmt.Sprintf("GROUP %-16s %s\n", g, g.Shape().String()))
+	}
+	w, err := os.Create(Ce(fn, ".szr"))
+	if err != nil {
+		return converged, err
+	}
+	defer w.Close()
+	otp = AddCR(otp)
+	otp.WriteTo(w)
+	err = frame.WriteInp(Ce(fn, ".inp"))
+	if err != nil {
+		return converged, err
+	}
+	lsts := make([]*Sect, 0, len(frame.Sects))
+	for _, sec := range frame.Sects {
+		if sec.Num < 100 || sec.Num > 900 {
+			continue
+		}
+		lsts = append(lsts, sec)
+	}
+	sort.Sort(SectByNum{lsts})
+	err = WriteLst(Ce(fn, ".lst"), lsts)
+	if err != nil {
+		return converged, err
+	}
+	return converged, nil
+}
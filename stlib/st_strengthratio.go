@@ -0,0 +1,267 @@
+package st
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// Column-to-beam strength ratio (柱梁耐力比).
+// At every node where columns and girders meet, the plastic moments are summed up in each horizontal direction:
+//
+//	ΣMpc / ΣMpb >= 1.5
+//
+// Girders whose horizontal direction is within 45° from X (Y) belong to the direction X (Y),
+// and members bend about the principal axis closer to the normal of the vertical plane of the direction.
+//
+// Plastic moment of steel sections is F Zp, reduced by the axial force of columns:
+//
+//	H (strong axis):          Mpc = min(1.0, 1.18(1-N/Ny)) Mp
+//	H (weak axis):            Mpc = {1 - ((N/Ny - Aw/A)/(1 - Aw/A))^2} Mp  (N/Ny > Aw/A)
+//	box, pipe and the others: Mpc = min(1.0, 1.14(1-N/Ny)) Mp
+//
+// Plastic moment of the other sections is taken from the yield surface of SECT (NZMAX, NZMIN, MXMAX, ...) used in analysis.
+
+// StrengthRatio is the column-to-beam strength ratio at a node in a direction.
+type StrengthRatio struct {
+	Node      *Node
+	Direction int // 0: X, 1: Y
+	Columns   []*Elem
+	Girders   []*Elem
+	N         []float64 // axial force of columns [tf]
+	Mpc       []float64 // [tfm]
+	Mpb       []float64 // [tfm]
+}
+
+func (sr *StrengthRatio) SumMpc() float64 {
+	sum := 0.0
+	for _, m := range sr.Mpc {
+		sum += m
+	}
+	return sum
+}
+
+func (sr *StrengthRatio) SumMpb() float64 {
+	sum := 0.0
+	for _, m := range sr.Mpb {
+		sum += m
+	}
+	return sum
+}
+
+func (sr *StrengthRatio) Ratio() float64 {
+	mpb := sr.SumMpb()
+	if mpb == 0.0 {
+		return 0.0
+	}
+	return sr.SumMpc() / mpb
+}
+
+// axialReduction returns the reduction factor of plastic moment of shape under the ratio of axial force nr = N/Ny.
+func axialReduction(shape Shape, strong bool, nr float64) float64 {
+	nr = math.Abs(nr)
+	if nr >= 1.0 {
+		return 0.0
+	}
+	weak := func(h, tw, tf float64) float64 {
+		aw := (h - 2.0*tf) * tw / shape.A()
+		if nr <= aw {
+			return 1.0
+		}
+		v := (nr - aw) / (1.0 - aw)
+		return 1.0 - v*v
+	}
+	switch sh := shape.(type) {
+	case HKYOU:
+		if !strong {
+			return weak(sh.H, sh.Tw, sh.Tf)
+		}
+		return math.Min(1.0, 1.18*(1.0-nr))
+	case HWEAK:
+		if strong {
+			return weak(sh.H, sh.Tw, sh.Tf)
+		}
+		return math.Min(1.0, 1.18*(1.0-nr))
+	}
+	return math.Min(1.0, 1.14*(1.0-nr))
+}
+
+// IsStrongAxis reports whether the strong axis of elem is closer to axis than the weak axis.
+func (elem *Elem) IsStrongAxis(axis []float64) (bool, error) {
+	s, w, err := elem.PrincipalAxis(elem.Cang)
+	if err != nil {
+		return false, err
+	}
+	return math.Abs(Dot(s, axis, 3)) >= math.Abs(Dot(w, axis, 3)), nil
+}
+
+// PlasticMoment returns the plastic moment [tfm] of elem bending about the principal axis closer to axis under axial force n [tf].
+func (elem *Elem) PlasticMoment(axis []float64, n float64) (float64, error) {
+	strong, err := elem.IsStrongAxis(axis)
+	if err != nil {
+		return 0.0, err
+	}
+	var sc *SColumn
+	switch al := elem.Sect.Allow.(type) {
+	case *SColumn:
+		sc = al
+	case *SGirder:
+		sc = &al.SColumn
+	}
+	if sc != nil {
+		zp := sc.Zpx()
+		if !strong {
+			zp = sc.Zpy()
+		}
+		mp := sc.F * zp * 0.01 * sc.multi
+		return mp * axialReduction(sc.Shape, strong, n/(sc.F*sc.A()*sc.multi)), nil
+	}
+	ind := 8
+	if !strong {
+		ind = 10
+	}
+	mu := 0.5 * (elem.Sect.Yield[ind] - elem.Sect.Yield[ind+1])
+	if mu <= 0.0 {
+		return 0.0, fmt.Errorf("PlasticMoment: SECT %d: no plastic moment", elem.Sect.Num)
+	}
+	nc := 0.5 * (elem.Sect.Yield[0] + elem.Sect.Yield[1])
+	nu := 0.5 * (elem.Sect.Yield[0] - elem.Sect.Yield[1])
+	if nu <= 0.0 {
+		return mu, nil
+	}
+	v := math.Abs(n-nc) / nu
+	if v >= 1.0 {
+		return 0.0, nil
+	}
+	return mu * math.Pow(1.0-math.Pow(v, elem.Sect.Exp), 1.0/elem.Sect.Exp), nil
+}
+
+// StrengthRatio returns the column-to-beam strength ratios at n with the axial force of columns in period.
+func (frame *Frame) StrengthRatio(n *Node, period string) ([]*StrengthRatio, error) {
+	cos45 := 0.5 * math.Sqrt(2.0)
+	rtn := make([]*StrengthRatio, 0)
+	els := frame.NodeToElemAny(n)
+	for d := 0; d < 2; d++ {
+		normal := []float64{0.0, 0.0, 0.0}
+		normal[1-d] = 1.0
+		sr := &StrengthRatio{
+			Node:      n,
+			Direction: d,
+			Columns:   make([]*Elem, 0),
+			Girders:   make([]*Elem, 0),
+			N:         make([]float64, 0),
+			Mpc:       make([]float64, 0),
+			Mpb:       make([]float64, 0),
+		}
+		for _, el := range els {
+			if !el.IsLineElem() {
+				continue
+			}
+			dir := el.Direction(true)
+			switch el.Etype {
+			case COLUMN:
+				if math.Abs(dir[2]) < cos45 {
+					continue
+				}
+				ind := 0
+				if el.Enod[1] == n {
+					ind = 1
+				}
+				nz := el.ReturnStress(period, ind, 0)
+				if ind == 1 {
+					nz = -nz
+				}
+				mp, err := el.PlasticMoment(normal, nz)
+				if err != nil {
+					return nil, err
+				}
+				sr.Columns = append(sr.Columns, el)
+				sr.N = append(sr.N, nz)
+				sr.Mpc = append(sr.Mpc, mp)
+			case GIRDER:
+				h := math.Hypot(dir[0], dir[1])
+				if h == 0.0 || math.Abs(dir[d])/h < cos45 {
+					continue
+				}
+				mp, err := el.PlasticMoment(normal, 0.0)
+				if err != nil {
+					return nil, err
+				}
+				sr.Girders = append(sr.Girders, el)
+				sr.Mpb = append(sr.Mpb, mp)
+			}
+		}
+		if len(sr.Columns) == 0 || len(sr.Girders) == 0 {
+			continue
+		}
+		rtn = append(rtn, sr)
+	}
+	return rtn, nil
+}
+
+// StrengthRatioCheck checks the column-to-beam strength ratios of all nodes and writes the result to Ce(fn, ".cbr").
+// The minimum ratios and the rates required/(ΣMpc/ΣMpb) are kept in the nodes as "STRENGTHRATIO" and "STRENGTHRATE".
+func (frame *Frame) StrengthRatioCheck(fn string, period string, required float64) error {
+	nodes := make([]*Node, 0)
+	for _, n := range frame.Nodes {
+		if n.Values == nil {
+			n.Values = make(map[string]float64)
+		}
+		delete(n.Values, "STRENGTHRATIO")
+		delete(n.Values, "STRENGTHRATE")
+		nodes = append(nodes, n)
+	}
+	sort.Sort(NodeByNum{nodes})
+	var otp bytes.Buffer
+	otp.WriteString("柱梁耐力比\n")
+	otp.WriteString(fmt.Sprintf("ΣMpc/ΣMpb >= %.2f 柱軸力: %s\n\n", required, period))
+	otp.WriteString(" NODE 方向  ΣMpc[tfm]  ΣMpb[tfm]     耐力比 判定\n")
+	minratio := 1e16
+	ng := 0
+	for _, n := range nodes {
+		srs, err := frame.StrengthRatio(n, period)
+		if err != nil {
+			return err
+		}
+		for _, sr := range srs {
+			ratio := sr.Ratio()
+			if ratio == 0.0 {
+				continue
+			}
+			judge := "OK"
+			if ratio < required {
+				judge = "NG"
+				ng++
+			}
+			if ratio < minratio {
+				minratio = ratio
+			}
+			if val, ok := n.Values["STRENGTHRATIO"]; !ok || ratio < val {
+				n.Values["STRENGTHRATIO"] = ratio
+				n.Values["STRENGTHRATE"] = required / ratio
+			}
+			otp.WriteString(fmt.Sprintf("%5d %4s %10.3f %10.3f %10.3f %s\n", n.Num, []string{"X", "Y"}[sr.Direction], sr.SumMpc(), sr.SumMpb(), ratio, judge))
+			for i, el := range sr.Columns {
+				otp.WriteString(fmt.Sprintf("            柱 ELEM %d SECT %d N=%.3f[tf] Mpc=%.3f[tfm]\n", el.Num, el.Sect.Num, sr.N[i], sr.Mpc[i]))
+			}
+			for i, el := range sr.Girders {
+				otp.WriteString(fmt.Sprintf("            梁 ELEM %d SECT %d Mpb=%.3f[tfm]\n", el.Num, el.Sect.Num, sr.Mpb[i]))
+			}
+		}
+	}
+	if minratio == 1e16 {
+		return errors.New("StrengthRatioCheck: no joint of columns and girders")
+	}
+	otp.WriteString(fmt.Sprintf("\n耐力比の最小値: %.3f NG: %d\n", minratio, ng))
+	w, err := os.Create(Ce(fn, ".cbr"))
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	otp = AddCR(otp)
+	otp.WriteTo(w)
+	return nil
+}
@@ -23,6 +23,15 @@ func (t *TagFrame) Checkout(name string) (*Frame, error) {
 	return f, nil
 }
 
+// Tag returns a copy of the tagged frame without checking it out.
+func (t *TagFrame) Tag(name string) (*Frame, error) {
+	f, exists := t.dict[name]
+	if !exists {
+		return nil, fmt.Errorf("tag %s doesn't exist", name)
+	}
+	return f.Snapshot(), nil
+}
+
 func (t *TagFrame) AddTag(frame *Frame, name string, bang bool) error {
 	if !bang {
 		if _, exists := t.dict[name]; exists {
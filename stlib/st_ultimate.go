@@ -0,0 +1,601 @@
+package st
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// Ultimate lateral strength (保有水平耐力, Building Standard Law Notification No.1792).
+//
+// Qu of each story is the story shear of the push-over analysis (ReadZoubun) at the first lap where any story drift reaches the target.
+// It is an error if no lap reaches the target.
+// It is compared with the required one
+//
+//	Qun = Ds Fes Qud
+//
+// where Qud is the story shear of Ai distribution with C0 = 1.0, and Fes = Fs Fe from the rigidity and the eccentricity of Frame.Fes (:fact).
+//
+// Ds is determined from the ranks of the member groups of the story.
+// Each member is ranked A to D:
+//
+//   - steel columns and girders (FA-FD): P-I-1, P-I-2, P-I -> A, P-II -> B, P-III -> C, P-IV -> D (BT_ratio_category)
+//   - steel braces (BA-BC): effective slenderness λ <= 495/√F -> A, λ <= 890/√F or λ >= 1980/√F -> B, otherwise C [F: N/mm2]
+//   - RC columns (FA-FD): h0/D, σ0/Fc, pt and τu/Fc at Qu, where h0 is the clear height without XFACE (or YFACE) of the section
+//   - RC girders (FA-FC): τu/Fc at Qu
+//   - RC walls (WA-WC): τu/Fc at Qu
+//   - CFT columns (FA-FD): D/t of the tube <= 1.5 times the limits of steel columns,
+//     33, 37, 48 √(235/F) for RPIPE and 50, 70, 100 (235/F) for CPIPE [F: N/mm2]
+//   - SRC columns and girders (FA-FD): the lower of the steel part and the RC part
+//
+// A story with SRC members takes Ds of RC, which is 0.05 larger than that of SRC.
+//
+// Braces which cannot be ranked are regarded as rank C.
+// The rank of a group is D if it has a member of rank D, A if γA >= 0.5 and γC <= 0.2, C if γC >= 0.5, and B otherwise,
+// where γA and γC are the ratios of the strength of members of rank A and C.
+// The strengths are the plastic moments for columns and girders, and the horizontal shears at Qu for braces and walls.
+// βu is the ratio of the horizontal shear carried by braces and walls to Qu.
+
+const (
+	RANK_A = iota
+	RANK_B
+	RANK_C
+	RANK_D
+)
+
+var RANKNAME = []string{"A", "B", "C", "D"}
+
+// DsSteel is Ds of steel stories: [FA-FD][βu=0 or BA, BB, BC][βu range].
+// The ranges of βu are (0.3, 0.7) for BB and (0.3, 0.5) for BC.
+var DsSteel = [][][]float64{
+	{{0.25, 0.25, 0.25}, {0.25, 0.30, 0.35}, {0.30, 0.35, 0.40}},
+	{{0.30, 0.30, 0.30}, {0.30, 0.30, 0.35}, {0.30, 0.35, 0.40}},
+	{{0.35, 0.35, 0.35}, {0.35, 0.35, 0.40}, {0.35, 0.40, 0.45}},
+	{{0.40, 0.40, 0.40}, {0.40, 0.45, 0.50}, {0.40, 0.45, 0.50}},
+}
+
+// DsRCFrame is Ds of RC stories without walls: [FA-FD].
+var DsRCFrame = []float64{0.30, 0.35, 0.40, 0.45}
+
+// DsRC is Ds of RC stories with walls: [FA-FD][WA-WD][βu <= 0.3, 0.3 < βu <= 0.7, 0.7 < βu].
+var DsRC = [][][]float64{
+	{{0.30, 0.35, 0.40}, {0.35, 0.40, 0.45}, {0.35, 0.45, 0.50}, {0.40, 0.50, 0.55}},
+	{{0.35, 0.35, 0.40}, {0.35, 0.40, 0.45}, {0.40, 0.45, 0.50}, {0.45, 0.50, 0.55}},
+	{{0.35, 0.40, 0.45}, {0.40, 0.45, 0.50}, {0.40, 0.45, 0.50}, {0.45, 0.50, 0.55}},
+	{{0.45, 0.45, 0.50}, {0.45, 0.50, 0.55}, {0.45, 0.50, 0.55}, {0.45, 0.50, 0.55}},
+}
+
+// SteelRank returns the rank of a steel column or girder.
+func SteelRank(sc *SColumn) int {
+	switch sc.Shape.BT_ratio_category(sc.Steel) {
+	case P_I_1, P_I_2, P_I:
+		return RANK_A
+	case P_II:
+		return RANK_B
+	case P_III:
+		return RANK_C
+	default:
+		return RANK_D
+	}
+}
+
+// BraceRank returns the rank of a steel brace from its effective slenderness.
+func (elem *Elem) BraceRank(sc *SColumn) int {
+	i := math.Sqrt(math.Min(sc.Ix(), sc.Iy()) / sc.A())
+	lambda := elem.Length() * 100.0 / i
+	f := math.Sqrt(sc.F * 98.0665) // [N/mm2]
+	switch {
+	case lambda <= 495.0/f:
+		return RANK_A
+	case lambda <= 890.0/f, lambda >= 1980.0/f:
+		return RANK_B
+	default:
+		return RANK_C
+	}
+}
+
+// RCRank returns the rank of an RC column or girder bending in the direction d (0: X, 1: Y) under the stress of period.
+func (elem *Elem) RCRank(rc *RCColumn, period string, d int) (int, error) {
+	axis := []float64{0.0, 0.0, 0.0}
+	axis[1-d] = 1.0
+	strong, err := elem.IsStrongAxis(axis)
+	if err != nil {
+		return RANK_D, err
+	}
+	b := rc.Breadth(strong)
+	dd := rc.Height(strong)
+	if b*dd == 0.0 || rc.fc == 0.0 {
+		return RANK_D, fmt.Errorf("RCRank: SECT %d: no section", elem.Sect.Num)
+	}
+	ind := 2
+	if !strong {
+		ind = 1
+	}
+	q := math.Max(math.Abs(elem.ReturnStress(period, 0, ind)), math.Abs(elem.ReturnStress(period, 1, ind)))
+	tau := q / (b * dd) / rc.fc
+	if elem.Etype != COLUMN {
+		switch {
+		case tau <= 0.15:
+			return RANK_A, nil
+		case tau <= 0.2:
+			return RANK_B, nil
+		default:
+			return RANK_C, nil
+		}
+	}
+	// clear height h0 between the faces of the girders
+	h0 := elem.Length() * 100.0
+	face := rc.XFace
+	if !strong {
+		face = rc.YFace
+	}
+	if len(face) >= 2 {
+		h0 -= face[0] + face[1]
+	}
+	hd := h0 / dd
+	n := math.Max(elem.ReturnStress(period, 0, 0), -elem.ReturnStress(period, 1, 0))
+	sigma := math.Max(n, 0.0) / (b * dd) / rc.fc
+	pt := rc.TensileReins(strong) / (b * dd)
+	switch {
+	case hd >= 2.5 && sigma <= 0.35 && pt <= 0.008 && tau <= 0.1:
+		return RANK_A, nil
+	case hd >= 2.0 && sigma <= 0.45 && pt <= 0.01 && tau <= 0.125:
+		return RANK_B, nil
+	case sigma <= 0.55 && tau <= 0.15:
+		return RANK_C, nil
+	default:
+		return RANK_D, nil
+	}
+}
+
+// TensileReins returns the area [cm2] of the reinforcements in the outer quarter of the section on the more reinforced side.
+func (rc *RCColumn) TensileReins(strong bool) float64 {
+	ind, lower, upper := 1, rc.Bound(1), rc.Bound(3)
+	if !strong {
+		ind, lower, upper = 0, rc.Bound(0), rc.Bound(2)
+	}
+	d := 0.25 * (upper - lower)
+	at := []float64{0.0, 0.0}
+	for _, r := range rc.Reins {
+		if r.Position[ind] <= lower+d {
+			at[0] += r.Area
+		} else if r.Position[ind] >= upper-d {
+			at[1] += r.Area
+		}
+	}
+	return math.Max(at[0], at[1])
+}
+
+// CFTRank returns the rank of a CFT column from the width-thickness ratio of the tube.
+func CFTRank(cf *CFTColumn) int {
+	var dt float64
+	var limits []float64
+	f := cf.Tube.F * 98.0665 // [N/mm2]
+	switch sh := cf.Tube.Shape.(type) {
+	case RPIPE:
+		dt = math.Max(sh.B/sh.Tf, sh.H/sh.Tw)
+		limits = []float64{33.0, 37.0, 48.0}
+		for i := range limits {
+			limits[i] *= math.Sqrt(235.0 / f)
+		}
+	case CPIPE:
+		dt = sh.D / sh.T
+		limits = []float64{50.0, 70.0, 100.0}
+		for i := range limits {
+			limits[i] *= 235.0 / f
+		}
+	default:
+		return RANK_D
+	}
+	for i, lim := range limits {
+		if dt <= 1.5*lim {
+			return i
+		}
+	}
+	return RANK_D
+}
+
+// SRCRank returns the rank of an SRC column or girder, which is the lower of the steel part and the RC part.
+func (elem *Elem) SRCRank(src *SRCColumn, period string, d int) (int, error) {
+	r, err := elem.RCRank(src.RC(), period, d)
+	if err != nil {
+		return RANK_D, err
+	}
+	if s := SteelRank(src.SPart); s > r {
+		return s, nil
+	}
+	return r, nil
+}
+
+// WallRank returns the rank of an RC wall from the horizontal shear q [tf] in it.
+func (elem *Elem) WallRank(rw *RCWall, q float64) (int, error) {
+	l := 0.0
+	for i := 0; i < elem.Enods; i++ {
+		for j := i + 1; j < elem.Enods; j++ {
+			l = math.Max(l, math.Hypot(elem.Enod[i].Coord[0]-elem.Enod[j].Coord[0], elem.Enod[i].Coord[1]-elem.Enod[j].Coord[1]))
+		}
+	}
+	if l*rw.Thick == 0.0 || rw.fc == 0.0 {
+		return RANK_D, fmt.Errorf("WallRank: ELEM %d: no section", elem.Num)
+	}
+	tau := math.Abs(q) / (l * 100.0 * rw.Thick) / rw.fc
+	switch {
+	case tau <= 0.2:
+		return RANK_A, nil
+	case tau <= 0.25:
+		return RANK_B, nil
+	default:
+		return RANK_C, nil
+	}
+}
+
+// GroupRank returns the rank of a member group from the ranks and the strengths of the members.
+func GroupRank(ranks []int, weights []float64) int {
+	sum := make([]float64, 4)
+	total := 0.0
+	for i, r := range ranks {
+		if r == RANK_D {
+			return RANK_D
+		}
+		sum[r] += weights[i]
+		total += weights[i]
+	}
+	if total == 0.0 {
+		return RANK_A
+	}
+	switch {
+	case sum[RANK_A]/total >= 0.5 && sum[RANK_C]/total <= 0.2:
+		return RANK_A
+	case sum[RANK_C]/total >= 0.5:
+		return RANK_C
+	default:
+		return RANK_B
+	}
+}
+
+// StructuralCharacteristic returns Ds from the ranks of the groups and βu.
+// brace is the rank of braces or walls, -1 if there is none.
+func StructuralCharacteristic(rc bool, frame, brace int, betau float64) float64 {
+	if rc {
+		if brace < 0 || betau == 0.0 {
+			return DsRCFrame[frame]
+		}
+		switch {
+		case betau <= 0.3:
+			return DsRC[frame][brace][0]
+		case betau <= 0.7:
+			return DsRC[frame][brace][1]
+		default:
+			return DsRC[frame][brace][2]
+		}
+	}
+	if brace <= RANK_A || betau == 0.0 {
+		return DsSteel[frame][0][0]
+	}
+	bound := 0.7
+	if brace >= RANK_C {
+		brace = RANK_C
+		bound = 0.5
+	}
+	switch {
+	case betau <= 0.3:
+		return DsSteel[frame][brace][0]
+	case betau <= bound:
+		return DsSteel[frame][brace][1]
+	default:
+		return DsSteel[frame][brace][2]
+	}
+}
+
+// Fs returns the shape factor of story i in the direction d from the rigidity.
+func (f *Fact) Fs(i, d int) float64 {
+	rs := f.Rigidity[i][d]
+	if rs >= 0.6 {
+		return 1.0
+	}
+	return 2.0 - rs/0.6
+}
+
+// Fe returns the shape factor of story i in the direction d from the eccentricity.
+func (f *Fact) Fe(i, d int) float64 {
+	re := f.Eccentricity[i][d]
+	switch {
+	case re <= 0.15:
+		return 1.0
+	case re >= 0.45:
+		return 1.5
+	default:
+		return 1.0 + 0.5*(re-0.15)/0.3
+	}
+}
+
+// UltimateStory is the ultimate lateral strength of a story in a direction.
+type UltimateStory struct {
+	Story     int
+	Direction int // 0: X, 1: Y
+	RC        bool
+	FrameRank int
+	BraceRank int // -1 if there is no brace nor wall
+	Betau     float64
+	Ds        float64
+	Fs        float64
+	Fe        float64
+	Qud       float64 // [tf]
+	Qun       float64 // [tf]
+	Qu        float64 // [tf]
+	Drift     float64
+}
+
+func (us *UltimateStory) Rate() float64 {
+	if us.Qun == 0.0 {
+		return 0.0
+	}
+	return us.Qu / us.Qun
+}
+
+// UltimateStrength returns the ultimate lateral strengths of the stories with the push-over result of period,
+// the ranks of the members and the lap of Qu.
+func (frame *Frame) UltimateStrength(period string, drift float64) ([]*UltimateStory, map[*Elem]int, int, error) {
+	nlap, ok := frame.Nlap[period]
+	if !ok || nlap == 0 {
+		return nil, nil, 0, fmt.Errorf("UltimateStrength: zoubun result not found for period %s", period)
+	}
+	if frame.Fes == nil || !frame.Fes.Calced {
+		return nil, nil, 0, errors.New("UltimateStrength: Fes is not calculated")
+	}
+	var d int
+	switch period {
+	default:
+		return nil, nil, 0, fmt.Errorf("UltimateStrength: unknown period %s: X or Y", period)
+	case "X":
+		d = 0
+	case "Y":
+		d = 1
+	}
+	l := frame.Ai.Nfloor
+	if l < 2 {
+		return nil, nil, 0, errors.New("UltimateStrength: Nfloor < 2")
+	}
+	if len(frame.Fes.Rigidity) < l-1 || len(frame.Fes.Eccentricity) < l-1 {
+		return nil, nil, 0, fmt.Errorf("UltimateStrength: Fes has %d stories, want %d", len(frame.Fes.Rigidity), l-1)
+	}
+	for i := 0; i < l-1; i++ {
+		if len(frame.Fes.Rigidity[i]) <= d || len(frame.Fes.Eccentricity[i]) <= d {
+			return nil, nil, 0, fmt.Errorf("UltimateStrength: %dF: Fes is not calculated", i+1)
+		}
+	}
+	if len(frame.Ai.Qi[d]) != l {
+		_, _, err := frame.AiDistribution()
+		if err != nil {
+			return nil, nil, 0, err
+		}
+	}
+	nodes := frame.FloorNodes(nil, nil)
+	elems := frame.FloorElems([]int{COLUMN, GIRDER, BRACE, WBRACE, SBRACE}, nil, nil)
+	var nper string
+	var drifts, shears []float64
+	lap := 0
+	reached := false
+	for lap < nlap && !reached {
+		lap++
+		nper = fmt.Sprintf("%s@%d", period, lap)
+		err := frame.LoadLap(nper)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		drifts, shears = frame.StoryDriftShear(nper, d, nodes, elems)
+		for _, dr := range drifts {
+			if math.Abs(dr) >= drift {
+				reached = true
+				break
+			}
+		}
+	}
+	if !reached {
+		return nil, nil, 0, fmt.Errorf("UltimateStrength: story drift doesn't reach 1/%.0f in %d laps of period %s", 1.0/drift, nlap, period)
+	}
+	axis := []float64{0.0, 0.0, 0.0}
+	axis[d] = 1.0
+	normal := []float64{0.0, 0.0, 0.0}
+	normal[1-d] = 1.0
+	cos45 := 0.5 * math.Sqrt(2.0)
+	ranks := make(map[*Elem]int)
+	rtn := make([]*UltimateStory, l-1)
+	for i := 0; i < l-1; i++ {
+		us := &UltimateStory{
+			Story:     i,
+			Direction: d,
+			BraceRank: -1,
+			Qu:        shears[i],
+			Drift:     drifts[i],
+		}
+		franks := make([]int, 0)
+		fweights := make([]float64, 0)
+		branks := make([]int, 0)
+		bweights := make([]float64, 0)
+		walls := make(map[*Elem]float64)
+		qb := 0.0
+		addframe := func(el *Elem) error {
+			var r int
+			var err error
+			switch al := el.Sect.Allow.(type) {
+			case *SColumn:
+				r = SteelRank(al)
+			case *SGirder:
+				r = SteelRank(&al.SColumn)
+			case *RCColumn:
+				us.RC = true
+				r, err = el.RCRank(al, nper, d)
+			case *RCGirder:
+				us.RC = true
+				r, err = el.RCRank(&al.RCColumn, nper, d)
+			case *CFTColumn:
+				r = CFTRank(al)
+			case *SRCColumn:
+				us.RC = true
+				r, err = el.SRCRank(al, nper, d)
+			case *SRCGirder:
+				us.RC = true
+				r, err = el.SRCRank(&al.SRCColumn, nper, d)
+			default:
+				return fmt.Errorf("UltimateStrength: ELEM %d: unknown section type", el.Num)
+			}
+			if err != nil {
+				return err
+			}
+			mp, err := el.PlasticMoment(normal, 0.0)
+			if err != nil {
+				return err
+			}
+			ranks[el] = r
+			franks = append(franks, r)
+			fweights = append(fweights, mp)
+			return nil
+		}
+		for _, el := range elems[i] {
+			switch el.Etype {
+			case COLUMN:
+				err := addframe(el)
+				if err != nil {
+					return nil, nil, 0, err
+				}
+			case BRACE, WBRACE:
+				q := -el.VectorStress(nper, 0, axis)
+				qb += q
+				if el.Parent != nil {
+					if _, ok := el.Parent.Sect.Allow.(*RCWall); ok {
+						walls[el.Parent] += q
+						continue
+					}
+				}
+				r := RANK_C
+				if al, ok := el.Sect.Allow.(*SBrace); ok {
+					r = el.BraceRank(&al.SColumn)
+				}
+				ranks[el] = r
+				branks = append(branks, r)
+				bweights = append(bweights, math.Abs(q))
+			}
+		}
+		for _, el := range frame.Elems {
+			if el.Etype != GIRDER || !el.IsLineElem() {
+				continue
+			}
+			z0 := el.Enod[0].Coord[2]
+			z1 := el.Enod[1].Coord[2]
+			if z0 < frame.Ai.Boundary[i+1] || z0 >= frame.Ai.Boundary[i+2] || z1 < frame.Ai.Boundary[i+1] || z1 >= frame.Ai.Boundary[i+2] {
+				continue
+			}
+			dir := el.Direction(true)
+			h := math.Hypot(dir[0], dir[1])
+			if h == 0.0 || math.Abs(dir[d])/h < cos45 {
+				continue
+			}
+			err := addframe(el)
+			if err != nil {
+				return nil, nil, 0, err
+			}
+		}
+		for el, q := range walls {
+			r, err := el.WallRank(el.Sect.Allow.(*RCWall), q)
+			if err != nil {
+				return nil, nil, 0, err
+			}
+			us.RC = true
+			ranks[el] = r
+			branks = append(branks, r)
+			bweights = append(bweights, math.Abs(q))
+		}
+		us.FrameRank = GroupRank(franks, fweights)
+		if len(branks) > 0 {
+			us.BraceRank = GroupRank(branks, bweights)
+		}
+		if us.Qu > 0.0 {
+			us.Betau = math.Min(math.Max(qb/us.Qu, 0.0), 1.0)
+		}
+		us.Ds = StructuralCharacteristic(us.RC, us.FrameRank, us.BraceRank, us.Betau)
+		us.Fs = frame.Fes.Fs(i, d)
+		us.Fe = frame.Fes.Fe(i, d)
+		us.Qud = frame.Ai.Qi[d][i+1] / frame.Ai.Base[d]
+		if !(us.Qud > 0.0) {
+			return nil, nil, 0, fmt.Errorf("UltimateStrength: %dF: Qud = %.3f", i+1, us.Qud)
+		}
+		us.Qun = us.Ds * us.Fs * us.Fe * us.Qud
+		rtn[i] = us
+	}
+	return rtn, ranks, lap, nil
+}
+
+// UltimateStrengthCheck checks Qu >= Qun of all stories in periods and writes the result to Ce(fn, ".qun").
+func (frame *Frame) UltimateStrengthCheck(fn string, periods []string, drift float64) error {
+	var otp bytes.Buffer
+	otp.WriteString("保有水平耐力の検討\n")
+	otp.WriteString(fmt.Sprintf("Qu >= Qun = Ds Fes Qud  Qu: 層間変形角 1/%.0f 時の層せん断力\n", 1.0/drift))
+	ng := 0
+	minrate := 1e16
+	for _, per := range periods {
+		uss, ranks, lap, err := frame.UltimateStrength(per, drift)
+		if err != nil {
+			return err
+		}
+		otp.WriteString(fmt.Sprintf("\n%s方向 LAP: %d/%d\n", per, lap, frame.Nlap[per]))
+		otp.WriteString(" 階 構造 柱梁 筋かい    βu    Ds    Fs    Fe   Fes    Qud[tf]    Qun[tf]     Qu[tf] 層間変形角  Qu/Qun 判定\n")
+		for i := len(uss) - 1; i >= 0; i-- {
+			us := uss[i]
+			st := "S "
+			bt := "B"
+			if us.RC {
+				st = "RC"
+				bt = "W"
+			}
+			brace := "  -"
+			if us.BraceRank >= 0 {
+				brace = " " + bt + RANKNAME[us.BraceRank]
+			}
+			judge := "OK"
+			rate := us.Rate()
+			if rate < 1.0 {
+				judge = "NG"
+				ng++
+			}
+			if rate < minrate {
+				minrate = rate
+			}
+			dr := "         -"
+			if us.Drift != 0.0 {
+				dr = fmt.Sprintf("  1/%6.0f", 1.0/math.Abs(us.Drift))
+			}
+			otp.WriteString(fmt.Sprintf("%2dF   %s   F%s    %s %5.3f %5.3f %5.3f %5.3f %5.3f %10.3f %10.3f %10.3f %s %7.3f %s\n", i+1, st, RANKNAME[us.FrameRank], brace, us.Betau, us.Ds, us.Fs, us.Fe, us.Fs*us.Fe, us.Qud, us.Qun, us.Qu, dr, rate, judge))
+		}
+		els := make([]*Elem, 0, len(ranks))
+		for el := range ranks {
+			els = append(els, el)
+		}
+		sort.Sort(ElemByNum{els})
+		otp.WriteString("\n部材種別\n ELEM SECT ETYPE  種別\n")
+		for _, el := range els {
+			var k string
+			switch el.Etype {
+			case COLUMN, GIRDER:
+				k = "F"
+			case WALL:
+				k = "W"
+			default:
+				k = "B"
+			}
+			otp.WriteString(fmt.Sprintf("%5d %4d %-6s %s%s\n", el.Num, el.Sect.Num, ETYPES[el.Etype], k, RANKNAME[ranks[el]]))
+		}
+	}
+	otp.WriteString(fmt.Sprintf("\nQu/Qunの最小値: %.3f NG: %d\n", minrate, ng))
+	w, err := os.Create(Ce(fn, ".qun"))
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	otp = AddCR(otp)
+	otp.WriteTo(w)
+	return nil
+}
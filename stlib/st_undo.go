@@ -48,6 +48,15 @@ func (u *UndoStack) Undo() (*Frame, error) {
 	return u.stack[u.position].Snapshot(), nil
 }
 
+// UndoFrame returns a copy of the frame num steps back in the stack without undoing.
+func (u *UndoStack) UndoFrame(num int) (*Frame, error) {
+	ind := u.position + num
+	if num < 0 || ind >= u.size || u.stack[ind] == nil {
+		return nil, fmt.Errorf("no frame %d steps back", num)
+	}
+	return u.stack[ind].Snapshot(), nil
+}
+
 func (u *UndoStack) UseUndo(yes bool) {
 	u.enabled = yes
 }
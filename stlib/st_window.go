@@ -150,15 +150,27 @@ func OpenFile(stw Window, filename string, readrcfile bool) error {
 		if err != nil {
 			return err
 		}
+	case ".json":
+		err = newframe.ReadJson(fn)
+		if err != nil {
+			return err
+		}
 	case ".dxf":
 		err = newframe.ReadDxf(fn, []float64{0.0, 0.0, 0.0}, stw.EPS())
 		if err != nil {
 			return err
 		}
 		newframe.SetFocus(nil)
+	case ".ifc":
+		err = newframe.ReadIfc(fn, stw.EPS())
+		if err != nil {
+			return err
+		}
+		newframe.SetFocus(nil)
 	}
 	stw.SetFrame(newframe)
 	frame = stw.Frame()
+	SetPeriodCompletion(frame.PeriodNames())
 	if s != nil {
 		frame.Show = s
 		for snum := range frame.Sects {
@@ -227,7 +239,13 @@ func SaveFile(stw Window, filename string) error {
 		scale := math.Min(float64(w)/(xmax-xmin), float64(h)/(ymax-ymin)) * stw.CanvasFitScale()
 		frame.View.Dists[1] *= scale
 	}
-	err := frame.WriteInp(filename)
+	var err error
+	switch filepath.Ext(filename) {
+	case ".json":
+		err = frame.WriteJson(filename, false)
+	default:
+		err = frame.WriteInp(filename)
+	}
 	if v != nil {
 		frame.View = v
 	}
@@ -242,15 +260,27 @@ func SaveFile(stw Window, filename string) error {
 func ReadFile(stw Window, filename string) error {
 	var err error
 	frame := stw.Frame()
+	switch {
+	case frame.IsInputFile(filename):
+		return frame.ReadData(filename)
+	case frame.IsOutputFile(filename):
+		return frame.ReadResult(filename, UpdateResult)
+	}
 	switch filepath.Ext(filename) {
 	default:
 		return fmt.Errorf("Unknown Format")
 	case ".inp":
 		err = frame.ReadInp(filename, []float64{0.0, 0.0, 0.0}, 0.0, false)
-	case ".inl", ".ihx", ".ihy":
-		err = frame.ReadData(filename)
-	case ".otl", ".ohx", ".ohy":
-		err = frame.ReadResult(filename, UpdateResult)
+	case ".json":
+		err = frame.ReadJson(filename)
+	case ".ifc":
+		err = frame.ReadIfc(filename, stw.EPS())
+	case ".s2k", ".$2k", ".tcl":
+		var messages []string
+		messages, err = frame.ImportModel(filename, stw.EPS(), "")
+		for _, m := range messages {
+			stw.History(m)
+		}
 	case ".rat", ".rat2":
 		err = frame.ReadRat(filename)
 	case ".lst":
@@ -263,6 +293,8 @@ func ReadFile(stw Window, filename string) error {
 		err = frame.ReadBuckling(filename)
 	case ".otx", ".oty", ".inc":
 		err = frame.ReadZoubun(filename)
+	case ResultExt:
+		err = frame.OpenResultStore(filename)
 	}
 	if err != nil {
 		return err
@@ -284,8 +316,9 @@ func ReadAll(stw Window) {
 			el.Children = make([]*Elem, 2)
 		}
 	}
-	exts := []string{".inl", ".ihx", ".ihy", ".otl", ".ohx", ".ohy", ".rat2", ".wgt", ".lst", ".kjn"}
-	read := make([]string, 10)
+	exts := append(frame.InputExts(), frame.OutputExts()...)
+	exts = append(exts, ".rat2", ".wgt", ".lst", ".kjn")
+	read := make([]string, len(exts))
 	nread := 0
 	for _, ext := range exts {
 		name := Ce(frame.Path, ext)
@@ -800,6 +833,9 @@ func SetPeriod(stw Window, per string) {
 	if frame == nil {
 		return
 	}
+	if err := frame.LoadLap(per); err != nil {
+		ErrorMessage(stw, err, ERROR)
+	}
 	frame.Show.Period = per
 	stw.SetLabel("PERIOD", per)
 }
@@ -812,6 +848,9 @@ func IncrementPeriod(stw Window, num int) {
 	pat := regexp.MustCompile("([a-zA-Z]+)(@[0-9]+)")
 	fs := pat.FindStringSubmatch(frame.Show.Period)
 	if len(fs) < 3 {
+		if per := frame.NextPeriod(frame.Show.Period, num); per != frame.Show.Period {
+			SetPeriod(stw, per)
+		}
 		return
 	}
 	if nl, ok := frame.Nlap[strings.ToUpper(fs[1])]; ok {